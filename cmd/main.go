@@ -1,22 +1,24 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"net"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/nullexp/limiter-x/internal/adapter/driven/bolt"
 	"github.com/nullexp/limiter-x/internal/adapter/driven/cache"
 	drivenDb "github.com/nullexp/limiter-x/internal/adapter/driven/db"
-	repository "github.com/nullexp/limiter-x/internal/adapter/driven/db/repository"
+	"github.com/nullexp/limiter-x/internal/port/driven"
 
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
 
 	grpcDriver "github.com/nullexp/limiter-x/internal/adapter/driver/grpc"
 	ratev1 "github.com/nullexp/limiter-x/internal/adapter/driver/grpc/proto/rate/v1"
@@ -27,31 +29,21 @@ import (
 )
 
 func main() {
-	log.Println("Starting the server")
-
 	err := godotenv.Load()
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
 
-	dsn := "host=" + os.Getenv("DB_HOST") +
-		" user=" + os.Getenv("DB_USER") +
-		" password=" + os.Getenv("DB_PASSWORD") +
-		" dbname=" + os.Getenv("DB_NAME") +
-		" port=" + os.Getenv("DB_PORT") +
-		" sslmode=disable"
-
-	log.Println("DSN:", dsn)
-
-	db, err := sql.Open("postgres", dsn)
-	if err != nil {
-		log.Fatalf("failed to connect database: %v", err)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
-	err = db.Ping()
-	if err != nil {
-		log.Fatalf("failed to ping database: %v", err)
-	}
+	log.Println("Starting the server")
+
+	store := openStorage()
 
 	port := os.Getenv("APP_PORT")
 	ip := os.Getenv("APP_IP")
@@ -67,29 +59,160 @@ func main() {
 	log.Println("successfully initialized")
 
 	// Create a new gRPC server
-	s := grpc.NewServer()
+	s := grpc.NewServer(grpc.UnaryInterceptor(grpcDriver.ActorInterceptor))
 
 	// Register the Greeter service
 
-	txFactory := drivenDb.NewPostgresDbTransactionFactory(db)
-	rateRepoFactory := repository.NewUserRateLimitRepositoryFactory()
-	cache := cache.NewRedisClient("", "", "redis", os.Getenv("REDIS_URL"))
-	cache.Connect()
+	failurePolicy, err := driver.ParseFailurePolicy(os.Getenv("CACHE_FAILURE_POLICY"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	serviceOptions := []driver.Option{driver.WithFailurePolicy(failurePolicy, envInt("EXPECTED_INSTANCES", 1))}
+	if leaseDuration := envMilliseconds("LEASE_MILI_SEC", 0); leaseDuration > 0 {
+		serviceOptions = append(serviceOptions, driver.WithLeaseDuration(leaseDuration))
+	}
+	isolation, err := drivenDb.ParseIsolationLevel(os.Getenv("DB_ISOLATION"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if isolation != sql.LevelDefault {
+		serviceOptions = append(serviceOptions, driver.WithIsolation(isolation))
+	}
+	if envBool("DB_ROW_LOCKING", false) {
+		// Count in the database with the user's row locked, the cache only keeps leases
+		serviceOptions = append(serviceOptions, driver.WithRowLocking())
+	}
+	retryPolicy := driver.DefaultRetryPolicy
+	retryPolicy.MaxAttempts = envInt("DB_RETRY_ATTEMPTS", retryPolicy.MaxAttempts)
+	retryPolicy.MaxDelay = envMilliseconds("DB_RETRY_MAX_DELAY_MILI_SEC", retryPolicy.MaxDelay)
+	serviceOptions = append(serviceOptions, driver.WithRetryPolicy(retryPolicy))
+	// Limit changes are always audited, denials only as a sample
+	serviceOptions = append(serviceOptions, driver.WithAuditLog(store.auditRepoFactory, envFloat("AUDIT_DENY_SAMPLE_RATE", 0.01)))
+	if usageFlush := envMilliseconds("USAGE_FLUSH_INTERVAL_MILI_SEC", 10*time.Second); usageFlush > 0 {
+		retention := driver.UsageRetention{
+			Hourly: time.Duration(envInt("USAGE_HOURLY_RETENTION_HOURS", int(driver.DefaultUsageRetention.Hourly/time.Hour))) * time.Hour,
+			Daily:  time.Duration(envInt("USAGE_DAILY_RETENTION_DAYS", int(driver.DefaultUsageRetention.Daily/(24*time.Hour)))) * 24 * time.Hour,
+		}
+		serviceOptions = append(serviceOptions, driver.WithUsageHistory(store.usageRepoFactory, usageFlush, retention))
+	}
+	// Quotas are read from the database on every check, so they are opt-in
+	if envBool("QUOTAS_ENABLED", false) {
+		serviceOptions = append(serviceOptions, driver.WithQuotas(store.quotaRepoFactory))
+	}
+	policyCacheTTL := envMilliseconds("POLICY_CACHE_TTL_MILI_SEC", 10*time.Second)
+	if envBool("POLICIES_ENABLED", false) {
+		serviceOptions = append(serviceOptions, driver.WithPolicies(store.policyRepoFactory, policyCacheTTL))
+	}
+	// The pools of a hierarchy are the policies of the ancestors
+	if envBool("HIERARCHY_ENABLED", false) {
+		if !envBool("POLICIES_ENABLED", false) {
+			log.Fatal("HIERARCHY_ENABLED requires POLICIES_ENABLED")
+		}
+		serviceOptions = append(serviceOptions, driver.WithHierarchy(store.parentRepoFactory, policyCacheTTL))
+	}
+	serviceOptions = append(serviceOptions, driver.WithDefaultLimit(envInt("DEFAULT_RATE_LIMIT", 100)))
+	if envBool("TIERS_ENABLED", false) {
+		serviceOptions = append(serviceOptions, driver.WithTiers(store.tierRepoFactory, envMilliseconds("TIER_CACHE_TTL_MILI_SEC", 10*time.Second)))
+	}
+	if envBool("ACCESS_LISTS_ENABLED", false) {
+		serviceOptions = append(serviceOptions, driver.WithAccessLists(store.accessRepoFactory, envMilliseconds("ACCESS_CACHE_TTL_MILI_SEC", 10*time.Second)))
+	}
+	if envBool("PENALTY_BOX_ENABLED", false) {
+		penaltyPolicy := driver.DefaultPenaltyPolicy
+		penaltyPolicy.Threshold = envInt("PENALTY_THRESHOLD", penaltyPolicy.Threshold)
+		penaltyPolicy.Window = envMilliseconds("PENALTY_WINDOW_MILI_SEC", penaltyPolicy.Window)
+		serviceOptions = append(serviceOptions, driver.WithPenaltyBox(penaltyPolicy))
+	}
 
 	windowMilSecond, err := strconv.Atoi(os.Getenv("WINDOW_MILI_SEC"))
 	if err != nil {
 		log.Fatal(err)
 	}
-	rateService := driver.NewRateLimitService(rateRepoFactory, cache, txFactory, time.Duration(windowMilSecond)*time.Millisecond)
+	window := time.Duration(windowMilSecond) * time.Millisecond
+	// Users and checks may use windows of their own up to this long, WINDOW_MILI_SEC by default
+	if maxWindow := envMilliseconds("MAX_WINDOW_MILI_SEC", 0); maxWindow > 0 {
+		serviceOptions = append(serviceOptions, driver.WithMaxWindow(maxWindow))
+	}
+
+	var backend driven.Cache
+	var pubsub driven.PubSub
+	cacheBackend := os.Getenv("CACHE_BACKEND")
+	if cacheBackend == "" && store.bolt != nil {
+		cacheBackend = "bolt"
+	}
+	switch cacheBackend {
+	case "", "redis":
+		redisConfig, err := cache.LoadRedisConfigFromEnv()
+		if err != nil {
+			log.Fatal(err)
+		}
+		backend, err = cache.NewRedisClientFromConfig(redisConfig)
+		if err != nil {
+			log.Fatal(err)
+		}
+		pubsub = backend.(driven.PubSub)
+	case "memcached":
+		backend = cache.NewMemcachedClient(strings.Split(os.Getenv("MEMCACHED_SERVERS"), ",")...)
+	case "bolt":
+		if store.bolt == nil {
+			log.Fatal("the bolt cache backend needs DB_BACKEND=bolt")
+		}
+		backend = bolt.NewCache(store.bolt)
+	case "memory":
+		// Only suitable for a single instance
+		backend = cache.NewMemoryClient(window, window)
+	default:
+		log.Fatalf("unknown cache backend %q", cacheBackend)
+	}
+
+	rateCache := cache.NewCircuitBreaker(backend, envInt("CACHE_BREAKER_FAILURES", 5), envMilliseconds("CACHE_BREAKER_COOLDOWN_MILI_SEC", 5*time.Second))
+	if nearTTL := envMilliseconds("NEAR_CACHE_TTL_MILI_SEC", 0); nearTTL > 0 {
+		if pubsub == nil {
+			log.Fatal("the near cache needs the redis cache backend for invalidations")
+		}
+		// Keep known-denied users, bans and policies in-process, invalidated through Redis pub/sub
+		rateCache = cache.NewNearCache(cache.NewMemoryClient(nearTTL, nearTTL), rateCache, pubsub, "limiter-x:invalidate", nearTTL, driver.LocalKeyPrefixes()...)
+		serviceOptions = append(serviceOptions, driver.WithDeniedMarkers(nearTTL))
+	}
+	if envBool("OVERRIDES_ENABLED", false) {
+		// Changes reach the other instances through Redis pub/sub if available, and by polling
+		serviceOptions = append(serviceOptions, driver.WithOverrides(store.overrideRepoFactory, pubsub, envMilliseconds("OVERRIDE_POLL_INTERVAL_MILI_SEC", 5*time.Second)))
+	}
+	if err := rateCache.Connect(); err != nil {
+		// Keep serving, the failure policy covers requests until the cache recovers
+		log.Printf("failed to connect to cache: %v", err)
+	}
+
+	// Background work runs until the server is told to stop
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	rateService := driver.NewRateLimitService(store.rateRepoFactory, rateCache, store.txFactory, window, serviceOptions...)
+	usageFlushed := make(chan struct{})
+	go func() {
+		defer close(usageFlushed)
+		rateService.RunUsageHistory(ctx)
+	}()
+	go rateService.RunOverrides(ctx)
 	grpcService := grpcDriver.NewRateLimiterService(rateService)
 	ratev1.RegisterRateLimiterServiceServer(s, grpcService)
 
 	// Register reflection service on gRPC server.
 	reflection.Register(s)
 
+	// Finish the requests in flight once a signal arrives
+	go func() {
+		<-ctx.Done()
+		log.Println("shutting down")
+		s.GracefulStop()
+	}()
+
 	// Log and start the server
 	log.Printf("gRPC server listening on %s", addr)
 	if err := s.Serve(lis); err != nil {
 		log.Fatalf("failed to serve: %v", err)
 	}
+
+	// Wait for the last collected usage to be written
+	<-usageFlushed
 }
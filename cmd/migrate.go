@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4"
+	drivenDb "github.com/nullexp/limiter-x/internal/adapter/driven/db"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/db/migration"
+)
+
+const migrateUsage = "usage: migrate up | down [steps] | version"
+
+// runMigrate handles the migrate subcommand against the database configured by DB_BACKEND:
+// up applies every pending migration, down reverts the given number of migrations (one by
+// default) and version prints the applied schema version.
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	dialect, err := drivenDb.ParseDialect(os.Getenv("DB_BACKEND"))
+	if err != nil {
+		return err
+	}
+	migrator, err := migration.New(dialect, dataSourceName(dialect))
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	switch args[0] {
+	case "up":
+		if err := migrator.Up(); err != nil {
+			return err
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		if err := migrator.Down(steps); err != nil {
+			return err
+		}
+	case "version":
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	version, dirty, err := migrator.Version()
+	switch {
+	case errors.Is(err, migrate.ErrNilVersion):
+		log.Println("no migrations applied")
+	case err != nil:
+		return err
+	case dirty:
+		log.Printf("schema version %d (dirty, the last migration failed)", version)
+	default:
+		log.Printf("schema version %d", version)
+	}
+	return nil
+}
+
+// migrateUp applies pending migrations before the server starts.
+func migrateUp(dialect drivenDb.Dialect) error {
+	migrator, err := migration.New(dialect, dataSourceName(dialect))
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	return migrator.Up()
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/bolt"
+	drivenDb "github.com/nullexp/limiter-x/internal/adapter/driven/db"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/db/memory"
+	repository "github.com/nullexp/limiter-x/internal/adapter/driven/db/repository"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	portRepository "github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+// storage is the persistence the service runs on, picked with DB_BACKEND.
+type storage struct {
+	txFactory           db.DbTransactionFactory
+	rateRepoFactory     portRepository.UserRateLimitRepositoryFactory
+	auditRepoFactory    portRepository.AuditEventRepositoryFactory
+	usageRepoFactory    portRepository.UsageRepositoryFactory
+	quotaRepoFactory    portRepository.QuotaRepositoryFactory
+	policyRepoFactory   portRepository.PolicyRepositoryFactory
+	parentRepoFactory   portRepository.ParentRepositoryFactory
+	tierRepoFactory     portRepository.TierRepositoryFactory
+	accessRepoFactory   portRepository.AccessRuleRepositoryFactory
+	overrideRepoFactory portRepository.OverrideRepositoryFactory
+	// bolt is set for the embedded backend, which also serves as the default cache
+	bolt *bolt.Store
+}
+
+func openStorage() storage {
+	backend := os.Getenv("DB_BACKEND")
+	if backend == "memory" {
+		// Nothing survives a restart, only suitable for trying the service out
+		return storage{
+			txFactory:           memory.NewTransactionFactory(memory.NewStore()),
+			rateRepoFactory:     memory.NewUserRateLimitRepositoryFactory(),
+			auditRepoFactory:    memory.NewAuditEventRepositoryFactory(),
+			usageRepoFactory:    memory.NewUsageRepositoryFactory(),
+			quotaRepoFactory:    memory.NewQuotaRepositoryFactory(),
+			policyRepoFactory:   memory.NewPolicyRepositoryFactory(),
+			parentRepoFactory:   memory.NewParentRepositoryFactory(),
+			tierRepoFactory:     memory.NewTierRepositoryFactory(),
+			accessRepoFactory:   memory.NewAccessRuleRepositoryFactory(),
+			overrideRepoFactory: memory.NewOverrideRepositoryFactory(),
+		}
+	}
+	if backend == "bolt" {
+		store, err := bolt.Open(filepath.Join(dataDir(), "limiter.db"),
+			envMilliseconds("BOLT_EXPIRY_INTERVAL_MILI_SEC", time.Minute),
+			envMilliseconds("BOLT_COMPACTION_INTERVAL_MILI_SEC", 24*time.Hour))
+		if err != nil {
+			log.Fatalf("failed to open embedded store: %v", err)
+		}
+		return storage{
+			txFactory:           bolt.NewTransactionFactory(store),
+			rateRepoFactory:     bolt.NewUserRateLimitRepositoryFactory(),
+			auditRepoFactory:    bolt.NewAuditEventRepositoryFactory(),
+			usageRepoFactory:    bolt.NewUsageRepositoryFactory(),
+			quotaRepoFactory:    bolt.NewQuotaRepositoryFactory(),
+			policyRepoFactory:   bolt.NewPolicyRepositoryFactory(),
+			parentRepoFactory:   bolt.NewParentRepositoryFactory(),
+			tierRepoFactory:     bolt.NewTierRepositoryFactory(),
+			accessRepoFactory:   bolt.NewAccessRuleRepositoryFactory(),
+			overrideRepoFactory: bolt.NewOverrideRepositoryFactory(),
+			bolt:                store,
+		}
+	}
+
+	dialect, err := drivenDb.ParseDialect(backend)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// SQLite has no database container to run the migrations in, so it migrates by default
+	if envBool("DB_AUTO_MIGRATE", dialect == drivenDb.Sqlite) {
+		if err := migrateUp(dialect); err != nil {
+			log.Fatalf("failed to migrate database: %v", err)
+		}
+	}
+	sqlDb, err := drivenDb.Open(dialect, dataSourceName(dialect))
+	if err != nil {
+		log.Fatalf("failed to connect database: %v", err)
+	}
+	return storage{
+		txFactory:           drivenDb.NewDbTransactionFactory(dialect, sqlDb),
+		rateRepoFactory:     repository.NewUserRateLimitRepositoryFactoryFor(dialect),
+		auditRepoFactory:    repository.NewAuditEventRepositoryFactoryFor(dialect),
+		usageRepoFactory:    repository.NewUsageRepositoryFactoryFor(dialect),
+		quotaRepoFactory:    repository.NewQuotaRepositoryFactoryFor(dialect),
+		policyRepoFactory:   repository.NewPolicyRepositoryFactoryFor(dialect),
+		parentRepoFactory:   repository.NewParentRepositoryFactoryFor(dialect),
+		tierRepoFactory:     repository.NewTierRepositoryFactoryFor(dialect),
+		accessRepoFactory:   repository.NewAccessRuleRepositoryFactoryFor(dialect),
+		overrideRepoFactory: repository.NewOverrideRepositoryFactoryFor(dialect),
+	}
+}
+
+// dataSourceName builds the connection string for the dialect from the DB_* variables.
+func dataSourceName(dialect drivenDb.Dialect) string {
+	switch dialect {
+	case drivenDb.Sqlite:
+		return filepath.Join(dataDir(), "rates.sqlite")
+	case drivenDb.Mysql:
+		config := mysql.NewConfig()
+		config.User = os.Getenv("DB_USER")
+		config.Passwd = os.Getenv("DB_PASSWORD")
+		config.Net = "tcp"
+		config.Addr = net.JoinHostPort(os.Getenv("DB_HOST"), os.Getenv("DB_PORT"))
+		config.DBName = os.Getenv("DB_NAME")
+		config.ParseTime = true
+		config.Loc = time.UTC
+		return config.FormatDSN()
+	default:
+		return "host=" + os.Getenv("DB_HOST") +
+			" user=" + os.Getenv("DB_USER") +
+			" password=" + os.Getenv("DB_PASSWORD") +
+			" dbname=" + os.Getenv("DB_NAME") +
+			" port=" + os.Getenv("DB_PORT") +
+			" sslmode=disable"
+	}
+}
+
+// dataDir returns DATA_DIR, where the file-based backends keep their data, creating it if needed.
+func dataDir() string {
+	dir := os.Getenv("DATA_DIR")
+	if dir == "" {
+		dir = "data"
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		log.Fatalf("failed to create data directory: %v", err)
+	}
+	return dir
+}
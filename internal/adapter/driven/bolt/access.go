@@ -0,0 +1,95 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+type AccessRuleRepositoryFactory struct{}
+
+func NewAccessRuleRepositoryFactory() *AccessRuleRepositoryFactory {
+	return &AccessRuleRepositoryFactory{}
+}
+
+func (f *AccessRuleRepositoryFactory) New(handler db.DbHandler) repository.AccessRuleRepository {
+	tx, _ := handler.(*Transaction)
+	return &AccessRuleRepository{tx: tx}
+}
+
+// AccessRuleRepository stores each access rule under its ID.
+type AccessRuleRepository struct {
+	tx *Transaction
+}
+
+// SetAccessRule stores the rule, replacing the one with its ID
+func (ar *AccessRuleRepository) SetAccessRule(ctx context.Context, rule model.AccessRule) error {
+	if ar.tx == nil {
+		return ErrNotBoltTransaction
+	}
+
+	now := time.Now().UTC()
+	_, err := ar.tx.mergeValue(accessRulesBucket, []byte(rule.Id), func(current []byte) ([]byte, error) {
+		rule.CreatedAt, rule.UpdatedAt = now, now
+		if current != nil {
+			var existing model.AccessRule
+			if err := json.Unmarshal(current, &existing); err != nil {
+				return nil, err
+			}
+			rule.CreatedAt = existing.CreatedAt
+		}
+		return json.Marshal(rule)
+	})
+	return err
+}
+
+// ListAccessRules retrieves all rules. It does not see the transaction's own pending writes
+func (ar *AccessRuleRepository) ListAccessRules(ctx context.Context) ([]model.AccessRule, error) {
+	if ar.tx == nil {
+		return nil, ErrNotBoltTransaction
+	}
+
+	var rules []model.AccessRule
+	err := ar.tx.scanFrom(accessRulesBucket, nil, nil, func(_, value []byte) (bool, error) {
+		var rule model.AccessRule
+		if err := json.Unmarshal(value, &rule); err != nil {
+			return false, err
+		}
+		rules = append(rules, rule)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Rules are stored by ID
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Match != rules[j].Match {
+			return rules[i].Match < rules[j].Match
+		}
+		return rules[i].Pattern < rules[j].Pattern
+	})
+	return rules, nil
+}
+
+// DeleteAccessRule removes the rule with the ID
+func (ar *AccessRuleRepository) DeleteAccessRule(ctx context.Context, id string) error {
+	if ar.tx == nil {
+		return ErrNotBoltTransaction
+	}
+
+	data, err := ar.tx.get(accessRulesBucket, []byte(id))
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return domain.ErrAccessRuleNotFound
+	}
+	ar.tx.delete(accessRulesBucket, []byte(id))
+	return nil
+}
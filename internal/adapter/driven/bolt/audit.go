@@ -0,0 +1,75 @@
+package bolt
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+type AuditEventRepositoryFactory struct{}
+
+func NewAuditEventRepositoryFactory() *AuditEventRepositoryFactory {
+	return &AuditEventRepositoryFactory{}
+}
+
+func (f *AuditEventRepositoryFactory) New(handler db.DbHandler) repository.AuditEventRepository {
+	tx, _ := handler.(*Transaction)
+	return &AuditEventRepository{tx: tx}
+}
+
+// AuditEventRepository keys events by their big-endian creation time followed by the ID, so the
+// bucket keeps them in the order they happened.
+type AuditEventRepository struct {
+	tx *Transaction
+}
+
+// AppendEvent stores a new audit event with an auto-generated ID
+func (ar *AuditEventRepository) AppendEvent(ctx context.Context, event model.AuditEvent) error {
+	if ar.tx == nil {
+		return ErrNotBoltTransaction
+	}
+
+	event.Id = uuid.New().String()
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	event.CreatedAt = event.CreatedAt.UTC()
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	key := binary.BigEndian.AppendUint64(nil, uint64(event.CreatedAt.UnixNano()))
+	ar.tx.put(auditEventsBucket, append(key, event.Id...), data)
+	return nil
+}
+
+// ListEvents retrieves the audit events matching the filter, newest first
+func (ar *AuditEventRepository) ListEvents(ctx context.Context, filter model.AuditFilter) ([]model.AuditEvent, error) {
+	if ar.tx == nil {
+		return nil, ErrNotBoltTransaction
+	}
+
+	var events []model.AuditEvent
+	err := ar.tx.scanBackwards(auditEventsBucket, func(key, value []byte) (bool, error) {
+		var event model.AuditEvent
+		if err := json.Unmarshal(value, &event); err != nil {
+			return false, err
+		}
+		if !filter.From.IsZero() && event.CreatedAt.Before(filter.From) {
+			// Everything further back is older still
+			return false, nil
+		}
+		if filter.Matches(event) {
+			events = append(events, event)
+		}
+		return filter.Limit <= 0 || len(events) < filter.Limit, nil
+	})
+	return events, err
+}
@@ -0,0 +1,136 @@
+package bolt
+
+import (
+	"context"
+	"encoding/binary"
+	"strconv"
+	"time"
+
+	"github.com/nullexp/limiter-x/internal/port/driven"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Cache keeps cache entries in the store. Each value is prefixed with its expiry in Unix
+// nanoseconds, zero meaning it never expires; expired entries read as missing until the store
+// removes them.
+type Cache struct {
+	store *Store
+}
+
+func NewCache(store *Store) driven.Cache {
+	return &Cache{store: store}
+}
+
+// Connect does nothing, the store is opened and closed by its owner.
+func (c *Cache) Connect() error {
+	return nil
+}
+
+// Disconnect does nothing, the store is opened and closed by its owner.
+func (c *Cache) Disconnect() error {
+	return nil
+}
+
+func (c *Cache) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	return c.store.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(cacheBucket).Put([]byte(key), encodeEntry(value, expiresAt(expiration)))
+	})
+}
+
+func (c *Cache) Fetch(ctx context.Context, key string) (value []byte, err error) {
+	err = c.store.View(func(tx *bolt.Tx) error {
+		entry, ok := liveEntry(tx, key)
+		if !ok {
+			return driven.ErrCacheMissed
+		}
+		value = entry
+		return nil
+	})
+	return
+}
+
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	return c.store.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(cacheBucket).Delete([]byte(key))
+	})
+}
+
+func (c *Cache) Update(ctx context.Context, key string, expiration time.Duration, fn func(current []byte) ([]byte, error)) error {
+	return c.store.Update(func(tx *bolt.Tx) error {
+		current, _ := liveEntry(tx, key)
+		value, err := fn(current)
+		if err != nil || value == nil {
+			return err
+		}
+		return tx.Bucket(cacheBucket).Put([]byte(key), encodeEntry(value, expiresAt(expiration)))
+	})
+}
+
+func (c *Cache) Increment(ctx context.Context, key string, delta int64, expiration time.Duration) (counter int64, err error) {
+	err = c.store.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(cacheBucket)
+		deadline := expiresAt(expiration)
+		if stored := bucket.Get([]byte(key)); stored != nil {
+			value, storedDeadline := decodeEntry(stored)
+			if storedDeadline == 0 || time.Now().UnixNano() < storedDeadline {
+				// Keep the expiry of an existing counter
+				deadline = storedDeadline
+				if counter, err = strconv.ParseInt(string(value), 10, 64); err != nil {
+					return err
+				}
+			}
+		}
+
+		counter += delta
+		return bucket.Put([]byte(key), encodeEntry([]byte(strconv.FormatInt(counter, 10)), deadline))
+	})
+	return
+}
+
+// removeExpired deletes every expired cache entry.
+func (s *Store) removeExpired() error {
+	now := time.Now().UnixNano()
+	return s.Update(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(cacheBucket).Cursor()
+		for key, stored := cursor.First(); key != nil; key, stored = cursor.Next() {
+			if _, deadline := decodeEntry(stored); deadline != 0 && deadline <= now {
+				if err := cursor.Delete(); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// liveEntry returns a copy of the value under key unless it is missing or expired.
+func liveEntry(tx *bolt.Tx, key string) ([]byte, bool) {
+	stored := tx.Bucket(cacheBucket).Get([]byte(key))
+	if stored == nil {
+		return nil, false
+	}
+	value, deadline := decodeEntry(stored)
+	if deadline != 0 && time.Now().UnixNano() >= deadline {
+		return nil, false
+	}
+	// Values handed out by bbolt are only valid during the transaction
+	return append([]byte{}, value...), true
+}
+
+func expiresAt(expiration time.Duration) int64 {
+	if expiration <= 0 {
+		return 0
+	}
+	return time.Now().Add(expiration).UnixNano()
+}
+
+func encodeEntry(value []byte, deadline int64) []byte {
+	entry := make([]byte, 8+len(value))
+	binary.BigEndian.PutUint64(entry, uint64(deadline))
+	copy(entry[8:], value)
+	return entry
+}
+
+func decodeEntry(entry []byte) ([]byte, int64) {
+	return entry[8:], int64(binary.BigEndian.Uint64(entry))
+}
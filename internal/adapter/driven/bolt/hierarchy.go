@@ -0,0 +1,104 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+type ParentRepositoryFactory struct{}
+
+func NewParentRepositoryFactory() *ParentRepositoryFactory {
+	return &ParentRepositoryFactory{}
+}
+
+func (f *ParentRepositoryFactory) New(handler db.DbHandler) repository.ParentRepository {
+	tx, _ := handler.(*Transaction)
+	return &ParentRepository{tx: tx}
+}
+
+// ParentRepository stores the parent link of each key under the key.
+type ParentRepository struct {
+	tx *Transaction
+}
+
+// SetParent stores the parent of the key, replacing the one it had
+func (pr *ParentRepository) SetParent(ctx context.Context, key, parent string) error {
+	if pr.tx == nil {
+		return ErrNotBoltTransaction
+	}
+
+	now := time.Now().UTC()
+	_, err := pr.tx.mergeValue(keyParentsBucket, []byte(key), func(current []byte) ([]byte, error) {
+		link := model.KeyParent{Key: key, Parent: parent, CreatedAt: now, UpdatedAt: now}
+		if current != nil {
+			var existing model.KeyParent
+			if err := json.Unmarshal(current, &existing); err != nil {
+				return nil, err
+			}
+			link.CreatedAt = existing.CreatedAt
+		}
+		return json.Marshal(link)
+	})
+	return err
+}
+
+// GetParent retrieves the parent link of the key
+func (pr *ParentRepository) GetParent(ctx context.Context, key string) (*model.KeyParent, error) {
+	if pr.tx == nil {
+		return nil, ErrNotBoltTransaction
+	}
+
+	data, err := pr.tx.get(keyParentsBucket, []byte(key))
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var link model.KeyParent
+	if err := json.Unmarshal(data, &link); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// ListChildren retrieves the links of the keys whose parent is the given key. Links are stored by
+// child, so this reads all of them, and it does not see the transaction's own pending writes
+func (pr *ParentRepository) ListChildren(ctx context.Context, parent string) ([]model.KeyParent, error) {
+	if pr.tx == nil {
+		return nil, ErrNotBoltTransaction
+	}
+
+	var children []model.KeyParent
+	err := pr.tx.scanFrom(keyParentsBucket, nil, nil, func(_, value []byte) (bool, error) {
+		var link model.KeyParent
+		if err := json.Unmarshal(value, &link); err != nil {
+			return false, err
+		}
+		if link.Parent == parent {
+			children = append(children, link)
+		}
+		return true, nil
+	})
+	return children, err
+}
+
+// DeleteParent removes the parent of the key
+func (pr *ParentRepository) DeleteParent(ctx context.Context, key string) error {
+	if pr.tx == nil {
+		return ErrNotBoltTransaction
+	}
+
+	data, err := pr.tx.get(keyParentsBucket, []byte(key))
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return domain.ErrParentNotFound
+	}
+	pr.tx.delete(keyParentsBucket, []byte(key))
+	return nil
+}
@@ -0,0 +1,86 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+// globalOverrideKey is the key of the global override.
+var globalOverrideKey = []byte("global")
+
+type OverrideRepositoryFactory struct{}
+
+func NewOverrideRepositoryFactory() *OverrideRepositoryFactory {
+	return &OverrideRepositoryFactory{}
+}
+
+func (f *OverrideRepositoryFactory) New(handler db.DbHandler) repository.OverrideRepository {
+	tx, _ := handler.(*Transaction)
+	return &OverrideRepository{tx: tx}
+}
+
+// OverrideRepository stores the global override under a single key.
+type OverrideRepository struct {
+	tx *Transaction
+}
+
+// SetOverride stores the override, replacing the one set before
+func (or *OverrideRepository) SetOverride(ctx context.Context, override model.Override) error {
+	if or.tx == nil {
+		return ErrNotBoltTransaction
+	}
+
+	now := time.Now().UTC()
+	_, err := or.tx.mergeValue(overridesBucket, globalOverrideKey, func(current []byte) ([]byte, error) {
+		override.CreatedAt, override.UpdatedAt = now, now
+		if current != nil {
+			var existing model.Override
+			if err := json.Unmarshal(current, &existing); err != nil {
+				return nil, err
+			}
+			override.CreatedAt = existing.CreatedAt
+		}
+		return json.Marshal(override)
+	})
+	return err
+}
+
+// GetOverride retrieves the override, nil if none is set
+func (or *OverrideRepository) GetOverride(ctx context.Context) (*model.Override, error) {
+	if or.tx == nil {
+		return nil, ErrNotBoltTransaction
+	}
+
+	data, err := or.tx.get(overridesBucket, globalOverrideKey)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var override model.Override
+	if err := json.Unmarshal(data, &override); err != nil {
+		return nil, err
+	}
+	return &override, nil
+}
+
+// DeleteOverride removes the override
+func (or *OverrideRepository) DeleteOverride(ctx context.Context) error {
+	if or.tx == nil {
+		return ErrNotBoltTransaction
+	}
+
+	data, err := or.tx.get(overridesBucket, globalOverrideKey)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return domain.ErrOverrideNotFound
+	}
+	or.tx.delete(overridesBucket, globalOverrideKey)
+	return nil
+}
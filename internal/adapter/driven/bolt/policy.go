@@ -0,0 +1,139 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+type PolicyRepositoryFactory struct{}
+
+func NewPolicyRepositoryFactory() *PolicyRepositoryFactory {
+	return &PolicyRepositoryFactory{}
+}
+
+func (f *PolicyRepositoryFactory) New(handler db.DbHandler) repository.PolicyRepository {
+	tx, _ := handler.(*Transaction)
+	return &PolicyRepository{tx: tx}
+}
+
+// PolicyRepository stores the limits of a key's policy together under the key, sorted by window.
+type PolicyRepository struct {
+	tx *Transaction
+}
+
+// SetPolicy replaces the limits of the key's policy, keeping the counts of the windows it had
+func (pr *PolicyRepository) SetPolicy(ctx context.Context, key string, limits []model.PolicyLimit) error {
+	if pr.tx == nil {
+		return ErrNotBoltTransaction
+	}
+
+	now := time.Now().UTC()
+	return pr.merge(key, func(existing []model.PolicyLimit) ([]model.PolicyLimit, error) {
+		previous := make(map[time.Duration]model.PolicyLimit, len(existing))
+		for _, limit := range existing {
+			previous[limit.Window] = limit
+		}
+
+		stored := make([]model.PolicyLimit, 0, len(limits))
+		for _, limit := range limits {
+			next := model.PolicyLimit{Id: uuid.New().String(), Key: key, Window: limit.Window, Limit: limit.Limit, Shadow: limit.Shadow, CreatedAt: now, UpdatedAt: now}
+			if old, ok := previous[limit.Window]; ok {
+				next.Id, next.Count, next.WindowStart, next.CreatedAt = old.Id, old.Count, old.WindowStart, old.CreatedAt
+			}
+			stored = append(stored, next)
+		}
+		sort.Slice(stored, func(i, j int) bool { return stored[i].Window < stored[j].Window })
+		return stored, nil
+	})
+}
+
+// GetPolicy retrieves the limits of the key's policy
+func (pr *PolicyRepository) GetPolicy(ctx context.Context, key string) ([]model.PolicyLimit, error) {
+	if pr.tx == nil {
+		return nil, ErrNotBoltTransaction
+	}
+
+	data, err := pr.tx.get(policiesBucket, []byte(key))
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var limits []model.PolicyLimit
+	if err := json.Unmarshal(data, &limits); err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+// GetPolicyForUpdate retrieves the limits of the key's policy. The embedded store has no locks,
+// so requests counted concurrently may overshoot a limit by the requests in flight
+func (pr *PolicyRepository) GetPolicyForUpdate(ctx context.Context, key string) ([]model.PolicyLimit, error) {
+	return pr.GetPolicy(ctx, key)
+}
+
+// UpdatePolicyCounts stores the counts and window starts of the key's limits
+func (pr *PolicyRepository) UpdatePolicyCounts(ctx context.Context, key string, limits []model.PolicyLimit) error {
+	if pr.tx == nil {
+		return ErrNotBoltTransaction
+	}
+
+	now := time.Now().UTC()
+	return pr.merge(key, func(existing []model.PolicyLimit) ([]model.PolicyLimit, error) {
+		if existing == nil {
+			// There is no policy to count against
+			return nil, nil
+		}
+		for i := range existing {
+			for _, limit := range limits {
+				if limit.Window == existing[i].Window {
+					existing[i].Count, existing[i].WindowStart, existing[i].UpdatedAt = limit.Count, limit.WindowStart.UTC(), now
+				}
+			}
+		}
+		return existing, nil
+	})
+}
+
+// DeletePolicy removes the key's policy
+func (pr *PolicyRepository) DeletePolicy(ctx context.Context, key string) error {
+	if pr.tx == nil {
+		return ErrNotBoltTransaction
+	}
+
+	data, err := pr.tx.get(policiesBucket, []byte(key))
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return domain.ErrPolicyNotFound
+	}
+	pr.tx.delete(policiesBucket, []byte(key))
+	return nil
+}
+
+// merge stores the limits fn derives from the current ones, removing the policy when fn returns
+// none.
+func (pr *PolicyRepository) merge(key string, fn func(existing []model.PolicyLimit) ([]model.PolicyLimit, error)) error {
+	_, err := pr.tx.mergeValue(policiesBucket, []byte(key), func(current []byte) ([]byte, error) {
+		var existing []model.PolicyLimit
+		if current != nil {
+			if err := json.Unmarshal(current, &existing); err != nil {
+				return nil, err
+			}
+		}
+
+		stored, err := fn(existing)
+		if err != nil || stored == nil {
+			return nil, err
+		}
+		return json.Marshal(stored)
+	})
+	return err
+}
@@ -0,0 +1,147 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+// quotaPeriods lists the periods a key can have a quota for, in the order they are returned.
+var quotaPeriods = []model.QuotaPeriod{model.QuotaDaily, model.QuotaMonthly, model.QuotaWeekly}
+
+type QuotaRepositoryFactory struct{}
+
+func NewQuotaRepositoryFactory() *QuotaRepositoryFactory {
+	return &QuotaRepositoryFactory{}
+}
+
+func (f *QuotaRepositoryFactory) New(handler db.DbHandler) repository.QuotaRepository {
+	tx, _ := handler.(*Transaction)
+	return &QuotaRepository{tx: tx}
+}
+
+// QuotaRepository stores one quota per key and period, keyed by both.
+type QuotaRepository struct {
+	tx *Transaction
+}
+
+// SetQuota stores a new quota with an auto-generated ID, or changes the key's existing quota for
+// the period
+func (qr *QuotaRepository) SetQuota(ctx context.Context, quota model.Quota) error {
+	if qr.tx == nil {
+		return ErrNotBoltTransaction
+	}
+
+	id := uuid.New().String()
+	now := time.Now().UTC()
+	if quota.TimeZone == "" {
+		quota.TimeZone = "UTC"
+	}
+	return qr.merge(quota.Key, quota.Period, func(existing *model.Quota) (*model.Quota, error) {
+		stored := quota
+		stored.Id, stored.PeriodStart, stored.Used, stored.CreatedAt, stored.UpdatedAt = id, time.Time{}, 0, now, now
+		if existing != nil {
+			stored.Id, stored.PeriodStart, stored.Used, stored.CreatedAt = existing.Id, existing.PeriodStart, existing.Used, existing.CreatedAt
+		}
+		return &stored, nil
+	})
+}
+
+// GetQuotasByKey retrieves the quotas of a key
+func (qr *QuotaRepository) GetQuotasByKey(ctx context.Context, key string) ([]model.Quota, error) {
+	if qr.tx == nil {
+		return nil, ErrNotBoltTransaction
+	}
+
+	var quotas []model.Quota
+	for _, period := range quotaPeriods {
+		data, err := qr.tx.get(quotasBucket, quotaKey(key, period))
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			continue
+		}
+		var quota model.Quota
+		if err := json.Unmarshal(data, &quota); err != nil {
+			return nil, err
+		}
+		quotas = append(quotas, quota)
+	}
+	return quotas, nil
+}
+
+// GetQuotasByKeyForUpdate retrieves the quotas of a key. The embedded store has no locks, so
+// requests counted concurrently may overshoot a quota by the requests in flight
+func (qr *QuotaRepository) GetQuotasByKeyForUpdate(ctx context.Context, key string) ([]model.Quota, error) {
+	return qr.GetQuotasByKey(ctx, key)
+}
+
+// AddQuotaUsage adds to what a quota counted in the period, starting over if the quota was still
+// counting an earlier one
+func (qr *QuotaRepository) AddQuotaUsage(ctx context.Context, key string, period model.QuotaPeriod, periodStart time.Time, units int64) error {
+	if qr.tx == nil {
+		return ErrNotBoltTransaction
+	}
+
+	now := time.Now().UTC()
+	return qr.merge(key, period, func(existing *model.Quota) (*model.Quota, error) {
+		if existing == nil {
+			// There is no quota to count against
+			return nil, nil
+		}
+		existing.Used = existing.UsedIn(periodStart) + units
+		if existing.Used < 0 {
+			existing.Used = 0
+		}
+		existing.PeriodStart, existing.UpdatedAt = periodStart.UTC(), now
+		return existing, nil
+	})
+}
+
+// DeleteQuota removes the key's quota for the period
+func (qr *QuotaRepository) DeleteQuota(ctx context.Context, key string, period model.QuotaPeriod) error {
+	if qr.tx == nil {
+		return ErrNotBoltTransaction
+	}
+
+	data, err := qr.tx.get(quotasBucket, quotaKey(key, period))
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return domain.ErrQuotaNotFound
+	}
+	qr.tx.delete(quotasBucket, quotaKey(key, period))
+	return nil
+}
+
+// merge stores the quota fn derives from the current one, removing it when fn returns nil.
+func (qr *QuotaRepository) merge(key string, period model.QuotaPeriod, fn func(existing *model.Quota) (*model.Quota, error)) error {
+	_, err := qr.tx.mergeValue(quotasBucket, quotaKey(key, period), func(current []byte) ([]byte, error) {
+		var existing *model.Quota
+		if current != nil {
+			existing = &model.Quota{}
+			if err := json.Unmarshal(current, existing); err != nil {
+				return nil, err
+			}
+		}
+
+		stored, err := fn(existing)
+		if err != nil || stored == nil {
+			return nil, err
+		}
+		return json.Marshal(stored)
+	})
+	return err
+}
+
+func quotaKey(key string, period model.QuotaPeriod) []byte {
+	return append(append([]byte(key), 0), period...)
+}
@@ -0,0 +1,129 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+var ErrNotBoltTransaction = errors.New("handler is not an embedded store transaction")
+
+type UserRateLimitRepositoryFactory struct{}
+
+func NewUserRateLimitRepositoryFactory() *UserRateLimitRepositoryFactory {
+	return &UserRateLimitRepositoryFactory{}
+}
+
+func (f *UserRateLimitRepositoryFactory) New(handler db.DbHandler) repository.UserRateLimitRepository {
+	tx, _ := handler.(*Transaction)
+	return &UserRateLimitRepository{tx: tx}
+}
+
+// UserRateLimitRepository stores one record per user, keyed by user ID.
+type UserRateLimitRepository struct {
+	tx *Transaction
+}
+
+// CreateRateLimit stores a new user rate limit record with an auto-generated ID and returns its
+// ID, unless the user already has a record
+func (ur *UserRateLimitRepository) CreateRateLimit(ctx context.Context, rateLimit model.UserRateLimit) (string, error) {
+	if ur.tx == nil {
+		return "", ErrNotBoltTransaction
+	}
+
+	id := uuid.New().String()
+	now := time.Now().UTC()
+	stored, err := ur.merge(rateLimit.UserId, func(existing *model.UserRateLimit) (model.UserRateLimit, error) {
+		if existing != nil {
+			return model.UserRateLimit{}, domain.ErrRateLimitConflict
+		}
+		created := rateLimit
+		created.Id, created.Version, created.CreatedAt, created.UpdatedAt = id, 1, now, now
+		return created, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return stored.Id, nil
+}
+
+// GetRateLimitByUserId retrieves a user's rate limit by user ID
+func (ur *UserRateLimitRepository) GetRateLimitByUserId(ctx context.Context, userId string) (*model.UserRateLimit, error) {
+	if ur.tx == nil {
+		return nil, ErrNotBoltTransaction
+	}
+
+	data, err := ur.tx.get(userRateLimitsBucket, []byte(userId))
+	if err != nil || data == nil {
+		return nil, err // Return nil if no records found
+	}
+
+	var rateLimit model.UserRateLimit
+	if err := json.Unmarshal(data, &rateLimit); err != nil {
+		return nil, err
+	}
+	return &rateLimit, nil
+}
+
+// GetRateLimitByUserIdForUpdate retrieves a user's rate limit by user ID. The embedded store has no
+// locks; a concurrent change is caught by the version check when the transaction commits instead
+func (ur *UserRateLimitRepository) GetRateLimitByUserIdForUpdate(ctx context.Context, userId string) (*model.UserRateLimit, error) {
+	return ur.GetRateLimitByUserId(ctx, userId)
+}
+
+// UpdateRateLimit updates an existing user's rate limit unless it changed since it was read
+func (ur *UserRateLimitRepository) UpdateRateLimit(ctx context.Context, rateLimit model.UserRateLimit) error {
+	if ur.tx == nil {
+		return ErrNotBoltTransaction
+	}
+
+	now := time.Now().UTC()
+	_, err := ur.merge(rateLimit.UserId, func(existing *model.UserRateLimit) (model.UserRateLimit, error) {
+		if existing == nil || existing.Id != rateLimit.Id || existing.Version != rateLimit.Version {
+			return model.UserRateLimit{}, domain.ErrRateLimitConflict
+		}
+
+		updated := rateLimit
+		updated.Version, updated.CreatedAt, updated.UpdatedAt = existing.Version+1, existing.CreatedAt, now
+		return updated, nil
+	})
+	return err
+}
+
+// DeleteRateLimit removes a user's rate limit record
+func (ur *UserRateLimitRepository) DeleteRateLimit(ctx context.Context, userId string) error {
+	if ur.tx == nil {
+		return ErrNotBoltTransaction
+	}
+
+	ur.tx.delete(userRateLimitsBucket, []byte(userId))
+	return nil
+}
+
+// merge stores the record fn derives from the user's current one, which is nil if there is none.
+func (ur *UserRateLimitRepository) merge(userId string, fn func(existing *model.UserRateLimit) (model.UserRateLimit, error)) (model.UserRateLimit, error) {
+	var stored model.UserRateLimit
+	_, err := ur.tx.mergeValue(userRateLimitsBucket, []byte(userId), func(current []byte) ([]byte, error) {
+		var existing *model.UserRateLimit
+		if current != nil {
+			existing = &model.UserRateLimit{}
+			if err := json.Unmarshal(current, existing); err != nil {
+				return nil, err
+			}
+		}
+
+		var err error
+		if stored, err = fn(existing); err != nil {
+			return nil, err
+		}
+		return json.Marshal(stored)
+	})
+	return stored, err
+}
@@ -0,0 +1,196 @@
+// Package bolt is an embedded backend for single-node deployments. One bbolt file holds both the
+// counted state normally kept in the cache and the records normally kept in the database, so a
+// single binary with a data directory is a complete deployment.
+package bolt
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	cacheBucket          = []byte("cache")
+	userRateLimitsBucket = []byte("user_rate_limits")
+	auditEventsBucket    = []byte("audit_events")
+	usageBucketsBucket   = []byte("usage_buckets")
+	quotasBucket         = []byte("quotas")
+	policiesBucket       = []byte("policies")
+	keyParentsBucket     = []byte("key_parents")
+	tiersBucket          = []byte("tiers")
+	tierMappingsBucket   = []byte("tier_mappings")
+	accessRulesBucket    = []byte("access_rules")
+	overridesBucket      = []byte("overrides")
+)
+
+// compactionTxMaxSize bounds the size of each transaction while copying during compaction.
+const compactionTxMaxSize = 64 << 20
+
+// Store owns the bbolt file. Reads and writes share mu, compaction takes it exclusively while it
+// swaps the file underneath.
+type Store struct {
+	path string
+
+	mu sync.RWMutex
+	db *bolt.DB
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Open opens or creates the store at path. Every expiryInterval expired cache entries are
+// removed, and every compactionInterval the file is rewritten to give freed pages back to the
+// file system. A zero interval disables the task.
+func Open(path string, expiryInterval, compactionInterval time.Duration) (*Store, error) {
+	db, err := openDB(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{path: path, db: db, done: make(chan struct{})}
+	s.every(expiryInterval, func() {
+		if err := s.removeExpired(); err != nil {
+			log.Printf("failed to remove expired cache entries: %v", err)
+		}
+	})
+	s.every(compactionInterval, func() {
+		if err := s.Compact(); err != nil {
+			log.Printf("failed to compact store: %v", err)
+		}
+	})
+	return s, nil
+}
+
+func openDB(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{cacheBucket, userRateLimitsBucket, auditEventsBucket, usageBucketsBucket, quotasBucket, policiesBucket, keyParentsBucket, tiersBucket, tierMappingsBucket, accessRulesBucket, overridesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Close stops the background tasks and closes the file.
+func (s *Store) Close() error {
+	close(s.done)
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Close()
+}
+
+// View runs fn in a read-only transaction.
+func (s *Store) View(fn func(tx *bolt.Tx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.db.View(fn)
+}
+
+// Update runs fn in a read-write transaction.
+func (s *Store) Update(fn func(tx *bolt.Tx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.db.Update(fn)
+}
+
+// Compact copies the live data into a fresh file and swaps it in place of the current one. If
+// the swap fails, the store keeps serving from the original file.
+func (s *Store) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmpPath, backupPath := s.path+".compact", s.path+".orig"
+	os.Remove(tmpPath)
+	dst, err := bolt.Open(tmpPath, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	if err := bolt.Compact(dst, s.db, compactionTxMaxSize); err != nil {
+		dst.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	// The original is kept aside until the compacted copy opened in its place
+	if err := s.db.Close(); err != nil {
+		os.Remove(tmpPath)
+		return s.reopen(err)
+	}
+	if err := os.Rename(s.path, backupPath); err != nil {
+		os.Remove(tmpPath)
+		return s.reopen(err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		return s.restore(backupPath, err)
+	}
+	db, err := openDB(s.path)
+	if err != nil {
+		return s.restore(backupPath, err)
+	}
+	s.db = db
+	os.Remove(backupPath)
+	return nil
+}
+
+// restore puts the original file back after swapping in the compacted copy failed, and reopens it.
+func (s *Store) restore(backupPath string, cause error) error {
+	if err := os.Rename(backupPath, s.path); err != nil {
+		return fmt.Errorf("%w, and restoring the original file failed: %v", cause, err)
+	}
+	return s.reopen(cause)
+}
+
+// reopen opens the file again after a failed compaction closed it, and returns the failure.
+func (s *Store) reopen(cause error) error {
+	db, err := openDB(s.path)
+	if err != nil {
+		return fmt.Errorf("%w, and reopening the store failed: %v", cause, err)
+	}
+	s.db = db
+	return cause
+}
+
+// every runs task at the given interval until the store is closed.
+func (s *Store) every(interval time.Duration, task func()) {
+	if interval <= 0 {
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+				task()
+			}
+		}
+	}()
+}
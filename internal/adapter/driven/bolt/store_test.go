@@ -0,0 +1,318 @@
+package bolt
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven"
+	"github.com/stretchr/testify/assert"
+)
+
+func openTestStore(t *testing.T) *Store {
+	store, err := Open(filepath.Join(t.TempDir(), "limiter.db"), 0, 0)
+	assert.Nil(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestTransaction_CommitAndRollback(t *testing.T) {
+	ctx := context.Background()
+	factory := NewTransactionFactory(openTestStore(t))
+	repoFactory := NewUserRateLimitRepositoryFactory()
+
+	tx := factory.NewTransaction()
+	handler, err := tx.Begin(ctx)
+	assert.Nil(t, err)
+	id, err := repoFactory.New(handler).CreateRateLimit(ctx, model.UserRateLimit{UserId: "user", RateLimit: 5})
+	assert.Nil(t, err)
+
+	// Pending writes are visible inside the transaction only
+	inside, err := repoFactory.New(handler).GetRateLimitByUserId(ctx, "user")
+	assert.Nil(t, err)
+	assert.Equal(t, id, inside.Id)
+	other, _ := factory.NewTransaction().Begin(ctx)
+	outside, err := repoFactory.New(other).GetRateLimitByUserId(ctx, "user")
+	assert.Nil(t, err)
+	assert.Nil(t, outside)
+
+	assert.Nil(t, tx.Commit(ctx))
+	outside, err = repoFactory.New(other).GetRateLimitByUserId(ctx, "user")
+	assert.Nil(t, err)
+	assert.Equal(t, 5, outside.RateLimit)
+
+	tx = factory.NewTransaction()
+	handler, _ = tx.Begin(ctx)
+	assert.Nil(t, repoFactory.New(handler).DeleteRateLimit(ctx, "user"))
+	tx.RollbackUnlessCommitted(ctx)
+	kept, err := repoFactory.New(other).GetRateLimitByUserId(ctx, "user")
+	assert.Nil(t, err)
+	assert.NotNil(t, kept)
+}
+
+func TestCache_ExpiryAndCompaction(t *testing.T) {
+	ctx := context.Background()
+	store := openTestStore(t)
+	cache := NewCache(store)
+
+	assert.Nil(t, cache.Set(ctx, "short", []byte("a"), time.Millisecond))
+	assert.Nil(t, cache.Set(ctx, "long", []byte("b"), 0))
+	count, err := cache.Increment(ctx, "counter", 3, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), count)
+
+	time.Sleep(5 * time.Millisecond)
+	_, err = cache.Fetch(ctx, "short")
+	assert.Equal(t, driven.ErrCacheMissed, err)
+
+	assert.Nil(t, store.removeExpired())
+	assert.Nil(t, store.Compact())
+
+	value, err := cache.Fetch(ctx, "long")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("b"), value)
+}
+
+func TestStore_CompactionFailureKeepsServing(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "limiter.db")
+	store, err := Open(path, 0, 0)
+	assert.Nil(t, err)
+	t.Cleanup(func() { store.Close() })
+	cache := NewCache(store)
+	assert.Nil(t, cache.Set(ctx, "key", []byte("a"), 0))
+
+	// A directory in the way of setting the original aside makes the swap fail
+	assert.Nil(t, os.MkdirAll(filepath.Join(path+".orig", "taken"), 0o700))
+	assert.NotNil(t, store.Compact())
+	_, err = os.Stat(path + ".compact")
+	assert.True(t, os.IsNotExist(err))
+
+	value, err := cache.Fetch(ctx, "key")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("a"), value)
+	assert.Nil(t, cache.Set(ctx, "other", []byte("b"), 0))
+
+	// Once the way is clear compaction succeeds and leaves nothing behind
+	assert.Nil(t, os.RemoveAll(path+".orig"))
+	assert.Nil(t, store.Compact())
+	for _, leftover := range []string{path + ".compact", path + ".orig"} {
+		_, err = os.Stat(leftover)
+		assert.True(t, os.IsNotExist(err), leftover)
+	}
+	value, err = cache.Fetch(ctx, "other")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("b"), value)
+}
+
+func TestAuditEventRepository_ListsNewestFirst(t *testing.T) {
+	ctx := context.Background()
+	factory := NewTransactionFactory(openTestStore(t))
+	repoFactory := NewAuditEventRepositoryFactory()
+	start := time.Now().Add(-time.Hour)
+
+	tx := factory.NewTransaction()
+	handler, err := tx.Begin(ctx)
+	assert.Nil(t, err)
+	for i := 0; i < 4; i++ {
+		assert.Nil(t, repoFactory.New(handler).AppendEvent(ctx, model.AuditEvent{Key: "user", Type: model.AuditRequestDenied, CreatedAt: start.Add(time.Duration(i) * time.Minute)}))
+	}
+	assert.Nil(t, tx.Commit(ctx))
+
+	handler, err = factory.NewTransaction().Begin(ctx)
+	assert.Nil(t, err)
+	events, err := repoFactory.New(handler).ListEvents(ctx, model.AuditFilter{Key: "user", From: start.Add(time.Minute), Limit: 2})
+	assert.Nil(t, err)
+	assert.Len(t, events, 2)
+	assert.True(t, events[0].CreatedAt.Equal(start.Add(3*time.Minute)))
+	assert.True(t, events[1].CreatedAt.Equal(start.Add(2*time.Minute)))
+
+	events, err = repoFactory.New(handler).ListEvents(ctx, model.AuditFilter{From: start.Add(time.Minute), To: start.Add(2 * time.Minute)})
+	assert.Nil(t, err)
+	assert.Len(t, events, 1)
+}
+
+func TestUsageRepository_AddListAndPrune(t *testing.T) {
+	ctx := context.Background()
+	factory := NewTransactionFactory(openTestStore(t))
+	repoFactory := NewUsageRepositoryFactory()
+	day := model.UsageDaily.Truncate(time.Now())
+
+	tx := factory.NewTransaction()
+	handler, err := tx.Begin(ctx)
+	assert.Nil(t, err)
+	for _, bucket := range []model.UsageBucket{
+		{Key: "tenant", Granularity: model.UsageHourly, Start: day.Add(-time.Hour), Allowed: 1},
+		{Key: "tenant", Granularity: model.UsageHourly, Start: day, Allowed: 2},
+		{Key: "tenant", Granularity: model.UsageHourly, Start: day, Denied: 1},
+		{Key: "tenants", Granularity: model.UsageHourly, Start: day, Allowed: 9},
+		{Key: "tenant", Granularity: model.UsageDaily, Start: day.Add(-24 * time.Hour), Allowed: 3},
+	} {
+		assert.Nil(t, repoFactory.New(handler).AddUsage(ctx, bucket))
+	}
+	assert.Nil(t, tx.Commit(ctx))
+
+	handler, err = tx.Begin(ctx)
+	assert.Nil(t, err)
+	buckets, err := repoFactory.New(handler).ListUsage(ctx, "tenant", model.UsageHourly, day.Add(-time.Hour), day.Add(time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, []model.UsageBucket{
+		{Key: "tenant", Granularity: model.UsageHourly, Start: day.Add(-time.Hour), Allowed: 1},
+		{Key: "tenant", Granularity: model.UsageHourly, Start: day, Allowed: 2, Denied: 1},
+	}, buckets)
+
+	assert.Nil(t, repoFactory.New(handler).DeleteUsageBefore(ctx, model.UsageHourly, day))
+	assert.Nil(t, tx.Commit(ctx))
+
+	handler, err = tx.Begin(ctx)
+	assert.Nil(t, err)
+	buckets, err = repoFactory.New(handler).ListUsage(ctx, "tenant", model.UsageHourly, day.Add(-time.Hour), day.Add(time.Hour))
+	assert.Nil(t, err)
+	assert.Len(t, buckets, 1)
+	buckets, err = repoFactory.New(handler).ListUsage(ctx, "tenant", model.UsageDaily, day.Add(-24*time.Hour), day)
+	assert.Nil(t, err)
+	assert.Len(t, buckets, 1)
+}
+
+func TestQuotaRepository_UsageFollowsPeriods(t *testing.T) {
+	ctx := context.Background()
+	factory := NewTransactionFactory(openTestStore(t))
+	repoFactory := NewQuotaRepositoryFactory()
+	today := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	tx := factory.NewTransaction()
+	handler, err := tx.Begin(ctx)
+	assert.Nil(t, err)
+	repo := repoFactory.New(handler)
+	assert.Nil(t, repo.SetQuota(ctx, model.Quota{Key: "tenant", Period: model.QuotaDaily, Limit: 10}))
+	assert.Nil(t, repo.AddQuotaUsage(ctx, "tenant", model.QuotaDaily, today, 4))
+	assert.Nil(t, repo.AddQuotaUsage(ctx, "tenant", model.QuotaDaily, today, -6))
+	assert.Nil(t, repo.AddQuotaUsage(ctx, "tenant", model.QuotaDaily, today, 3))
+	assert.Nil(t, tx.Commit(ctx))
+
+	handler, err = tx.Begin(ctx)
+	assert.Nil(t, err)
+	repo = repoFactory.New(handler)
+	quotas, err := repo.GetQuotasByKey(ctx, "tenant")
+	assert.Nil(t, err)
+	assert.Len(t, quotas, 1)
+	assert.Equal(t, int64(3), quotas[0].UsedIn(today))
+
+	// Usage of a new period starts over, and changing the limit keeps it
+	assert.Nil(t, repo.AddQuotaUsage(ctx, "tenant", model.QuotaDaily, today.AddDate(0, 0, 1), 2))
+	assert.Nil(t, repo.SetQuota(ctx, model.Quota{Key: "tenant", Period: model.QuotaDaily, Limit: 20}))
+	assert.Nil(t, tx.Commit(ctx))
+
+	handler, err = tx.Begin(ctx)
+	assert.Nil(t, err)
+	repo = repoFactory.New(handler)
+	quotas, err = repo.GetQuotasByKey(ctx, "tenant")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(20), quotas[0].Limit)
+	assert.Equal(t, int64(2), quotas[0].UsedIn(today.AddDate(0, 0, 1)))
+	assert.Nil(t, repo.DeleteQuota(ctx, "tenant", model.QuotaDaily))
+	assert.ErrorIs(t, repo.DeleteQuota(ctx, "tenant", model.QuotaDaily), domain.ErrQuotaNotFound)
+	assert.Nil(t, tx.Commit(ctx))
+}
+
+func TestPolicyRepository_KeepsCountsOfKeptWindows(t *testing.T) {
+	ctx := context.Background()
+	factory := NewTransactionFactory(openTestStore(t))
+	repoFactory := NewPolicyRepositoryFactory()
+	windowStart := time.Now().UTC().Truncate(time.Second)
+
+	tx := factory.NewTransaction()
+	handler, err := tx.Begin(ctx)
+	assert.Nil(t, err)
+	repo := repoFactory.New(handler)
+	assert.Nil(t, repo.SetPolicy(ctx, "tenant", []model.PolicyLimit{{Window: time.Minute, Limit: 100}, {Window: time.Second, Limit: 5}}))
+	assert.Nil(t, repo.UpdatePolicyCounts(ctx, "tenant", []model.PolicyLimit{{Window: time.Minute, Count: 7, WindowStart: windowStart}}))
+	assert.Nil(t, tx.Commit(ctx))
+
+	handler, err = tx.Begin(ctx)
+	assert.Nil(t, err)
+	repo = repoFactory.New(handler)
+	assert.Nil(t, repo.SetPolicy(ctx, "tenant", []model.PolicyLimit{{Window: time.Minute, Limit: 50}, {Window: time.Hour, Limit: 1000}}))
+	assert.Nil(t, tx.Commit(ctx))
+
+	handler, err = tx.Begin(ctx)
+	assert.Nil(t, err)
+	repo = repoFactory.New(handler)
+	limits, err := repo.GetPolicy(ctx, "tenant")
+	assert.Nil(t, err)
+	assert.Len(t, limits, 2)
+	assert.Equal(t, time.Minute, limits[0].Window)
+	assert.Equal(t, 50, limits[0].Limit)
+	assert.Equal(t, 7, limits[0].Count)
+	assert.True(t, limits[0].WindowStart.Equal(windowStart))
+	assert.Equal(t, 0, limits[1].Count)
+
+	assert.Nil(t, repo.DeletePolicy(ctx, "tenant"))
+	assert.ErrorIs(t, repo.DeletePolicy(ctx, "tenant"), domain.ErrPolicyNotFound)
+	assert.Nil(t, tx.Commit(ctx))
+}
+
+func TestParentRepository_ListsChildren(t *testing.T) {
+	ctx := context.Background()
+	factory := NewTransactionFactory(openTestStore(t))
+	repoFactory := NewParentRepositoryFactory()
+
+	tx := factory.NewTransaction()
+	handler, err := tx.Begin(ctx)
+	assert.Nil(t, err)
+	repo := repoFactory.New(handler)
+	assert.Nil(t, repo.SetParent(ctx, "user-2", "team-a"))
+	assert.Nil(t, repo.SetParent(ctx, "user-1", "team-a"))
+	assert.Nil(t, repo.SetParent(ctx, "team-a", "org"))
+	assert.Nil(t, tx.Commit(ctx))
+
+	handler, err = tx.Begin(ctx)
+	assert.Nil(t, err)
+	repo = repoFactory.New(handler)
+	parent, err := repo.GetParent(ctx, "team-a")
+	assert.Nil(t, err)
+	assert.Equal(t, "org", parent.Parent)
+
+	children, err := repo.ListChildren(ctx, "team-a")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"user-1", "user-2"}, []string{children[0].Key, children[1].Key})
+
+	assert.Nil(t, repo.DeleteParent(ctx, "team-a"))
+	assert.ErrorIs(t, repo.DeleteParent(ctx, "team-a"), domain.ErrParentNotFound)
+	assert.Nil(t, tx.Commit(ctx))
+}
+
+func TestTierRepository_ResolvesMappings(t *testing.T) {
+	ctx := context.Background()
+	factory := NewTransactionFactory(openTestStore(t))
+	repoFactory := NewTierRepositoryFactory()
+
+	tx := factory.NewTransaction()
+	handler, err := tx.Begin(ctx)
+	assert.Nil(t, err)
+	repo := repoFactory.New(handler)
+	assert.Nil(t, repo.SetTier(ctx, model.Tier{Name: "pro", RateLimit: 500}))
+	assert.Nil(t, repo.SetTierMapping(ctx, "plan-pro", "pro"))
+	assert.Nil(t, tx.Commit(ctx))
+
+	handler, err = tx.Begin(ctx)
+	assert.Nil(t, err)
+	repo = repoFactory.New(handler)
+	tier, err := repo.GetTierFor(ctx, "plan-pro")
+	assert.Nil(t, err)
+	assert.Equal(t, 500, tier.RateLimit)
+	mappings, err := repo.ListTierMappings(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "pro", mappings[0].Tier)
+
+	assert.Nil(t, repo.DeleteTier(ctx, "pro"))
+	assert.ErrorIs(t, repo.DeleteTier(ctx, "pro"), domain.ErrTierNotFound)
+	tier, err = repo.GetTierFor(ctx, "plan-pro")
+	assert.Nil(t, err)
+	assert.Nil(t, tier)
+	assert.Nil(t, tx.Commit(ctx))
+}
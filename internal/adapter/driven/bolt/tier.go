@@ -0,0 +1,167 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+type TierRepositoryFactory struct{}
+
+func NewTierRepositoryFactory() *TierRepositoryFactory {
+	return &TierRepositoryFactory{}
+}
+
+func (f *TierRepositoryFactory) New(handler db.DbHandler) repository.TierRepository {
+	tx, _ := handler.(*Transaction)
+	return &TierRepository{tx: tx}
+}
+
+// TierRepository stores each tier under its name and each mapping under its role or plan.
+type TierRepository struct {
+	tx *Transaction
+}
+
+// SetTier stores the tier, replacing the one with the same name
+func (tr *TierRepository) SetTier(ctx context.Context, tier model.Tier) error {
+	if tr.tx == nil {
+		return ErrNotBoltTransaction
+	}
+
+	now := time.Now().UTC()
+	_, err := tr.tx.mergeValue(tiersBucket, []byte(tier.Name), func(current []byte) ([]byte, error) {
+		tier.CreatedAt, tier.UpdatedAt = now, now
+		if current != nil {
+			var existing model.Tier
+			if err := json.Unmarshal(current, &existing); err != nil {
+				return nil, err
+			}
+			tier.CreatedAt = existing.CreatedAt
+		}
+		return json.Marshal(tier)
+	})
+	return err
+}
+
+// GetTier retrieves the tier with the name
+func (tr *TierRepository) GetTier(ctx context.Context, name string) (*model.Tier, error) {
+	if tr.tx == nil {
+		return nil, ErrNotBoltTransaction
+	}
+
+	data, err := tr.tx.get(tiersBucket, []byte(name))
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var tier model.Tier
+	if err := json.Unmarshal(data, &tier); err != nil {
+		return nil, err
+	}
+	return &tier, nil
+}
+
+// ListTiers retrieves all tiers. It does not see the transaction's own pending writes
+func (tr *TierRepository) ListTiers(ctx context.Context) ([]model.Tier, error) {
+	if tr.tx == nil {
+		return nil, ErrNotBoltTransaction
+	}
+
+	var tiers []model.Tier
+	err := tr.tx.scanFrom(tiersBucket, nil, nil, func(_, value []byte) (bool, error) {
+		var tier model.Tier
+		if err := json.Unmarshal(value, &tier); err != nil {
+			return false, err
+		}
+		tiers = append(tiers, tier)
+		return true, nil
+	})
+	return tiers, err
+}
+
+// DeleteTier removes the tier with the name
+func (tr *TierRepository) DeleteTier(ctx context.Context, name string) error {
+	return tr.delete(tiersBucket, name, domain.ErrTierNotFound)
+}
+
+// SetTierMapping stores the tier of the role or plan, replacing the one it had
+func (tr *TierRepository) SetTierMapping(ctx context.Context, subject, tier string) error {
+	if tr.tx == nil {
+		return ErrNotBoltTransaction
+	}
+
+	now := time.Now().UTC()
+	_, err := tr.tx.mergeValue(tierMappingsBucket, []byte(subject), func(current []byte) ([]byte, error) {
+		mapping := model.TierMapping{Subject: subject, Tier: tier, CreatedAt: now, UpdatedAt: now}
+		if current != nil {
+			var existing model.TierMapping
+			if err := json.Unmarshal(current, &existing); err != nil {
+				return nil, err
+			}
+			mapping.CreatedAt = existing.CreatedAt
+		}
+		return json.Marshal(mapping)
+	})
+	return err
+}
+
+// ListTierMappings retrieves all mappings. It does not see the transaction's own pending writes
+func (tr *TierRepository) ListTierMappings(ctx context.Context) ([]model.TierMapping, error) {
+	if tr.tx == nil {
+		return nil, ErrNotBoltTransaction
+	}
+
+	var mappings []model.TierMapping
+	err := tr.tx.scanFrom(tierMappingsBucket, nil, nil, func(_, value []byte) (bool, error) {
+		var mapping model.TierMapping
+		if err := json.Unmarshal(value, &mapping); err != nil {
+			return false, err
+		}
+		mappings = append(mappings, mapping)
+		return true, nil
+	})
+	return mappings, err
+}
+
+// DeleteTierMapping removes the mapping of the role or plan
+func (tr *TierRepository) DeleteTierMapping(ctx context.Context, subject string) error {
+	return tr.delete(tierMappingsBucket, subject, domain.ErrTierMappingNotFound)
+}
+
+// GetTierFor retrieves the tier the role or plan is mapped to
+func (tr *TierRepository) GetTierFor(ctx context.Context, subject string) (*model.Tier, error) {
+	if tr.tx == nil {
+		return nil, ErrNotBoltTransaction
+	}
+
+	data, err := tr.tx.get(tierMappingsBucket, []byte(subject))
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var mapping model.TierMapping
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return nil, err
+	}
+	return tr.GetTier(ctx, mapping.Tier)
+}
+
+// delete removes the value under the key and returns notFound if there is none.
+func (tr *TierRepository) delete(bucket []byte, key string, notFound error) error {
+	if tr.tx == nil {
+		return ErrNotBoltTransaction
+	}
+
+	data, err := tr.tx.get(bucket, []byte(key))
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return notFound
+	}
+	tr.tx.delete(bucket, []byte(key))
+	return nil
+}
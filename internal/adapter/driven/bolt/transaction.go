@@ -0,0 +1,186 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	ErrNoTransaction   = errors.New("no transaction in progress")
+	ErrSQLNotSupported = errors.New("the embedded store does not support SQL")
+)
+
+// Transaction buffers repository writes and applies them in one bbolt write transaction on
+// commit. Nothing is held while it is open, so cache calls made in between cannot deadlock on
+// bbolt's single writer. Reads see the transaction's own pending writes.
+type Transaction struct {
+	store *Store
+
+	begun, committed bool
+	pending          []write
+}
+
+// write is a pending put, or a delete when value is nil. A put with a merge recomputes its value
+// from the stored one on commit, which is how upserts and version checks see the writes of
+// transactions that committed in the meantime. A merge that computes a nil value deletes the key.
+type write struct {
+	bucket, key, value []byte
+	merge              func(current []byte) ([]byte, error)
+}
+
+func (t *Transaction) Begin(ctx context.Context, opts ...db.TxOption) (db.DbHandler, error) {
+	t.begun, t.committed, t.pending = true, false, nil
+	return t, nil
+}
+
+func (t *Transaction) Commit(ctx context.Context) error {
+	if !t.begun {
+		return ErrNoTransaction
+	}
+
+	err := t.store.Update(func(tx *bolt.Tx) error {
+		for _, w := range t.pending {
+			bucket := tx.Bucket(w.bucket)
+			value := w.value
+			if w.merge != nil {
+				var err error
+				if value, err = w.merge(bucket.Get(w.key)); err != nil {
+					return err
+				}
+			}
+			if value == nil {
+				if err := bucket.Delete(w.key); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := bucket.Put(w.key, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	t.begun, t.committed, t.pending = false, err == nil, nil
+	return err
+}
+
+func (t *Transaction) Rollback(ctx context.Context) error {
+	if !t.begun {
+		return ErrNoTransaction
+	}
+	t.begun, t.pending = false, nil
+	return nil
+}
+
+func (t *Transaction) RollbackUnlessCommitted(ctx context.Context) {
+	if !t.committed && t.begun {
+		if err := t.Rollback(ctx); err != nil {
+			log.Print(err)
+		}
+	}
+}
+
+// QueryContext is part of db.DbHandler; the embedded store has no SQL.
+func (t *Transaction) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, ErrSQLNotSupported
+}
+
+// QueryRowContext is part of db.DbHandler; the embedded store has no SQL, so it returns nil.
+func (t *Transaction) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+// ExecContext is part of db.DbHandler; the embedded store has no SQL.
+func (t *Transaction) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return nil, ErrSQLNotSupported
+}
+
+// get reads key from bucket, preferring the transaction's own pending writes.
+func (t *Transaction) get(bucket, key []byte) ([]byte, error) {
+	for i := len(t.pending) - 1; i >= 0; i-- {
+		if w := t.pending[i]; string(w.bucket) == string(bucket) && string(w.key) == string(key) {
+			return w.value, nil
+		}
+	}
+
+	var value []byte
+	err := t.store.View(func(tx *bolt.Tx) error {
+		if stored := tx.Bucket(bucket).Get(key); stored != nil {
+			value = append([]byte{}, stored...)
+		}
+		return nil
+	})
+	return value, err
+}
+
+// scanFrom calls fn for every stored entry of bucket whose key has the prefix, in key order
+// starting at the first key not before start, stopping when fn returns false. It does not see the
+// transaction's own pending writes.
+func (t *Transaction) scanFrom(bucket, prefix, start []byte, fn func(key, value []byte) (bool, error)) error {
+	return t.store.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(bucket).Cursor()
+		for key, value := cursor.Seek(start); key != nil && bytes.HasPrefix(key, prefix); key, value = cursor.Next() {
+			next, err := fn(key, value)
+			if err != nil || !next {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// scanBackwards calls fn for every stored entry of bucket from the last key to the first,
+// stopping when fn returns false. It does not see the transaction's own pending writes.
+func (t *Transaction) scanBackwards(bucket []byte, fn func(key, value []byte) (bool, error)) error {
+	return t.store.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(bucket).Cursor()
+		for key, value := cursor.Last(); key != nil; key, value = cursor.Prev() {
+			next, err := fn(key, value)
+			if err != nil || !next {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (t *Transaction) put(bucket, key, value []byte) {
+	t.pending = append(t.pending, write{bucket: bucket, key: key, value: value})
+}
+
+// mergeValue puts the value fn computes from the current one, computing it against what this
+// transaction sees now and again against the stored value on commit.
+func (t *Transaction) mergeValue(bucket, key []byte, fn func(current []byte) ([]byte, error)) ([]byte, error) {
+	current, err := t.get(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	value, err := fn(current)
+	if err != nil {
+		return nil, err
+	}
+	t.pending = append(t.pending, write{bucket: bucket, key: key, value: value, merge: fn})
+	return value, nil
+}
+
+func (t *Transaction) delete(bucket, key []byte) {
+	t.pending = append(t.pending, write{bucket: bucket, key: key})
+}
+
+type TransactionFactory struct {
+	store *Store
+}
+
+func NewTransactionFactory(store *Store) *TransactionFactory {
+	return &TransactionFactory{store: store}
+}
+
+func (f *TransactionFactory) NewTransaction() db.DbTransaction {
+	return &Transaction{store: f.store}
+}
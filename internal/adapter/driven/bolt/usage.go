@@ -0,0 +1,97 @@
+package bolt
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+type UsageRepositoryFactory struct{}
+
+func NewUsageRepositoryFactory() *UsageRepositoryFactory {
+	return &UsageRepositoryFactory{}
+}
+
+func (f *UsageRepositoryFactory) New(handler db.DbHandler) repository.UsageRepository {
+	tx, _ := handler.(*Transaction)
+	return &UsageRepository{tx: tx}
+}
+
+// UsageRepository keys buckets by granularity, then key, then big-endian start time, so a key's
+// buckets are stored in time order and retention can walk one granularity at a time.
+type UsageRepository struct {
+	tx *Transaction
+}
+
+// AddUsage stores a usage bucket or adds its counts to the stored one
+func (ur *UsageRepository) AddUsage(ctx context.Context, bucket model.UsageBucket) error {
+	if ur.tx == nil {
+		return ErrNotBoltTransaction
+	}
+
+	bucket.Start = bucket.Start.UTC()
+	key := binary.BigEndian.AppendUint64(usagePrefix(bucket.Granularity, bucket.Key), uint64(bucket.Start.Unix()))
+	_, err := ur.tx.mergeValue(usageBucketsBucket, key, func(current []byte) ([]byte, error) {
+		added := bucket
+		if current != nil {
+			var stored model.UsageBucket
+			if err := json.Unmarshal(current, &stored); err != nil {
+				return nil, err
+			}
+			added.Allowed += stored.Allowed
+			added.Denied += stored.Denied
+		}
+		return json.Marshal(added)
+	})
+	return err
+}
+
+// ListUsage retrieves a key's usage buckets within a time range, oldest first
+func (ur *UsageRepository) ListUsage(ctx context.Context, key string, granularity model.UsageGranularity, from, to time.Time) ([]model.UsageBucket, error) {
+	if ur.tx == nil {
+		return nil, ErrNotBoltTransaction
+	}
+
+	var buckets []model.UsageBucket
+	prefix := usagePrefix(granularity, key)
+	start := binary.BigEndian.AppendUint64(append([]byte{}, prefix...), uint64(from.Unix()))
+	err := ur.tx.scanFrom(usageBucketsBucket, prefix, start, func(_, value []byte) (bool, error) {
+		var bucket model.UsageBucket
+		if err := json.Unmarshal(value, &bucket); err != nil {
+			return false, err
+		}
+		if !bucket.Start.Before(to) {
+			return false, nil
+		}
+		buckets = append(buckets, bucket)
+		return true, nil
+	})
+	return buckets, err
+}
+
+// DeleteUsageBefore removes the usage buckets of a granularity that start before a time
+func (ur *UsageRepository) DeleteUsageBefore(ctx context.Context, granularity model.UsageGranularity, before time.Time) error {
+	if ur.tx == nil {
+		return ErrNotBoltTransaction
+	}
+
+	prefix := append([]byte(granularity), 0)
+	return ur.tx.scanFrom(usageBucketsBucket, prefix, prefix, func(key, _ []byte) (bool, error) {
+		// Keys end with the start time
+		if start := int64(binary.BigEndian.Uint64(key[len(key)-8:])); start < before.Unix() {
+			ur.tx.delete(usageBucketsBucket, append([]byte{}, key...))
+		}
+		return true, nil
+	})
+}
+
+// usagePrefix is the part of the key shared by a key's buckets of one granularity.
+func usagePrefix(granularity model.UsageGranularity, key string) []byte {
+	prefix := append([]byte(granularity), 0)
+	return append(append(prefix, key...), 0)
+}
@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/nullexp/limiter-x/internal/port/driven"
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// CircuitBreaker guards a cache so that an unreachable backend fails fast with
+// driven.ErrCacheUnavailable instead of stalling every request on timeouts. After
+// failureThreshold consecutive failures the breaker opens; once cooldown has passed a single
+// trial call is let through, closing the breaker again if it succeeds.
+type CircuitBreaker struct {
+	cache driven.Cache
+
+	failureThreshold int
+	cooldown         time.Duration
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+func NewCircuitBreaker(cache driven.Cache, failureThreshold int, cooldown time.Duration) driven.Cache {
+	if failureThreshold < 1 {
+		failureThreshold = 1
+	}
+	return &CircuitBreaker{cache: cache, failureThreshold: failureThreshold, cooldown: cooldown}
+}
+
+func (cb *CircuitBreaker) Connect() error {
+	return cb.call(context.Background(), func() error { return cb.cache.Connect() })
+}
+
+func (cb *CircuitBreaker) Disconnect() error {
+	return cb.cache.Disconnect()
+}
+
+func (cb *CircuitBreaker) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	return cb.call(ctx, func() error { return cb.cache.Set(ctx, key, value, expiration) })
+}
+
+func (cb *CircuitBreaker) Fetch(ctx context.Context, key string) (val []byte, err error) {
+	err = cb.call(ctx, func() error {
+		val, err = cb.cache.Fetch(ctx, key)
+		return err
+	})
+	return
+}
+
+func (cb *CircuitBreaker) Delete(ctx context.Context, key string) error {
+	return cb.call(ctx, func() error { return cb.cache.Delete(ctx, key) })
+}
+
+func (cb *CircuitBreaker) Update(ctx context.Context, key string, expiration time.Duration, fn func(current []byte) ([]byte, error)) error {
+	return cb.call(ctx, func() error { return cb.cache.Update(ctx, key, expiration, fn) })
+}
+
+func (cb *CircuitBreaker) Increment(ctx context.Context, key string, delta int64, expiration time.Duration) (counter int64, err error) {
+	err = cb.call(ctx, func() error {
+		counter, err = cb.cache.Increment(ctx, key, delta, expiration)
+		return err
+	})
+	return
+}
+
+// call runs fn unless the breaker is open and records its outcome.
+func (cb *CircuitBreaker) call(ctx context.Context, fn func() error) error {
+	if !cb.allow() {
+		return driven.ErrCacheUnavailable
+	}
+
+	err := fn()
+	switch {
+	case err == nil || errors.Is(err, driven.ErrCacheMissed):
+		cb.succeed()
+	case ctx.Err() != nil:
+		// The caller gave up, which says nothing about the backend
+		cb.abandon()
+	default:
+		cb.fail(err)
+	}
+	return err
+}
+
+func (cb *CircuitBreaker) allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case breakerOpen:
+		if time.Since(cb.openedAt) < cb.cooldown {
+			return false
+		}
+		cb.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		// A trial call is already in flight
+		return false
+	default:
+		return true
+	}
+}
+
+func (cb *CircuitBreaker) succeed() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state != breakerClosed {
+		log.Print("cache circuit breaker closed, backend recovered")
+	}
+	cb.state = breakerClosed
+	cb.failures = 0
+}
+
+func (cb *CircuitBreaker) abandon() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == breakerHalfOpen {
+		cb.state = breakerOpen
+	}
+}
+
+func (cb *CircuitBreaker) fail(err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures++
+	if cb.state == breakerHalfOpen || cb.failures >= cb.failureThreshold {
+		if cb.state != breakerOpen {
+			log.Printf("cache circuit breaker opened: %v", err)
+		}
+		cb.state = breakerOpen
+		cb.openedAt = time.Now()
+	}
+}
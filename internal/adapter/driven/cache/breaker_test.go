@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nullexp/limiter-x/internal/port/driven"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyCache wraps a memory cache and fails every call while down is set.
+type flakyCache struct {
+	driven.Cache
+	down  bool
+	calls int
+}
+
+var errBackendDown = errors.New("connection refused")
+
+func (f *flakyCache) Fetch(ctx context.Context, key string) ([]byte, error) {
+	f.calls++
+	if f.down {
+		return nil, errBackendDown
+	}
+	return f.Cache.Fetch(ctx, key)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	backend := &flakyCache{Cache: NewMemoryClient(time.Hour, time.Hour), down: true}
+	assert.Nil(t, backend.Connect())
+
+	breaker := NewCircuitBreaker(backend, 2, 50*time.Millisecond)
+
+	// Failures pass through until the threshold is reached
+	_, err := breaker.Fetch(ctx, "key")
+	assert.ErrorIs(t, err, errBackendDown)
+	_, err = breaker.Fetch(ctx, "key")
+	assert.ErrorIs(t, err, errBackendDown)
+
+	// Then the breaker fails fast without touching the backend
+	_, err = breaker.Fetch(ctx, "key")
+	assert.ErrorIs(t, err, driven.ErrCacheUnavailable)
+	assert.Equal(t, 2, backend.calls)
+
+	// After the cooldown a trial call goes through and closes the breaker again
+	backend.down = false
+	time.Sleep(60 * time.Millisecond)
+	_, err = breaker.Fetch(ctx, "key")
+	assert.ErrorIs(t, err, driven.ErrCacheMissed)
+	_, err = breaker.Fetch(ctx, "key")
+	assert.ErrorIs(t, err, driven.ErrCacheMissed)
+	assert.Equal(t, 4, backend.calls)
+}
@@ -2,6 +2,8 @@ package cache
 
 import (
 	"context"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/nullexp/limiter-x/internal/port/driven"
@@ -11,6 +13,9 @@ import (
 type MemoryClient struct {
 	client *cache.Cache
 
+	// mu serializes writes so that Update and Increment are atomic with respect to them
+	mu sync.Mutex
+
 	defaultExpiration, cleanupInterval time.Duration
 }
 
@@ -31,6 +36,9 @@ func (rc *MemoryClient) Disconnect() error {
 }
 
 func (rc *MemoryClient) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
 	rc.client.Set(key, value, expiration)
 
 	return nil
@@ -47,7 +55,54 @@ func (rc *MemoryClient) Fetch(ctx context.Context, key string) ([]byte, error) {
 }
 
 func (rc *MemoryClient) Delete(ctx context.Context, key string) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
 	rc.client.Delete(key)
 
 	return nil
 }
+
+func (rc *MemoryClient) Update(ctx context.Context, key string, expiration time.Duration, fn func(current []byte) ([]byte, error)) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	var current []byte
+	if value, exist := rc.client.Get(key); exist {
+		current = value.([]byte)
+	}
+
+	value, err := fn(current)
+	if err != nil || value == nil {
+		return err
+	}
+
+	rc.client.Set(key, value, expiration)
+
+	return nil
+}
+
+func (rc *MemoryClient) Increment(ctx context.Context, key string, delta int64, expiration time.Duration) (int64, error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	var counter int64
+	if value, expiresAt, exist := rc.client.GetWithExpiration(key); exist {
+		var err error
+		counter, err = strconv.ParseInt(string(value.([]byte)), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+
+		// Keep the expiry of an existing counter
+		expiration = cache.NoExpiration
+		if !expiresAt.IsZero() {
+			expiration = time.Until(expiresAt)
+		}
+	}
+	counter += delta
+
+	rc.client.Set(key, []byte(strconv.FormatInt(counter, 10)), expiration)
+
+	return counter, nil
+}
@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/nullexp/limiter-x/internal/port/driven"
+)
+
+// memcachedRelativeLimit is the longest expiry memcached takes as relative seconds; anything
+// above it is read as a Unix timestamp.
+const memcachedRelativeLimit = 30 * 24 * time.Hour
+
+type MemcachedClient struct {
+	servers []string
+
+	client *memcache.Client
+}
+
+func NewMemcachedClient(servers ...string) driven.Cache {
+	return &MemcachedClient{servers: servers}
+}
+
+func (mc *MemcachedClient) Connect() error {
+	if mc.client == nil {
+		mc.client = memcache.New(mc.servers...)
+	}
+
+	return mc.client.Ping()
+}
+
+func (mc *MemcachedClient) Disconnect() error {
+	return mc.client.Close()
+}
+
+func (mc *MemcachedClient) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	return mc.client.Set(&memcache.Item{Key: key, Value: value, Expiration: memcachedExpiration(expiration)})
+}
+
+func (mc *MemcachedClient) Fetch(ctx context.Context, key string) ([]byte, error) {
+	item, err := mc.client.Get(key)
+	if err == memcache.ErrCacheMiss {
+		return nil, driven.ErrCacheMissed
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return item.Value, nil
+}
+
+func (mc *MemcachedClient) Delete(ctx context.Context, key string) error {
+	err := mc.client.Delete(key)
+	if err == memcache.ErrCacheMiss {
+		return nil
+	}
+
+	return err
+}
+
+// Update replaces the value with gets/cas, or add when the key is missing, retrying until no
+// other client changed the key in between.
+func (mc *MemcachedClient) Update(ctx context.Context, key string, expiration time.Duration, fn func(current []byte) ([]byte, error)) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		item, err := mc.client.Get(key)
+		if err != nil && err != memcache.ErrCacheMiss {
+			return err
+		}
+
+		var current []byte
+		if item != nil {
+			current = item.Value
+		}
+		value, err := fn(current)
+		if err != nil || value == nil {
+			return err
+		}
+
+		if item == nil {
+			err = mc.client.Add(&memcache.Item{Key: key, Value: value, Expiration: memcachedExpiration(expiration)})
+		} else {
+			item.Value = value
+			item.Expiration = memcachedExpiration(expiration)
+			err = mc.client.CompareAndSwap(item)
+		}
+
+		switch err {
+		case memcache.ErrNotStored, memcache.ErrCASConflict, memcache.ErrCacheMiss:
+			// Someone else got there first, start over from their value
+			continue
+		default:
+			return err
+		}
+	}
+}
+
+// Increment uses memcached's incr/decr, creating the counter with add when it is missing.
+func (mc *MemcachedClient) Increment(ctx context.Context, key string, delta int64, expiration time.Duration) (int64, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		var counter uint64
+		var err error
+		if delta >= 0 {
+			counter, err = mc.client.Increment(key, uint64(delta))
+		} else {
+			// memcached never goes below zero
+			counter, err = mc.client.Decrement(key, uint64(-delta))
+		}
+		if err == nil {
+			return int64(counter), nil
+		}
+		if err != memcache.ErrCacheMiss {
+			return 0, err
+		}
+
+		if delta < 0 {
+			delta = 0
+		}
+		err = mc.client.Add(&memcache.Item{Key: key, Value: []byte(strconv.FormatInt(delta, 10)), Expiration: memcachedExpiration(expiration)})
+		if err == nil {
+			return delta, nil
+		}
+		if err != memcache.ErrNotStored {
+			return 0, err
+		}
+		// The counter was created concurrently, increment it instead
+	}
+}
+
+// memcachedExpiration converts an expiration into memcached's whole seconds, rounding up so
+// that short windows do not turn into "never expires".
+func memcachedExpiration(expiration time.Duration) int32 {
+	if expiration <= 0 {
+		return 0
+	}
+	if expiration > memcachedRelativeLimit {
+		return int32(time.Now().Add(expiration).Unix())
+	}
+
+	return int32((expiration + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,312 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nullexp/limiter-x/internal/port/driven"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMemcached is an in-process server speaking the subset of the memcached text protocol the
+// adapter uses: get/gets, set/add/cas, delete, incr/decr and version.
+type fakeMemcached struct {
+	listener net.Listener
+
+	mu    sync.Mutex
+	items map[string]*fakeItem
+	cas   uint64
+}
+
+type fakeItem struct {
+	value     []byte
+	flags     uint32
+	cas       uint64
+	expiresAt time.Time
+}
+
+func newFakeMemcached(t *testing.T) *fakeMemcached {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	server := &fakeMemcached{listener: listener, items: make(map[string]*fakeItem)}
+	go server.serve()
+	t.Cleanup(func() { listener.Close() })
+	return server
+}
+
+func (s *fakeMemcached) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeMemcached) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeMemcached) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch command := fields[0]; command {
+		case "get", "gets":
+			s.get(writer, fields[1:], command == "gets")
+		case "set", "add", "cas":
+			size, _ := strconv.Atoi(fields[4])
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, data); err != nil {
+				return
+			}
+			fmt.Fprint(writer, s.store(command, fields[1:], data[:size]))
+		case "delete":
+			fmt.Fprint(writer, s.delete(fields[1]))
+		case "incr", "decr":
+			fmt.Fprint(writer, s.incr(fields[1], fields[2], command == "decr"))
+		case "version":
+			fmt.Fprint(writer, "VERSION 1.6.0\r\n")
+		default:
+			fmt.Fprint(writer, "ERROR\r\n")
+		}
+		if err := writer.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// lookup returns a live item, dropping it if it expired. The caller must hold s.mu.
+func (s *fakeMemcached) lookup(key string) *fakeItem {
+	item, ok := s.items[key]
+	if !ok {
+		return nil
+	}
+	if !item.expiresAt.IsZero() && !time.Now().Before(item.expiresAt) {
+		delete(s.items, key)
+		return nil
+	}
+	return item
+}
+
+func (s *fakeMemcached) get(writer io.Writer, keys []string, withCas bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		item := s.lookup(key)
+		if item == nil {
+			continue
+		}
+		if withCas {
+			fmt.Fprintf(writer, "VALUE %s %d %d %d\r\n", key, item.flags, len(item.value), item.cas)
+		} else {
+			fmt.Fprintf(writer, "VALUE %s %d %d\r\n", key, item.flags, len(item.value))
+		}
+		fmt.Fprintf(writer, "%s\r\n", item.value)
+	}
+	fmt.Fprint(writer, "END\r\n")
+}
+
+func (s *fakeMemcached) store(command string, args []string, value []byte) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := args[0]
+	flags, _ := strconv.ParseUint(args[1], 10, 32)
+	exptime, _ := strconv.ParseInt(args[2], 10, 64)
+
+	existing := s.lookup(key)
+	switch command {
+	case "add":
+		if existing != nil {
+			return "NOT_STORED\r\n"
+		}
+	case "cas":
+		if existing == nil {
+			return "NOT_FOUND\r\n"
+		}
+		if unique, _ := strconv.ParseUint(args[4], 10, 64); unique != existing.cas {
+			return "EXISTS\r\n"
+		}
+	}
+
+	s.cas++
+	item := &fakeItem{value: append([]byte{}, value...), flags: uint32(flags), cas: s.cas}
+	switch {
+	case exptime < 0:
+		item.expiresAt = time.Now()
+	case exptime > int64(memcachedRelativeLimit/time.Second):
+		item.expiresAt = time.Unix(exptime, 0)
+	case exptime > 0:
+		item.expiresAt = time.Now().Add(time.Duration(exptime) * time.Second)
+	}
+	s.items[key] = item
+	return "STORED\r\n"
+}
+
+func (s *fakeMemcached) delete(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lookup(key) == nil {
+		return "NOT_FOUND\r\n"
+	}
+	delete(s.items, key)
+	return "DELETED\r\n"
+}
+
+func (s *fakeMemcached) incr(key, delta string, decrement bool) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item := s.lookup(key)
+	if item == nil {
+		return "NOT_FOUND\r\n"
+	}
+	counter, err := strconv.ParseUint(string(item.value), 10, 64)
+	if err != nil {
+		return "CLIENT_ERROR cannot increment or decrement non-numeric value\r\n"
+	}
+	by, _ := strconv.ParseUint(delta, 10, 64)
+	switch {
+	case !decrement:
+		counter += by
+	case by > counter:
+		counter = 0
+	default:
+		counter -= by
+	}
+
+	s.cas++
+	item.value, item.cas = []byte(strconv.FormatUint(counter, 10)), s.cas
+	return fmt.Sprintf("%d\r\n", counter)
+}
+
+func newTestMemcachedClient(t *testing.T) driven.Cache {
+	client := NewMemcachedClient(newFakeMemcached(t).addr())
+	assert.Nil(t, client.Connect())
+	t.Cleanup(func() { client.Disconnect() })
+	return client
+}
+
+func TestMemcachedClient(t *testing.T) {
+	ctx := context.Background()
+	client := newTestMemcachedClient(t)
+
+	_, err := client.Fetch(ctx, "user")
+	assert.ErrorIs(t, err, driven.ErrCacheMissed)
+
+	assert.Nil(t, client.Set(ctx, "user", []byte("state"), time.Minute))
+	value, err := client.Fetch(ctx, "user")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("state"), value)
+
+	assert.Nil(t, client.Delete(ctx, "user"))
+	_, err = client.Fetch(ctx, "user")
+	assert.ErrorIs(t, err, driven.ErrCacheMissed)
+
+	// Deleting a missing key is not an error
+	assert.Nil(t, client.Delete(ctx, "user"))
+
+	// Short expirations round up to a whole second instead of never expiring
+	assert.Nil(t, client.Set(ctx, "short", []byte("state"), 100*time.Millisecond))
+	_, err = client.Fetch(ctx, "short")
+	assert.Nil(t, err)
+}
+
+func TestMemcachedClient_Update(t *testing.T) {
+	ctx := context.Background()
+	client := newTestMemcachedClient(t)
+
+	// Returning nil leaves a missing key missing
+	assert.Nil(t, client.Update(ctx, "counter", time.Minute, func(current []byte) ([]byte, error) {
+		assert.Nil(t, current)
+		return nil, nil
+	}))
+	_, err := client.Fetch(ctx, "counter")
+	assert.ErrorIs(t, err, driven.ErrCacheMissed)
+
+	// Concurrent check-and-consume never goes past the limit
+	const limit, workers = 20, 8
+	var mu sync.Mutex
+	granted := 0
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				var took bool
+				err := client.Update(ctx, "counter", time.Minute, func(current []byte) ([]byte, error) {
+					count := 0
+					if current != nil {
+						count, _ = strconv.Atoi(string(current))
+					}
+					took = count < limit
+					if !took {
+						return nil, nil
+					}
+					return []byte(strconv.Itoa(count + 1)), nil
+				})
+				assert.Nil(t, err)
+				if took {
+					mu.Lock()
+					granted++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, limit, granted)
+	value, err := client.Fetch(ctx, "counter")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte(strconv.Itoa(limit)), value)
+}
+
+func TestMemcachedClient_Increment(t *testing.T) {
+	ctx := context.Background()
+	client := newTestMemcachedClient(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Increment(ctx, "violations", 1, time.Minute)
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+
+	counter, err := client.Increment(ctx, "violations", 5, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(15), counter)
+
+	counter, err = client.Increment(ctx, "violations", -20, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), counter)
+}
@@ -0,0 +1,185 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nullexp/limiter-x/internal/port/driven"
+)
+
+// NearCache layers an in-process cache over a shared remote one. Keys starting with one of the
+// configured prefixes are also kept locally for a short TTL, so hot reads of them skip the round
+// trip to the remote cache. Every write or delete of such a key is broadcast on a pub/sub channel
+// and other instances drop their local copy, keeping them coherent.
+type NearCache struct {
+	local, remote driven.Cache
+	pubsub        driven.PubSub
+
+	channel  string
+	ttl      time.Duration
+	prefixes []string
+
+	// instance tags invalidations so an instance ignores its own
+	instance   []byte
+	subscribed atomic.Bool
+	cancel     context.CancelFunc
+}
+
+func NewNearCache(local, remote driven.Cache, pubsub driven.PubSub, channel string, ttl time.Duration, prefixes ...string) driven.Cache {
+	return &NearCache{
+		local:    local,
+		remote:   remote,
+		pubsub:   pubsub,
+		channel:  channel,
+		ttl:      ttl,
+		prefixes: prefixes,
+		instance: []byte(uuid.New().String()),
+	}
+}
+
+func (nc *NearCache) Connect() error {
+	if err := nc.local.Connect(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	nc.cancel = cancel
+	go nc.listen(ctx)
+
+	return nc.remote.Connect()
+}
+
+func (nc *NearCache) Disconnect() error {
+	if nc.cancel != nil {
+		nc.cancel()
+	}
+	if err := nc.local.Disconnect(); err != nil {
+		return err
+	}
+	return nc.remote.Disconnect()
+}
+
+func (nc *NearCache) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	if err := nc.remote.Set(ctx, key, value, expiration); err != nil {
+		return err
+	}
+	if !nc.isLocal(key) {
+		return nil
+	}
+
+	ttl := nc.ttl
+	if expiration > 0 && expiration < ttl {
+		ttl = expiration
+	}
+	if err := nc.local.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	return nc.invalidate(ctx, key)
+}
+
+func (nc *NearCache) Fetch(ctx context.Context, key string) ([]byte, error) {
+	if !nc.isLocal(key) || !nc.subscribed.Load() {
+		return nc.remote.Fetch(ctx, key)
+	}
+
+	if value, err := nc.local.Fetch(ctx, key); err == nil {
+		return value, nil
+	}
+
+	value, err := nc.remote.Fetch(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := nc.local.Set(ctx, key, value, nc.ttl); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (nc *NearCache) Delete(ctx context.Context, key string) error {
+	if err := nc.remote.Delete(ctx, key); err != nil {
+		return err
+	}
+	return nc.dropLocal(ctx, key)
+}
+
+func (nc *NearCache) Update(ctx context.Context, key string, expiration time.Duration, fn func(current []byte) ([]byte, error)) error {
+	if err := nc.remote.Update(ctx, key, expiration, fn); err != nil {
+		return err
+	}
+	return nc.dropLocal(ctx, key)
+}
+
+func (nc *NearCache) Increment(ctx context.Context, key string, delta int64, expiration time.Duration) (int64, error) {
+	counter, err := nc.remote.Increment(ctx, key, delta, expiration)
+	if err != nil {
+		return 0, err
+	}
+	return counter, nc.dropLocal(ctx, key)
+}
+
+// dropLocal forgets the local copy of a key changed in place on the remote cache, here and on
+// every other instance.
+func (nc *NearCache) dropLocal(ctx context.Context, key string) error {
+	if !nc.isLocal(key) {
+		return nil
+	}
+	if err := nc.local.Delete(ctx, key); err != nil {
+		return err
+	}
+	return nc.invalidate(ctx, key)
+}
+
+// isLocal reports whether key may be kept in the local tier.
+func (nc *NearCache) isLocal(key string) bool {
+	for _, prefix := range nc.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// invalidate tells the other instances to drop their local copy of key.
+func (nc *NearCache) invalidate(ctx context.Context, key string) error {
+	message := append(append(append([]byte{}, nc.instance...), ' '), key...)
+	return nc.pubsub.Publish(ctx, nc.channel, message)
+}
+
+// listen applies invalidations from other instances until ctx is done. While it is not
+// subscribed the local tier is bypassed, since invalidations could be missed.
+func (nc *NearCache) listen(ctx context.Context) {
+	for ctx.Err() == nil {
+		messages, err := nc.pubsub.Subscribe(ctx, nc.channel)
+		if err != nil {
+			log.Printf("near cache failed to subscribe to invalidations: %v", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		nc.subscribed.Store(true)
+		for message := range messages {
+			instance, key, ok := bytes.Cut(message, []byte{' '})
+			if !ok || bytes.Equal(instance, nc.instance) {
+				continue
+			}
+			if err := nc.local.Delete(ctx, string(key)); err != nil {
+				log.Printf("near cache failed to invalidate %s: %v", key, err)
+			}
+		}
+		nc.subscribed.Store(false)
+
+		// Entries may have changed while the subscription was down
+		if err := nc.local.Disconnect(); err != nil {
+			log.Printf("near cache failed to flush: %v", err)
+		}
+	}
+}
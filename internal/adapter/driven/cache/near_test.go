@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nullexp/limiter-x/internal/port/driven"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryPubSub broadcasts messages to every subscriber in-process.
+type memoryPubSub struct {
+	mu          sync.Mutex
+	subscribers []chan []byte
+}
+
+func (ps *memoryPubSub) Publish(ctx context.Context, channel string, message []byte) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	for _, subscriber := range ps.subscribers {
+		subscriber <- message
+	}
+	return nil
+}
+
+func (ps *memoryPubSub) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	messages := make(chan []byte, 16)
+	ps.subscribers = append(ps.subscribers, messages)
+	return messages, nil
+}
+
+func newTestNearCache(t *testing.T, remote driven.Cache, pubsub driven.PubSub) driven.Cache {
+	near := NewNearCache(NewMemoryClient(time.Minute, time.Minute), remote, pubsub, "invalidate", time.Minute, "denied:")
+	assert.Nil(t, near.Connect())
+	t.Cleanup(func() { near.Disconnect() })
+
+	// Wait for the invalidation subscription
+	assert.Eventually(t, func() bool { return near.(*NearCache).subscribed.Load() }, time.Second, time.Millisecond)
+	return near
+}
+
+func TestNearCache(t *testing.T) {
+	ctx := context.Background()
+	remote := NewMemoryClient(time.Minute, time.Minute)
+	pubsub := &memoryPubSub{}
+	first := newTestNearCache(t, remote, pubsub)
+	second := newTestNearCache(t, remote, pubsub)
+
+	assert.Nil(t, first.Set(ctx, "denied:user", []byte("1"), time.Minute))
+
+	// The second instance reads through and keeps a local copy
+	value, err := second.Fetch(ctx, "denied:user")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("1"), value)
+
+	// A change behind its back is not seen until invalidated
+	assert.Nil(t, remote.Set(ctx, "denied:user", []byte("2"), time.Minute))
+	value, err = second.Fetch(ctx, "denied:user")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("1"), value)
+
+	// A delete through the first instance drops the second one's copy
+	assert.Nil(t, first.Delete(ctx, "denied:user"))
+	assert.Eventually(t, func() bool {
+		_, err := second.Fetch(ctx, "denied:user")
+		return err == driven.ErrCacheMissed
+	}, time.Second, time.Millisecond)
+
+	// Keys without a local prefix always go to the remote cache
+	assert.Nil(t, first.Set(ctx, "user", []byte("3"), time.Minute))
+	assert.Nil(t, remote.Set(ctx, "user", []byte("4"), time.Minute))
+	value, err = first.Fetch(ctx, "user")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("4"), value)
+}
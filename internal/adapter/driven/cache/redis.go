@@ -2,24 +2,62 @@ package cache
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/nullexp/limiter-x/internal/port/driven"
 	redis "github.com/redis/go-redis/v9"
 )
 
+// RedisMode selects the Redis deployment the client talks to.
+type RedisMode string
+
+const (
+	RedisSingle   RedisMode = "single"
+	RedisCluster  RedisMode = "cluster"
+	RedisSentinel RedisMode = "sentinel"
+)
+
+// ParseRedisMode converts the configuration value of a Redis mode.
+func ParseRedisMode(value string) (RedisMode, error) {
+	switch mode := RedisMode(strings.ToLower(value)); mode {
+	case "":
+		return RedisSingle, nil
+	case RedisSingle, RedisCluster, RedisSentinel:
+		return mode, nil
+	}
+	return RedisSingle, fmt.Errorf("unknown redis mode %q", value)
+}
+
 type RedisClient struct {
-	username, password, fullAddress, clientName string
+	mode    RedisMode
+	options *redis.UniversalOptions
 
-	client *redis.Client
+	client redis.UniversalClient
 }
 
-func NewRedisWithClient(client *redis.Client) driven.Cache {
+func NewRedisWithClient(client redis.UniversalClient) driven.Cache {
 	return &RedisClient{client: client}
 }
 
 func NewRedisClient(username, password, clientName, fullAddress string) driven.Cache {
-	return &RedisClient{username: username, password: password, fullAddress: fullAddress, clientName: clientName}
+	return NewRedisUniversalClient(RedisSingle, &redis.UniversalOptions{
+		Addrs: []string{fullAddress},
+
+		Password: password,
+
+		Username: username,
+
+		ClientName: clientName,
+	})
+}
+
+// NewRedisUniversalClient creates a client for a single node, a cluster or a Sentinel-managed
+// deployment. For a cluster Addrs holds seed nodes, for Sentinel it holds the sentinels and
+// MasterName the monitored master.
+func NewRedisUniversalClient(mode RedisMode, options *redis.UniversalOptions) driven.Cache {
+	return &RedisClient{mode: mode, options: options}
 }
 
 func (rc *RedisClient) Connect() error {
@@ -31,15 +69,14 @@ func (rc *RedisClient) Connect() error {
 		return ping()
 	}
 
-	rc.client = redis.NewClient(&redis.Options{
-		Addr: rc.fullAddress,
-
-		Password: rc.password,
-
-		Username: rc.username,
-
-		ClientName: rc.clientName,
-	})
+	switch rc.mode {
+	case RedisCluster:
+		rc.client = redis.NewClusterClient(rc.options.Cluster())
+	case RedisSentinel:
+		rc.client = redis.NewFailoverClient(rc.options.Failover())
+	default:
+		rc.client = redis.NewClient(rc.options.Simple())
+	}
 
 	return ping()
 }
@@ -65,3 +102,84 @@ func (rc *RedisClient) Fetch(ctx context.Context, key string) (val []byte, err e
 func (rc *RedisClient) Delete(ctx context.Context, key string) error {
 	return rc.client.Del(ctx, key).Err()
 }
+
+func (rc *RedisClient) Update(ctx context.Context, key string, expiration time.Duration, fn func(current []byte) ([]byte, error)) error {
+	update := func(tx *redis.Tx) error {
+		current, err := tx.Get(ctx, key).Bytes()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+
+		value, err := fn(current)
+		if err != nil || value == nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, value, expiration)
+			return nil
+		})
+		return err
+	}
+
+	// Retry while the key keeps changing between the read and the write
+	for {
+		err := rc.client.Watch(ctx, update, key)
+		if err != redis.TxFailedErr {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// incrementScript adds to a counter and sets the expiry only when the counter has none yet.
+var incrementScript = redis.NewScript(`
+local counter = redis.call('INCRBY', KEYS[1], ARGV[1])
+if tonumber(ARGV[2]) > 0 and redis.call('PTTL', KEYS[1]) == -1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return counter
+`)
+
+func (rc *RedisClient) Increment(ctx context.Context, key string, delta int64, expiration time.Duration) (int64, error) {
+	return incrementScript.Run(ctx, rc.client, []string{key}, delta, expiration.Milliseconds()).Int64()
+}
+
+func (rc *RedisClient) Publish(ctx context.Context, channel string, message []byte) error {
+	return rc.client.Publish(ctx, channel, message).Err()
+}
+
+func (rc *RedisClient) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	sub := rc.client.Subscribe(ctx, channel)
+	// Wait for the subscription to be confirmed so no message published afterwards is missed
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return nil, err
+	}
+
+	messages := make(chan []byte)
+	go func() {
+		defer close(messages)
+		defer sub.Close()
+
+		incoming := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-incoming:
+				if !ok {
+					return
+				}
+				select {
+				case messages <- []byte(msg.Payload):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return messages, nil
+}
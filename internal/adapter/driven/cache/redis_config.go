@@ -0,0 +1,220 @@
+package cache
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/nullexp/limiter-x/internal/port/driven"
+	redis "github.com/redis/go-redis/v9"
+)
+
+// RedisConfig holds the connection settings of the Redis adapter.
+type RedisConfig struct {
+	Mode       RedisMode
+	Addrs      []string
+	MasterName string
+	ClientName string
+	DB         int
+
+	// ACL credentials, SentinelPassword authenticates against the sentinels themselves
+	Username         string
+	Password         string
+	SentinelPassword string
+
+	TLS                   bool
+	TLSCAFile             string
+	TLSCertFile           string
+	TLSKeyFile            string
+	TLSServerName         string
+	TLSInsecureSkipVerify bool
+
+	// Zero values keep the go-redis defaults
+	PoolSize     int
+	MinIdleConns int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+// LoadRedisConfigFromEnv reads the Redis configuration from REDIS_* environment variables. When
+// REDIS_CONFIG_FILE names a file in .env format, its values are used for variables that are not
+// set in the environment.
+func LoadRedisConfigFromEnv() (RedisConfig, error) {
+	file := map[string]string{}
+	if path := os.Getenv("REDIS_CONFIG_FILE"); path != "" {
+		var err error
+		file, err = godotenv.Read(path)
+		if err != nil {
+			return RedisConfig{}, fmt.Errorf("failed to read redis config file: %w", err)
+		}
+	}
+
+	return LoadRedisConfig(func(name string) string {
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+		return file[name]
+	})
+}
+
+// LoadRedisConfig builds the Redis configuration from the REDIS_* values returned by lookup.
+func LoadRedisConfig(lookup func(name string) string) (RedisConfig, error) {
+	mode, err := ParseRedisMode(lookup("REDIS_MODE"))
+	if err != nil {
+		return RedisConfig{}, err
+	}
+
+	config := RedisConfig{
+		Mode:             mode,
+		Addrs:            []string{lookup("REDIS_URL")},
+		MasterName:       lookup("REDIS_MASTER_NAME"),
+		ClientName:       lookup("REDIS_CLIENT_NAME"),
+		Username:         lookup("REDIS_USERNAME"),
+		Password:         lookup("REDIS_PASSWORD"),
+		SentinelPassword: lookup("REDIS_SENTINEL_PASSWORD"),
+		TLSCAFile:        lookup("REDIS_TLS_CA_FILE"),
+		TLSCertFile:      lookup("REDIS_TLS_CERT_FILE"),
+		TLSKeyFile:       lookup("REDIS_TLS_KEY_FILE"),
+		TLSServerName:    lookup("REDIS_TLS_SERVER_NAME"),
+	}
+	if addrs := lookup("REDIS_ADDRS"); addrs != "" {
+		config.Addrs = strings.Split(addrs, ",")
+		for i := range config.Addrs {
+			config.Addrs[i] = strings.TrimSpace(config.Addrs[i])
+		}
+	}
+	if config.ClientName == "" {
+		config.ClientName = "redis"
+	}
+
+	parser := configParser{lookup: lookup}
+	config.DB = parser.int("REDIS_DB")
+	config.TLS = parser.bool("REDIS_TLS")
+	config.TLSInsecureSkipVerify = parser.bool("REDIS_TLS_INSECURE_SKIP_VERIFY")
+	config.PoolSize = parser.int("REDIS_POOL_SIZE")
+	config.MinIdleConns = parser.int("REDIS_MIN_IDLE_CONNS")
+	config.DialTimeout = parser.milliseconds("REDIS_DIAL_TIMEOUT_MILI_SEC")
+	config.ReadTimeout = parser.milliseconds("REDIS_READ_TIMEOUT_MILI_SEC")
+	config.WriteTimeout = parser.milliseconds("REDIS_WRITE_TIMEOUT_MILI_SEC")
+	if parser.err != nil {
+		return RedisConfig{}, parser.err
+	}
+
+	if err := config.validate(); err != nil {
+		return RedisConfig{}, err
+	}
+
+	// Client certificates are only used over TLS, so asking for one turns it on
+	if config.TLSCAFile != "" || config.TLSCertFile != "" {
+		config.TLS = true
+	}
+	return config, nil
+}
+
+// validate rejects configurations the mode cannot connect with.
+func (c RedisConfig) validate() error {
+	for _, addr := range c.Addrs {
+		if addr == "" {
+			return fmt.Errorf("missing redis address in %s mode", c.Mode)
+		}
+	}
+	if c.Mode == RedisSentinel && c.MasterName == "" {
+		return fmt.Errorf("the %s redis mode needs REDIS_MASTER_NAME", c.Mode)
+	}
+	return nil
+}
+
+// UniversalOptions converts the configuration into go-redis options, loading TLS material.
+func (c RedisConfig) UniversalOptions() (*redis.UniversalOptions, error) {
+	options := &redis.UniversalOptions{
+		Addrs:            c.Addrs,
+		MasterName:       c.MasterName,
+		ClientName:       c.ClientName,
+		DB:               c.DB,
+		Username:         c.Username,
+		Password:         c.Password,
+		SentinelPassword: c.SentinelPassword,
+		PoolSize:         c.PoolSize,
+		MinIdleConns:     c.MinIdleConns,
+		DialTimeout:      c.DialTimeout,
+		ReadTimeout:      c.ReadTimeout,
+		WriteTimeout:     c.WriteTimeout,
+	}
+	if !c.TLS {
+		return options, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.TLSServerName,
+		InsecureSkipVerify: c.TLSInsecureSkipVerify,
+	}
+	if c.TLSCAFile != "" {
+		ca, err := os.ReadFile(c.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read redis CA file: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in redis CA file %s", c.TLSCAFile)
+		}
+	}
+	if c.TLSCertFile != "" || c.TLSKeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(c.TLSCertFile, c.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load redis client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	options.TLSConfig = tlsConfig
+	return options, nil
+}
+
+// NewRedisClientFromConfig creates a Redis client from a typed configuration.
+func NewRedisClientFromConfig(config RedisConfig) (driven.Cache, error) {
+	options, err := config.UniversalOptions()
+	if err != nil {
+		return nil, err
+	}
+	return NewRedisUniversalClient(config.Mode, options), nil
+}
+
+// configParser converts configuration values, keeping the first error.
+type configParser struct {
+	lookup func(name string) string
+	err    error
+}
+
+func (p *configParser) int(name string) int {
+	value := p.lookup(name)
+	if value == "" || p.err != nil {
+		return 0
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		p.err = fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return parsed
+}
+
+func (p *configParser) bool(name string) bool {
+	value := p.lookup(name)
+	if value == "" || p.err != nil {
+		return false
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		p.err = fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return parsed
+}
+
+func (p *configParser) milliseconds(name string) time.Duration {
+	return time.Duration(p.int(name)) * time.Millisecond
+}
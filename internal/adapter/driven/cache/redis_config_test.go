@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadRedisConfig(t *testing.T) {
+	values := map[string]string{
+		"REDIS_MODE":                   "cluster",
+		"REDIS_ADDRS":                  "redis-0:6379,redis-1:6379",
+		"REDIS_USERNAME":               "limiter",
+		"REDIS_PASSWORD":               "secret",
+		"REDIS_DB":                     "2",
+		"REDIS_POOL_SIZE":              "32",
+		"REDIS_MIN_IDLE_CONNS":         "4",
+		"REDIS_DIAL_TIMEOUT_MILI_SEC":  "500",
+		"REDIS_READ_TIMEOUT_MILI_SEC":  "100",
+		"REDIS_WRITE_TIMEOUT_MILI_SEC": "150",
+	}
+	config, err := LoadRedisConfig(func(name string) string { return values[name] })
+	assert.Nil(t, err)
+
+	options, err := config.UniversalOptions()
+	assert.Nil(t, err)
+	assert.Equal(t, RedisCluster, config.Mode)
+	assert.Equal(t, []string{"redis-0:6379", "redis-1:6379"}, options.Addrs)
+	assert.Equal(t, "limiter", options.Username)
+	assert.Equal(t, "secret", options.Password)
+	assert.Equal(t, 2, options.DB)
+	assert.Equal(t, 32, options.PoolSize)
+	assert.Equal(t, 4, options.MinIdleConns)
+	assert.Equal(t, 500*time.Millisecond, options.DialTimeout)
+	assert.Equal(t, 100*time.Millisecond, options.ReadTimeout)
+	assert.Equal(t, 150*time.Millisecond, options.WriteTimeout)
+	assert.Nil(t, options.TLSConfig)
+
+	values["REDIS_POOL_SIZE"] = "many"
+	_, err = LoadRedisConfig(func(name string) string { return values[name] })
+	assert.NotNil(t, err)
+}
+
+func TestLoadRedisConfig_Modes(t *testing.T) {
+	tests := []struct {
+		name       string
+		values     map[string]string
+		mode       RedisMode
+		addrs      []string
+		masterName string
+		err        bool
+	}{
+		{name: "Single by default", values: map[string]string{"REDIS_URL": "redis:6379"}, mode: RedisSingle, addrs: []string{"redis:6379"}},
+		{name: "Single", values: map[string]string{"REDIS_MODE": "single", "REDIS_URL": "redis:6379"}, mode: RedisSingle, addrs: []string{"redis:6379"}},
+		{name: "Cluster", values: map[string]string{"REDIS_MODE": "Cluster", "REDIS_ADDRS": "redis-0:6379, redis-1:6379"}, mode: RedisCluster, addrs: []string{"redis-0:6379", "redis-1:6379"}},
+		{name: "Sentinel", values: map[string]string{"REDIS_MODE": "sentinel", "REDIS_ADDRS": "sentinel-0:26379,sentinel-1:26379", "REDIS_MASTER_NAME": "limiter"}, mode: RedisSentinel, addrs: []string{"sentinel-0:26379", "sentinel-1:26379"}, masterName: "limiter"},
+		{name: "Unknown mode", values: map[string]string{"REDIS_MODE": "replicated", "REDIS_URL": "redis:6379"}, err: true},
+		{name: "Sentinel without master", values: map[string]string{"REDIS_MODE": "sentinel", "REDIS_ADDRS": "sentinel-0:26379"}, err: true},
+		{name: "No address", values: map[string]string{"REDIS_MODE": "single"}, err: true},
+		{name: "Empty address", values: map[string]string{"REDIS_MODE": "cluster", "REDIS_ADDRS": "redis-0:6379,,redis-1:6379"}, err: true},
+		{name: "Invalid number", values: map[string]string{"REDIS_URL": "redis:6379", "REDIS_DB": "first"}, err: true},
+		{name: "Invalid flag", values: map[string]string{"REDIS_URL": "redis:6379", "REDIS_TLS": "maybe"}, err: true},
+		{name: "Invalid timeout", values: map[string]string{"REDIS_URL": "redis:6379", "REDIS_DIAL_TIMEOUT_MILI_SEC": "1s"}, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := LoadRedisConfig(func(name string) string { return test.values[name] })
+			if test.err {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, test.mode, config.Mode)
+			assert.Equal(t, test.addrs, config.Addrs)
+			assert.Equal(t, test.masterName, config.MasterName)
+
+			options, err := config.UniversalOptions()
+			assert.Nil(t, err)
+			assert.Equal(t, test.addrs, options.Addrs)
+			assert.Equal(t, test.masterName, options.MasterName)
+		})
+	}
+}
+
+func TestLoadRedisConfig_TLS(t *testing.T) {
+	values := map[string]string{
+		"REDIS_URL":             "redis:6380",
+		"REDIS_TLS_CA_FILE":     filepath.Join(t.TempDir(), "missing.pem"),
+		"REDIS_TLS_SERVER_NAME": "redis.internal",
+	}
+	config, err := LoadRedisConfig(func(name string) string { return values[name] })
+	assert.Nil(t, err)
+	assert.True(t, config.TLS)
+
+	_, err = config.UniversalOptions()
+	assert.NotNil(t, err)
+
+	delete(values, "REDIS_TLS_CA_FILE")
+	values["REDIS_TLS"] = "true"
+	config, err = LoadRedisConfig(func(name string) string { return values[name] })
+	assert.Nil(t, err)
+
+	options, err := config.UniversalOptions()
+	assert.Nil(t, err)
+	assert.Equal(t, "redis.internal", options.TLSConfig.ServerName)
+}
+
+func TestLoadRedisConfigFromEnv_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redis.env")
+	assert.Nil(t, os.WriteFile(path, []byte("REDIS_URL=from-file:6379\nREDIS_PASSWORD=file-secret\n"), 0o600))
+
+	t.Setenv("REDIS_CONFIG_FILE", path)
+	t.Setenv("REDIS_PASSWORD", "env-secret")
+
+	config, err := LoadRedisConfigFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"from-file:6379"}, config.Addrs)
+	// The environment wins over the file
+	assert.Equal(t, "env-secret", config.Password)
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+)
+
+// Dialect names a supported SQL database.
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	Mysql    Dialect = "mysql"
+	Sqlite   Dialect = "sqlite"
+)
+
+// ParseDialect reads a dialect name, defaulting to Postgres. MariaDB uses the MySQL dialect.
+func ParseDialect(value string) (Dialect, error) {
+	switch value {
+	case "", "postgres":
+		return Postgres, nil
+	case "mysql", "mariadb":
+		return Mysql, nil
+	case "sqlite":
+		return Sqlite, nil
+	}
+	return "", fmt.Errorf("unknown database dialect %q", value)
+}
+
+// Open connects to the database described by dsn and checks that it answers. For SQLite the dsn
+// is the path of the database file.
+func Open(dialect Dialect, dsn string) (*sql.DB, error) {
+	var driverName string
+	switch dialect {
+	case Sqlite:
+		return OpenSqlite(dsn)
+	case Mysql:
+		driverName = "mysql"
+	default:
+		driverName = "postgres"
+	}
+
+	sqlDb, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := sqlDb.Ping(); err != nil {
+		sqlDb.Close()
+		return nil, err
+	}
+	return sqlDb, nil
+}
+
+// NewDbTransactionFactory returns the transaction factory for the dialect.
+func NewDbTransactionFactory(dialect Dialect, sqlDb *sql.DB) db.DbTransactionFactory {
+	switch dialect {
+	case Sqlite:
+		return NewSqliteDbTransactionFactory(sqlDb)
+	case Mysql:
+		return NewMysqlDbTransactionFactory(sqlDb)
+	default:
+		return NewPostgresDbTransactionFactory(sqlDb)
+	}
+}
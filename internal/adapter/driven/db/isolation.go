@@ -0,0 +1,25 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ParseIsolationLevel reads an isolation level name such as "read-committed" or "serializable".
+// An empty name keeps the database's default level.
+func ParseIsolationLevel(value string) (sql.IsolationLevel, error) {
+	switch strings.ReplaceAll(strings.ToLower(value), "_", "-") {
+	case "", "default":
+		return sql.LevelDefault, nil
+	case "read-uncommitted":
+		return sql.LevelReadUncommitted, nil
+	case "read-committed":
+		return sql.LevelReadCommitted, nil
+	case "repeatable-read":
+		return sql.LevelRepeatableRead, nil
+	case "serializable":
+		return sql.LevelSerializable, nil
+	}
+	return sql.LevelDefault, fmt.Errorf("unknown isolation level %q", value)
+}
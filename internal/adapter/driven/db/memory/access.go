@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+const accessRulesTable = "access_rules"
+
+type AccessRuleRepositoryFactory struct{}
+
+func NewAccessRuleRepositoryFactory() *AccessRuleRepositoryFactory {
+	return &AccessRuleRepositoryFactory{}
+}
+
+func (f *AccessRuleRepositoryFactory) New(handler db.DbHandler) repository.AccessRuleRepository {
+	tx, _ := handler.(*Transaction)
+	return &AccessRuleRepository{tx: tx}
+}
+
+// AccessRuleRepository keeps each access rule under its ID.
+type AccessRuleRepository struct {
+	tx *Transaction
+}
+
+// SetAccessRule stores the rule, replacing the one with its ID
+func (ar *AccessRuleRepository) SetAccessRule(ctx context.Context, rule model.AccessRule) error {
+	if ar.tx == nil {
+		return ErrNotMemoryTransaction
+	}
+
+	now := time.Now().UTC()
+	_, err := ar.tx.merge(accessRulesTable, rule.Id, func(current any, exists bool) (any, error) {
+		rule.CreatedAt, rule.UpdatedAt = now, now
+		if exists {
+			rule.CreatedAt = current.(model.AccessRule).CreatedAt
+		}
+		return rule, nil
+	})
+	return err
+}
+
+// ListAccessRules retrieves all rules
+func (ar *AccessRuleRepository) ListAccessRules(ctx context.Context) ([]model.AccessRule, error) {
+	if ar.tx == nil {
+		return nil, ErrNotMemoryTransaction
+	}
+
+	var rules []model.AccessRule
+	ar.tx.scan(accessRulesTable, func(_ string, value any) bool {
+		rules = append(rules, value.(model.AccessRule))
+		return true
+	})
+	sortAccessRules(rules)
+	return rules, nil
+}
+
+// DeleteAccessRule removes the rule with the ID
+func (ar *AccessRuleRepository) DeleteAccessRule(ctx context.Context, id string) error {
+	if ar.tx == nil {
+		return ErrNotMemoryTransaction
+	}
+	if _, ok := ar.tx.get(accessRulesTable, id); !ok {
+		return domain.ErrAccessRuleNotFound
+	}
+
+	ar.tx.delete(accessRulesTable, id)
+	return nil
+}
+
+// sortAccessRules orders rules stored by ID by match and pattern.
+func sortAccessRules(rules []model.AccessRule) {
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Match != rules[j].Match {
+			return rules[i].Match < rules[j].Match
+		}
+		return rules[i].Pattern < rules[j].Pattern
+	})
+}
@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+const auditEventsTable = "audit_events"
+
+type AuditEventRepositoryFactory struct{}
+
+func NewAuditEventRepositoryFactory() *AuditEventRepositoryFactory {
+	return &AuditEventRepositoryFactory{}
+}
+
+func (f *AuditEventRepositoryFactory) New(handler db.DbHandler) repository.AuditEventRepository {
+	tx, _ := handler.(*Transaction)
+	return &AuditEventRepository{tx: tx}
+}
+
+// AuditEventRepository keys events by creation time, so scanning the table visits them in the
+// order they happened.
+type AuditEventRepository struct {
+	tx *Transaction
+}
+
+// AppendEvent stores a new audit event with an auto-generated ID
+func (ar *AuditEventRepository) AppendEvent(ctx context.Context, event model.AuditEvent) error {
+	if ar.tx == nil {
+		return ErrNotMemoryTransaction
+	}
+
+	event.Id = uuid.New().String()
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	event.CreatedAt = event.CreatedAt.UTC()
+	ar.tx.put(auditEventsTable, fmt.Sprintf("%020d/%s", event.CreatedAt.UnixNano(), event.Id), event)
+	return nil
+}
+
+// ListEvents retrieves the audit events matching the filter, newest first
+func (ar *AuditEventRepository) ListEvents(ctx context.Context, filter model.AuditFilter) ([]model.AuditEvent, error) {
+	if ar.tx == nil {
+		return nil, ErrNotMemoryTransaction
+	}
+
+	var events []model.AuditEvent
+	ar.tx.scan(auditEventsTable, func(key string, value any) bool {
+		if event := value.(model.AuditEvent); filter.Matches(event) {
+			events = append(events, event)
+		}
+		return true
+	})
+
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	if filter.Limit > 0 && len(events) > filter.Limit {
+		events = events[:filter.Limit]
+	}
+	return events, nil
+}
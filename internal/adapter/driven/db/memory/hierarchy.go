@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+const keyParentsTable = "key_parents"
+
+type ParentRepositoryFactory struct{}
+
+func NewParentRepositoryFactory() *ParentRepositoryFactory {
+	return &ParentRepositoryFactory{}
+}
+
+func (f *ParentRepositoryFactory) New(handler db.DbHandler) repository.ParentRepository {
+	tx, _ := handler.(*Transaction)
+	return &ParentRepository{tx: tx}
+}
+
+// ParentRepository keeps the parent link of each key under the key.
+type ParentRepository struct {
+	tx *Transaction
+}
+
+// SetParent stores the parent of the key, replacing the one it had
+func (pr *ParentRepository) SetParent(ctx context.Context, key, parent string) error {
+	if pr.tx == nil {
+		return ErrNotMemoryTransaction
+	}
+
+	now := time.Now().UTC()
+	_, err := pr.tx.merge(keyParentsTable, key, func(current any, exists bool) (any, error) {
+		link := model.KeyParent{Key: key, Parent: parent, CreatedAt: now, UpdatedAt: now}
+		if exists {
+			link.CreatedAt = current.(model.KeyParent).CreatedAt
+		}
+		return link, nil
+	})
+	return err
+}
+
+// GetParent retrieves the parent link of the key
+func (pr *ParentRepository) GetParent(ctx context.Context, key string) (*model.KeyParent, error) {
+	if pr.tx == nil {
+		return nil, ErrNotMemoryTransaction
+	}
+
+	value, ok := pr.tx.get(keyParentsTable, key)
+	if !ok {
+		return nil, nil
+	}
+	link := value.(model.KeyParent)
+	return &link, nil
+}
+
+// ListChildren retrieves the links of the keys whose parent is the given key
+func (pr *ParentRepository) ListChildren(ctx context.Context, parent string) ([]model.KeyParent, error) {
+	if pr.tx == nil {
+		return nil, ErrNotMemoryTransaction
+	}
+
+	var children []model.KeyParent
+	pr.tx.scan(keyParentsTable, func(_ string, value any) bool {
+		if link := value.(model.KeyParent); link.Parent == parent {
+			children = append(children, link)
+		}
+		return true
+	})
+	return children, nil
+}
+
+// DeleteParent removes the parent of the key
+func (pr *ParentRepository) DeleteParent(ctx context.Context, key string) error {
+	if pr.tx == nil {
+		return ErrNotMemoryTransaction
+	}
+	if _, ok := pr.tx.get(keyParentsTable, key); !ok {
+		return domain.ErrParentNotFound
+	}
+
+	pr.tx.delete(keyParentsTable, key)
+	return nil
+}
@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+const (
+	overridesTable = "overrides"
+	// globalOverrideId is the key of the global override.
+	globalOverrideId = "global"
+)
+
+type OverrideRepositoryFactory struct{}
+
+func NewOverrideRepositoryFactory() *OverrideRepositoryFactory {
+	return &OverrideRepositoryFactory{}
+}
+
+func (f *OverrideRepositoryFactory) New(handler db.DbHandler) repository.OverrideRepository {
+	tx, _ := handler.(*Transaction)
+	return &OverrideRepository{tx: tx}
+}
+
+// OverrideRepository keeps the global override under a single key.
+type OverrideRepository struct {
+	tx *Transaction
+}
+
+// SetOverride stores the override, replacing the one set before
+func (or *OverrideRepository) SetOverride(ctx context.Context, override model.Override) error {
+	if or.tx == nil {
+		return ErrNotMemoryTransaction
+	}
+
+	now := time.Now().UTC()
+	_, err := or.tx.merge(overridesTable, globalOverrideId, func(current any, exists bool) (any, error) {
+		override.CreatedAt, override.UpdatedAt = now, now
+		if exists {
+			override.CreatedAt = current.(model.Override).CreatedAt
+		}
+		return override, nil
+	})
+	return err
+}
+
+// GetOverride retrieves the override, nil if none is set
+func (or *OverrideRepository) GetOverride(ctx context.Context) (*model.Override, error) {
+	if or.tx == nil {
+		return nil, ErrNotMemoryTransaction
+	}
+
+	value, ok := or.tx.get(overridesTable, globalOverrideId)
+	if !ok {
+		return nil, nil
+	}
+	override := value.(model.Override)
+	return &override, nil
+}
+
+// DeleteOverride removes the override
+func (or *OverrideRepository) DeleteOverride(ctx context.Context) error {
+	if or.tx == nil {
+		return ErrNotMemoryTransaction
+	}
+
+	if _, ok := or.tx.get(overridesTable, globalOverrideId); !ok {
+		return domain.ErrOverrideNotFound
+	}
+	or.tx.delete(overridesTable, globalOverrideId)
+	return nil
+}
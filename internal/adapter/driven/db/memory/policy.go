@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+const policiesTable = "policies"
+
+type PolicyRepositoryFactory struct{}
+
+func NewPolicyRepositoryFactory() *PolicyRepositoryFactory {
+	return &PolicyRepositoryFactory{}
+}
+
+func (f *PolicyRepositoryFactory) New(handler db.DbHandler) repository.PolicyRepository {
+	tx, _ := handler.(*Transaction)
+	return &PolicyRepository{tx: tx}
+}
+
+// PolicyRepository keeps the limits of a key's policy together in one entry, sorted by window.
+type PolicyRepository struct {
+	tx *Transaction
+}
+
+// SetPolicy replaces the limits of the key's policy, keeping the counts of the windows it had
+func (pr *PolicyRepository) SetPolicy(ctx context.Context, key string, limits []model.PolicyLimit) error {
+	if pr.tx == nil {
+		return ErrNotMemoryTransaction
+	}
+
+	now := time.Now().UTC()
+	_, err := pr.tx.merge(policiesTable, key, func(current any, exists bool) (any, error) {
+		existing := make(map[time.Duration]model.PolicyLimit)
+		if exists {
+			for _, limit := range current.([]model.PolicyLimit) {
+				existing[limit.Window] = limit
+			}
+		}
+
+		stored := make([]model.PolicyLimit, 0, len(limits))
+		for _, limit := range limits {
+			next := model.PolicyLimit{Id: uuid.New().String(), Key: key, Window: limit.Window, Limit: limit.Limit, Shadow: limit.Shadow, CreatedAt: now, UpdatedAt: now}
+			if previous, ok := existing[limit.Window]; ok {
+				next.Id, next.Count, next.WindowStart, next.CreatedAt = previous.Id, previous.Count, previous.WindowStart, previous.CreatedAt
+			}
+			stored = append(stored, next)
+		}
+		sort.Slice(stored, func(i, j int) bool { return stored[i].Window < stored[j].Window })
+		return stored, nil
+	})
+	return err
+}
+
+// GetPolicy retrieves the limits of the key's policy
+func (pr *PolicyRepository) GetPolicy(ctx context.Context, key string) ([]model.PolicyLimit, error) {
+	if pr.tx == nil {
+		return nil, ErrNotMemoryTransaction
+	}
+
+	value, ok := pr.tx.get(policiesTable, key)
+	if !ok {
+		return nil, nil
+	}
+	// Callers may change the limits they get, which must not change the stored ones
+	return append([]model.PolicyLimit(nil), value.([]model.PolicyLimit)...), nil
+}
+
+// GetPolicyForUpdate retrieves the limits of the key's policy. The in-memory store has no locks,
+// so requests counted concurrently may overshoot a limit by the requests in flight
+func (pr *PolicyRepository) GetPolicyForUpdate(ctx context.Context, key string) ([]model.PolicyLimit, error) {
+	return pr.GetPolicy(ctx, key)
+}
+
+// UpdatePolicyCounts stores the counts and window starts of the key's limits
+func (pr *PolicyRepository) UpdatePolicyCounts(ctx context.Context, key string, limits []model.PolicyLimit) error {
+	if pr.tx == nil {
+		return ErrNotMemoryTransaction
+	}
+	if _, ok := pr.tx.get(policiesTable, key); !ok {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	_, err := pr.tx.merge(policiesTable, key, func(current any, exists bool) (any, error) {
+		if !exists {
+			// Removed in the meantime, there is nothing to count against
+			return nil, nil
+		}
+		stored := append([]model.PolicyLimit(nil), current.([]model.PolicyLimit)...)
+		for i := range stored {
+			for _, limit := range limits {
+				if limit.Window == stored[i].Window {
+					stored[i].Count, stored[i].WindowStart, stored[i].UpdatedAt = limit.Count, limit.WindowStart.UTC(), now
+				}
+			}
+		}
+		return stored, nil
+	})
+	return err
+}
+
+// DeletePolicy removes the key's policy
+func (pr *PolicyRepository) DeletePolicy(ctx context.Context, key string) error {
+	if pr.tx == nil {
+		return ErrNotMemoryTransaction
+	}
+	if _, ok := pr.tx.get(policiesTable, key); !ok {
+		return domain.ErrPolicyNotFound
+	}
+
+	pr.tx.delete(policiesTable, key)
+	return nil
+}
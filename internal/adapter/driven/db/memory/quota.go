@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+const quotasTable = "quotas"
+
+// quotaPeriods lists the periods a key can have a quota for, in the order they are returned.
+var quotaPeriods = []model.QuotaPeriod{model.QuotaDaily, model.QuotaMonthly, model.QuotaWeekly}
+
+type QuotaRepositoryFactory struct{}
+
+func NewQuotaRepositoryFactory() *QuotaRepositoryFactory {
+	return &QuotaRepositoryFactory{}
+}
+
+func (f *QuotaRepositoryFactory) New(handler db.DbHandler) repository.QuotaRepository {
+	tx, _ := handler.(*Transaction)
+	return &QuotaRepository{tx: tx}
+}
+
+// QuotaRepository keeps one quota per key and period, like the unique index on the quotas table.
+type QuotaRepository struct {
+	tx *Transaction
+}
+
+// SetQuota stores a new quota with an auto-generated ID, or changes the key's existing quota for
+// the period
+func (qr *QuotaRepository) SetQuota(ctx context.Context, quota model.Quota) error {
+	if qr.tx == nil {
+		return ErrNotMemoryTransaction
+	}
+
+	id := uuid.New().String()
+	now := time.Now().UTC()
+	if quota.TimeZone == "" {
+		quota.TimeZone = "UTC"
+	}
+	_, err := qr.tx.merge(quotasTable, quotaKey(quota.Key, quota.Period), func(current any, exists bool) (any, error) {
+		stored := quota
+		stored.Id, stored.PeriodStart, stored.Used, stored.CreatedAt, stored.UpdatedAt = id, time.Time{}, 0, now, now
+		if exists {
+			existing := current.(model.Quota)
+			stored.Id, stored.PeriodStart, stored.Used, stored.CreatedAt = existing.Id, existing.PeriodStart, existing.Used, existing.CreatedAt
+		}
+		return stored, nil
+	})
+	return err
+}
+
+// GetQuotasByKey retrieves the quotas of a key
+func (qr *QuotaRepository) GetQuotasByKey(ctx context.Context, key string) ([]model.Quota, error) {
+	if qr.tx == nil {
+		return nil, ErrNotMemoryTransaction
+	}
+
+	var quotas []model.Quota
+	for _, period := range quotaPeriods {
+		if value, ok := qr.tx.get(quotasTable, quotaKey(key, period)); ok {
+			quotas = append(quotas, value.(model.Quota))
+		}
+	}
+	return quotas, nil
+}
+
+// GetQuotasByKeyForUpdate retrieves the quotas of a key. The in-memory store has no locks, so
+// requests counted concurrently may overshoot a quota by the requests in flight
+func (qr *QuotaRepository) GetQuotasByKeyForUpdate(ctx context.Context, key string) ([]model.Quota, error) {
+	return qr.GetQuotasByKey(ctx, key)
+}
+
+// AddQuotaUsage adds to what a quota counted in the period, starting over if the quota was still
+// counting an earlier one
+func (qr *QuotaRepository) AddQuotaUsage(ctx context.Context, key string, period model.QuotaPeriod, periodStart time.Time, units int64) error {
+	if qr.tx == nil {
+		return ErrNotMemoryTransaction
+	}
+	if _, ok := qr.tx.get(quotasTable, quotaKey(key, period)); !ok {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	_, err := qr.tx.merge(quotasTable, quotaKey(key, period), func(current any, exists bool) (any, error) {
+		if !exists {
+			// Removed in the meantime, there is nothing to count against
+			return nil, nil
+		}
+		quota := current.(model.Quota)
+		quota.Used = quota.UsedIn(periodStart) + units
+		if quota.Used < 0 {
+			quota.Used = 0
+		}
+		quota.PeriodStart, quota.UpdatedAt = periodStart.UTC(), now
+		return quota, nil
+	})
+	return err
+}
+
+// DeleteQuota removes the key's quota for the period
+func (qr *QuotaRepository) DeleteQuota(ctx context.Context, key string, period model.QuotaPeriod) error {
+	if qr.tx == nil {
+		return ErrNotMemoryTransaction
+	}
+	if _, ok := qr.tx.get(quotasTable, quotaKey(key, period)); !ok {
+		return domain.ErrQuotaNotFound
+	}
+
+	qr.tx.delete(quotasTable, quotaKey(key, period))
+	return nil
+}
+
+func quotaKey(key string, period model.QuotaPeriod) string {
+	return key + "/" + string(period)
+}
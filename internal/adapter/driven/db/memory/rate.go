@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+const userRateLimitsTable = "user_rate_limits"
+
+var ErrNotMemoryTransaction = errors.New("handler is not an in-memory transaction")
+
+type UserRateLimitRepositoryFactory struct{}
+
+func NewUserRateLimitRepositoryFactory() *UserRateLimitRepositoryFactory {
+	return &UserRateLimitRepositoryFactory{}
+}
+
+func (f *UserRateLimitRepositoryFactory) New(handler db.DbHandler) repository.UserRateLimitRepository {
+	tx, _ := handler.(*Transaction)
+	return &UserRateLimitRepository{tx: tx}
+}
+
+// UserRateLimitRepository keeps one record per user, keyed by user ID like the unique index on
+// the user_rate_limits table.
+type UserRateLimitRepository struct {
+	tx *Transaction
+}
+
+// CreateRateLimit stores a new user rate limit record with an auto-generated ID and returns its
+// ID, unless the user already has a record
+func (ur *UserRateLimitRepository) CreateRateLimit(ctx context.Context, rateLimit model.UserRateLimit) (string, error) {
+	if ur.tx == nil {
+		return "", ErrNotMemoryTransaction
+	}
+
+	id := uuid.New().String()
+	now := time.Now().UTC()
+	stored, err := ur.tx.merge(userRateLimitsTable, rateLimit.UserId, func(current any, exists bool) (any, error) {
+		if exists {
+			return nil, domain.ErrRateLimitConflict
+		}
+		created := rateLimit
+		created.Id, created.Version, created.CreatedAt, created.UpdatedAt = id, 1, now, now
+		return created, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return stored.(model.UserRateLimit).Id, nil
+}
+
+// GetRateLimitByUserId retrieves a user's rate limit by user ID
+func (ur *UserRateLimitRepository) GetRateLimitByUserId(ctx context.Context, userId string) (*model.UserRateLimit, error) {
+	if ur.tx == nil {
+		return nil, ErrNotMemoryTransaction
+	}
+
+	value, ok := ur.tx.get(userRateLimitsTable, userId)
+	if !ok {
+		return nil, nil // Return nil if no records found
+	}
+	rateLimit := value.(model.UserRateLimit)
+	return &rateLimit, nil
+}
+
+// GetRateLimitByUserIdForUpdate retrieves a user's rate limit by user ID. The in-memory store has no
+// locks; a concurrent change is caught by the version check when the transaction commits instead
+func (ur *UserRateLimitRepository) GetRateLimitByUserIdForUpdate(ctx context.Context, userId string) (*model.UserRateLimit, error) {
+	return ur.GetRateLimitByUserId(ctx, userId)
+}
+
+// UpdateRateLimit updates an existing user's rate limit unless it changed since it was read
+func (ur *UserRateLimitRepository) UpdateRateLimit(ctx context.Context, rateLimit model.UserRateLimit) error {
+	if ur.tx == nil {
+		return ErrNotMemoryTransaction
+	}
+
+	now := time.Now().UTC()
+	_, err := ur.tx.merge(userRateLimitsTable, rateLimit.UserId, func(current any, exists bool) (any, error) {
+		if !exists {
+			return nil, domain.ErrRateLimitConflict
+		}
+		existing := current.(model.UserRateLimit)
+		if existing.Id != rateLimit.Id || existing.Version != rateLimit.Version {
+			return nil, domain.ErrRateLimitConflict
+		}
+
+		updated := rateLimit
+		updated.Version, updated.CreatedAt, updated.UpdatedAt = existing.Version+1, existing.CreatedAt, now
+		return updated, nil
+	})
+	return err
+}
+
+// DeleteRateLimit removes a user's rate limit record
+func (ur *UserRateLimitRepository) DeleteRateLimit(ctx context.Context, userId string) error {
+	if ur.tx == nil {
+		return ErrNotMemoryTransaction
+	}
+
+	ur.tx.delete(userRateLimitsTable, userId)
+	return nil
+}
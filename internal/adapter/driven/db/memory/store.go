@@ -0,0 +1,100 @@
+// Package memory keeps repository data in process, for tests and for running the service without
+// a database. Transactions buffer their writes and apply them to the shared Store atomically on
+// commit, so rolled back work is never seen by anyone else.
+package memory
+
+import (
+	"sort"
+	"sync"
+)
+
+// Store holds committed rows by table and key. It is safe for concurrent use.
+type Store struct {
+	mu     sync.RWMutex
+	tables map[string]map[string]any
+}
+
+func NewStore() *Store {
+	return &Store{tables: make(map[string]map[string]any)}
+}
+
+func (s *Store) get(table, key string) (any, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value, ok := s.tables[table][key]
+	return value, ok
+}
+
+// rows returns a copy of a table's committed rows.
+func (s *Store) rows(table string) map[string]any {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows := make(map[string]any, len(s.tables[table]))
+	for key, value := range s.tables[table] {
+		rows[key] = value
+	}
+	return rows
+}
+
+// apply makes all writes visible at once, or none of them if a merge fails.
+func (s *Store) apply(writes []write) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type row struct {
+		value  any
+		exists bool
+	}
+	type rowId struct{ table, key string }
+
+	// Stage the writes first, so later writes see earlier ones and a failed merge changes nothing
+	staged := make(map[rowId]row)
+	var order []rowId
+	for _, w := range writes {
+		id := rowId{w.table, w.key}
+		current, ok := staged[id]
+		if !ok {
+			current.value, current.exists = s.tables[w.table][w.key]
+			order = append(order, id)
+		}
+
+		switch {
+		case w.merge != nil:
+			value, err := w.merge(current.value, current.exists)
+			if err != nil {
+				return err
+			}
+			staged[id] = row{value: value, exists: value != nil}
+		case w.deleted:
+			staged[id] = row{}
+		default:
+			staged[id] = row{value: w.value, exists: true}
+		}
+	}
+
+	for _, id := range order {
+		rows, ok := s.tables[id.table]
+		if !ok {
+			rows = make(map[string]any)
+			s.tables[id.table] = rows
+		}
+		if r := staged[id]; r.exists {
+			rows[id.key] = r.value
+		} else {
+			delete(rows, id.key)
+		}
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of rows in order, so scans are deterministic.
+func sortedKeys(rows map[string]any) []string {
+	keys := make([]string, 0, len(rows))
+	for key := range rows {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,147 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+const (
+	tiersTable        = "tiers"
+	tierMappingsTable = "tier_mappings"
+)
+
+type TierRepositoryFactory struct{}
+
+func NewTierRepositoryFactory() *TierRepositoryFactory {
+	return &TierRepositoryFactory{}
+}
+
+func (f *TierRepositoryFactory) New(handler db.DbHandler) repository.TierRepository {
+	tx, _ := handler.(*Transaction)
+	return &TierRepository{tx: tx}
+}
+
+// TierRepository keeps each tier under its name and each mapping under its role or plan.
+type TierRepository struct {
+	tx *Transaction
+}
+
+// SetTier stores the tier, replacing the one with the same name
+func (tr *TierRepository) SetTier(ctx context.Context, tier model.Tier) error {
+	if tr.tx == nil {
+		return ErrNotMemoryTransaction
+	}
+
+	now := time.Now().UTC()
+	_, err := tr.tx.merge(tiersTable, tier.Name, func(current any, exists bool) (any, error) {
+		tier.CreatedAt, tier.UpdatedAt = now, now
+		if exists {
+			tier.CreatedAt = current.(model.Tier).CreatedAt
+		}
+		return tier, nil
+	})
+	return err
+}
+
+// GetTier retrieves the tier with the name
+func (tr *TierRepository) GetTier(ctx context.Context, name string) (*model.Tier, error) {
+	if tr.tx == nil {
+		return nil, ErrNotMemoryTransaction
+	}
+
+	value, ok := tr.tx.get(tiersTable, name)
+	if !ok {
+		return nil, nil
+	}
+	tier := value.(model.Tier)
+	return &tier, nil
+}
+
+// ListTiers retrieves all tiers
+func (tr *TierRepository) ListTiers(ctx context.Context) ([]model.Tier, error) {
+	if tr.tx == nil {
+		return nil, ErrNotMemoryTransaction
+	}
+
+	var tiers []model.Tier
+	tr.tx.scan(tiersTable, func(_ string, value any) bool {
+		tiers = append(tiers, value.(model.Tier))
+		return true
+	})
+	return tiers, nil
+}
+
+// DeleteTier removes the tier with the name
+func (tr *TierRepository) DeleteTier(ctx context.Context, name string) error {
+	if tr.tx == nil {
+		return ErrNotMemoryTransaction
+	}
+	if _, ok := tr.tx.get(tiersTable, name); !ok {
+		return domain.ErrTierNotFound
+	}
+
+	tr.tx.delete(tiersTable, name)
+	return nil
+}
+
+// SetTierMapping stores the tier of the role or plan, replacing the one it had
+func (tr *TierRepository) SetTierMapping(ctx context.Context, subject, tier string) error {
+	if tr.tx == nil {
+		return ErrNotMemoryTransaction
+	}
+
+	now := time.Now().UTC()
+	_, err := tr.tx.merge(tierMappingsTable, subject, func(current any, exists bool) (any, error) {
+		mapping := model.TierMapping{Subject: subject, Tier: tier, CreatedAt: now, UpdatedAt: now}
+		if exists {
+			mapping.CreatedAt = current.(model.TierMapping).CreatedAt
+		}
+		return mapping, nil
+	})
+	return err
+}
+
+// ListTierMappings retrieves all mappings
+func (tr *TierRepository) ListTierMappings(ctx context.Context) ([]model.TierMapping, error) {
+	if tr.tx == nil {
+		return nil, ErrNotMemoryTransaction
+	}
+
+	var mappings []model.TierMapping
+	tr.tx.scan(tierMappingsTable, func(_ string, value any) bool {
+		mappings = append(mappings, value.(model.TierMapping))
+		return true
+	})
+	return mappings, nil
+}
+
+// DeleteTierMapping removes the mapping of the role or plan
+func (tr *TierRepository) DeleteTierMapping(ctx context.Context, subject string) error {
+	if tr.tx == nil {
+		return ErrNotMemoryTransaction
+	}
+	if _, ok := tr.tx.get(tierMappingsTable, subject); !ok {
+		return domain.ErrTierMappingNotFound
+	}
+
+	tr.tx.delete(tierMappingsTable, subject)
+	return nil
+}
+
+// GetTierFor retrieves the tier the role or plan is mapped to
+func (tr *TierRepository) GetTierFor(ctx context.Context, subject string) (*model.Tier, error) {
+	if tr.tx == nil {
+		return nil, ErrNotMemoryTransaction
+	}
+
+	value, ok := tr.tx.get(tierMappingsTable, subject)
+	if !ok {
+		return nil, nil
+	}
+	return tr.GetTier(ctx, value.(model.TierMapping).Tier)
+}
@@ -0,0 +1,143 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+)
+
+var (
+	ErrNoTransaction   = errors.New("no transaction in progress")
+	ErrSQLNotSupported = errors.New("the in-memory store does not support SQL")
+)
+
+// Transaction buffers writes until Commit. Reads see committed rows plus the transaction's own
+// pending writes.
+type Transaction struct {
+	store *Store
+
+	begun, committed bool
+	pending          []write
+}
+
+// write is a pending put, or a delete when deleted is set. A put with a merge recomputes its
+// value from the row as committed when the transaction commits, which is how upserts and version
+// checks see the writes of transactions that committed in the meantime. A merge that computes a
+// nil value leaves no row.
+type write struct {
+	table, key string
+	value      any
+	deleted    bool
+	merge      func(current any, exists bool) (any, error)
+}
+
+func (t *Transaction) Begin(ctx context.Context, opts ...db.TxOption) (db.DbHandler, error) {
+	t.begun, t.committed, t.pending = true, false, nil
+	return t, nil
+}
+
+func (t *Transaction) Commit(ctx context.Context) error {
+	if !t.begun {
+		return ErrNoTransaction
+	}
+
+	err := t.store.apply(t.pending)
+	t.begun, t.committed, t.pending = false, err == nil, nil
+	return err
+}
+
+func (t *Transaction) Rollback(ctx context.Context) error {
+	if !t.begun {
+		return ErrNoTransaction
+	}
+	t.begun, t.pending = false, nil
+	return nil
+}
+
+func (t *Transaction) RollbackUnlessCommitted(ctx context.Context) {
+	if !t.committed && t.begun {
+		if err := t.Rollback(ctx); err != nil {
+			log.Print(err)
+		}
+	}
+}
+
+// QueryContext is part of db.DbHandler; the in-memory store has no SQL.
+func (t *Transaction) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, ErrSQLNotSupported
+}
+
+// QueryRowContext is part of db.DbHandler; the in-memory store has no SQL, so it returns nil.
+func (t *Transaction) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+// ExecContext is part of db.DbHandler; the in-memory store has no SQL.
+func (t *Transaction) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return nil, ErrSQLNotSupported
+}
+
+func (t *Transaction) get(table, key string) (any, bool) {
+	for i := len(t.pending) - 1; i >= 0; i-- {
+		if w := t.pending[i]; w.table == table && w.key == key {
+			return w.value, !w.deleted
+		}
+	}
+	return t.store.get(table, key)
+}
+
+// scan calls fn for every visible row of table in key order, stopping when fn returns false.
+func (t *Transaction) scan(table string, fn func(key string, value any) bool) {
+	rows := t.store.rows(table)
+	for _, w := range t.pending {
+		if w.table != table {
+			continue
+		}
+		if w.deleted {
+			delete(rows, w.key)
+			continue
+		}
+		rows[w.key] = w.value
+	}
+
+	for _, key := range sortedKeys(rows) {
+		if !fn(key, rows[key]) {
+			return
+		}
+	}
+}
+
+func (t *Transaction) put(table, key string, value any) {
+	t.pending = append(t.pending, write{table: table, key: key, value: value})
+}
+
+// merge puts the value fn computes from the current row, computing it against the rows this
+// transaction sees now and again against the committed row on commit.
+func (t *Transaction) merge(table, key string, fn func(current any, exists bool) (any, error)) (any, error) {
+	current, exists := t.get(table, key)
+	value, err := fn(current, exists)
+	if err != nil {
+		return nil, err
+	}
+	t.pending = append(t.pending, write{table: table, key: key, value: value, deleted: value == nil, merge: fn})
+	return value, nil
+}
+
+func (t *Transaction) delete(table, key string) {
+	t.pending = append(t.pending, write{table: table, key: key, deleted: true})
+}
+
+type TransactionFactory struct {
+	store *Store
+}
+
+func NewTransactionFactory(store *Store) *TransactionFactory {
+	return &TransactionFactory{store: store}
+}
+
+func (f *TransactionFactory) NewTransaction() db.DbTransaction {
+	return &Transaction{store: f.store}
+}
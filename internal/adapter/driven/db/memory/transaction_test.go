@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransaction_CommitAndRollback(t *testing.T) {
+	ctx := context.Background()
+	factory := NewTransactionFactory(NewStore())
+	repoFactory := NewUserRateLimitRepositoryFactory()
+
+	tx := factory.NewTransaction()
+	handler, err := tx.Begin(ctx)
+	assert.Nil(t, err)
+	id, err := repoFactory.New(handler).CreateRateLimit(ctx, model.UserRateLimit{UserId: "user", RateLimit: 5})
+	assert.Nil(t, err)
+
+	// Pending writes are visible inside the transaction only
+	inside, err := repoFactory.New(handler).GetRateLimitByUserId(ctx, "user")
+	assert.Nil(t, err)
+	assert.Equal(t, id, inside.Id)
+	other, _ := factory.NewTransaction().Begin(ctx)
+	outside, err := repoFactory.New(other).GetRateLimitByUserId(ctx, "user")
+	assert.Nil(t, err)
+	assert.Nil(t, outside)
+
+	assert.Nil(t, tx.Commit(ctx))
+	outside, err = repoFactory.New(other).GetRateLimitByUserId(ctx, "user")
+	assert.Nil(t, err)
+	assert.Equal(t, 5, outside.RateLimit)
+
+	tx = factory.NewTransaction()
+	handler, _ = tx.Begin(ctx)
+	assert.Nil(t, repoFactory.New(handler).DeleteRateLimit(ctx, "user"))
+	deleted, err := repoFactory.New(handler).GetRateLimitByUserId(ctx, "user")
+	assert.Nil(t, err)
+	assert.Nil(t, deleted)
+	tx.RollbackUnlessCommitted(ctx)
+
+	kept, err := repoFactory.New(other).GetRateLimitByUserId(ctx, "user")
+	assert.Nil(t, err)
+	assert.NotNil(t, kept)
+	assert.Equal(t, ErrNoTransaction, tx.Commit(ctx))
+}
+
+func TestTransaction_ConcurrentCommits(t *testing.T) {
+	ctx := context.Background()
+	factory := NewTransactionFactory(NewStore())
+	repoFactory := NewUserRateLimitRepositoryFactory()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tx := factory.NewTransaction()
+			handler, _ := tx.Begin(ctx)
+			_, err := repoFactory.New(handler).CreateRateLimit(ctx, model.UserRateLimit{UserId: uuid.New().String()})
+			assert.Nil(t, err)
+			assert.Nil(t, tx.Commit(ctx))
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, factory.store.rows(userRateLimitsTable), 50)
+}
+
+func TestTransaction_CreateConflictOnCommit(t *testing.T) {
+	ctx := context.Background()
+	factory := NewTransactionFactory(NewStore())
+	repoFactory := NewUserRateLimitRepositoryFactory()
+
+	// Both transactions find no record, only the first to commit creates it
+	first, second := factory.NewTransaction(), factory.NewTransaction()
+	firstHandler, _ := first.Begin(ctx)
+	secondHandler, _ := second.Begin(ctx)
+	for i, handler := range []db.DbHandler{firstHandler, secondHandler} {
+		_, err := repoFactory.New(handler).CreateRateLimit(ctx, model.UserRateLimit{UserId: "user", RequestCount: i + 1})
+		assert.Nil(t, err)
+	}
+
+	assert.Nil(t, first.Commit(ctx))
+	assert.Equal(t, domain.ErrRateLimitConflict, second.Commit(ctx))
+
+	check, _ := factory.NewTransaction().Begin(ctx)
+	rateLimit, err := repoFactory.New(check).GetRateLimitByUserId(ctx, "user")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, rateLimit.RequestCount)
+	assert.Equal(t, int64(1), rateLimit.Version)
+
+	// Creating over an existing record fails straight away
+	_, err = repoFactory.New(check).CreateRateLimit(ctx, model.UserRateLimit{UserId: "user"})
+	assert.Equal(t, domain.ErrRateLimitConflict, err)
+}
+
+func TestTransaction_VersionConflictOnCommit(t *testing.T) {
+	ctx := context.Background()
+	factory := NewTransactionFactory(NewStore())
+	repoFactory := NewUserRateLimitRepositoryFactory()
+
+	setup := factory.NewTransaction()
+	handler, _ := setup.Begin(ctx)
+	_, err := repoFactory.New(handler).CreateRateLimit(ctx, model.UserRateLimit{UserId: "user", RateLimit: 5})
+	assert.Nil(t, err)
+	assert.Nil(t, setup.Commit(ctx))
+
+	// Both transactions read the same version, only the first to commit wins
+	first, second := factory.NewTransaction(), factory.NewTransaction()
+	firstHandler, _ := first.Begin(ctx)
+	secondHandler, _ := second.Begin(ctx)
+	for _, handler := range []db.DbHandler{firstHandler, secondHandler} {
+		rateLimit, err := repoFactory.New(handler).GetRateLimitByUserId(ctx, "user")
+		assert.Nil(t, err)
+		rateLimit.RequestCount++
+		assert.Nil(t, repoFactory.New(handler).UpdateRateLimit(ctx, *rateLimit))
+	}
+
+	assert.Nil(t, first.Commit(ctx))
+	assert.Equal(t, domain.ErrRateLimitConflict, second.Commit(ctx))
+
+	check, _ := factory.NewTransaction().Begin(ctx)
+	rateLimit, err := repoFactory.New(check).GetRateLimitByUserId(ctx, "user")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, rateLimit.RequestCount)
+	assert.Equal(t, int64(2), rateLimit.Version)
+}
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+const usageBucketsTable = "usage_buckets"
+
+type UsageRepositoryFactory struct{}
+
+func NewUsageRepositoryFactory() *UsageRepositoryFactory {
+	return &UsageRepositoryFactory{}
+}
+
+func (f *UsageRepositoryFactory) New(handler db.DbHandler) repository.UsageRepository {
+	tx, _ := handler.(*Transaction)
+	return &UsageRepository{tx: tx}
+}
+
+// UsageRepository keeps one row per key, granularity and bucket start, like the primary key of
+// the usage_buckets table.
+type UsageRepository struct {
+	tx *Transaction
+}
+
+// AddUsage stores a usage bucket or adds its counts to the stored one
+func (ur *UsageRepository) AddUsage(ctx context.Context, bucket model.UsageBucket) error {
+	if ur.tx == nil {
+		return ErrNotMemoryTransaction
+	}
+
+	bucket.Start = bucket.Start.UTC()
+	_, err := ur.tx.merge(usageBucketsTable, usageKey(bucket.Granularity, bucket.Key, bucket.Start), func(current any, exists bool) (any, error) {
+		added := bucket
+		if exists {
+			stored := current.(model.UsageBucket)
+			added.Allowed += stored.Allowed
+			added.Denied += stored.Denied
+		}
+		return added, nil
+	})
+	return err
+}
+
+// ListUsage retrieves a key's usage buckets within a time range, oldest first
+func (ur *UsageRepository) ListUsage(ctx context.Context, key string, granularity model.UsageGranularity, from, to time.Time) ([]model.UsageBucket, error) {
+	if ur.tx == nil {
+		return nil, ErrNotMemoryTransaction
+	}
+
+	var buckets []model.UsageBucket
+	ur.tx.scan(usageBucketsTable, func(_ string, value any) bool {
+		bucket := value.(model.UsageBucket)
+		if bucket.Key == key && bucket.Granularity == granularity && !bucket.Start.Before(from) && bucket.Start.Before(to) {
+			buckets = append(buckets, bucket)
+		}
+		return true
+	})
+	return buckets, nil
+}
+
+// DeleteUsageBefore removes the usage buckets of a granularity that start before a time
+func (ur *UsageRepository) DeleteUsageBefore(ctx context.Context, granularity model.UsageGranularity, before time.Time) error {
+	if ur.tx == nil {
+		return ErrNotMemoryTransaction
+	}
+
+	var expired []string
+	ur.tx.scan(usageBucketsTable, func(key string, value any) bool {
+		if bucket := value.(model.UsageBucket); bucket.Granularity == granularity && bucket.Start.Before(before) {
+			expired = append(expired, key)
+		}
+		return true
+	})
+	for _, key := range expired {
+		ur.tx.delete(usageBucketsTable, key)
+	}
+	return nil
+}
+
+// usageKey orders a key's buckets by start time.
+func usageKey(granularity model.UsageGranularity, key string, start time.Time) string {
+	return fmt.Sprintf("%s/%s/%020d", granularity, key, start.Unix())
+}
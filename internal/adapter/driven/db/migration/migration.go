@@ -0,0 +1,110 @@
+// Package migration embeds the schema migrations of every supported SQL dialect, so the server
+// binary can apply them itself.
+package migration
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	drivenDb "github.com/nullexp/limiter-x/internal/adapter/driven/db"
+)
+
+//go:embed postgres/*.sql mysql/*.sql sqlite/*.sql
+var files embed.FS
+
+// Migrator applies the embedded migrations of one dialect. Every run holds the database's
+// migration lock (pg_advisory_lock on PostgreSQL, GET_LOCK on MySQL), so replicas starting
+// together apply each migration once and the others wait for it to finish.
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+// New connects to the database described by dsn with its own connection, which Close releases.
+func New(dialect drivenDb.Dialect, dsn string) (*Migrator, error) {
+	dsn, err := migrationDSN(dialect, dsn)
+	if err != nil {
+		return nil, err
+	}
+	sqlDb, err := drivenDb.Open(dialect, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	driver, err := databaseDriver(dialect, sqlDb)
+	if err != nil {
+		sqlDb.Close()
+		return nil, err
+	}
+	source, err := iofs.New(files, string(dialect))
+	if err != nil {
+		driver.Close()
+		return nil, err
+	}
+	m, err := migrate.NewWithInstance("iofs", source, string(dialect), driver)
+	if err != nil {
+		driver.Close()
+		return nil, err
+	}
+	return &Migrator{m: m}, nil
+}
+
+// migrationDSN returns the dsn the migrations of the dialect are applied with. MySQL migrations
+// may hold several statements, which the driver only runs with multiStatements set.
+func migrationDSN(dialect drivenDb.Dialect, dsn string) (string, error) {
+	if dialect != drivenDb.Mysql {
+		return dsn, nil
+	}
+	config, err := mysqlDriver.ParseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	config.MultiStatements = true
+	return config.FormatDSN(), nil
+}
+
+func databaseDriver(dialect drivenDb.Dialect, sqlDb *sql.DB) (database.Driver, error) {
+	switch dialect {
+	case drivenDb.Sqlite:
+		return sqlite3.WithInstance(sqlDb, &sqlite3.Config{})
+	case drivenDb.Mysql:
+		return mysql.WithInstance(sqlDb, &mysql.Config{})
+	default:
+		return postgres.WithInstance(sqlDb, &postgres.Config{})
+	}
+}
+
+// Up applies every pending migration. An up to date schema is not an error.
+func (mg *Migrator) Up() error {
+	if err := mg.m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// Down reverts the given number of applied migrations.
+func (mg *Migrator) Down(steps int) error {
+	return mg.m.Steps(-steps)
+}
+
+// Version returns the applied schema version, and whether a failed migration left it dirty.
+// It returns migrate.ErrNilVersion before the first migration.
+func (mg *Migrator) Version() (uint, bool, error) {
+	return mg.m.Version()
+}
+
+// Close releases the migrator's database connection.
+func (mg *Migrator) Close() error {
+	sourceErr, dbErr := mg.m.Close()
+	if sourceErr != nil {
+		return sourceErr
+	}
+	return dbErr
+}
@@ -0,0 +1,111 @@
+package migration
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	drivenDb "github.com/nullexp/limiter-x/internal/adapter/driven/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrator_Sqlite(t *testing.T) {
+	migrator, err := New(drivenDb.Sqlite, filepath.Join(t.TempDir(), "rates.db"))
+	assert.Nil(t, err)
+	defer migrator.Close()
+
+	_, _, err = migrator.Version()
+	assert.Equal(t, migrate.ErrNilVersion, err)
+
+	ups, err := fs.Glob(files, "sqlite/*.up.sql")
+	assert.Nil(t, err)
+
+	assert.Nil(t, migrator.Up())
+	version, dirty, err := migrator.Version()
+	assert.Nil(t, err)
+	assert.False(t, dirty)
+	assert.Equal(t, uint(len(ups)), version)
+
+	// Running again once up to date is not an error
+	assert.Nil(t, migrator.Up())
+
+	// Every migration reverts cleanly
+	assert.Nil(t, migrator.Down(len(ups)))
+	_, _, err = migrator.Version()
+	assert.Equal(t, migrate.ErrNilVersion, err)
+}
+
+func TestEmbeddedDialects(t *testing.T) {
+	for _, dialect := range []drivenDb.Dialect{drivenDb.Postgres, drivenDb.Mysql, drivenDb.Sqlite} {
+		entries, err := files.ReadDir(string(dialect))
+		assert.Nil(t, err)
+		assert.NotEmpty(t, entries, dialect)
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	for _, dialect := range []drivenDb.Dialect{drivenDb.Postgres, drivenDb.Mysql, drivenDb.Sqlite} {
+		t.Run(string(dialect), func(t *testing.T) {
+			source, err := iofs.New(files, string(dialect))
+			assert.Nil(t, err)
+			defer source.Close()
+
+			// Versions follow each other without gaps, each with an up and a down migration
+			ups, err := fs.Glob(files, string(dialect)+"/*.up.sql")
+			assert.Nil(t, err)
+			version, err := source.First()
+			assert.Nil(t, err)
+			for expect := uint(1); ; expect++ {
+				assert.Equal(t, expect, version)
+				for _, read := range []func(uint) (io.ReadCloser, string, error){source.ReadUp, source.ReadDown} {
+					migration, _, err := read(version)
+					if assert.Nil(t, err, "version %d", version) {
+						body, err := io.ReadAll(migration)
+						assert.Nil(t, err)
+						assert.NotEmpty(t, body, "version %d", version)
+						migration.Close()
+					}
+				}
+
+				if version, err = source.Next(version); errors.Is(err, fs.ErrNotExist) {
+					assert.Equal(t, uint(len(ups)), expect)
+					break
+				}
+				if !assert.Nil(t, err) {
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestMigrationDSN(t *testing.T) {
+	config := mysqlDriver.NewConfig()
+	config.User = "rates"
+	config.Net = "tcp"
+	config.Addr = "db:3306"
+	config.DBName = "rates"
+	config.ParseTime = true
+
+	// MySQL migrations holding several statements need them enabled on the migrator's connection
+	dsn, err := migrationDSN(drivenDb.Mysql, config.FormatDSN())
+	assert.Nil(t, err)
+	parsed, err := mysqlDriver.ParseDSN(dsn)
+	assert.Nil(t, err)
+	assert.True(t, parsed.MultiStatements)
+	assert.True(t, parsed.ParseTime)
+	assert.Equal(t, "db:3306", parsed.Addr)
+	assert.Equal(t, "rates", parsed.DBName)
+
+	_, err = migrationDSN(drivenDb.Mysql, "not a dsn")
+	assert.NotNil(t, err)
+
+	dsn, err = migrationDSN(drivenDb.Postgres, "host=db sslmode=disable")
+	assert.Nil(t, err)
+	assert.Equal(t, "host=db sslmode=disable", dsn)
+}
@@ -0,0 +1,8 @@
+CREATE TABLE user_rate_limits (
+    id CHAR(36) NOT NULL DEFAULT (UUID()) PRIMARY KEY,
+    user_id CHAR(36) NOT NULL,
+    request_count INT NOT NULL DEFAULT 0,
+    rate_limit INT NOT NULL DEFAULT 100,  -- Default rate limit for users
+    timestamp DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    UNIQUE KEY user_rate_limits_user_id (user_id)  -- Needed by ON DUPLICATE KEY UPDATE
+);
@@ -0,0 +1,4 @@
+ALTER TABLE user_rate_limits
+    DROP COLUMN version,
+    DROP COLUMN updated_at,
+    DROP COLUMN created_at;
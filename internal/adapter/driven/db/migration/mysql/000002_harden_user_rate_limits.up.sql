@@ -0,0 +1,5 @@
+-- user_id is unique since the first migration
+ALTER TABLE user_rate_limits
+    ADD COLUMN created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    ADD COLUMN updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    ADD COLUMN version BIGINT NOT NULL DEFAULT 1;  -- Incremented by every update, for optimistic locking
@@ -0,0 +1,13 @@
+-- Append-only log of configuration changes and sampled deny decisions
+CREATE TABLE audit_events (
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    limit_key VARCHAR(255) NOT NULL,  -- The rate limited key, e.g. a user ID
+    actor VARCHAR(255) NOT NULL DEFAULT '',
+    event_type VARCHAR(64) NOT NULL,
+    old_value TEXT NOT NULL,
+    new_value TEXT NOT NULL,
+    detail TEXT NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    KEY audit_events_created_at (created_at),
+    KEY audit_events_limit_key_created_at (limit_key, created_at)
+);
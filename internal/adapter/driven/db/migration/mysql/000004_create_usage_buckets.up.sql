@@ -0,0 +1,10 @@
+-- Allowed and denied requests per key, aggregated into hourly and daily buckets
+CREATE TABLE usage_buckets (
+    limit_key VARCHAR(255) NOT NULL,  -- The rate limited key, e.g. a user ID
+    granularity VARCHAR(8) NOT NULL,  -- 'hour' or 'day'
+    bucket_start DATETIME(6) NOT NULL,
+    allowed BIGINT NOT NULL DEFAULT 0,
+    denied BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (limit_key, granularity, bucket_start),
+    KEY usage_buckets_granularity_bucket_start (granularity, bucket_start)  -- Retention removes old buckets across all keys
+);
@@ -0,0 +1,14 @@
+-- Long-period quotas and the requests counted against them in the current period
+CREATE TABLE quotas (
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    limit_key VARCHAR(255) NOT NULL,  -- The rate limited key, e.g. a user ID
+    period VARCHAR(8) NOT NULL,  -- 'day', 'week' or 'month'
+    quota_limit BIGINT NOT NULL,
+    time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    reset_day INT NOT NULL DEFAULT 0,
+    period_start DATETIME(6),
+    used BIGINT NOT NULL DEFAULT 0,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    UNIQUE KEY quotas_limit_key_period (limit_key, period)
+);
@@ -0,0 +1,13 @@
+-- The limits of rate limit policies, one per key and window, and the requests counted in their
+-- current window
+CREATE TABLE policy_limits (
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    limit_key VARCHAR(255) NOT NULL,  -- The rate limited key, e.g. a user ID
+    window_ms BIGINT NOT NULL,
+    rate_limit INT NOT NULL,
+    request_count INT NOT NULL DEFAULT 0,
+    window_start DATETIME(6),
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    UNIQUE KEY policy_limits_limit_key_window_ms (limit_key, window_ms)
+);
@@ -0,0 +1,8 @@
+-- The parent of rate limited keys, such as the team of a user or the organization of a team
+CREATE TABLE key_parents (
+    limit_key VARCHAR(255) NOT NULL PRIMARY KEY,  -- The rate limited key, e.g. a user ID
+    parent_key VARCHAR(255) NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    KEY key_parents_parent_key (parent_key)
+);
@@ -0,0 +1,2 @@
+DROP TABLE tier_mappings;
+DROP TABLE tiers;
@@ -0,0 +1,16 @@
+-- Tiers such as free, pro or enterprise with the limit of the users they apply to
+CREATE TABLE tiers (
+    name VARCHAR(255) NOT NULL PRIMARY KEY,
+    rate_limit INT NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)
+);
+
+-- The tier of the users with a role or plan
+CREATE TABLE tier_mappings (
+    subject VARCHAR(255) NOT NULL PRIMARY KEY,  -- A role ID or plan name
+    tier_name VARCHAR(255) NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    KEY tier_mappings_tier_name (tier_name)
+);
@@ -0,0 +1,12 @@
+-- The allowlist and denylist, consulted before requests are counted
+CREATE TABLE access_rules (
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    action VARCHAR(16) NOT NULL,  -- allow or deny
+    match_type VARCHAR(16) NOT NULL,  -- exact, prefix or cidr
+    pattern VARCHAR(255) NOT NULL,
+    reason VARCHAR(1024) NOT NULL DEFAULT '',
+    expires_at DATETIME(6),  -- NULL if the rule never expires
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    UNIQUE KEY access_rules_match_type_pattern (match_type, pattern)
+);
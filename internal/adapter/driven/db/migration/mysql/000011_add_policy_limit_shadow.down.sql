@@ -0,0 +1 @@
+ALTER TABLE policy_limits DROP COLUMN shadow;
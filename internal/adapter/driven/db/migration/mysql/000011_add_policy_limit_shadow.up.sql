@@ -0,0 +1,2 @@
+-- Whether the limit is only counted and never denies a request
+ALTER TABLE policy_limits ADD COLUMN shadow BOOLEAN NOT NULL DEFAULT FALSE;
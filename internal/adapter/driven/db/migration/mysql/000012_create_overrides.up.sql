@@ -0,0 +1,10 @@
+-- The global emergency override, a single row with the ID global while one is set
+CREATE TABLE overrides (
+    id VARCHAR(16) NOT NULL PRIMARY KEY,
+    mode VARCHAR(16) NOT NULL,  -- allow_all, deny_all or multiplier
+    multiplier DOUBLE NOT NULL DEFAULT 1,
+    reason VARCHAR(1024) NOT NULL DEFAULT '',
+    expires_at DATETIME(6) NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)
+);
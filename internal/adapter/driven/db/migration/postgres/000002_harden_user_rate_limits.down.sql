@@ -0,0 +1,6 @@
+ALTER TABLE user_rate_limits
+    DROP COLUMN version,
+    DROP COLUMN updated_at,
+    DROP COLUMN created_at;
+
+DROP INDEX user_rate_limits_user_id_key;
@@ -0,0 +1,16 @@
+-- Keep only the most recent row of users with duplicates before making user_id unique
+DELETE FROM user_rate_limits
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY timestamp DESC NULLS LAST, id) AS position
+        FROM user_rate_limits
+    ) ranked
+    WHERE position > 1
+);
+
+CREATE UNIQUE INDEX user_rate_limits_user_id_key ON user_rate_limits (user_id);
+
+ALTER TABLE user_rate_limits
+    ADD COLUMN created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN version BIGINT NOT NULL DEFAULT 1;  -- Incremented by every update, for optimistic locking
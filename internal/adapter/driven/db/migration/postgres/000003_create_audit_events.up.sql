@@ -0,0 +1,14 @@
+-- Append-only log of configuration changes and sampled deny decisions
+CREATE TABLE audit_events (
+    id UUID PRIMARY KEY,
+    limit_key TEXT NOT NULL,  -- The rate limited key, e.g. a user ID
+    actor TEXT NOT NULL DEFAULT '',
+    event_type TEXT NOT NULL,
+    old_value TEXT NOT NULL DEFAULT '',
+    new_value TEXT NOT NULL DEFAULT '',
+    detail TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX audit_events_created_at ON audit_events (created_at);
+CREATE INDEX audit_events_limit_key_created_at ON audit_events (limit_key, created_at);
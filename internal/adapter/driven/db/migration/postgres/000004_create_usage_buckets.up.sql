@@ -0,0 +1,12 @@
+-- Allowed and denied requests per key, aggregated into hourly and daily buckets
+CREATE TABLE usage_buckets (
+    limit_key TEXT NOT NULL,  -- The rate limited key, e.g. a user ID
+    granularity TEXT NOT NULL,  -- 'hour' or 'day'
+    bucket_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    allowed BIGINT NOT NULL DEFAULT 0,
+    denied BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (limit_key, granularity, bucket_start)
+);
+
+-- Retention removes old buckets across all keys
+CREATE INDEX usage_buckets_granularity_bucket_start ON usage_buckets (granularity, bucket_start);
@@ -0,0 +1,14 @@
+-- Long-period quotas and the requests counted against them in the current period
+CREATE TABLE quotas (
+    id UUID PRIMARY KEY,
+    limit_key TEXT NOT NULL,  -- The rate limited key, e.g. a user ID
+    period TEXT NOT NULL,  -- 'day', 'week' or 'month'
+    quota_limit BIGINT NOT NULL,
+    time_zone TEXT NOT NULL DEFAULT 'UTC',
+    reset_day INT NOT NULL DEFAULT 0,
+    period_start TIMESTAMP WITH TIME ZONE,
+    used BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (limit_key, period)
+);
@@ -0,0 +1,13 @@
+-- The limits of rate limit policies, one per key and window, and the requests counted in their
+-- current window
+CREATE TABLE policy_limits (
+    id UUID PRIMARY KEY,
+    limit_key TEXT NOT NULL,  -- The rate limited key, e.g. a user ID
+    window_ms BIGINT NOT NULL,
+    rate_limit INT NOT NULL,
+    request_count INT NOT NULL DEFAULT 0,
+    window_start TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (limit_key, window_ms)
+);
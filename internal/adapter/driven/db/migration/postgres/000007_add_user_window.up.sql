@@ -0,0 +1,2 @@
+-- The length of the user's window, zero for the service's default window
+ALTER TABLE user_rate_limits ADD COLUMN window_ms BIGINT NOT NULL DEFAULT 0;
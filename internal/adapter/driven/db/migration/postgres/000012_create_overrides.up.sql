@@ -0,0 +1,10 @@
+-- The global emergency override, a single row with the ID global while one is set
+CREATE TABLE overrides (
+    id TEXT PRIMARY KEY,
+    mode TEXT NOT NULL,  -- allow_all, deny_all or multiplier
+    multiplier DOUBLE PRECISION NOT NULL DEFAULT 1,
+    reason TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,7 @@
+CREATE TABLE user_rate_limits (
+    id TEXT PRIMARY KEY,  -- UUID generated by the application
+    user_id TEXT NOT NULL,
+    request_count INTEGER NOT NULL DEFAULT 0,
+    rate_limit INTEGER NOT NULL DEFAULT 100,  -- Default rate limit for users
+    timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+);
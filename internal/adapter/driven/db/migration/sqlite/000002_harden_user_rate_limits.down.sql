@@ -0,0 +1,5 @@
+ALTER TABLE user_rate_limits DROP COLUMN version;
+ALTER TABLE user_rate_limits DROP COLUMN updated_at;
+ALTER TABLE user_rate_limits DROP COLUMN created_at;
+
+DROP INDEX user_rate_limits_user_id_key;
@@ -0,0 +1,20 @@
+-- Keep only the most recent row of users with duplicates before making user_id unique
+DELETE FROM user_rate_limits
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY timestamp DESC NULLS LAST, id) AS position
+        FROM user_rate_limits
+    )
+    WHERE position > 1
+);
+
+CREATE UNIQUE INDEX user_rate_limits_user_id_key ON user_rate_limits (user_id);
+
+-- SQLite cannot add columns with a CURRENT_TIMESTAMP default, so existing rows are filled in below
+ALTER TABLE user_rate_limits ADD COLUMN created_at DATETIME;
+ALTER TABLE user_rate_limits ADD COLUMN updated_at DATETIME;
+ALTER TABLE user_rate_limits ADD COLUMN version INTEGER NOT NULL DEFAULT 1;  -- Incremented by every update, for optimistic locking
+
+UPDATE user_rate_limits
+SET created_at = COALESCE(timestamp, CURRENT_TIMESTAMP),
+    updated_at = COALESCE(timestamp, CURRENT_TIMESTAMP);
@@ -0,0 +1,14 @@
+-- Long-period quotas and the requests counted against them in the current period
+CREATE TABLE quotas (
+    id TEXT PRIMARY KEY,  -- UUID generated by the application
+    limit_key TEXT NOT NULL,  -- The rate limited key, e.g. a user ID
+    period TEXT NOT NULL,  -- 'day', 'week' or 'month'
+    quota_limit INTEGER NOT NULL,
+    time_zone TEXT NOT NULL DEFAULT 'UTC',
+    reset_day INTEGER NOT NULL DEFAULT 0,
+    period_start DATETIME,
+    used INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (limit_key, period)
+);
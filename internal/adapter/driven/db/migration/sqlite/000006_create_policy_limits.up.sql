@@ -0,0 +1,13 @@
+-- The limits of rate limit policies, one per key and window, and the requests counted in their
+-- current window
+CREATE TABLE policy_limits (
+    id TEXT PRIMARY KEY,  -- UUID generated by the application
+    limit_key TEXT NOT NULL,  -- The rate limited key, e.g. a user ID
+    window_ms INTEGER NOT NULL,
+    rate_limit INTEGER NOT NULL,
+    request_count INTEGER NOT NULL DEFAULT 0,
+    window_start DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (limit_key, window_ms)
+);
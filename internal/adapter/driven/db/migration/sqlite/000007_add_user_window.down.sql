@@ -0,0 +1 @@
+ALTER TABLE user_rate_limits DROP COLUMN window_ms;
@@ -0,0 +1,9 @@
+-- The parent of rate limited keys, such as the team of a user or the organization of a team
+CREATE TABLE key_parents (
+    limit_key TEXT PRIMARY KEY,  -- The rate limited key, e.g. a user ID
+    parent_key TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX key_parents_parent_key ON key_parents (parent_key);
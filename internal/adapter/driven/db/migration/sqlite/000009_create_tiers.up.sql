@@ -0,0 +1,17 @@
+-- Tiers such as free, pro or enterprise with the limit of the users they apply to
+CREATE TABLE tiers (
+    name TEXT PRIMARY KEY,
+    rate_limit INTEGER NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The tier of the users with a role or plan
+CREATE TABLE tier_mappings (
+    subject TEXT PRIMARY KEY,  -- A role ID or plan name
+    tier_name TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX tier_mappings_tier_name ON tier_mappings (tier_name);
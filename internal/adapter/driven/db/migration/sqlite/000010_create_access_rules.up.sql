@@ -0,0 +1,12 @@
+-- The allowlist and denylist, consulted before requests are counted
+CREATE TABLE access_rules (
+    id TEXT PRIMARY KEY,  -- UUID generated by the application
+    action TEXT NOT NULL,  -- allow or deny
+    match_type TEXT NOT NULL,  -- exact, prefix or cidr
+    pattern TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    expires_at DATETIME,  -- NULL if the rule never expires
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (match_type, pattern)
+);
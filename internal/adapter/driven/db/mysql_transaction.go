@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+)
+
+type MysqlDbTransaction struct {
+	db        *sql.DB
+	tx        *sql.Tx
+	committed bool
+}
+
+func NewMysqlDbTransaction(db *sql.DB) *MysqlDbTransaction {
+	return &MysqlDbTransaction{db: db}
+}
+
+func (m *MysqlDbTransaction) Begin(ctx context.Context, opts ...db.TxOption) (db.DbHandler, error) {
+	options := db.NewTxOptions(opts...)
+	tx, err := m.db.BeginTx(ctx, &sql.TxOptions{Isolation: options.Isolation, ReadOnly: options.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	m.tx = tx
+	return m, nil
+}
+
+func (m *MysqlDbTransaction) Commit(ctx context.Context) error {
+	if m.tx == nil {
+		return errors.New("no transaction in progress")
+	}
+	err := m.tx.Commit()
+	if err == nil {
+		m.committed = true
+	}
+	return err
+}
+
+func (m *MysqlDbTransaction) Rollback(ctx context.Context) error {
+	if m.tx == nil {
+		return errors.New("no transaction in progress")
+	}
+	return m.tx.Rollback()
+}
+
+func (m *MysqlDbTransaction) RollbackUnlessCommitted(ctx context.Context) {
+	if !m.committed {
+		err := m.Rollback(ctx)
+		if err != nil {
+			log.Print(err)
+		}
+	}
+}
+
+func (m *MysqlDbTransaction) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return m.tx.QueryContext(ctx, query, args...)
+}
+
+func (m *MysqlDbTransaction) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return m.tx.ExecContext(ctx, query, args...)
+}
+
+func (m *MysqlDbTransaction) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return m.tx.QueryRowContext(ctx, query, args...)
+}
+
+type MysqlDbTransactionFactory struct {
+	db *sql.DB
+}
+
+func NewMysqlDbTransactionFactory(db *sql.DB) *MysqlDbTransactionFactory {
+	return &MysqlDbTransactionFactory{db: db}
+}
+
+func (f *MysqlDbTransactionFactory) NewTransaction() db.DbTransaction {
+	return &MysqlDbTransaction{db: f.db}
+}
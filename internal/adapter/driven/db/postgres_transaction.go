@@ -19,8 +19,9 @@ func NewPostgresDbTransaction(db *sql.DB) *PostgresDbTransaction {
 	return &PostgresDbTransaction{db: db}
 }
 
-func (p *PostgresDbTransaction) Begin(ctx context.Context) (db.DbHandler, error) {
-	tx, err := p.db.BeginTx(ctx, nil)
+func (p *PostgresDbTransaction) Begin(ctx context.Context, opts ...db.TxOption) (db.DbHandler, error) {
+	options := db.NewTxOptions(opts...)
+	tx, err := p.db.BeginTx(ctx, &sql.TxOptions{Isolation: options.Isolation, ReadOnly: options.ReadOnly})
 	if err != nil {
 		return nil, err
 	}
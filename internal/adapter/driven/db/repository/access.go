@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+const (
+	// updateAccessRuleOnConflict replaces an existing rule in PostgreSQL and SQLite
+	updateAccessRuleOnConflict = `
+        ON CONFLICT (id) DO UPDATE
+        SET action = excluded.action,
+            reason = excluded.reason,
+            expires_at = excluded.expires_at,
+            updated_at = excluded.updated_at
+    `
+	// updateAccessRuleOnDuplicateKey replaces an existing rule in MySQL and MariaDB
+	updateAccessRuleOnDuplicateKey = `
+        ON DUPLICATE KEY UPDATE
+            action = VALUES(action),
+            reason = VALUES(reason),
+            expires_at = VALUES(expires_at),
+            updated_at = VALUES(updated_at)
+    `
+)
+
+// AccessRuleRepositoryFactory creates access rule repositories for one SQL dialect. Queries are
+// written with PostgreSQL's numbered parameters and rewritten for the other dialects.
+type AccessRuleRepositoryFactory struct {
+	numbered bool
+	upsert   string
+}
+
+// NewAccessRuleRepositoryFactory returns the factory for PostgreSQL.
+func NewAccessRuleRepositoryFactory() *AccessRuleRepositoryFactory {
+	return &AccessRuleRepositoryFactory{numbered: true, upsert: updateAccessRuleOnConflict}
+}
+
+// NewMysqlAccessRuleRepositoryFactory returns the factory for MySQL and MariaDB.
+func NewMysqlAccessRuleRepositoryFactory() *AccessRuleRepositoryFactory {
+	return &AccessRuleRepositoryFactory{upsert: updateAccessRuleOnDuplicateKey}
+}
+
+// NewSqliteAccessRuleRepositoryFactory returns the factory for SQLite.
+func NewSqliteAccessRuleRepositoryFactory() *AccessRuleRepositoryFactory {
+	return &AccessRuleRepositoryFactory{upsert: updateAccessRuleOnConflict}
+}
+
+func (f *AccessRuleRepositoryFactory) New(handler db.DbHandler) repository.AccessRuleRepository {
+	return &AccessRuleRepository{statements: statements{handler: handler, numbered: f.numbered}, upsert: f.upsert}
+}
+
+// AccessRuleRepository stores the allowlist and denylist in the access_rules table.
+type AccessRuleRepository struct {
+	statements
+	upsert string
+}
+
+// SetAccessRule inserts the rule or replaces the one with its ID. The match and pattern of a rule
+// never change, a rule for another pattern is another rule
+func (ar *AccessRuleRepository) SetAccessRule(ctx context.Context, rule model.AccessRule) error {
+	query := `
+        INSERT INTO access_rules (id, action, match_type, pattern, reason, expires_at, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+    ` + ar.upsert
+	expiresAt := sql.NullTime{Time: rule.ExpiresAt.UTC(), Valid: !rule.ExpiresAt.IsZero()}
+	_, err := ar.exec(ctx, query, rule.Id, string(rule.Action), string(rule.Match), rule.Pattern, rule.Reason, expiresAt, time.Now().UTC())
+	return err
+}
+
+// ListAccessRules retrieves all rules
+func (ar *AccessRuleRepository) ListAccessRules(ctx context.Context) ([]model.AccessRule, error) {
+	query := `
+        SELECT id, action, match_type, pattern, reason, expires_at, created_at, updated_at
+        FROM access_rules
+        ORDER BY match_type, pattern
+    `
+	rows, err := ar.query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []model.AccessRule
+	for rows.Next() {
+		var (
+			rule          model.AccessRule
+			action, match string
+			expiresAt     sql.NullTime
+		)
+		if err := rows.Scan(&rule.Id, &action, &match, &rule.Pattern, &rule.Reason, &expiresAt, &rule.CreatedAt, &rule.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rule.Action, rule.Match = model.AccessAction(action), model.AccessMatch(match)
+		rule.ExpiresAt = expiresAt.Time
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// DeleteAccessRule removes the rule with the ID
+func (ar *AccessRuleRepository) DeleteAccessRule(ctx context.Context, id string) error {
+	query := `
+        DELETE FROM access_rules
+        WHERE id = $1
+    `
+	result, err := ar.exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrAccessRuleNotFound
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+// AuditEventRepositoryFactory creates audit log repositories for one SQL dialect. The audit
+// queries are plain SQL, so the dialects only differ in how parameters are written.
+type AuditEventRepositoryFactory struct {
+	bind func(position int) string
+}
+
+// NewAuditEventRepositoryFactory returns the factory for PostgreSQL.
+func NewAuditEventRepositoryFactory() *AuditEventRepositoryFactory {
+	return &AuditEventRepositoryFactory{bind: func(position int) string { return "$" + strconv.Itoa(position) }}
+}
+
+// NewMysqlAuditEventRepositoryFactory returns the factory for MySQL and MariaDB.
+func NewMysqlAuditEventRepositoryFactory() *AuditEventRepositoryFactory {
+	return &AuditEventRepositoryFactory{bind: func(int) string { return "?" }}
+}
+
+// NewSqliteAuditEventRepositoryFactory returns the factory for SQLite.
+func NewSqliteAuditEventRepositoryFactory() *AuditEventRepositoryFactory {
+	return &AuditEventRepositoryFactory{bind: func(int) string { return "?" }}
+}
+
+func (f *AuditEventRepositoryFactory) New(handler db.DbHandler) repository.AuditEventRepository {
+	return &AuditEventRepository{handler: handler, bind: f.bind}
+}
+
+// AuditEventRepository appends to and reads the audit_events table. Event IDs are generated here,
+// as not every dialect can return a generated one.
+type AuditEventRepository struct {
+	handler db.DbHandler
+	bind    func(position int) string
+}
+
+// AppendEvent inserts a new audit event
+func (ar *AuditEventRepository) AppendEvent(ctx context.Context, event model.AuditEvent) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	params := make([]string, 8)
+	for i := range params {
+		params[i] = ar.bind(i + 1)
+	}
+	query := `
+        INSERT INTO audit_events (id, limit_key, actor, event_type, old_value, new_value, detail, created_at)
+        VALUES (` + strings.Join(params, ", ") + `)
+    `
+	_, err := ar.handler.ExecContext(ctx, query, uuid.New().String(), event.Key, event.Actor, string(event.Type), event.OldValue, event.NewValue, event.Detail,
+		event.CreatedAt.UTC())
+	return err
+}
+
+// ListEvents retrieves the audit events matching the filter, newest first
+func (ar *AuditEventRepository) ListEvents(ctx context.Context, filter model.AuditFilter) ([]model.AuditEvent, error) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, condition+" "+ar.bind(len(args)))
+	}
+	if filter.Key != "" {
+		where("limit_key =", filter.Key)
+	}
+	if filter.Actor != "" {
+		where("actor =", filter.Actor)
+	}
+	if filter.Type != "" {
+		where("event_type =", string(filter.Type))
+	}
+	if !filter.From.IsZero() {
+		where("created_at >=", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		where("created_at <", filter.To.UTC())
+	}
+
+	query := `
+        SELECT id, limit_key, actor, event_type, old_value, new_value, detail, created_at
+        FROM audit_events
+    `
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += " LIMIT " + ar.bind(len(args))
+	}
+
+	rows, err := ar.handler.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []model.AuditEvent
+	for rows.Next() {
+		var event model.AuditEvent
+		if err := rows.Scan(&event.Id, &event.Key, &event.Actor, &event.Type, &event.OldValue, &event.NewValue, &event.Detail, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
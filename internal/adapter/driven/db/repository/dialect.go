@@ -0,0 +1,114 @@
+package repository
+
+import (
+	drivenDb "github.com/nullexp/limiter-x/internal/adapter/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+// NewUserRateLimitRepositoryFactoryFor returns the rate limit repository factory for the dialect.
+func NewUserRateLimitRepositoryFactoryFor(dialect drivenDb.Dialect) repository.UserRateLimitRepositoryFactory {
+	switch dialect {
+	case drivenDb.Sqlite:
+		return NewSqliteUserRateLimitRepositoryFactory()
+	case drivenDb.Mysql:
+		return NewMysqlUserRateLimitRepositoryFactory()
+	default:
+		return NewUserRateLimitRepositoryFactory()
+	}
+}
+
+// NewAuditEventRepositoryFactoryFor returns the audit log repository factory for the dialect.
+func NewAuditEventRepositoryFactoryFor(dialect drivenDb.Dialect) repository.AuditEventRepositoryFactory {
+	switch dialect {
+	case drivenDb.Sqlite:
+		return NewSqliteAuditEventRepositoryFactory()
+	case drivenDb.Mysql:
+		return NewMysqlAuditEventRepositoryFactory()
+	default:
+		return NewAuditEventRepositoryFactory()
+	}
+}
+
+// NewUsageRepositoryFactoryFor returns the usage history repository factory for the dialect.
+func NewUsageRepositoryFactoryFor(dialect drivenDb.Dialect) repository.UsageRepositoryFactory {
+	switch dialect {
+	case drivenDb.Sqlite:
+		return NewSqliteUsageRepositoryFactory()
+	case drivenDb.Mysql:
+		return NewMysqlUsageRepositoryFactory()
+	default:
+		return NewUsageRepositoryFactory()
+	}
+}
+
+// NewQuotaRepositoryFactoryFor returns the quota repository factory for the dialect.
+func NewQuotaRepositoryFactoryFor(dialect drivenDb.Dialect) repository.QuotaRepositoryFactory {
+	switch dialect {
+	case drivenDb.Sqlite:
+		return NewSqliteQuotaRepositoryFactory()
+	case drivenDb.Mysql:
+		return NewMysqlQuotaRepositoryFactory()
+	default:
+		return NewQuotaRepositoryFactory()
+	}
+}
+
+// NewPolicyRepositoryFactoryFor returns the policy repository factory for the dialect.
+func NewPolicyRepositoryFactoryFor(dialect drivenDb.Dialect) repository.PolicyRepositoryFactory {
+	switch dialect {
+	case drivenDb.Sqlite:
+		return NewSqlitePolicyRepositoryFactory()
+	case drivenDb.Mysql:
+		return NewMysqlPolicyRepositoryFactory()
+	default:
+		return NewPolicyRepositoryFactory()
+	}
+}
+
+// NewParentRepositoryFactoryFor returns the parent repository factory for the dialect.
+func NewParentRepositoryFactoryFor(dialect drivenDb.Dialect) repository.ParentRepositoryFactory {
+	switch dialect {
+	case drivenDb.Sqlite:
+		return NewSqliteParentRepositoryFactory()
+	case drivenDb.Mysql:
+		return NewMysqlParentRepositoryFactory()
+	default:
+		return NewParentRepositoryFactory()
+	}
+}
+
+// NewTierRepositoryFactoryFor returns the tier repository factory for the dialect.
+func NewTierRepositoryFactoryFor(dialect drivenDb.Dialect) repository.TierRepositoryFactory {
+	switch dialect {
+	case drivenDb.Sqlite:
+		return NewSqliteTierRepositoryFactory()
+	case drivenDb.Mysql:
+		return NewMysqlTierRepositoryFactory()
+	default:
+		return NewTierRepositoryFactory()
+	}
+}
+
+// NewAccessRuleRepositoryFactoryFor returns the access rule repository factory for the dialect.
+func NewAccessRuleRepositoryFactoryFor(dialect drivenDb.Dialect) repository.AccessRuleRepositoryFactory {
+	switch dialect {
+	case drivenDb.Sqlite:
+		return NewSqliteAccessRuleRepositoryFactory()
+	case drivenDb.Mysql:
+		return NewMysqlAccessRuleRepositoryFactory()
+	default:
+		return NewAccessRuleRepositoryFactory()
+	}
+}
+
+// NewOverrideRepositoryFactoryFor returns the override repository factory for the dialect.
+func NewOverrideRepositoryFactoryFor(dialect drivenDb.Dialect) repository.OverrideRepositoryFactory {
+	switch dialect {
+	case drivenDb.Sqlite:
+		return NewSqliteOverrideRepositoryFactory()
+	case drivenDb.Mysql:
+		return NewMysqlOverrideRepositoryFactory()
+	default:
+		return NewOverrideRepositoryFactory()
+	}
+}
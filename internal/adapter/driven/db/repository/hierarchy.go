@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+const (
+	// updateParentOnConflict changes an existing parent in PostgreSQL and SQLite
+	updateParentOnConflict = `
+        ON CONFLICT (limit_key) DO UPDATE
+        SET parent_key = excluded.parent_key,
+            updated_at = excluded.updated_at
+    `
+	// updateParentOnDuplicateKey changes an existing parent in MySQL and MariaDB
+	updateParentOnDuplicateKey = `
+        ON DUPLICATE KEY UPDATE
+            parent_key = VALUES(parent_key),
+            updated_at = VALUES(updated_at)
+    `
+)
+
+// ParentRepositoryFactory creates parent repositories for one SQL dialect. Queries are written
+// with PostgreSQL's numbered parameters and rewritten for the other dialects.
+type ParentRepositoryFactory struct {
+	numbered bool
+	upsert   string
+}
+
+// NewParentRepositoryFactory returns the factory for PostgreSQL.
+func NewParentRepositoryFactory() *ParentRepositoryFactory {
+	return &ParentRepositoryFactory{numbered: true, upsert: updateParentOnConflict}
+}
+
+// NewMysqlParentRepositoryFactory returns the factory for MySQL and MariaDB.
+func NewMysqlParentRepositoryFactory() *ParentRepositoryFactory {
+	return &ParentRepositoryFactory{upsert: updateParentOnDuplicateKey}
+}
+
+// NewSqliteParentRepositoryFactory returns the factory for SQLite.
+func NewSqliteParentRepositoryFactory() *ParentRepositoryFactory {
+	return &ParentRepositoryFactory{upsert: updateParentOnConflict}
+}
+
+func (f *ParentRepositoryFactory) New(handler db.DbHandler) repository.ParentRepository {
+	return &ParentRepository{statements: statements{handler: handler, numbered: f.numbered}, upsert: f.upsert}
+}
+
+// ParentRepository stores the parents of keys in the key_parents table.
+type ParentRepository struct {
+	statements
+	upsert string
+}
+
+// SetParent inserts or changes the parent of the key
+func (pr *ParentRepository) SetParent(ctx context.Context, key, parent string) error {
+	query := `
+        INSERT INTO key_parents (limit_key, parent_key, created_at, updated_at)
+        VALUES ($1, $2, $3, $3)
+    ` + pr.upsert
+	_, err := pr.exec(ctx, query, key, parent, time.Now().UTC())
+	return err
+}
+
+// GetParent retrieves the parent link of the key
+func (pr *ParentRepository) GetParent(ctx context.Context, key string) (*model.KeyParent, error) {
+	query := `
+        SELECT limit_key, parent_key, created_at, updated_at
+        FROM key_parents
+        WHERE limit_key = $1
+    `
+	parents, err := pr.list(ctx, query, key)
+	if err != nil || len(parents) == 0 {
+		return nil, err
+	}
+	return &parents[0], nil
+}
+
+// ListChildren retrieves the links of the keys whose parent is the given key
+func (pr *ParentRepository) ListChildren(ctx context.Context, parent string) ([]model.KeyParent, error) {
+	query := `
+        SELECT limit_key, parent_key, created_at, updated_at
+        FROM key_parents
+        WHERE parent_key = $1
+        ORDER BY limit_key
+    `
+	return pr.list(ctx, query, parent)
+}
+
+func (pr *ParentRepository) list(ctx context.Context, query string, key string) ([]model.KeyParent, error) {
+	rows, err := pr.query(ctx, query, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parents []model.KeyParent
+	for rows.Next() {
+		var parent model.KeyParent
+		if err := rows.Scan(&parent.Key, &parent.Parent, &parent.CreatedAt, &parent.UpdatedAt); err != nil {
+			return nil, err
+		}
+		parents = append(parents, parent)
+	}
+	return parents, rows.Err()
+}
+
+// DeleteParent removes the parent of the key
+func (pr *ParentRepository) DeleteParent(ctx context.Context, key string) error {
+	query := `
+        DELETE FROM key_parents
+        WHERE limit_key = $1
+    `
+	result, err := pr.exec(ctx, query, key)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrParentNotFound
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+type MysqlUserRateLimitRepositoryFactory struct{}
+
+func NewMysqlUserRateLimitRepositoryFactory() *MysqlUserRateLimitRepositoryFactory {
+	return &MysqlUserRateLimitRepositoryFactory{}
+}
+
+func (f *MysqlUserRateLimitRepositoryFactory) New(handler db.DbHandler) repository.UserRateLimitRepository {
+	return NewMysqlUserRateLimitRepository(handler)
+}
+
+// MysqlUserRateLimitRepository stores user rate limits in MySQL or MariaDB. Neither supports
+// RETURNING, so record IDs are generated here rather than by the column default.
+type MysqlUserRateLimitRepository struct {
+	handler db.DbHandler
+}
+
+func NewMysqlUserRateLimitRepository(handler db.DbHandler) *MysqlUserRateLimitRepository {
+	return &MysqlUserRateLimitRepository{handler: handler}
+}
+
+// CreateRateLimit inserts a user rate limit record with an auto-generated ID and returns its ID,
+// unless the user already has a record
+func (ur *MysqlUserRateLimitRepository) CreateRateLimit(ctx context.Context, rateLimit model.UserRateLimit) (string, error) {
+	query := `
+        INSERT INTO user_rate_limits (id, user_id, request_count, rate_limit, timestamp, window_ms, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+        ON DUPLICATE KEY UPDATE id = id
+    `
+	id := uuid.New().String()
+	now := time.Now().UTC()
+	result, err := ur.handler.ExecContext(ctx, query, id, rateLimit.UserId, rateLimit.RequestCount, rateLimit.RateLimit, rateLimit.Timestamp, rateLimit.Window.Milliseconds(), now, now)
+	if err != nil {
+		return "", err
+	}
+
+	// One affected row means a new record, the existing record is left unchanged otherwise
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return "", err
+	}
+	if affected != 1 {
+		// The record was created since it was found missing
+		return "", domain.ErrRateLimitConflict
+	}
+	return id, nil
+}
+
+// GetRateLimitByUserId retrieves a user's rate limit by user ID
+func (ur *MysqlUserRateLimitRepository) GetRateLimitByUserId(ctx context.Context, userId string) (*model.UserRateLimit, error) {
+	query := `
+        SELECT id, user_id, request_count, rate_limit, timestamp, window_ms, version, created_at, updated_at
+        FROM user_rate_limits
+        WHERE user_id = ?
+    `
+	return ur.getRateLimit(ctx, query, userId)
+}
+
+// GetRateLimitByUserIdForUpdate retrieves a user's rate limit by user ID and locks the row until the
+// transaction ends
+func (ur *MysqlUserRateLimitRepository) GetRateLimitByUserIdForUpdate(ctx context.Context, userId string) (*model.UserRateLimit, error) {
+	query := `
+        SELECT id, user_id, request_count, rate_limit, timestamp, window_ms, version, created_at, updated_at
+        FROM user_rate_limits
+        WHERE user_id = ? FOR UPDATE
+    `
+	return ur.getRateLimit(ctx, query, userId)
+}
+
+func (ur *MysqlUserRateLimitRepository) getRateLimit(ctx context.Context, query string, userId string) (*model.UserRateLimit, error) {
+	var (
+		rateLimit model.UserRateLimit
+		windowMs  int64
+	)
+	err := ur.handler.QueryRowContext(ctx, query, userId).Scan(&rateLimit.Id, &rateLimit.UserId, &rateLimit.RequestCount, &rateLimit.RateLimit, &rateLimit.Timestamp,
+		&windowMs, &rateLimit.Version, &rateLimit.CreatedAt, &rateLimit.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Return nil if no records found
+		}
+		return nil, err
+	}
+
+	rateLimit.Window = time.Duration(windowMs) * time.Millisecond
+	return &rateLimit, nil
+}
+
+// UpdateRateLimit updates an existing user's rate limit unless it changed since it was read
+func (ur *MysqlUserRateLimitRepository) UpdateRateLimit(ctx context.Context, rateLimit model.UserRateLimit) error {
+	query := `
+        UPDATE user_rate_limits
+        SET request_count = ?, rate_limit = ?, timestamp = ?, window_ms = ?, updated_at = ?, version = version + 1
+        WHERE id = ? AND version = ?
+    `
+	result, err := ur.handler.ExecContext(ctx, query, rateLimit.RequestCount, rateLimit.RateLimit, rateLimit.Timestamp, rateLimit.Window.Milliseconds(), time.Now().UTC(), rateLimit.Id, rateLimit.Version)
+	if err != nil {
+		return err
+	}
+	return conflictUnlessUpdated(result)
+}
+
+// DeleteRateLimit removes a user's rate limit record
+func (ur *MysqlUserRateLimitRepository) DeleteRateLimit(ctx context.Context, userId string) error {
+	query := `
+        DELETE FROM user_rate_limits
+        WHERE user_id = ?
+    `
+	_, err := ur.handler.ExecContext(ctx, query, userId)
+	return err
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+// globalOverrideId is the ID of the row of the global override.
+const globalOverrideId = "global"
+
+const (
+	// updateOverrideOnConflict replaces the override in PostgreSQL and SQLite
+	updateOverrideOnConflict = `
+        ON CONFLICT (id) DO UPDATE
+        SET mode = excluded.mode,
+            multiplier = excluded.multiplier,
+            reason = excluded.reason,
+            expires_at = excluded.expires_at,
+            updated_at = excluded.updated_at
+    `
+	// updateOverrideOnDuplicateKey replaces the override in MySQL and MariaDB
+	updateOverrideOnDuplicateKey = `
+        ON DUPLICATE KEY UPDATE
+            mode = VALUES(mode),
+            multiplier = VALUES(multiplier),
+            reason = VALUES(reason),
+            expires_at = VALUES(expires_at),
+            updated_at = VALUES(updated_at)
+    `
+)
+
+// OverrideRepositoryFactory creates override repositories for one SQL dialect. Queries are
+// written with PostgreSQL's numbered parameters and rewritten for the other dialects.
+type OverrideRepositoryFactory struct {
+	numbered bool
+	upsert   string
+}
+
+// NewOverrideRepositoryFactory returns the factory for PostgreSQL.
+func NewOverrideRepositoryFactory() *OverrideRepositoryFactory {
+	return &OverrideRepositoryFactory{numbered: true, upsert: updateOverrideOnConflict}
+}
+
+// NewMysqlOverrideRepositoryFactory returns the factory for MySQL and MariaDB.
+func NewMysqlOverrideRepositoryFactory() *OverrideRepositoryFactory {
+	return &OverrideRepositoryFactory{upsert: updateOverrideOnDuplicateKey}
+}
+
+// NewSqliteOverrideRepositoryFactory returns the factory for SQLite.
+func NewSqliteOverrideRepositoryFactory() *OverrideRepositoryFactory {
+	return &OverrideRepositoryFactory{upsert: updateOverrideOnConflict}
+}
+
+func (f *OverrideRepositoryFactory) New(handler db.DbHandler) repository.OverrideRepository {
+	return &OverrideRepository{statements: statements{handler: handler, numbered: f.numbered}, upsert: f.upsert}
+}
+
+// OverrideRepository stores the global override in the single row of the overrides table.
+type OverrideRepository struct {
+	statements
+	upsert string
+}
+
+// SetOverride stores the override, replacing the one set before
+func (or *OverrideRepository) SetOverride(ctx context.Context, override model.Override) error {
+	query := `
+        INSERT INTO overrides (id, mode, multiplier, reason, expires_at, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $6)
+    ` + or.upsert
+	_, err := or.exec(ctx, query, globalOverrideId, string(override.Mode), override.Multiplier, override.Reason, override.ExpiresAt.UTC(), time.Now().UTC())
+	return err
+}
+
+// GetOverride retrieves the override, nil if none is set
+func (or *OverrideRepository) GetOverride(ctx context.Context) (*model.Override, error) {
+	query := `
+        SELECT mode, multiplier, reason, expires_at, created_at, updated_at
+        FROM overrides
+        WHERE id = $1
+    `
+	rows, err := or.query(ctx, query, globalOverrideId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	var (
+		override model.Override
+		mode     string
+	)
+	if err := rows.Scan(&mode, &override.Multiplier, &override.Reason, &override.ExpiresAt, &override.CreatedAt, &override.UpdatedAt); err != nil {
+		return nil, err
+	}
+	override.Mode = model.OverrideMode(mode)
+	return &override, rows.Err()
+}
+
+// DeleteOverride removes the override
+func (or *OverrideRepository) DeleteOverride(ctx context.Context) error {
+	query := `
+        DELETE FROM overrides
+        WHERE id = $1
+    `
+	result, err := or.exec(ctx, query, globalOverrideId)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrOverrideNotFound
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+const (
+	// updatePolicyLimitOnConflict changes an existing limit in PostgreSQL and SQLite
+	updatePolicyLimitOnConflict = `
+        ON CONFLICT (limit_key, window_ms) DO UPDATE
+        SET rate_limit = excluded.rate_limit,
+            shadow = excluded.shadow,
+            updated_at = excluded.updated_at
+    `
+	// updatePolicyLimitOnDuplicateKey changes an existing limit in MySQL and MariaDB
+	updatePolicyLimitOnDuplicateKey = `
+        ON DUPLICATE KEY UPDATE
+            rate_limit = VALUES(rate_limit),
+            shadow = VALUES(shadow),
+            updated_at = VALUES(updated_at)
+    `
+)
+
+// PolicyRepositoryFactory creates policy repositories for one SQL dialect. Queries are written
+// with PostgreSQL's numbered parameters and rewritten for the other dialects.
+type PolicyRepositoryFactory struct {
+	numbered bool
+	upsert   string
+	lock     string
+}
+
+// NewPolicyRepositoryFactory returns the factory for PostgreSQL.
+func NewPolicyRepositoryFactory() *PolicyRepositoryFactory {
+	return &PolicyRepositoryFactory{numbered: true, upsert: updatePolicyLimitOnConflict, lock: "FOR UPDATE"}
+}
+
+// NewMysqlPolicyRepositoryFactory returns the factory for MySQL and MariaDB.
+func NewMysqlPolicyRepositoryFactory() *PolicyRepositoryFactory {
+	return &PolicyRepositoryFactory{upsert: updatePolicyLimitOnDuplicateKey, lock: "FOR UPDATE"}
+}
+
+// NewSqlitePolicyRepositoryFactory returns the factory for SQLite, which has no row locks, but
+// transactions begin with the database write lock, so rows cannot change until they end.
+func NewSqlitePolicyRepositoryFactory() *PolicyRepositoryFactory {
+	return &PolicyRepositoryFactory{upsert: updatePolicyLimitOnConflict}
+}
+
+func (f *PolicyRepositoryFactory) New(handler db.DbHandler) repository.PolicyRepository {
+	return &PolicyRepository{statements: statements{handler: handler, numbered: f.numbered}, upsert: f.upsert, lock: f.lock}
+}
+
+// PolicyRepository stores policies in the policy_limits table.
+type PolicyRepository struct {
+	statements
+	upsert string
+	lock   string
+}
+
+// SetPolicy replaces the limits of the key's policy, removing the windows it no longer has and
+// inserting or changing the others
+func (pr *PolicyRepository) SetPolicy(ctx context.Context, key string, limits []model.PolicyLimit) error {
+	existing, err := pr.getPolicy(ctx, pr.lock, key)
+	if err != nil {
+		return err
+	}
+	kept := make(map[time.Duration]bool, len(limits))
+	for _, limit := range limits {
+		kept[limit.Window] = true
+	}
+	for _, limit := range existing {
+		if kept[limit.Window] {
+			continue
+		}
+		query := `
+            DELETE FROM policy_limits
+            WHERE limit_key = $1 AND window_ms = $2
+        `
+		if _, err := pr.exec(ctx, query, key, limit.Window.Milliseconds()); err != nil {
+			return err
+		}
+	}
+
+	query := `
+        INSERT INTO policy_limits (id, limit_key, window_ms, rate_limit, shadow, request_count, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, 0, $6, $6)
+    ` + pr.upsert
+	now := time.Now().UTC()
+	for _, limit := range limits {
+		if _, err := pr.exec(ctx, query, uuid.New().String(), key, limit.Window.Milliseconds(), limit.Limit, limit.Shadow, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPolicy retrieves the limits of the key's policy
+func (pr *PolicyRepository) GetPolicy(ctx context.Context, key string) ([]model.PolicyLimit, error) {
+	return pr.getPolicy(ctx, "", key)
+}
+
+// GetPolicyForUpdate retrieves the limits of the key's policy and locks them until the
+// transaction ends
+func (pr *PolicyRepository) GetPolicyForUpdate(ctx context.Context, key string) ([]model.PolicyLimit, error) {
+	return pr.getPolicy(ctx, pr.lock, key)
+}
+
+func (pr *PolicyRepository) getPolicy(ctx context.Context, lock string, key string) ([]model.PolicyLimit, error) {
+	query := `
+        SELECT id, limit_key, window_ms, rate_limit, shadow, request_count, window_start, created_at, updated_at
+        FROM policy_limits
+        WHERE limit_key = $1
+        ORDER BY window_ms
+    ` + lock
+	rows, err := pr.query(ctx, query, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var limits []model.PolicyLimit
+	for rows.Next() {
+		var (
+			limit       model.PolicyLimit
+			windowMs    int64
+			windowStart sql.NullTime
+		)
+		if err := rows.Scan(&limit.Id, &limit.Key, &windowMs, &limit.Limit, &limit.Shadow, &limit.Count, &windowStart, &limit.CreatedAt, &limit.UpdatedAt); err != nil {
+			return nil, err
+		}
+		limit.Window = time.Duration(windowMs) * time.Millisecond
+		limit.WindowStart = windowStart.Time
+		limits = append(limits, limit)
+	}
+	return limits, rows.Err()
+}
+
+// UpdatePolicyCounts stores the counts and window starts of the key's limits
+func (pr *PolicyRepository) UpdatePolicyCounts(ctx context.Context, key string, limits []model.PolicyLimit) error {
+	query := `
+        UPDATE policy_limits
+        SET request_count = $1, window_start = $2, updated_at = $3
+        WHERE limit_key = $4 AND window_ms = $5
+    `
+	now := time.Now().UTC()
+	for _, limit := range limits {
+		if _, err := pr.exec(ctx, query, limit.Count, limit.WindowStart.UTC(), now, key, limit.Window.Milliseconds()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeletePolicy removes the limits of the key's policy
+func (pr *PolicyRepository) DeletePolicy(ctx context.Context, key string) error {
+	query := `
+        DELETE FROM policy_limits
+        WHERE limit_key = $1
+    `
+	result, err := pr.exec(ctx, query, key)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrPolicyNotFound
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+const (
+	// updateQuotaOnConflict changes an existing quota in PostgreSQL and SQLite
+	updateQuotaOnConflict = `
+        ON CONFLICT (limit_key, period) DO UPDATE
+        SET quota_limit = excluded.quota_limit,
+            time_zone = excluded.time_zone,
+            reset_day = excluded.reset_day,
+            updated_at = excluded.updated_at
+    `
+	// updateQuotaOnDuplicateKey changes an existing quota in MySQL and MariaDB
+	updateQuotaOnDuplicateKey = `
+        ON DUPLICATE KEY UPDATE
+            quota_limit = VALUES(quota_limit),
+            time_zone = VALUES(time_zone),
+            reset_day = VALUES(reset_day),
+            updated_at = VALUES(updated_at)
+    `
+)
+
+// QuotaRepositoryFactory creates quota repositories for one SQL dialect. Queries are written
+// with PostgreSQL's numbered parameters and rewritten for the other dialects.
+type QuotaRepositoryFactory struct {
+	numbered bool
+	upsert   string
+	lock     string
+}
+
+// NewQuotaRepositoryFactory returns the factory for PostgreSQL.
+func NewQuotaRepositoryFactory() *QuotaRepositoryFactory {
+	return &QuotaRepositoryFactory{numbered: true, upsert: updateQuotaOnConflict, lock: "FOR UPDATE"}
+}
+
+// NewMysqlQuotaRepositoryFactory returns the factory for MySQL and MariaDB.
+func NewMysqlQuotaRepositoryFactory() *QuotaRepositoryFactory {
+	return &QuotaRepositoryFactory{upsert: updateQuotaOnDuplicateKey, lock: "FOR UPDATE"}
+}
+
+// NewSqliteQuotaRepositoryFactory returns the factory for SQLite, which has no row locks, but
+// transactions begin with the database write lock, so rows cannot change until they end.
+func NewSqliteQuotaRepositoryFactory() *QuotaRepositoryFactory {
+	return &QuotaRepositoryFactory{upsert: updateQuotaOnConflict}
+}
+
+func (f *QuotaRepositoryFactory) New(handler db.DbHandler) repository.QuotaRepository {
+	return &QuotaRepository{statements: statements{handler: handler, numbered: f.numbered}, upsert: f.upsert, lock: f.lock}
+}
+
+// QuotaRepository stores quotas in the quotas table.
+type QuotaRepository struct {
+	statements
+	upsert string
+	lock   string
+}
+
+// SetQuota inserts a quota with an auto-generated ID, or changes the key's existing quota for
+// the period
+func (qr *QuotaRepository) SetQuota(ctx context.Context, quota model.Quota) error {
+	query := `
+        INSERT INTO quotas (id, limit_key, period, quota_limit, time_zone, reset_day, used, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, 0, $7, $7)
+    ` + qr.upsert
+	timeZone := quota.TimeZone
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+	_, err := qr.exec(ctx, query, uuid.New().String(), quota.Key, string(quota.Period), quota.Limit, timeZone, quota.ResetDay, time.Now().UTC())
+	return err
+}
+
+// GetQuotasByKey retrieves the quotas of a key
+func (qr *QuotaRepository) GetQuotasByKey(ctx context.Context, key string) ([]model.Quota, error) {
+	return qr.getQuotas(ctx, "", key)
+}
+
+// GetQuotasByKeyForUpdate retrieves the quotas of a key and locks them until the transaction ends
+func (qr *QuotaRepository) GetQuotasByKeyForUpdate(ctx context.Context, key string) ([]model.Quota, error) {
+	return qr.getQuotas(ctx, qr.lock, key)
+}
+
+func (qr *QuotaRepository) getQuotas(ctx context.Context, lock string, key string) ([]model.Quota, error) {
+	query := `
+        SELECT id, limit_key, period, quota_limit, time_zone, reset_day, period_start, used, created_at, updated_at
+        FROM quotas
+        WHERE limit_key = $1
+        ORDER BY period
+    ` + lock
+	rows, err := qr.query(ctx, query, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var quotas []model.Quota
+	for rows.Next() {
+		var (
+			quota       model.Quota
+			periodStart sql.NullTime
+		)
+		if err := rows.Scan(&quota.Id, &quota.Key, &quota.Period, &quota.Limit, &quota.TimeZone, &quota.ResetDay, &periodStart, &quota.Used,
+			&quota.CreatedAt, &quota.UpdatedAt); err != nil {
+			return nil, err
+		}
+		quota.PeriodStart = periodStart.Time
+		quotas = append(quotas, quota)
+	}
+	return quotas, rows.Err()
+}
+
+// AddQuotaUsage adds to what a quota counted in the period, starting over if the quota was still
+// counting an earlier one
+func (qr *QuotaRepository) AddQuotaUsage(ctx context.Context, key string, period model.QuotaPeriod, periodStart time.Time, units int64) error {
+	query := `
+        UPDATE quotas
+        SET used = CASE
+                WHEN period_start = $1 AND used + $2 > 0 THEN used + $2
+                WHEN period_start = $1 OR $2 < 0 THEN 0
+                ELSE $2
+            END,
+            period_start = $1,
+            updated_at = $3
+        WHERE limit_key = $4 AND period = $5
+    `
+	_, err := qr.exec(ctx, query, periodStart.UTC(), units, time.Now().UTC(), key, string(period))
+	return err
+}
+
+// DeleteQuota removes the key's quota for the period
+func (qr *QuotaRepository) DeleteQuota(ctx context.Context, key string, period model.QuotaPeriod) error {
+	query := `
+        DELETE FROM quotas
+        WHERE limit_key = $1 AND period = $2
+    `
+	result, err := qr.exec(ctx, query, key, string(period))
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrQuotaNotFound
+	}
+	return nil
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
 	"github.com/nullexp/limiter-x/internal/domain/model"
 	"github.com/nullexp/limiter-x/internal/port/driven/db"
 	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
@@ -28,16 +30,22 @@ func NewUserRateLimitRepository(handler db.DbHandler) *UserRateLimitRepository {
 	return &UserRateLimitRepository{handler: handler}
 }
 
-// CreateRateLimit inserts a new user rate limit record with an auto-generated ID
+// CreateRateLimit inserts a new user rate limit record with an auto-generated ID and returns its
+// ID, unless the user already has a record
 func (ur *UserRateLimitRepository) CreateRateLimit(ctx context.Context, rateLimit model.UserRateLimit) (string, error) {
 	query := `
-        INSERT INTO user_rate_limits (user_id, request_count, rate_limit, timestamp)
-        VALUES ($1, $2, $3, $4)
+        INSERT INTO user_rate_limits (user_id, request_count, rate_limit, timestamp, window_ms, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $6)
+        ON CONFLICT (user_id) DO NOTHING
         RETURNING id
     `
 	var id string
-	err := ur.handler.QueryRowContext(ctx, query, rateLimit.UserId, rateLimit.RequestCount, rateLimit.RateLimit, rateLimit.Timestamp).Scan(&id)
+	err := ur.handler.QueryRowContext(ctx, query, rateLimit.UserId, rateLimit.RequestCount, rateLimit.RateLimit, rateLimit.Timestamp, rateLimit.Window.Milliseconds(), time.Now().UTC()).Scan(&id)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// The record was created since it was found missing
+			return "", domain.ErrRateLimitConflict
+		}
 		return "", err
 	}
 	return id, nil
@@ -46,13 +54,31 @@ func (ur *UserRateLimitRepository) CreateRateLimit(ctx context.Context, rateLimi
 // GetRateLimitByUserId retrieves a user's rate limit by user ID
 func (ur *UserRateLimitRepository) GetRateLimitByUserId(ctx context.Context, userId string) (*model.UserRateLimit, error) {
 	query := `
-        SELECT id, user_id, request_count, rate_limit, timestamp
+        SELECT id, user_id, request_count, rate_limit, timestamp, window_ms, version, created_at, updated_at
         FROM user_rate_limits
         WHERE user_id = $1
     `
+	return ur.getRateLimit(ctx, query, userId)
+}
+
+// GetRateLimitByUserIdForUpdate retrieves a user's rate limit by user ID and locks the row until the
+// transaction ends
+func (ur *UserRateLimitRepository) GetRateLimitByUserIdForUpdate(ctx context.Context, userId string) (*model.UserRateLimit, error) {
+	query := `
+        SELECT id, user_id, request_count, rate_limit, timestamp, window_ms, version, created_at, updated_at
+        FROM user_rate_limits
+        WHERE user_id = $1 FOR UPDATE
+    `
+	return ur.getRateLimit(ctx, query, userId)
+}
 
-	var rateLimit model.UserRateLimit
-	err := ur.handler.QueryRowContext(ctx, query, userId).Scan(&rateLimit.Id, &rateLimit.UserId, &rateLimit.RequestCount, &rateLimit.RateLimit, &rateLimit.Timestamp)
+func (ur *UserRateLimitRepository) getRateLimit(ctx context.Context, query string, userId string) (*model.UserRateLimit, error) {
+	var (
+		rateLimit model.UserRateLimit
+		windowMs  int64
+	)
+	err := ur.handler.QueryRowContext(ctx, query, userId).Scan(&rateLimit.Id, &rateLimit.UserId, &rateLimit.RequestCount, &rateLimit.RateLimit, &rateLimit.Timestamp,
+		&windowMs, &rateLimit.Version, &rateLimit.CreatedAt, &rateLimit.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil // Return nil if no records found
@@ -60,18 +86,22 @@ func (ur *UserRateLimitRepository) GetRateLimitByUserId(ctx context.Context, use
 		return nil, err
 	}
 
+	rateLimit.Window = time.Duration(windowMs) * time.Millisecond
 	return &rateLimit, nil
 }
 
-// UpdateRateLimit updates an existing user's rate limit
+// UpdateRateLimit updates an existing user's rate limit unless it changed since it was read
 func (ur *UserRateLimitRepository) UpdateRateLimit(ctx context.Context, rateLimit model.UserRateLimit) error {
 	query := `
         UPDATE user_rate_limits
-        SET request_count = $1, rate_limit = $2, timestamp = $3
-        WHERE id = $4
+        SET request_count = $1, rate_limit = $2, timestamp = $3, window_ms = $4, updated_at = $5, version = version + 1
+        WHERE id = $6 AND version = $7
     `
-	_, err := ur.handler.ExecContext(ctx, query, rateLimit.RequestCount, rateLimit.RateLimit, rateLimit.Timestamp, rateLimit.Id)
-	return err
+	result, err := ur.handler.ExecContext(ctx, query, rateLimit.RequestCount, rateLimit.RateLimit, rateLimit.Timestamp, rateLimit.Window.Milliseconds(), time.Now().UTC(), rateLimit.Id, rateLimit.Version)
+	if err != nil {
+		return err
+	}
+	return conflictUnlessUpdated(result)
 }
 
 // DeleteRateLimit removes a user's rate limit record
@@ -88,9 +118,21 @@ func (ur *UserRateLimitRepository) DeleteRateLimit(ctx context.Context, userId s
 func (ur *UserRateLimitRepository) UpdateUserRateLimit(ctx context.Context, userId string, newRateLimit int) error {
 	query := `
         UPDATE user_rate_limits
-        SET rate_limit = $1
-        WHERE user_id = $2
+        SET rate_limit = $1, updated_at = $2, version = version + 1
+        WHERE user_id = $3
     `
-	_, err := ur.handler.ExecContext(ctx, query, newRateLimit, userId)
+	_, err := ur.handler.ExecContext(ctx, query, newRateLimit, time.Now().UTC(), userId)
 	return err
 }
+
+// conflictUnlessUpdated reports a version conflict when an optimistic update matched no row.
+func conflictUnlessUpdated(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrRateLimitConflict
+	}
+	return nil
+}
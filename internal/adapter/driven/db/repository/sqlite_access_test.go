@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSqliteAccessRuleRepository(t *testing.T) {
+	ctx := context.Background()
+	txFactory := newSqliteTransactionFactory(t)
+	repoFactory := NewSqliteAccessRuleRepositoryFactory()
+
+	tx := txFactory.NewTransaction()
+	handler, err := tx.Begin(ctx)
+	assert.Nil(t, err)
+	defer tx.RollbackUnlessCommitted(ctx)
+	repo := repoFactory.New(handler)
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	deny := model.AccessRule{Id: uuid.New().String(), Action: model.AccessDeny, Match: model.MatchCIDR, Pattern: "10.0.0.0/8", Reason: "abuse", ExpiresAt: expiresAt}
+	assert.Nil(t, repo.SetAccessRule(ctx, deny))
+	assert.Nil(t, repo.SetAccessRule(ctx, model.AccessRule{Id: uuid.New().String(), Action: model.AccessAllow, Match: model.MatchPrefix, Pattern: "svc-"}))
+
+	// Setting a rule again replaces it
+	deny.Reason = "scraping"
+	assert.Nil(t, repo.SetAccessRule(ctx, deny))
+
+	rules, err := repo.ListAccessRules(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, rules, 2)
+	assert.Equal(t, model.MatchCIDR, rules[0].Match)
+	assert.Equal(t, "scraping", rules[0].Reason)
+	assert.True(t, expiresAt.Equal(rules[0].ExpiresAt))
+	assert.True(t, rules[1].ExpiresAt.IsZero())
+
+	assert.Nil(t, repo.DeleteAccessRule(ctx, deny.Id))
+	assert.ErrorIs(t, repo.DeleteAccessRule(ctx, deny.Id), domain.ErrAccessRuleNotFound)
+}
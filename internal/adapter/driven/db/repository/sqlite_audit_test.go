@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSqliteAuditEventRepository(t *testing.T) {
+	ctx := context.Background()
+	txFactory := newSqliteTransactionFactory(t)
+	repoFactory := NewSqliteAuditEventRepositoryFactory()
+	start := time.Now().Add(-time.Hour)
+
+	tx := txFactory.NewTransaction()
+	handler, err := tx.Begin(ctx)
+	assert.Nil(t, err)
+	defer tx.RollbackUnlessCommitted(ctx)
+	repo := repoFactory.New(handler)
+
+	events := []model.AuditEvent{
+		{Key: "alice", Actor: "admin", Type: model.AuditLimitChanged, OldValue: "10", NewValue: "20", CreatedAt: start},
+		{Key: "alice", Type: model.AuditRequestDenied, Detail: "20 of 20 requests used", CreatedAt: start.Add(time.Minute)},
+		{Key: "bob", Actor: "admin", Type: model.AuditLimitChanged, NewValue: "5", CreatedAt: start.Add(2 * time.Minute)},
+	}
+	for _, event := range events {
+		assert.Nil(t, repo.AppendEvent(ctx, event))
+	}
+
+	all, err := repo.ListEvents(ctx, model.AuditFilter{})
+	assert.Nil(t, err)
+	assert.Len(t, all, 3)
+	assert.Equal(t, "bob", all[0].Key, "newest first")
+	assert.NotEmpty(t, all[0].Id)
+	assert.True(t, all[2].CreatedAt.Equal(start))
+
+	tests := []struct {
+		name   string
+		filter model.AuditFilter
+		expect int
+	}{
+		{name: "By key", filter: model.AuditFilter{Key: "alice"}, expect: 2},
+		{name: "By actor", filter: model.AuditFilter{Actor: "admin"}, expect: 2},
+		{name: "By type", filter: model.AuditFilter{Type: model.AuditRequestDenied}, expect: 1},
+		{name: "By time range", filter: model.AuditFilter{From: start.Add(time.Minute), To: start.Add(2 * time.Minute)}, expect: 1},
+		{name: "Combined", filter: model.AuditFilter{Key: "alice", Actor: "admin", Type: model.AuditLimitChanged}, expect: 1},
+		{name: "Limited", filter: model.AuditFilter{Limit: 2}, expect: 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			found, err := repo.ListEvents(ctx, test.filter)
+			assert.Nil(t, err)
+			assert.Len(t, found, test.expect)
+			for _, event := range found {
+				assert.True(t, test.filter.Matches(event))
+			}
+		})
+	}
+}
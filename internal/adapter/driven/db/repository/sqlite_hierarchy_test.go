@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSqliteParentRepository(t *testing.T) {
+	ctx := context.Background()
+	txFactory := newSqliteTransactionFactory(t)
+	repoFactory := NewSqliteParentRepositoryFactory()
+
+	tx := txFactory.NewTransaction()
+	handler, err := tx.Begin(ctx)
+	assert.Nil(t, err)
+	defer tx.RollbackUnlessCommitted(ctx)
+	repo := repoFactory.New(handler)
+
+	assert.Nil(t, repo.SetParent(ctx, "team-a", "org"))
+	assert.Nil(t, repo.SetParent(ctx, "user-2", "team-a"))
+	assert.Nil(t, repo.SetParent(ctx, "user-1", "team-b"))
+
+	// Setting the parent again moves the key
+	assert.Nil(t, repo.SetParent(ctx, "user-1", "team-a"))
+	parent, err := repo.GetParent(ctx, "user-1")
+	assert.Nil(t, err)
+	assert.Equal(t, "team-a", parent.Parent)
+
+	children, err := repo.ListChildren(ctx, "team-a")
+	assert.Nil(t, err)
+	assert.Len(t, children, 2)
+	assert.Equal(t, "user-1", children[0].Key)
+	assert.Equal(t, "user-2", children[1].Key)
+
+	assert.Nil(t, repo.DeleteParent(ctx, "user-1"))
+	parent, err = repo.GetParent(ctx, "user-1")
+	assert.Nil(t, err)
+	assert.Nil(t, parent)
+	assert.ErrorIs(t, repo.DeleteParent(ctx, "user-1"), domain.ErrParentNotFound)
+}
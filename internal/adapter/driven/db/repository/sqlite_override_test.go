@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSqliteOverrideRepository(t *testing.T) {
+	ctx := context.Background()
+	txFactory := newSqliteTransactionFactory(t)
+	repoFactory := NewSqliteOverrideRepositoryFactory()
+
+	tx := txFactory.NewTransaction()
+	handler, err := tx.Begin(ctx)
+	assert.Nil(t, err)
+	defer tx.RollbackUnlessCommitted(ctx)
+	repo := repoFactory.New(handler)
+
+	override, err := repo.GetOverride(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, override)
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	assert.Nil(t, repo.SetOverride(ctx, model.Override{Mode: model.OverrideDenyAll, Reason: "incident", ExpiresAt: expiresAt}))
+	// Setting another replaces it
+	assert.Nil(t, repo.SetOverride(ctx, model.Override{Mode: model.OverrideMultiplier, Multiplier: 0.25, Reason: "incident", ExpiresAt: expiresAt}))
+
+	override, err = repo.GetOverride(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, model.OverrideMultiplier, override.Mode)
+	assert.Equal(t, 0.25, override.Multiplier)
+	assert.Equal(t, "incident", override.Reason)
+	assert.True(t, override.ExpiresAt.Equal(expiresAt))
+
+	assert.Nil(t, repo.DeleteOverride(ctx))
+	assert.Equal(t, domain.ErrOverrideNotFound, repo.DeleteOverride(ctx))
+	override, err = repo.GetOverride(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, override)
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSqlitePolicyRepository(t *testing.T) {
+	ctx := context.Background()
+	txFactory := newSqliteTransactionFactory(t)
+	repoFactory := NewSqlitePolicyRepositoryFactory()
+	windowStart := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+
+	tx := txFactory.NewTransaction()
+	handler, err := tx.Begin(ctx)
+	assert.Nil(t, err)
+	defer tx.RollbackUnlessCommitted(ctx)
+	repo := repoFactory.New(handler)
+
+	assert.Nil(t, repo.SetPolicy(ctx, "tenant", []model.PolicyLimit{
+		{Window: time.Hour, Limit: 2000},
+		{Window: time.Second, Limit: 5},
+		{Window: time.Minute, Limit: 100, Shadow: true},
+	}))
+	assert.Nil(t, repo.UpdatePolicyCounts(ctx, "tenant", []model.PolicyLimit{
+		{Window: time.Second, Count: 3, WindowStart: windowStart},
+		{Window: time.Hour, Count: 30, WindowStart: windowStart},
+	}))
+
+	limits, err := repo.GetPolicyForUpdate(ctx, "tenant")
+	assert.Nil(t, err)
+	assert.Len(t, limits, 3)
+	assert.Equal(t, []time.Duration{time.Second, time.Minute, time.Hour}, []time.Duration{limits[0].Window, limits[1].Window, limits[2].Window})
+	assert.Equal(t, 3, limits[0].Count)
+	assert.True(t, limits[0].WindowStart.Equal(windowStart))
+	assert.True(t, limits[1].WindowStart.IsZero())
+	assert.Equal(t, []bool{false, true, false}, []bool{limits[0].Shadow, limits[1].Shadow, limits[2].Shadow})
+
+	// Replacing the policy keeps the counts of the windows it still has
+	assert.Nil(t, repo.SetPolicy(ctx, "tenant", []model.PolicyLimit{{Window: time.Second, Limit: 10, Shadow: true}, {Window: 24 * time.Hour, Limit: 10000}}))
+	limits, err = repo.GetPolicy(ctx, "tenant")
+	assert.Nil(t, err)
+	assert.Len(t, limits, 2)
+	assert.Equal(t, 10, limits[0].Limit)
+	assert.True(t, limits[0].Shadow)
+	assert.Equal(t, 3, limits[0].Count)
+	assert.Equal(t, 24*time.Hour, limits[1].Window)
+	assert.Equal(t, 0, limits[1].Count)
+
+	assert.Nil(t, repo.DeletePolicy(ctx, "tenant"))
+	assert.Equal(t, domain.ErrPolicyNotFound, repo.DeletePolicy(ctx, "tenant"))
+	limits, err = repo.GetPolicy(ctx, "tenant")
+	assert.Nil(t, err)
+	assert.Empty(t, limits)
+}
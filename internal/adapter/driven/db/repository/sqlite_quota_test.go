@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSqliteQuotaRepository(t *testing.T) {
+	ctx := context.Background()
+	txFactory := newSqliteTransactionFactory(t)
+	repoFactory := NewSqliteQuotaRepositoryFactory()
+	today := time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)
+
+	tx := txFactory.NewTransaction()
+	handler, err := tx.Begin(ctx)
+	assert.Nil(t, err)
+	defer tx.RollbackUnlessCommitted(ctx)
+	repo := repoFactory.New(handler)
+
+	assert.Nil(t, repo.SetQuota(ctx, model.Quota{Key: "tenant", Period: model.QuotaDaily, Limit: 100}))
+	assert.Nil(t, repo.SetQuota(ctx, model.Quota{Key: "tenant", Period: model.QuotaMonthly, Limit: 1000, TimeZone: "Europe/Berlin", ResetDay: 15}))
+	assert.Nil(t, repo.AddQuotaUsage(ctx, "tenant", model.QuotaDaily, today, 7))
+	assert.Nil(t, repo.AddQuotaUsage(ctx, "tenant", model.QuotaDaily, today, 3))
+
+	// Changing a quota keeps what it counted
+	assert.Nil(t, repo.SetQuota(ctx, model.Quota{Key: "tenant", Period: model.QuotaDaily, Limit: 50}))
+
+	quotas, err := repo.GetQuotasByKeyForUpdate(ctx, "tenant")
+	assert.Nil(t, err)
+	assert.Len(t, quotas, 2)
+	daily, monthly := quotas[0], quotas[1]
+	assert.Equal(t, int64(50), daily.Limit)
+	assert.Equal(t, "UTC", daily.TimeZone)
+	assert.Equal(t, int64(10), daily.UsedIn(today))
+	assert.Equal(t, "Europe/Berlin", monthly.TimeZone)
+	assert.Equal(t, 15, monthly.ResetDay)
+	assert.True(t, monthly.PeriodStart.IsZero())
+
+	// A new period starts over, and returned units never take the count below zero
+	tomorrow := today.AddDate(0, 0, 1)
+	assert.Nil(t, repo.AddQuotaUsage(ctx, "tenant", model.QuotaDaily, tomorrow, 2))
+	assert.Nil(t, repo.AddQuotaUsage(ctx, "tenant", model.QuotaDaily, tomorrow, -5))
+	quotas, err = repo.GetQuotasByKey(ctx, "tenant")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), quotas[0].UsedIn(tomorrow))
+	assert.True(t, quotas[0].PeriodStart.Equal(tomorrow))
+
+	assert.Nil(t, repo.DeleteQuota(ctx, "tenant", model.QuotaMonthly))
+	assert.Equal(t, domain.ErrQuotaNotFound, repo.DeleteQuota(ctx, "tenant", model.QuotaMonthly))
+	quotas, err = repo.GetQuotasByKey(ctx, "tenant")
+	assert.Nil(t, err)
+	assert.Len(t, quotas, 1)
+}
+
+func TestPositional(t *testing.T) {
+	query, args := positional("WHERE a = $2 AND b = $1 OR c = $2", []interface{}{"first", "second"})
+	assert.Equal(t, "WHERE a = ? AND b = ? OR c = ?", query)
+	assert.Equal(t, []interface{}{"second", "first", "second"}, args)
+}
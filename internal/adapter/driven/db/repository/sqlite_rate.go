@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+type SqliteUserRateLimitRepositoryFactory struct{}
+
+func NewSqliteUserRateLimitRepositoryFactory() *SqliteUserRateLimitRepositoryFactory {
+	return &SqliteUserRateLimitRepositoryFactory{}
+}
+
+func (f *SqliteUserRateLimitRepositoryFactory) New(handler db.DbHandler) repository.UserRateLimitRepository {
+	return NewSqliteUserRateLimitRepository(handler)
+}
+
+// SqliteUserRateLimitRepository stores user rate limits in SQLite, which has no UUID type, so
+// record IDs are generated here rather than by the database.
+type SqliteUserRateLimitRepository struct {
+	handler db.DbHandler
+}
+
+func NewSqliteUserRateLimitRepository(handler db.DbHandler) *SqliteUserRateLimitRepository {
+	return &SqliteUserRateLimitRepository{handler: handler}
+}
+
+// CreateRateLimit inserts a new user rate limit record with an auto-generated ID and returns its
+// ID, unless the user already has a record
+func (ur *SqliteUserRateLimitRepository) CreateRateLimit(ctx context.Context, rateLimit model.UserRateLimit) (string, error) {
+	query := `
+        INSERT INTO user_rate_limits (id, user_id, request_count, rate_limit, timestamp, window_ms, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+        ON CONFLICT (user_id) DO NOTHING
+        RETURNING id
+    `
+	now := time.Now().UTC()
+	var id string
+	err := ur.handler.QueryRowContext(ctx, query, uuid.New().String(), rateLimit.UserId, rateLimit.RequestCount, rateLimit.RateLimit, rateLimit.Timestamp, rateLimit.Window.Milliseconds(), now, now).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// The record was created since it was found missing
+			return "", domain.ErrRateLimitConflict
+		}
+		return "", err
+	}
+	return id, nil
+}
+
+// GetRateLimitByUserId retrieves a user's rate limit by user ID
+func (ur *SqliteUserRateLimitRepository) GetRateLimitByUserId(ctx context.Context, userId string) (*model.UserRateLimit, error) {
+	query := `
+        SELECT id, user_id, request_count, rate_limit, timestamp, window_ms, version, created_at, updated_at
+        FROM user_rate_limits
+        WHERE user_id = ?
+    `
+	return ur.getRateLimit(ctx, query, userId)
+}
+
+// GetRateLimitByUserIdForUpdate retrieves a user's rate limit by user ID. SQLite has no row
+// locks, but transactions begin with the database write lock, so the row cannot change until the
+// transaction ends
+func (ur *SqliteUserRateLimitRepository) GetRateLimitByUserIdForUpdate(ctx context.Context, userId string) (*model.UserRateLimit, error) {
+	return ur.GetRateLimitByUserId(ctx, userId)
+}
+
+func (ur *SqliteUserRateLimitRepository) getRateLimit(ctx context.Context, query string, userId string) (*model.UserRateLimit, error) {
+	var (
+		rateLimit model.UserRateLimit
+		windowMs  int64
+	)
+	err := ur.handler.QueryRowContext(ctx, query, userId).Scan(&rateLimit.Id, &rateLimit.UserId, &rateLimit.RequestCount, &rateLimit.RateLimit, &rateLimit.Timestamp,
+		&windowMs, &rateLimit.Version, &rateLimit.CreatedAt, &rateLimit.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil // Return nil if no records found
+		}
+		return nil, err
+	}
+
+	rateLimit.Window = time.Duration(windowMs) * time.Millisecond
+	return &rateLimit, nil
+}
+
+// UpdateRateLimit updates an existing user's rate limit unless it changed since it was read
+func (ur *SqliteUserRateLimitRepository) UpdateRateLimit(ctx context.Context, rateLimit model.UserRateLimit) error {
+	query := `
+        UPDATE user_rate_limits
+        SET request_count = ?, rate_limit = ?, timestamp = ?, window_ms = ?, updated_at = ?, version = version + 1
+        WHERE id = ? AND version = ?
+    `
+	result, err := ur.handler.ExecContext(ctx, query, rateLimit.RequestCount, rateLimit.RateLimit, rateLimit.Timestamp, rateLimit.Window.Milliseconds(), time.Now().UTC(), rateLimit.Id, rateLimit.Version)
+	if err != nil {
+		return err
+	}
+	return conflictUnlessUpdated(result)
+}
+
+// DeleteRateLimit removes a user's rate limit record
+func (ur *SqliteUserRateLimitRepository) DeleteRateLimit(ctx context.Context, userId string) error {
+	query := `
+        DELETE FROM user_rate_limits
+        WHERE user_id = ?
+    `
+	_, err := ur.handler.ExecContext(ctx, query, userId)
+	return err
+}
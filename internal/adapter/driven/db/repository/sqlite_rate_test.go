@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/db"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/db/migration"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSqliteTransactionFactory(t *testing.T) *db.SqliteDbTransactionFactory {
+	path := filepath.Join(t.TempDir(), "rates.db")
+	migrator, err := migration.New(db.Sqlite, path)
+	assert.Nil(t, err)
+	assert.Nil(t, migrator.Up())
+	assert.Nil(t, migrator.Close())
+
+	sqlDb, err := db.OpenSqlite(path)
+	assert.Nil(t, err)
+	t.Cleanup(func() { sqlDb.Close() })
+
+	return db.NewSqliteDbTransactionFactory(sqlDb)
+}
+
+func TestSqliteUserRateLimitRepository(t *testing.T) {
+	ctx := context.Background()
+	txFactory := newSqliteTransactionFactory(t)
+	repoFactory := NewSqliteUserRateLimitRepositoryFactory()
+	userId := uuid.New().String()
+
+	tx := txFactory.NewTransaction()
+	handler, err := tx.Begin(ctx)
+	assert.Nil(t, err)
+	repo := repoFactory.New(handler)
+
+	id, err := repo.CreateRateLimit(ctx, model.UserRateLimit{UserId: userId, RequestCount: 1, RateLimit: 10, Timestamp: time.Now(), Window: time.Second * 30})
+	assert.Nil(t, err)
+
+	rateLimit, err := repo.GetRateLimitByUserId(ctx, userId)
+	assert.Nil(t, err)
+	assert.Equal(t, id, rateLimit.Id)
+	assert.Equal(t, 10, rateLimit.RateLimit)
+	assert.Equal(t, time.Second*30, rateLimit.Window)
+
+	rateLimit.RequestCount = 7
+	rateLimit.Window = time.Minute
+	assert.Nil(t, repo.UpdateRateLimit(ctx, *rateLimit))
+	assert.Nil(t, tx.Commit(ctx))
+
+	// A rolled back delete leaves the record in place
+	tx = txFactory.NewTransaction()
+	handler, err = tx.Begin(ctx)
+	assert.Nil(t, err)
+	repo = repoFactory.New(handler)
+	assert.Nil(t, repo.DeleteRateLimit(ctx, userId))
+	assert.Nil(t, tx.Rollback(ctx))
+
+	tx = txFactory.NewTransaction()
+	handler, err = tx.Begin(ctx)
+	assert.Nil(t, err)
+	defer tx.RollbackUnlessCommitted(ctx)
+	rateLimit, err = repoFactory.New(handler).GetRateLimitByUserId(ctx, userId)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, rateLimit.RequestCount)
+	assert.Equal(t, time.Minute, rateLimit.Window)
+
+	missing, err := repoFactory.New(handler).GetRateLimitByUserId(ctx, uuid.New().String())
+	assert.Nil(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestSqliteUserRateLimitRepository_CreateAndVersionConflict(t *testing.T) {
+	ctx := context.Background()
+	txFactory := newSqliteTransactionFactory(t)
+	repoFactory := NewSqliteUserRateLimitRepositoryFactory()
+	userId := uuid.New().String()
+
+	tx := txFactory.NewTransaction()
+	handler, err := tx.Begin(ctx)
+	assert.Nil(t, err)
+	defer tx.RollbackUnlessCommitted(ctx)
+	repo := repoFactory.New(handler)
+
+	id, err := repo.CreateRateLimit(ctx, model.UserRateLimit{UserId: userId, RequestCount: 3, RateLimit: 10, Timestamp: time.Now()})
+	assert.Nil(t, err)
+
+	// Creating again neither adds a second record nor overwrites what the first one counted
+	_, err = repo.CreateRateLimit(ctx, model.UserRateLimit{UserId: userId, RequestCount: 1, RateLimit: 20, Timestamp: time.Now()})
+	assert.Equal(t, domain.ErrRateLimitConflict, err)
+
+	rateLimit, err := repo.GetRateLimitByUserId(ctx, userId)
+	assert.Nil(t, err)
+	assert.Equal(t, id, rateLimit.Id)
+	assert.Equal(t, 3, rateLimit.RequestCount)
+	assert.Equal(t, 10, rateLimit.RateLimit)
+	assert.Equal(t, int64(1), rateLimit.Version)
+	assert.False(t, rateLimit.CreatedAt.IsZero())
+
+	stale := *rateLimit
+	rateLimit.RequestCount = 1
+	assert.Nil(t, repo.UpdateRateLimit(ctx, *rateLimit))
+
+	// The record moved on since stale was read
+	stale.RequestCount = 2
+	assert.Equal(t, domain.ErrRateLimitConflict, repo.UpdateRateLimit(ctx, stale))
+}
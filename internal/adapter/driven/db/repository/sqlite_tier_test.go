@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSqliteTierRepository(t *testing.T) {
+	ctx := context.Background()
+	txFactory := newSqliteTransactionFactory(t)
+	repoFactory := NewSqliteTierRepositoryFactory()
+
+	tx := txFactory.NewTransaction()
+	handler, err := tx.Begin(ctx)
+	assert.Nil(t, err)
+	defer tx.RollbackUnlessCommitted(ctx)
+	repo := repoFactory.New(handler)
+
+	assert.Nil(t, repo.SetTier(ctx, model.Tier{Name: "pro", RateLimit: 500}))
+	assert.Nil(t, repo.SetTier(ctx, model.Tier{Name: "free", RateLimit: 50}))
+	// Setting a tier again changes its limit
+	assert.Nil(t, repo.SetTier(ctx, model.Tier{Name: "pro", RateLimit: 1000}))
+
+	tiers, err := repo.ListTiers(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, tiers, 2)
+	assert.Equal(t, "free", tiers[0].Name)
+	assert.Equal(t, 1000, tiers[1].RateLimit)
+
+	assert.Nil(t, repo.SetTierMapping(ctx, "plan-pro", "pro"))
+	tier, err := repo.GetTierFor(ctx, "plan-pro")
+	assert.Nil(t, err)
+	assert.Equal(t, 1000, tier.RateLimit)
+	tier, err = repo.GetTierFor(ctx, "plan-unknown")
+	assert.Nil(t, err)
+	assert.Nil(t, tier)
+
+	mappings, err := repo.ListTierMappings(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, mappings, 1)
+	assert.Equal(t, "pro", mappings[0].Tier)
+
+	assert.Nil(t, repo.DeleteTierMapping(ctx, "plan-pro"))
+	assert.ErrorIs(t, repo.DeleteTierMapping(ctx, "plan-pro"), domain.ErrTierMappingNotFound)
+	assert.Nil(t, repo.DeleteTier(ctx, "pro"))
+	assert.ErrorIs(t, repo.DeleteTier(ctx, "pro"), domain.ErrTierNotFound)
+	tier, err = repo.GetTier(ctx, "pro")
+	assert.Nil(t, err)
+	assert.Nil(t, tier)
+}
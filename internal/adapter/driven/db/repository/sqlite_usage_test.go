@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSqliteUsageRepository(t *testing.T) {
+	ctx := context.Background()
+	txFactory := newSqliteTransactionFactory(t)
+	repoFactory := NewSqliteUsageRepositoryFactory()
+	day := model.UsageDaily.Truncate(time.Now())
+
+	tx := txFactory.NewTransaction()
+	handler, err := tx.Begin(ctx)
+	assert.Nil(t, err)
+	defer tx.RollbackUnlessCommitted(ctx)
+	repo := repoFactory.New(handler)
+
+	for _, bucket := range []model.UsageBucket{
+		{Key: "tenant", Granularity: model.UsageHourly, Start: day, Allowed: 5, Denied: 1},
+		{Key: "tenant", Granularity: model.UsageHourly, Start: day, Allowed: 2},
+		{Key: "tenant", Granularity: model.UsageHourly, Start: day.Add(2 * time.Hour), Denied: 3},
+		{Key: "tenant", Granularity: model.UsageDaily, Start: day, Allowed: 7, Denied: 4},
+		{Key: "other", Granularity: model.UsageHourly, Start: day, Allowed: 9},
+	} {
+		assert.Nil(t, repo.AddUsage(ctx, bucket))
+	}
+
+	// Adding to an existing bucket sums the counts
+	buckets, err := repo.ListUsage(ctx, "tenant", model.UsageHourly, day, day.Add(24*time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, []model.UsageBucket{
+		{Key: "tenant", Granularity: model.UsageHourly, Start: day, Allowed: 7, Denied: 1},
+		{Key: "tenant", Granularity: model.UsageHourly, Start: day.Add(2 * time.Hour), Denied: 3},
+	}, buckets)
+
+	buckets, err = repo.ListUsage(ctx, "tenant", model.UsageHourly, day.Add(time.Hour), day.Add(2*time.Hour))
+	assert.Nil(t, err)
+	assert.Empty(t, buckets)
+
+	// Retention only removes the granularity it is given
+	assert.Nil(t, repo.DeleteUsageBefore(ctx, model.UsageHourly, day.Add(time.Hour)))
+	buckets, err = repo.ListUsage(ctx, "tenant", model.UsageHourly, day, day.Add(24*time.Hour))
+	assert.Nil(t, err)
+	assert.Len(t, buckets, 1)
+	buckets, err = repo.ListUsage(ctx, "tenant", model.UsageDaily, day, day.Add(24*time.Hour))
+	assert.Nil(t, err)
+	assert.Len(t, buckets, 1)
+}
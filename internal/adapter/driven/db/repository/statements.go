@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strconv"
+
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+)
+
+// statements runs queries written with PostgreSQL's numbered parameters, rewriting them for
+// dialects without.
+type statements struct {
+	handler  db.DbHandler
+	numbered bool
+}
+
+func (s statements) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	query, args = s.rebind(query, args...)
+	return s.handler.ExecContext(ctx, query, args...)
+}
+
+func (s statements) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	query, args = s.rebind(query, args...)
+	return s.handler.QueryContext(ctx, query, args...)
+}
+
+// rebind rewrites the query for dialects without numbered parameters.
+func (s statements) rebind(query string, args ...interface{}) (string, []interface{}) {
+	if s.numbered {
+		return query, args
+	}
+	return positional(query, args)
+}
+
+// numberedParameter matches the $1, $2, ... parameters of PostgreSQL queries.
+var numberedParameter = regexp.MustCompile(`\$(\d+)`)
+
+// positional rewrites a query with numbered parameters to one with ? parameters, repeating the
+// arguments of parameters used more than once.
+func positional(query string, args []interface{}) (string, []interface{}) {
+	var ordered []interface{}
+	query = numberedParameter.ReplaceAllStringFunc(query, func(parameter string) string {
+		position, _ := strconv.Atoi(parameter[1:])
+		ordered = append(ordered, args[position-1])
+		return "?"
+	})
+	return query, ordered
+}
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+const (
+	// updateTierOnConflict changes an existing tier in PostgreSQL and SQLite
+	updateTierOnConflict = `
+        ON CONFLICT (name) DO UPDATE
+        SET rate_limit = excluded.rate_limit,
+            updated_at = excluded.updated_at
+    `
+	// updateTierOnDuplicateKey changes an existing tier in MySQL and MariaDB
+	updateTierOnDuplicateKey = `
+        ON DUPLICATE KEY UPDATE
+            rate_limit = VALUES(rate_limit),
+            updated_at = VALUES(updated_at)
+    `
+	// updateTierMappingOnConflict changes an existing mapping in PostgreSQL and SQLite
+	updateTierMappingOnConflict = `
+        ON CONFLICT (subject) DO UPDATE
+        SET tier_name = excluded.tier_name,
+            updated_at = excluded.updated_at
+    `
+	// updateTierMappingOnDuplicateKey changes an existing mapping in MySQL and MariaDB
+	updateTierMappingOnDuplicateKey = `
+        ON DUPLICATE KEY UPDATE
+            tier_name = VALUES(tier_name),
+            updated_at = VALUES(updated_at)
+    `
+)
+
+// TierRepositoryFactory creates tier repositories for one SQL dialect. Queries are written with
+// PostgreSQL's numbered parameters and rewritten for the other dialects.
+type TierRepositoryFactory struct {
+	numbered      bool
+	upsertTier    string
+	upsertMapping string
+}
+
+// NewTierRepositoryFactory returns the factory for PostgreSQL.
+func NewTierRepositoryFactory() *TierRepositoryFactory {
+	return &TierRepositoryFactory{numbered: true, upsertTier: updateTierOnConflict, upsertMapping: updateTierMappingOnConflict}
+}
+
+// NewMysqlTierRepositoryFactory returns the factory for MySQL and MariaDB.
+func NewMysqlTierRepositoryFactory() *TierRepositoryFactory {
+	return &TierRepositoryFactory{upsertTier: updateTierOnDuplicateKey, upsertMapping: updateTierMappingOnDuplicateKey}
+}
+
+// NewSqliteTierRepositoryFactory returns the factory for SQLite.
+func NewSqliteTierRepositoryFactory() *TierRepositoryFactory {
+	return &TierRepositoryFactory{upsertTier: updateTierOnConflict, upsertMapping: updateTierMappingOnConflict}
+}
+
+func (f *TierRepositoryFactory) New(handler db.DbHandler) repository.TierRepository {
+	return &TierRepository{
+		statements:    statements{handler: handler, numbered: f.numbered},
+		upsertTier:    f.upsertTier,
+		upsertMapping: f.upsertMapping,
+	}
+}
+
+// TierRepository stores tiers in the tiers table and the roles and plans mapped to them in the
+// tier_mappings table.
+type TierRepository struct {
+	statements
+	upsertTier    string
+	upsertMapping string
+}
+
+// SetTier inserts or changes the tier
+func (tr *TierRepository) SetTier(ctx context.Context, tier model.Tier) error {
+	query := `
+        INSERT INTO tiers (name, rate_limit, created_at, updated_at)
+        VALUES ($1, $2, $3, $3)
+    ` + tr.upsertTier
+	_, err := tr.exec(ctx, query, tier.Name, tier.RateLimit, time.Now().UTC())
+	return err
+}
+
+// GetTier retrieves the tier with the name
+func (tr *TierRepository) GetTier(ctx context.Context, name string) (*model.Tier, error) {
+	query := `
+        SELECT name, rate_limit, created_at, updated_at
+        FROM tiers
+        WHERE name = $1
+    `
+	tiers, err := tr.listTiers(ctx, query, name)
+	if err != nil || len(tiers) == 0 {
+		return nil, err
+	}
+	return &tiers[0], nil
+}
+
+// ListTiers retrieves all tiers
+func (tr *TierRepository) ListTiers(ctx context.Context) ([]model.Tier, error) {
+	query := `
+        SELECT name, rate_limit, created_at, updated_at
+        FROM tiers
+        ORDER BY name
+    `
+	return tr.listTiers(ctx, query)
+}
+
+// GetTierFor retrieves the tier the role or plan is mapped to
+func (tr *TierRepository) GetTierFor(ctx context.Context, subject string) (*model.Tier, error) {
+	query := `
+        SELECT t.name, t.rate_limit, t.created_at, t.updated_at
+        FROM tier_mappings m
+        JOIN tiers t ON t.name = m.tier_name
+        WHERE m.subject = $1
+    `
+	tiers, err := tr.listTiers(ctx, query, subject)
+	if err != nil || len(tiers) == 0 {
+		return nil, err
+	}
+	return &tiers[0], nil
+}
+
+func (tr *TierRepository) listTiers(ctx context.Context, query string, args ...any) ([]model.Tier, error) {
+	rows, err := tr.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tiers []model.Tier
+	for rows.Next() {
+		var tier model.Tier
+		if err := rows.Scan(&tier.Name, &tier.RateLimit, &tier.CreatedAt, &tier.UpdatedAt); err != nil {
+			return nil, err
+		}
+		tiers = append(tiers, tier)
+	}
+	return tiers, rows.Err()
+}
+
+// DeleteTier removes the tier with the name
+func (tr *TierRepository) DeleteTier(ctx context.Context, name string) error {
+	query := `
+        DELETE FROM tiers
+        WHERE name = $1
+    `
+	return tr.delete(ctx, query, name, domain.ErrTierNotFound)
+}
+
+// SetTierMapping inserts or changes the tier of the role or plan
+func (tr *TierRepository) SetTierMapping(ctx context.Context, subject, tier string) error {
+	query := `
+        INSERT INTO tier_mappings (subject, tier_name, created_at, updated_at)
+        VALUES ($1, $2, $3, $3)
+    ` + tr.upsertMapping
+	_, err := tr.exec(ctx, query, subject, tier, time.Now().UTC())
+	return err
+}
+
+// ListTierMappings retrieves all mappings
+func (tr *TierRepository) ListTierMappings(ctx context.Context) ([]model.TierMapping, error) {
+	query := `
+        SELECT subject, tier_name, created_at, updated_at
+        FROM tier_mappings
+        ORDER BY subject
+    `
+	rows, err := tr.query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mappings []model.TierMapping
+	for rows.Next() {
+		var mapping model.TierMapping
+		if err := rows.Scan(&mapping.Subject, &mapping.Tier, &mapping.CreatedAt, &mapping.UpdatedAt); err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings, rows.Err()
+}
+
+// DeleteTierMapping removes the mapping of the role or plan
+func (tr *TierRepository) DeleteTierMapping(ctx context.Context, subject string) error {
+	query := `
+        DELETE FROM tier_mappings
+        WHERE subject = $1
+    `
+	return tr.delete(ctx, query, subject, domain.ErrTierMappingNotFound)
+}
+
+// delete runs a delete of one row and returns notFound if there was none.
+func (tr *TierRepository) delete(ctx context.Context, query, key string, notFound error) error {
+	result, err := tr.exec(ctx, query, key)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return notFound
+	}
+	return nil
+}
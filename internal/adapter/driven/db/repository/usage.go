@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+)
+
+const (
+	// incrementOnConflict adds to an existing bucket in PostgreSQL and SQLite
+	incrementOnConflict = `
+        ON CONFLICT (limit_key, granularity, bucket_start) DO UPDATE
+        SET allowed = usage_buckets.allowed + excluded.allowed,
+            denied = usage_buckets.denied + excluded.denied
+    `
+	// incrementOnDuplicateKey adds to an existing bucket in MySQL and MariaDB
+	incrementOnDuplicateKey = `
+        ON DUPLICATE KEY UPDATE
+            allowed = allowed + VALUES(allowed),
+            denied = denied + VALUES(denied)
+    `
+)
+
+// UsageRepositoryFactory creates usage history repositories for one SQL dialect, which differ in
+// how parameters are written and how an existing bucket is added to.
+type UsageRepositoryFactory struct {
+	bind      func(position int) string
+	increment string
+}
+
+// NewUsageRepositoryFactory returns the factory for PostgreSQL.
+func NewUsageRepositoryFactory() *UsageRepositoryFactory {
+	return &UsageRepositoryFactory{bind: func(position int) string { return "$" + strconv.Itoa(position) }, increment: incrementOnConflict}
+}
+
+// NewMysqlUsageRepositoryFactory returns the factory for MySQL and MariaDB.
+func NewMysqlUsageRepositoryFactory() *UsageRepositoryFactory {
+	return &UsageRepositoryFactory{bind: func(int) string { return "?" }, increment: incrementOnDuplicateKey}
+}
+
+// NewSqliteUsageRepositoryFactory returns the factory for SQLite.
+func NewSqliteUsageRepositoryFactory() *UsageRepositoryFactory {
+	return &UsageRepositoryFactory{bind: func(int) string { return "?" }, increment: incrementOnConflict}
+}
+
+func (f *UsageRepositoryFactory) New(handler db.DbHandler) repository.UsageRepository {
+	return &UsageRepository{handler: handler, bind: f.bind, increment: f.increment}
+}
+
+// UsageRepository stores usage history in the usage_buckets table.
+type UsageRepository struct {
+	handler   db.DbHandler
+	bind      func(position int) string
+	increment string
+}
+
+// AddUsage inserts a usage bucket or adds its counts to the stored one
+func (ur *UsageRepository) AddUsage(ctx context.Context, bucket model.UsageBucket) error {
+	query := `
+        INSERT INTO usage_buckets (limit_key, granularity, bucket_start, allowed, denied)
+        VALUES (` + ur.bind(1) + `, ` + ur.bind(2) + `, ` + ur.bind(3) + `, ` + ur.bind(4) + `, ` + ur.bind(5) + `)
+    ` + ur.increment
+	_, err := ur.handler.ExecContext(ctx, query, bucket.Key, string(bucket.Granularity), bucket.Start.UTC(), bucket.Allowed, bucket.Denied)
+	return err
+}
+
+// ListUsage retrieves a key's usage buckets within a time range, oldest first
+func (ur *UsageRepository) ListUsage(ctx context.Context, key string, granularity model.UsageGranularity, from, to time.Time) ([]model.UsageBucket, error) {
+	query := `
+        SELECT limit_key, granularity, bucket_start, allowed, denied
+        FROM usage_buckets
+        WHERE limit_key = ` + ur.bind(1) + ` AND granularity = ` + ur.bind(2) + `
+            AND bucket_start >= ` + ur.bind(3) + ` AND bucket_start < ` + ur.bind(4) + `
+        ORDER BY bucket_start
+    `
+	rows, err := ur.handler.QueryContext(ctx, query, key, string(granularity), from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buckets []model.UsageBucket
+	for rows.Next() {
+		var bucket model.UsageBucket
+		if err := rows.Scan(&bucket.Key, &bucket.Granularity, &bucket.Start, &bucket.Allowed, &bucket.Denied); err != nil {
+			return nil, err
+		}
+		bucket.Start = bucket.Start.UTC()
+		buckets = append(buckets, bucket)
+	}
+	return buckets, rows.Err()
+}
+
+// DeleteUsageBefore removes the usage buckets of a granularity that start before a time
+func (ur *UsageRepository) DeleteUsageBefore(ctx context.Context, granularity model.UsageGranularity, before time.Time) error {
+	query := `
+        DELETE FROM usage_buckets
+        WHERE granularity = ` + ur.bind(1) + ` AND bucket_start < ` + ur.bind(2) + `
+    `
+	_, err := ur.handler.ExecContext(ctx, query, string(granularity), before.UTC())
+	return err
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+)
+
+// OpenSqlite opens the SQLite database file at path. Transactions take the write lock when they
+// begin and the pool is limited to one connection, since SQLite allows a single writer anyway.
+func OpenSqlite(path string) (*sql.DB, error) {
+	sqlDb, err := sql.Open("sqlite3", "file:"+path+"?_txlock=immediate&_busy_timeout=5000&_foreign_keys=on")
+	if err != nil {
+		return nil, err
+	}
+	sqlDb.SetMaxOpenConns(1)
+
+	if err := sqlDb.Ping(); err != nil {
+		sqlDb.Close()
+		return nil, err
+	}
+	return sqlDb, nil
+}
+
+type SqliteDbTransaction struct {
+	db        *sql.DB
+	tx        *sql.Tx
+	committed bool
+}
+
+func NewSqliteDbTransaction(db *sql.DB) *SqliteDbTransaction {
+	return &SqliteDbTransaction{db: db}
+}
+
+func (s *SqliteDbTransaction) Begin(ctx context.Context, opts ...db.TxOption) (db.DbHandler, error) {
+	options := db.NewTxOptions(opts...)
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: options.Isolation, ReadOnly: options.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	s.tx = tx
+	return s, nil
+}
+
+func (s *SqliteDbTransaction) Commit(ctx context.Context) error {
+	if s.tx == nil {
+		return errors.New("no transaction in progress")
+	}
+	err := s.tx.Commit()
+	if err == nil {
+		s.committed = true
+	}
+	return err
+}
+
+func (s *SqliteDbTransaction) Rollback(ctx context.Context) error {
+	if s.tx == nil {
+		return errors.New("no transaction in progress")
+	}
+	return s.tx.Rollback()
+}
+
+func (s *SqliteDbTransaction) RollbackUnlessCommitted(ctx context.Context) {
+	if !s.committed {
+		err := s.Rollback(ctx)
+		if err != nil {
+			log.Print(err)
+		}
+	}
+}
+
+func (s *SqliteDbTransaction) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return s.tx.QueryContext(ctx, query, args...)
+}
+
+func (s *SqliteDbTransaction) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return s.tx.ExecContext(ctx, query, args...)
+}
+
+func (s *SqliteDbTransaction) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return s.tx.QueryRowContext(ctx, query, args...)
+}
+
+type SqliteDbTransactionFactory struct {
+	db *sql.DB
+}
+
+func NewSqliteDbTransactionFactory(db *sql.DB) *SqliteDbTransactionFactory {
+	return &SqliteDbTransactionFactory{db: db}
+}
+
+func (f *SqliteDbTransactionFactory) NewTransaction() db.DbTransaction {
+	return &SqliteDbTransaction{db: f.db}
+}
@@ -0,0 +1,30 @@
+package grpc
+
+import (
+	"context"
+
+	driverService "github.com/nullexp/limiter-x/internal/port/driver/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// actorHeader is the metadata key callers identify themselves with for the audit log.
+const actorHeader = "x-actor"
+
+// ActorInterceptor records who makes each call in the request context: the x-actor metadata if
+// the caller sent it, or otherwise the caller's address.
+func ActorInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	actor := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(actorHeader); len(values) > 0 {
+			actor = values[0]
+		}
+	}
+	if actor == "" {
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			actor = p.Addr.String()
+		}
+	}
+	return handler(driverService.ContextWithActor(ctx, actor), req)
+}
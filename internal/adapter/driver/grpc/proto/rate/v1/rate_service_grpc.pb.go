@@ -22,6 +22,30 @@ const (
 	RateLimiterService_CheckRateLimit_FullMethodName      = "/rateLimiter.RateLimiterService/CheckRateLimit"
 	RateLimiterService_GetUserRateLimit_FullMethodName    = "/rateLimiter.RateLimiterService/GetUserRateLimit"
 	RateLimiterService_UpdateUserRateLimit_FullMethodName = "/rateLimiter.RateLimiterService/UpdateUserRateLimit"
+	RateLimiterService_LeaseQuota_FullMethodName          = "/rateLimiter.RateLimiterService/LeaseQuota"
+	RateLimiterService_ReturnQuota_FullMethodName         = "/rateLimiter.RateLimiterService/ReturnQuota"
+	RateLimiterService_ListAuditEvents_FullMethodName     = "/rateLimiter.RateLimiterService/ListAuditEvents"
+	RateLimiterService_GetUsageHistory_FullMethodName     = "/rateLimiter.RateLimiterService/GetUsageHistory"
+	RateLimiterService_SetQuota_FullMethodName            = "/rateLimiter.RateLimiterService/SetQuota"
+	RateLimiterService_DeleteQuota_FullMethodName         = "/rateLimiter.RateLimiterService/DeleteQuota"
+	RateLimiterService_GetQuotas_FullMethodName           = "/rateLimiter.RateLimiterService/GetQuotas"
+	RateLimiterService_SetPolicy_FullMethodName           = "/rateLimiter.RateLimiterService/SetPolicy"
+	RateLimiterService_GetPolicy_FullMethodName           = "/rateLimiter.RateLimiterService/GetPolicy"
+	RateLimiterService_DeletePolicy_FullMethodName        = "/rateLimiter.RateLimiterService/DeletePolicy"
+	RateLimiterService_SetParent_FullMethodName           = "/rateLimiter.RateLimiterService/SetParent"
+	RateLimiterService_DeleteParent_FullMethodName        = "/rateLimiter.RateLimiterService/DeleteParent"
+	RateLimiterService_GetHierarchy_FullMethodName        = "/rateLimiter.RateLimiterService/GetHierarchy"
+	RateLimiterService_SetTier_FullMethodName             = "/rateLimiter.RateLimiterService/SetTier"
+	RateLimiterService_DeleteTier_FullMethodName          = "/rateLimiter.RateLimiterService/DeleteTier"
+	RateLimiterService_ListTiers_FullMethodName           = "/rateLimiter.RateLimiterService/ListTiers"
+	RateLimiterService_SetTierMapping_FullMethodName      = "/rateLimiter.RateLimiterService/SetTierMapping"
+	RateLimiterService_DeleteTierMapping_FullMethodName   = "/rateLimiter.RateLimiterService/DeleteTierMapping"
+	RateLimiterService_SetAccessRule_FullMethodName       = "/rateLimiter.RateLimiterService/SetAccessRule"
+	RateLimiterService_DeleteAccessRule_FullMethodName    = "/rateLimiter.RateLimiterService/DeleteAccessRule"
+	RateLimiterService_ListAccessRules_FullMethodName     = "/rateLimiter.RateLimiterService/ListAccessRules"
+	RateLimiterService_SetOverride_FullMethodName         = "/rateLimiter.RateLimiterService/SetOverride"
+	RateLimiterService_ClearOverride_FullMethodName       = "/rateLimiter.RateLimiterService/ClearOverride"
+	RateLimiterService_GetOverride_FullMethodName         = "/rateLimiter.RateLimiterService/GetOverride"
 )
 
 // RateLimiterServiceClient is the client API for RateLimiterService service.
@@ -36,6 +60,54 @@ type RateLimiterServiceClient interface {
 	GetUserRateLimit(ctx context.Context, in *GetUserRateLimitRequest, opts ...grpc.CallOption) (*GetUserRateLimitResponse, error)
 	// Update the rate limit for a specific user (e.g., admins can increase or decrease the limit)
 	UpdateUserRateLimit(ctx context.Context, in *UpdateUserRateLimitRequest, opts ...grpc.CallOption) (*UpdateUserRateLimitResponse, error)
+	// Lease a chunk of the user's quota so a client can spend it locally
+	LeaseQuota(ctx context.Context, in *LeaseQuotaRequest, opts ...grpc.CallOption) (*LeaseQuotaResponse, error)
+	// Return the unused units of a lease back to the user's quota
+	ReturnQuota(ctx context.Context, in *ReturnQuotaRequest, opts ...grpc.CallOption) (*ReturnQuotaResponse, error)
+	// List audit log entries, such as limit changes and sampled denials, newest first
+	ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (*ListAuditEventsResponse, error)
+	// Get the allowed and denied requests of a key per hour or day
+	GetUsageHistory(ctx context.Context, in *GetUsageHistoryRequest, opts ...grpc.CallOption) (*GetUsageHistoryResponse, error)
+	// Create or change a daily, weekly or monthly quota of a key
+	SetQuota(ctx context.Context, in *SetQuotaRequest, opts ...grpc.CallOption) (*SetQuotaResponse, error)
+	// Delete the quota of a key for a period
+	DeleteQuota(ctx context.Context, in *DeleteQuotaRequest, opts ...grpc.CallOption) (*DeleteQuotaResponse, error)
+	// Get the quotas of a key with their usage in the current period
+	GetQuotas(ctx context.Context, in *GetQuotasRequest, opts ...grpc.CallOption) (*GetQuotasResponse, error)
+	// Replace the limits that all apply to a key, such as 5 per second and 100 per minute
+	SetPolicy(ctx context.Context, in *SetPolicyRequest, opts ...grpc.CallOption) (*SetPolicyResponse, error)
+	// Get the limits of a key's policy with what is left of them
+	GetPolicy(ctx context.Context, in *GetPolicyRequest, opts ...grpc.CallOption) (*GetPolicyResponse, error)
+	// Delete a key's policy
+	DeletePolicy(ctx context.Context, in *DeletePolicyRequest, opts ...grpc.CallOption) (*DeletePolicyResponse, error)
+	// Make a key the child of another, so its requests also count against its ancestors' policies
+	SetParent(ctx context.Context, in *SetParentRequest, opts ...grpc.CallOption) (*SetParentResponse, error)
+	// Remove the parent of a key
+	DeleteParent(ctx context.Context, in *DeleteParentRequest, opts ...grpc.CallOption) (*DeleteParentResponse, error)
+	// Get the ancestors and children of a key
+	GetHierarchy(ctx context.Context, in *GetHierarchyRequest, opts ...grpc.CallOption) (*GetHierarchyResponse, error)
+	// Create a tier, such as free, pro or enterprise, or change its limit
+	SetTier(ctx context.Context, in *SetTierRequest, opts ...grpc.CallOption) (*SetTierResponse, error)
+	// Delete a tier no role or plan is mapped to
+	DeleteTier(ctx context.Context, in *DeleteTierRequest, opts ...grpc.CallOption) (*DeleteTierResponse, error)
+	// List the tiers with the roles and plans mapped to them
+	ListTiers(ctx context.Context, in *ListTiersRequest, opts ...grpc.CallOption) (*ListTiersResponse, error)
+	// Map a role or plan to a tier
+	SetTierMapping(ctx context.Context, in *SetTierMappingRequest, opts ...grpc.CallOption) (*SetTierMappingResponse, error)
+	// Remove the mapping of a role or plan
+	DeleteTierMapping(ctx context.Context, in *DeleteTierMappingRequest, opts ...grpc.CallOption) (*DeleteTierMappingResponse, error)
+	// Add a rule to the allowlist or denylist
+	SetAccessRule(ctx context.Context, in *SetAccessRuleRequest, opts ...grpc.CallOption) (*SetAccessRuleResponse, error)
+	// Remove a rule from the allowlist or denylist
+	DeleteAccessRule(ctx context.Context, in *DeleteAccessRuleRequest, opts ...grpc.CallOption) (*DeleteAccessRuleResponse, error)
+	// List the rules of the allowlist and denylist that have not expired
+	ListAccessRules(ctx context.Context, in *ListAccessRulesRequest, opts ...grpc.CallOption) (*ListAccessRulesResponse, error)
+	// Put every key under a global emergency override for a while
+	SetOverride(ctx context.Context, in *SetOverrideRequest, opts ...grpc.CallOption) (*SetOverrideResponse, error)
+	// End the global override before it expires
+	ClearOverride(ctx context.Context, in *ClearOverrideRequest, opts ...grpc.CallOption) (*ClearOverrideResponse, error)
+	// Get the global override in effect
+	GetOverride(ctx context.Context, in *GetOverrideRequest, opts ...grpc.CallOption) (*GetOverrideResponse, error)
 }
 
 type rateLimiterServiceClient struct {
@@ -76,6 +148,246 @@ func (c *rateLimiterServiceClient) UpdateUserRateLimit(ctx context.Context, in *
 	return out, nil
 }
 
+func (c *rateLimiterServiceClient) LeaseQuota(ctx context.Context, in *LeaseQuotaRequest, opts ...grpc.CallOption) (*LeaseQuotaResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LeaseQuotaResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_LeaseQuota_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterServiceClient) ReturnQuota(ctx context.Context, in *ReturnQuotaRequest, opts ...grpc.CallOption) (*ReturnQuotaResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReturnQuotaResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_ReturnQuota_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterServiceClient) ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (*ListAuditEventsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListAuditEventsResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_ListAuditEvents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterServiceClient) GetUsageHistory(ctx context.Context, in *GetUsageHistoryRequest, opts ...grpc.CallOption) (*GetUsageHistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetUsageHistoryResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_GetUsageHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterServiceClient) SetQuota(ctx context.Context, in *SetQuotaRequest, opts ...grpc.CallOption) (*SetQuotaResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetQuotaResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_SetQuota_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterServiceClient) DeleteQuota(ctx context.Context, in *DeleteQuotaRequest, opts ...grpc.CallOption) (*DeleteQuotaResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteQuotaResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_DeleteQuota_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterServiceClient) GetQuotas(ctx context.Context, in *GetQuotasRequest, opts ...grpc.CallOption) (*GetQuotasResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetQuotasResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_GetQuotas_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterServiceClient) SetPolicy(ctx context.Context, in *SetPolicyRequest, opts ...grpc.CallOption) (*SetPolicyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetPolicyResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_SetPolicy_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterServiceClient) GetPolicy(ctx context.Context, in *GetPolicyRequest, opts ...grpc.CallOption) (*GetPolicyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetPolicyResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_GetPolicy_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterServiceClient) DeletePolicy(ctx context.Context, in *DeletePolicyRequest, opts ...grpc.CallOption) (*DeletePolicyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeletePolicyResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_DeletePolicy_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterServiceClient) SetParent(ctx context.Context, in *SetParentRequest, opts ...grpc.CallOption) (*SetParentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetParentResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_SetParent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterServiceClient) DeleteParent(ctx context.Context, in *DeleteParentRequest, opts ...grpc.CallOption) (*DeleteParentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteParentResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_DeleteParent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterServiceClient) GetHierarchy(ctx context.Context, in *GetHierarchyRequest, opts ...grpc.CallOption) (*GetHierarchyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetHierarchyResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_GetHierarchy_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterServiceClient) SetTier(ctx context.Context, in *SetTierRequest, opts ...grpc.CallOption) (*SetTierResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetTierResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_SetTier_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterServiceClient) DeleteTier(ctx context.Context, in *DeleteTierRequest, opts ...grpc.CallOption) (*DeleteTierResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteTierResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_DeleteTier_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterServiceClient) ListTiers(ctx context.Context, in *ListTiersRequest, opts ...grpc.CallOption) (*ListTiersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListTiersResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_ListTiers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterServiceClient) SetTierMapping(ctx context.Context, in *SetTierMappingRequest, opts ...grpc.CallOption) (*SetTierMappingResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetTierMappingResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_SetTierMapping_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterServiceClient) DeleteTierMapping(ctx context.Context, in *DeleteTierMappingRequest, opts ...grpc.CallOption) (*DeleteTierMappingResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteTierMappingResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_DeleteTierMapping_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterServiceClient) SetAccessRule(ctx context.Context, in *SetAccessRuleRequest, opts ...grpc.CallOption) (*SetAccessRuleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetAccessRuleResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_SetAccessRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterServiceClient) DeleteAccessRule(ctx context.Context, in *DeleteAccessRuleRequest, opts ...grpc.CallOption) (*DeleteAccessRuleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteAccessRuleResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_DeleteAccessRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterServiceClient) ListAccessRules(ctx context.Context, in *ListAccessRulesRequest, opts ...grpc.CallOption) (*ListAccessRulesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListAccessRulesResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_ListAccessRules_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterServiceClient) SetOverride(ctx context.Context, in *SetOverrideRequest, opts ...grpc.CallOption) (*SetOverrideResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetOverrideResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_SetOverride_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterServiceClient) ClearOverride(ctx context.Context, in *ClearOverrideRequest, opts ...grpc.CallOption) (*ClearOverrideResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ClearOverrideResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_ClearOverride_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rateLimiterServiceClient) GetOverride(ctx context.Context, in *GetOverrideRequest, opts ...grpc.CallOption) (*GetOverrideResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetOverrideResponse)
+	err := c.cc.Invoke(ctx, RateLimiterService_GetOverride_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // RateLimiterServiceServer is the server API for RateLimiterService service.
 // All implementations must embed UnimplementedRateLimiterServiceServer
 // for forward compatibility
@@ -88,6 +400,54 @@ type RateLimiterServiceServer interface {
 	GetUserRateLimit(context.Context, *GetUserRateLimitRequest) (*GetUserRateLimitResponse, error)
 	// Update the rate limit for a specific user (e.g., admins can increase or decrease the limit)
 	UpdateUserRateLimit(context.Context, *UpdateUserRateLimitRequest) (*UpdateUserRateLimitResponse, error)
+	// Lease a chunk of the user's quota so a client can spend it locally
+	LeaseQuota(context.Context, *LeaseQuotaRequest) (*LeaseQuotaResponse, error)
+	// Return the unused units of a lease back to the user's quota
+	ReturnQuota(context.Context, *ReturnQuotaRequest) (*ReturnQuotaResponse, error)
+	// List audit log entries, such as limit changes and sampled denials, newest first
+	ListAuditEvents(context.Context, *ListAuditEventsRequest) (*ListAuditEventsResponse, error)
+	// Get the allowed and denied requests of a key per hour or day
+	GetUsageHistory(context.Context, *GetUsageHistoryRequest) (*GetUsageHistoryResponse, error)
+	// Create or change a daily, weekly or monthly quota of a key
+	SetQuota(context.Context, *SetQuotaRequest) (*SetQuotaResponse, error)
+	// Delete the quota of a key for a period
+	DeleteQuota(context.Context, *DeleteQuotaRequest) (*DeleteQuotaResponse, error)
+	// Get the quotas of a key with their usage in the current period
+	GetQuotas(context.Context, *GetQuotasRequest) (*GetQuotasResponse, error)
+	// Replace the limits that all apply to a key, such as 5 per second and 100 per minute
+	SetPolicy(context.Context, *SetPolicyRequest) (*SetPolicyResponse, error)
+	// Get the limits of a key's policy with what is left of them
+	GetPolicy(context.Context, *GetPolicyRequest) (*GetPolicyResponse, error)
+	// Delete a key's policy
+	DeletePolicy(context.Context, *DeletePolicyRequest) (*DeletePolicyResponse, error)
+	// Make a key the child of another, so its requests also count against its ancestors' policies
+	SetParent(context.Context, *SetParentRequest) (*SetParentResponse, error)
+	// Remove the parent of a key
+	DeleteParent(context.Context, *DeleteParentRequest) (*DeleteParentResponse, error)
+	// Get the ancestors and children of a key
+	GetHierarchy(context.Context, *GetHierarchyRequest) (*GetHierarchyResponse, error)
+	// Create a tier, such as free, pro or enterprise, or change its limit
+	SetTier(context.Context, *SetTierRequest) (*SetTierResponse, error)
+	// Delete a tier no role or plan is mapped to
+	DeleteTier(context.Context, *DeleteTierRequest) (*DeleteTierResponse, error)
+	// List the tiers with the roles and plans mapped to them
+	ListTiers(context.Context, *ListTiersRequest) (*ListTiersResponse, error)
+	// Map a role or plan to a tier
+	SetTierMapping(context.Context, *SetTierMappingRequest) (*SetTierMappingResponse, error)
+	// Remove the mapping of a role or plan
+	DeleteTierMapping(context.Context, *DeleteTierMappingRequest) (*DeleteTierMappingResponse, error)
+	// Add a rule to the allowlist or denylist
+	SetAccessRule(context.Context, *SetAccessRuleRequest) (*SetAccessRuleResponse, error)
+	// Remove a rule from the allowlist or denylist
+	DeleteAccessRule(context.Context, *DeleteAccessRuleRequest) (*DeleteAccessRuleResponse, error)
+	// List the rules of the allowlist and denylist that have not expired
+	ListAccessRules(context.Context, *ListAccessRulesRequest) (*ListAccessRulesResponse, error)
+	// Put every key under a global emergency override for a while
+	SetOverride(context.Context, *SetOverrideRequest) (*SetOverrideResponse, error)
+	// End the global override before it expires
+	ClearOverride(context.Context, *ClearOverrideRequest) (*ClearOverrideResponse, error)
+	// Get the global override in effect
+	GetOverride(context.Context, *GetOverrideRequest) (*GetOverrideResponse, error)
 	mustEmbedUnimplementedRateLimiterServiceServer()
 }
 
@@ -104,6 +464,78 @@ func (UnimplementedRateLimiterServiceServer) GetUserRateLimit(context.Context, *
 func (UnimplementedRateLimiterServiceServer) UpdateUserRateLimit(context.Context, *UpdateUserRateLimitRequest) (*UpdateUserRateLimitResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateUserRateLimit not implemented")
 }
+func (UnimplementedRateLimiterServiceServer) LeaseQuota(context.Context, *LeaseQuotaRequest) (*LeaseQuotaResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LeaseQuota not implemented")
+}
+func (UnimplementedRateLimiterServiceServer) ReturnQuota(context.Context, *ReturnQuotaRequest) (*ReturnQuotaResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReturnQuota not implemented")
+}
+func (UnimplementedRateLimiterServiceServer) ListAuditEvents(context.Context, *ListAuditEventsRequest) (*ListAuditEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAuditEvents not implemented")
+}
+func (UnimplementedRateLimiterServiceServer) GetUsageHistory(context.Context, *GetUsageHistoryRequest) (*GetUsageHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUsageHistory not implemented")
+}
+func (UnimplementedRateLimiterServiceServer) SetQuota(context.Context, *SetQuotaRequest) (*SetQuotaResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetQuota not implemented")
+}
+func (UnimplementedRateLimiterServiceServer) DeleteQuota(context.Context, *DeleteQuotaRequest) (*DeleteQuotaResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteQuota not implemented")
+}
+func (UnimplementedRateLimiterServiceServer) GetQuotas(context.Context, *GetQuotasRequest) (*GetQuotasResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetQuotas not implemented")
+}
+func (UnimplementedRateLimiterServiceServer) SetPolicy(context.Context, *SetPolicyRequest) (*SetPolicyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPolicy not implemented")
+}
+func (UnimplementedRateLimiterServiceServer) GetPolicy(context.Context, *GetPolicyRequest) (*GetPolicyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPolicy not implemented")
+}
+func (UnimplementedRateLimiterServiceServer) DeletePolicy(context.Context, *DeletePolicyRequest) (*DeletePolicyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeletePolicy not implemented")
+}
+func (UnimplementedRateLimiterServiceServer) SetParent(context.Context, *SetParentRequest) (*SetParentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetParent not implemented")
+}
+func (UnimplementedRateLimiterServiceServer) DeleteParent(context.Context, *DeleteParentRequest) (*DeleteParentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteParent not implemented")
+}
+func (UnimplementedRateLimiterServiceServer) GetHierarchy(context.Context, *GetHierarchyRequest) (*GetHierarchyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHierarchy not implemented")
+}
+func (UnimplementedRateLimiterServiceServer) SetTier(context.Context, *SetTierRequest) (*SetTierResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetTier not implemented")
+}
+func (UnimplementedRateLimiterServiceServer) DeleteTier(context.Context, *DeleteTierRequest) (*DeleteTierResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteTier not implemented")
+}
+func (UnimplementedRateLimiterServiceServer) ListTiers(context.Context, *ListTiersRequest) (*ListTiersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTiers not implemented")
+}
+func (UnimplementedRateLimiterServiceServer) SetTierMapping(context.Context, *SetTierMappingRequest) (*SetTierMappingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetTierMapping not implemented")
+}
+func (UnimplementedRateLimiterServiceServer) DeleteTierMapping(context.Context, *DeleteTierMappingRequest) (*DeleteTierMappingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteTierMapping not implemented")
+}
+func (UnimplementedRateLimiterServiceServer) SetAccessRule(context.Context, *SetAccessRuleRequest) (*SetAccessRuleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetAccessRule not implemented")
+}
+func (UnimplementedRateLimiterServiceServer) DeleteAccessRule(context.Context, *DeleteAccessRuleRequest) (*DeleteAccessRuleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAccessRule not implemented")
+}
+func (UnimplementedRateLimiterServiceServer) ListAccessRules(context.Context, *ListAccessRulesRequest) (*ListAccessRulesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAccessRules not implemented")
+}
+func (UnimplementedRateLimiterServiceServer) SetOverride(context.Context, *SetOverrideRequest) (*SetOverrideResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetOverride not implemented")
+}
+func (UnimplementedRateLimiterServiceServer) ClearOverride(context.Context, *ClearOverrideRequest) (*ClearOverrideResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ClearOverride not implemented")
+}
+func (UnimplementedRateLimiterServiceServer) GetOverride(context.Context, *GetOverrideRequest) (*GetOverrideResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOverride not implemented")
+}
 func (UnimplementedRateLimiterServiceServer) mustEmbedUnimplementedRateLimiterServiceServer() {}
 
 // UnsafeRateLimiterServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -171,24 +603,552 @@ func _RateLimiterService_UpdateUserRateLimit_Handler(srv interface{}, ctx contex
 	return interceptor(ctx, in, info, handler)
 }
 
-// RateLimiterService_ServiceDesc is the grpc.ServiceDesc for RateLimiterService service.
-// It's only intended for direct use with grpc.RegisterService,
-// and not to be introspected or modified (even as a copy)
-var RateLimiterService_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "rateLimiter.RateLimiterService",
-	HandlerType: (*RateLimiterServiceServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "CheckRateLimit",
-			Handler:    _RateLimiterService_CheckRateLimit_Handler,
-		},
-		{
-			MethodName: "GetUserRateLimit",
-			Handler:    _RateLimiterService_GetUserRateLimit_Handler,
-		},
-		{
-			MethodName: "UpdateUserRateLimit",
-			Handler:    _RateLimiterService_UpdateUserRateLimit_Handler,
+func _RateLimiterService_LeaseQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LeaseQuotaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).LeaseQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_LeaseQuota_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).LeaseQuota(ctx, req.(*LeaseQuotaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiterService_ReturnQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReturnQuotaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).ReturnQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_ReturnQuota_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).ReturnQuota(ctx, req.(*ReturnQuotaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiterService_ListAuditEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAuditEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).ListAuditEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_ListAuditEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).ListAuditEvents(ctx, req.(*ListAuditEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiterService_GetUsageHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUsageHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).GetUsageHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_GetUsageHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).GetUsageHistory(ctx, req.(*GetUsageHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiterService_SetQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetQuotaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).SetQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_SetQuota_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).SetQuota(ctx, req.(*SetQuotaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiterService_DeleteQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteQuotaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).DeleteQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_DeleteQuota_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).DeleteQuota(ctx, req.(*DeleteQuotaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiterService_GetQuotas_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetQuotasRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).GetQuotas(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_GetQuotas_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).GetQuotas(ctx, req.(*GetQuotasRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiterService_SetPolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetPolicyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).SetPolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_SetPolicy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).SetPolicy(ctx, req.(*SetPolicyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiterService_GetPolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPolicyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).GetPolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_GetPolicy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).GetPolicy(ctx, req.(*GetPolicyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiterService_DeletePolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeletePolicyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).DeletePolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_DeletePolicy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).DeletePolicy(ctx, req.(*DeletePolicyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiterService_SetParent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetParentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).SetParent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_SetParent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).SetParent(ctx, req.(*SetParentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiterService_DeleteParent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteParentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).DeleteParent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_DeleteParent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).DeleteParent(ctx, req.(*DeleteParentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiterService_GetHierarchy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetHierarchyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).GetHierarchy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_GetHierarchy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).GetHierarchy(ctx, req.(*GetHierarchyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiterService_SetTier_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetTierRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).SetTier(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_SetTier_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).SetTier(ctx, req.(*SetTierRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiterService_DeleteTier_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteTierRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).DeleteTier(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_DeleteTier_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).DeleteTier(ctx, req.(*DeleteTierRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiterService_ListTiers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTiersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).ListTiers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_ListTiers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).ListTiers(ctx, req.(*ListTiersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiterService_SetTierMapping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetTierMappingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).SetTierMapping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_SetTierMapping_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).SetTierMapping(ctx, req.(*SetTierMappingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiterService_DeleteTierMapping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteTierMappingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).DeleteTierMapping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_DeleteTierMapping_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).DeleteTierMapping(ctx, req.(*DeleteTierMappingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiterService_SetAccessRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetAccessRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).SetAccessRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_SetAccessRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).SetAccessRule(ctx, req.(*SetAccessRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiterService_DeleteAccessRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAccessRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).DeleteAccessRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_DeleteAccessRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).DeleteAccessRule(ctx, req.(*DeleteAccessRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiterService_ListAccessRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAccessRulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).ListAccessRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_ListAccessRules_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).ListAccessRules(ctx, req.(*ListAccessRulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiterService_SetOverride_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetOverrideRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).SetOverride(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_SetOverride_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).SetOverride(ctx, req.(*SetOverrideRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiterService_ClearOverride_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClearOverrideRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).ClearOverride(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_ClearOverride_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).ClearOverride(ctx, req.(*ClearOverrideRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RateLimiterService_GetOverride_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOverrideRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimiterServiceServer).GetOverride(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimiterService_GetOverride_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimiterServiceServer).GetOverride(ctx, req.(*GetOverrideRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RateLimiterService_ServiceDesc is the grpc.ServiceDesc for RateLimiterService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RateLimiterService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "rateLimiter.RateLimiterService",
+	HandlerType: (*RateLimiterServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CheckRateLimit",
+			Handler:    _RateLimiterService_CheckRateLimit_Handler,
+		},
+		{
+			MethodName: "GetUserRateLimit",
+			Handler:    _RateLimiterService_GetUserRateLimit_Handler,
+		},
+		{
+			MethodName: "UpdateUserRateLimit",
+			Handler:    _RateLimiterService_UpdateUserRateLimit_Handler,
+		},
+		{
+			MethodName: "LeaseQuota",
+			Handler:    _RateLimiterService_LeaseQuota_Handler,
+		},
+		{
+			MethodName: "ReturnQuota",
+			Handler:    _RateLimiterService_ReturnQuota_Handler,
+		},
+		{
+			MethodName: "ListAuditEvents",
+			Handler:    _RateLimiterService_ListAuditEvents_Handler,
+		},
+		{
+			MethodName: "GetUsageHistory",
+			Handler:    _RateLimiterService_GetUsageHistory_Handler,
+		},
+		{
+			MethodName: "SetQuota",
+			Handler:    _RateLimiterService_SetQuota_Handler,
+		},
+		{
+			MethodName: "DeleteQuota",
+			Handler:    _RateLimiterService_DeleteQuota_Handler,
+		},
+		{
+			MethodName: "GetQuotas",
+			Handler:    _RateLimiterService_GetQuotas_Handler,
+		},
+		{
+			MethodName: "SetPolicy",
+			Handler:    _RateLimiterService_SetPolicy_Handler,
+		},
+		{
+			MethodName: "GetPolicy",
+			Handler:    _RateLimiterService_GetPolicy_Handler,
+		},
+		{
+			MethodName: "DeletePolicy",
+			Handler:    _RateLimiterService_DeletePolicy_Handler,
+		},
+		{
+			MethodName: "SetParent",
+			Handler:    _RateLimiterService_SetParent_Handler,
+		},
+		{
+			MethodName: "DeleteParent",
+			Handler:    _RateLimiterService_DeleteParent_Handler,
+		},
+		{
+			MethodName: "GetHierarchy",
+			Handler:    _RateLimiterService_GetHierarchy_Handler,
+		},
+		{
+			MethodName: "SetTier",
+			Handler:    _RateLimiterService_SetTier_Handler,
+		},
+		{
+			MethodName: "DeleteTier",
+			Handler:    _RateLimiterService_DeleteTier_Handler,
+		},
+		{
+			MethodName: "ListTiers",
+			Handler:    _RateLimiterService_ListTiers_Handler,
+		},
+		{
+			MethodName: "SetTierMapping",
+			Handler:    _RateLimiterService_SetTierMapping_Handler,
+		},
+		{
+			MethodName: "DeleteTierMapping",
+			Handler:    _RateLimiterService_DeleteTierMapping_Handler,
+		},
+		{
+			MethodName: "SetAccessRule",
+			Handler:    _RateLimiterService_SetAccessRule_Handler,
+		},
+		{
+			MethodName: "DeleteAccessRule",
+			Handler:    _RateLimiterService_DeleteAccessRule_Handler,
+		},
+		{
+			MethodName: "ListAccessRules",
+			Handler:    _RateLimiterService_ListAccessRules_Handler,
+		},
+		{
+			MethodName: "SetOverride",
+			Handler:    _RateLimiterService_SetOverride_Handler,
+		},
+		{
+			MethodName: "ClearOverride",
+			Handler:    _RateLimiterService_ClearOverride_Handler,
+		},
+		{
+			MethodName: "GetOverride",
+			Handler:    _RateLimiterService_GetOverride_Handler,
 		},
 	},
 	Streams:  []grpc.StreamDesc{},
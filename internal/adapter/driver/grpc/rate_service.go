@@ -2,8 +2,11 @@ package grpc
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	ratev1 "github.com/nullexp/limiter-x/internal/adapter/driver/grpc/proto/rate/v1"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
 	driverService "github.com/nullexp/limiter-x/internal/port/driver/service"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -22,17 +25,36 @@ func NewRateLimiterService(rateLimiterService driverService.RateLimiter) *RateLi
 
 // CheckRateLimit implements the rate-limiting logic for the CheckRateLimit gRPC call.
 func (rls *RateLimiterService) CheckRateLimit(ctx context.Context, request *ratev1.CheckRateLimitRequest) (*ratev1.CheckRateLimitResponse, error) {
-	// Call the RateLimit method from the service
-	allowed, err := rls.service.RateLimit(ctx, request.UserId, int(request.Limit))
+	// Call the Check method from the service
+	decision, err := rls.service.Check(ctx, driverService.CheckRequest{
+		UserId: request.UserId,
+		Limit:  int(request.Limit),
+		Window: time.Duration(request.WindowMs) * time.Millisecond,
+		Role:   request.Role,
+	})
 	if err != nil {
+		if errors.Is(err, domain.ErrInvalidWindow) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
 		return nil, status.Errorf(codes.Internal, "failed to check rate limit: %v", err)
 	}
 
 	// Return the response
-	return &ratev1.CheckRateLimitResponse{
-		Allowed: allowed,
+	response := &ratev1.CheckRateLimitResponse{
+		Allowed: decision.Allowed,
 		Message: "Rate limit checked",
-	}, nil
+		Reason:  decision.Reason,
+	}
+	if decision.Binding != nil {
+		response.Binding = limitMessage(*decision.Binding)
+	}
+	if !decision.PenalizedUntil.IsZero() {
+		response.PenalizedUntil = decision.PenalizedUntil.UnixMilli()
+	}
+	if decision.Shadow != nil {
+		response.Shadow = limitMessage(*decision.Shadow)
+	}
+	return response, nil
 }
 
 // GetUserRateLimit implements the GetUserRateLimit gRPC call.
@@ -55,8 +77,11 @@ func (rls *RateLimiterService) GetUserRateLimit(ctx context.Context, request *ra
 // UpdateUserRateLimit implements the UpdateUserRateLimit gRPC call.
 func (rls *RateLimiterService) UpdateUserRateLimit(ctx context.Context, request *ratev1.UpdateUserRateLimitRequest) (*ratev1.UpdateUserRateLimitResponse, error) {
 	// Call the UpdateUserRateLimit method from the service
-	err := rls.service.UpdateUserRateLimit(ctx, request.UserId, int(request.NewLimit))
+	err := rls.service.UpdateUserRateLimit(ctx, request.UserId, int(request.NewLimit), time.Duration(request.WindowMs)*time.Millisecond)
 	if err != nil {
+		if errors.Is(err, domain.ErrInvalidWindow) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
 		return nil, status.Errorf(codes.Internal, "failed to update user rate limit: %v", err)
 	}
 
@@ -67,3 +92,495 @@ func (rls *RateLimiterService) UpdateUserRateLimit(ctx context.Context, request
 		Message:      "User rate limit updated successfully",
 	}, nil
 }
+
+// LeaseQuota implements the LeaseQuota gRPC call.
+func (rls *RateLimiterService) LeaseQuota(ctx context.Context, request *ratev1.LeaseQuotaRequest) (*ratev1.LeaseQuotaResponse, error) {
+	// Call the LeaseQuota method from the service
+	lease, err := rls.service.LeaseQuota(ctx, request.UserId, int(request.Units), int(request.Limit), request.Role)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidLeaseUnits) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to lease quota: %v", err)
+	}
+
+	// Return the granted lease
+	return &ratev1.LeaseQuotaResponse{
+		LeaseId:   lease.LeaseId,
+		Granted:   int32(lease.Granted),
+		ExpiresAt: lease.ExpiresAt.UnixMilli(),
+		Message:   "Quota leased",
+	}, nil
+}
+
+// ReturnQuota implements the ReturnQuota gRPC call.
+func (rls *RateLimiterService) ReturnQuota(ctx context.Context, request *ratev1.ReturnQuotaRequest) (*ratev1.ReturnQuotaResponse, error) {
+	// Call the ReturnQuota method from the service
+	returned, err := rls.service.ReturnQuota(ctx, request.LeaseId, int(request.Unused))
+	if err != nil {
+		if errors.Is(err, domain.ErrLeaseNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to return quota: %v", err)
+	}
+
+	// Return the number of credited requests
+	return &ratev1.ReturnQuotaResponse{
+		Returned: int32(returned),
+		Message:  "Quota returned",
+	}, nil
+}
+
+// ListAuditEvents implements the ListAuditEvents gRPC call.
+func (rls *RateLimiterService) ListAuditEvents(ctx context.Context, request *ratev1.ListAuditEventsRequest) (*ratev1.ListAuditEventsResponse, error) {
+	query := driverService.AuditQuery{
+		Key:       request.Key,
+		Actor:     request.Actor,
+		EventType: request.EventType,
+		Limit:     int(request.Limit),
+	}
+	if request.From > 0 {
+		query.From = time.UnixMilli(request.From)
+	}
+	if request.To > 0 {
+		query.To = time.UnixMilli(request.To)
+	}
+
+	// Call the ListAuditEvents method from the service
+	events, err := rls.service.ListAuditEvents(ctx, query)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidAuditEventType) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, domain.ErrAuditLogDisabled) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to list audit events: %v", err)
+	}
+
+	// Return the matching events
+	response := &ratev1.ListAuditEventsResponse{Events: make([]*ratev1.AuditEvent, 0, len(events))}
+	for _, event := range events {
+		response.Events = append(response.Events, &ratev1.AuditEvent{
+			Id:        event.Id,
+			Key:       event.Key,
+			Actor:     event.Actor,
+			EventType: event.EventType,
+			OldValue:  event.OldValue,
+			NewValue:  event.NewValue,
+			Detail:    event.Detail,
+			CreatedAt: event.CreatedAt.UnixMilli(),
+		})
+	}
+	return response, nil
+}
+
+// GetUsageHistory implements the GetUsageHistory gRPC call.
+func (rls *RateLimiterService) GetUsageHistory(ctx context.Context, request *ratev1.GetUsageHistoryRequest) (*ratev1.GetUsageHistoryResponse, error) {
+	query := driverService.UsageQuery{Key: request.Key, Granularity: request.Granularity}
+	if request.From > 0 {
+		query.From = time.UnixMilli(request.From)
+	}
+	if request.To > 0 {
+		query.To = time.UnixMilli(request.To)
+	}
+
+	// Call the GetUsageHistory method from the service
+	points, err := rls.service.GetUsageHistory(ctx, query)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidUsageGranularity) || errors.Is(err, domain.ErrInvalidUsageRange) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, domain.ErrUsageHistoryDisabled) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get usage history: %v", err)
+	}
+
+	// Return the series
+	response := &ratev1.GetUsageHistoryResponse{Points: make([]*ratev1.UsagePoint, 0, len(points))}
+	for _, point := range points {
+		response.Points = append(response.Points, &ratev1.UsagePoint{
+			Start:   point.Start.UnixMilli(),
+			Allowed: point.Allowed,
+			Denied:  point.Denied,
+		})
+	}
+	return response, nil
+}
+
+// SetQuota implements the SetQuota gRPC call.
+func (rls *RateLimiterService) SetQuota(ctx context.Context, request *ratev1.SetQuotaRequest) (*ratev1.SetQuotaResponse, error) {
+	// Call the SetQuota method from the service
+	err := rls.service.SetQuota(ctx, driverService.QuotaModel{
+		Key:      request.Key,
+		Period:   request.Period,
+		Limit:    request.Limit,
+		TimeZone: request.TimeZone,
+		ResetDay: int(request.ResetDay),
+	})
+	if err != nil {
+		return nil, quotaError("failed to set quota", err)
+	}
+
+	return &ratev1.SetQuotaResponse{Message: "Quota set successfully"}, nil
+}
+
+// DeleteQuota implements the DeleteQuota gRPC call.
+func (rls *RateLimiterService) DeleteQuota(ctx context.Context, request *ratev1.DeleteQuotaRequest) (*ratev1.DeleteQuotaResponse, error) {
+	// Call the DeleteQuota method from the service
+	if err := rls.service.DeleteQuota(ctx, request.Key, request.Period); err != nil {
+		return nil, quotaError("failed to delete quota", err)
+	}
+
+	return &ratev1.DeleteQuotaResponse{Message: "Quota deleted successfully"}, nil
+}
+
+// GetQuotas implements the GetQuotas gRPC call.
+func (rls *RateLimiterService) GetQuotas(ctx context.Context, request *ratev1.GetQuotasRequest) (*ratev1.GetQuotasResponse, error) {
+	// Call the GetQuotas method from the service
+	quotas, err := rls.service.GetQuotas(ctx, request.Key)
+	if err != nil {
+		return nil, quotaError("failed to get quotas", err)
+	}
+
+	// Return the quotas with their current usage
+	response := &ratev1.GetQuotasResponse{Quotas: make([]*ratev1.Quota, 0, len(quotas))}
+	for _, quota := range quotas {
+		response.Quotas = append(response.Quotas, &ratev1.Quota{
+			Period:    quota.Period,
+			Limit:     quota.Limit,
+			TimeZone:  quota.TimeZone,
+			ResetDay:  int32(quota.ResetDay),
+			Used:      quota.Used,
+			Remaining: quota.Remaining,
+			ResetsAt:  quota.ResetsAt.UnixMilli(),
+		})
+	}
+	return response, nil
+}
+
+// quotaError maps an error of the quota calls to its gRPC status.
+func quotaError(message string, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidQuotaPeriod), errors.Is(err, domain.ErrInvalidQuotaLimit),
+		errors.Is(err, domain.ErrInvalidQuotaTimeZone), errors.Is(err, domain.ErrInvalidQuotaResetDay):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrQuotaNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrQuotasDisabled):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Errorf(codes.Internal, "%s: %v", message, err)
+}
+
+// SetPolicy implements the SetPolicy gRPC call.
+func (rls *RateLimiterService) SetPolicy(ctx context.Context, request *ratev1.SetPolicyRequest) (*ratev1.SetPolicyResponse, error) {
+	limits := make([]driverService.LimitModel, 0, len(request.Limits))
+	for _, limit := range request.Limits {
+		limits = append(limits, driverService.LimitModel{Limit: limit.Limit, Window: time.Duration(limit.WindowMs) * time.Millisecond, Shadow: limit.Shadow})
+	}
+
+	// Call the SetPolicy method from the service
+	if err := rls.service.SetPolicy(ctx, request.Key, limits); err != nil {
+		return nil, policyError("failed to set policy", err)
+	}
+
+	return &ratev1.SetPolicyResponse{Message: "Policy set successfully"}, nil
+}
+
+// GetPolicy implements the GetPolicy gRPC call.
+func (rls *RateLimiterService) GetPolicy(ctx context.Context, request *ratev1.GetPolicyRequest) (*ratev1.GetPolicyResponse, error) {
+	// Call the GetPolicy method from the service
+	limits, err := rls.service.GetPolicy(ctx, request.Key)
+	if err != nil {
+		return nil, policyError("failed to get policy", err)
+	}
+
+	// Return the limits with what is left of them
+	response := &ratev1.GetPolicyResponse{Limits: make([]*ratev1.Limit, 0, len(limits))}
+	for _, limit := range limits {
+		response.Limits = append(response.Limits, limitMessage(limit))
+	}
+	return response, nil
+}
+
+// DeletePolicy implements the DeletePolicy gRPC call.
+func (rls *RateLimiterService) DeletePolicy(ctx context.Context, request *ratev1.DeletePolicyRequest) (*ratev1.DeletePolicyResponse, error) {
+	// Call the DeletePolicy method from the service
+	if err := rls.service.DeletePolicy(ctx, request.Key); err != nil {
+		return nil, policyError("failed to delete policy", err)
+	}
+
+	return &ratev1.DeletePolicyResponse{Message: "Policy deleted successfully"}, nil
+}
+
+// SetParent implements the SetParent gRPC call.
+func (rls *RateLimiterService) SetParent(ctx context.Context, request *ratev1.SetParentRequest) (*ratev1.SetParentResponse, error) {
+	// Call the SetParent method from the service
+	if err := rls.service.SetParent(ctx, request.Key, request.Parent); err != nil {
+		return nil, hierarchyError("failed to set parent", err)
+	}
+
+	return &ratev1.SetParentResponse{Message: "Parent set successfully"}, nil
+}
+
+// DeleteParent implements the DeleteParent gRPC call.
+func (rls *RateLimiterService) DeleteParent(ctx context.Context, request *ratev1.DeleteParentRequest) (*ratev1.DeleteParentResponse, error) {
+	// Call the DeleteParent method from the service
+	if err := rls.service.DeleteParent(ctx, request.Key); err != nil {
+		return nil, hierarchyError("failed to delete parent", err)
+	}
+
+	return &ratev1.DeleteParentResponse{Message: "Parent deleted successfully"}, nil
+}
+
+// GetHierarchy implements the GetHierarchy gRPC call.
+func (rls *RateLimiterService) GetHierarchy(ctx context.Context, request *ratev1.GetHierarchyRequest) (*ratev1.GetHierarchyResponse, error) {
+	// Call the GetHierarchy method from the service
+	hierarchy, err := rls.service.GetHierarchy(ctx, request.Key)
+	if err != nil {
+		return nil, hierarchyError("failed to get hierarchy", err)
+	}
+
+	return &ratev1.GetHierarchyResponse{Ancestors: hierarchy.Ancestors, Children: hierarchy.Children}, nil
+}
+
+// SetTier implements the SetTier gRPC call.
+func (rls *RateLimiterService) SetTier(ctx context.Context, request *ratev1.SetTierRequest) (*ratev1.SetTierResponse, error) {
+	// Call the SetTier method from the service
+	if err := rls.service.SetTier(ctx, request.Name, int(request.Limit)); err != nil {
+		return nil, tierError("failed to set tier", err)
+	}
+
+	return &ratev1.SetTierResponse{Message: "Tier set successfully"}, nil
+}
+
+// DeleteTier implements the DeleteTier gRPC call.
+func (rls *RateLimiterService) DeleteTier(ctx context.Context, request *ratev1.DeleteTierRequest) (*ratev1.DeleteTierResponse, error) {
+	// Call the DeleteTier method from the service
+	if err := rls.service.DeleteTier(ctx, request.Name); err != nil {
+		return nil, tierError("failed to delete tier", err)
+	}
+
+	return &ratev1.DeleteTierResponse{Message: "Tier deleted successfully"}, nil
+}
+
+// ListTiers implements the ListTiers gRPC call.
+func (rls *RateLimiterService) ListTiers(ctx context.Context, request *ratev1.ListTiersRequest) (*ratev1.ListTiersResponse, error) {
+	// Call the ListTiers method from the service
+	tiers, err := rls.service.ListTiers(ctx)
+	if err != nil {
+		return nil, tierError("failed to list tiers", err)
+	}
+
+	response := &ratev1.ListTiersResponse{Tiers: make([]*ratev1.Tier, 0, len(tiers))}
+	for _, tier := range tiers {
+		response.Tiers = append(response.Tiers, &ratev1.Tier{Name: tier.Name, Limit: int32(tier.Limit), Subjects: tier.Subjects})
+	}
+	return response, nil
+}
+
+// SetTierMapping implements the SetTierMapping gRPC call.
+func (rls *RateLimiterService) SetTierMapping(ctx context.Context, request *ratev1.SetTierMappingRequest) (*ratev1.SetTierMappingResponse, error) {
+	// Call the SetTierMapping method from the service
+	if err := rls.service.SetTierMapping(ctx, request.Subject, request.Tier); err != nil {
+		return nil, tierError("failed to set tier mapping", err)
+	}
+
+	return &ratev1.SetTierMappingResponse{Message: "Tier mapping set successfully"}, nil
+}
+
+// DeleteTierMapping implements the DeleteTierMapping gRPC call.
+func (rls *RateLimiterService) DeleteTierMapping(ctx context.Context, request *ratev1.DeleteTierMappingRequest) (*ratev1.DeleteTierMappingResponse, error) {
+	// Call the DeleteTierMapping method from the service
+	if err := rls.service.DeleteTierMapping(ctx, request.Subject); err != nil {
+		return nil, tierError("failed to delete tier mapping", err)
+	}
+
+	return &ratev1.DeleteTierMappingResponse{Message: "Tier mapping deleted successfully"}, nil
+}
+
+// SetAccessRule implements the SetAccessRule gRPC call.
+func (rls *RateLimiterService) SetAccessRule(ctx context.Context, request *ratev1.SetAccessRuleRequest) (*ratev1.SetAccessRuleResponse, error) {
+	rule := driverService.AccessRuleModel{
+		Action:  request.GetRule().GetAction(),
+		Match:   request.GetRule().GetMatch(),
+		Pattern: request.GetRule().GetPattern(),
+		Reason:  request.GetRule().GetReason(),
+	}
+	if expiresAt := request.GetRule().GetExpiresAt(); expiresAt > 0 {
+		rule.ExpiresAt = time.UnixMilli(expiresAt)
+	}
+
+	// Call the SetAccessRule method from the service
+	id, err := rls.service.SetAccessRule(ctx, rule)
+	if err != nil {
+		return nil, accessError("failed to set access rule", err)
+	}
+
+	return &ratev1.SetAccessRuleResponse{Id: id, Message: "Access rule set successfully"}, nil
+}
+
+// DeleteAccessRule implements the DeleteAccessRule gRPC call.
+func (rls *RateLimiterService) DeleteAccessRule(ctx context.Context, request *ratev1.DeleteAccessRuleRequest) (*ratev1.DeleteAccessRuleResponse, error) {
+	// Call the DeleteAccessRule method from the service
+	if err := rls.service.DeleteAccessRule(ctx, request.Id); err != nil {
+		return nil, accessError("failed to delete access rule", err)
+	}
+
+	return &ratev1.DeleteAccessRuleResponse{Message: "Access rule deleted successfully"}, nil
+}
+
+// ListAccessRules implements the ListAccessRules gRPC call.
+func (rls *RateLimiterService) ListAccessRules(ctx context.Context, request *ratev1.ListAccessRulesRequest) (*ratev1.ListAccessRulesResponse, error) {
+	// Call the ListAccessRules method from the service
+	rules, err := rls.service.ListAccessRules(ctx)
+	if err != nil {
+		return nil, accessError("failed to list access rules", err)
+	}
+
+	response := &ratev1.ListAccessRulesResponse{Rules: make([]*ratev1.AccessRule, 0, len(rules))}
+	for _, rule := range rules {
+		message := &ratev1.AccessRule{
+			Id:      rule.Id,
+			Action:  rule.Action,
+			Match:   rule.Match,
+			Pattern: rule.Pattern,
+			Reason:  rule.Reason,
+		}
+		if !rule.ExpiresAt.IsZero() {
+			message.ExpiresAt = rule.ExpiresAt.UnixMilli()
+		}
+		response.Rules = append(response.Rules, message)
+	}
+	return response, nil
+}
+
+// SetOverride implements the SetOverride gRPC call.
+func (rls *RateLimiterService) SetOverride(ctx context.Context, request *ratev1.SetOverrideRequest) (*ratev1.SetOverrideResponse, error) {
+	// Call the SetOverride method from the service
+	override, err := rls.service.SetOverride(ctx, driverService.OverrideModel{
+		Mode:       request.Mode,
+		Multiplier: request.Multiplier,
+		Reason:     request.Reason,
+		Duration:   time.Duration(request.DurationMs) * time.Millisecond,
+	})
+	if err != nil {
+		return nil, overrideError("failed to set override", err)
+	}
+
+	return &ratev1.SetOverrideResponse{Override: overrideMessage(*override), Message: "Override set successfully"}, nil
+}
+
+// ClearOverride implements the ClearOverride gRPC call.
+func (rls *RateLimiterService) ClearOverride(ctx context.Context, request *ratev1.ClearOverrideRequest) (*ratev1.ClearOverrideResponse, error) {
+	// Call the ClearOverride method from the service
+	if err := rls.service.ClearOverride(ctx); err != nil {
+		return nil, overrideError("failed to clear override", err)
+	}
+
+	return &ratev1.ClearOverrideResponse{Message: "Override cleared successfully"}, nil
+}
+
+// GetOverride implements the GetOverride gRPC call.
+func (rls *RateLimiterService) GetOverride(ctx context.Context, request *ratev1.GetOverrideRequest) (*ratev1.GetOverrideResponse, error) {
+	// Call the GetOverride method from the service
+	override, err := rls.service.GetOverride(ctx)
+	if err != nil {
+		return nil, overrideError("failed to get override", err)
+	}
+
+	return &ratev1.GetOverrideResponse{Override: overrideMessage(*override)}, nil
+}
+
+// overrideMessage converts the global override to its gRPC message.
+func overrideMessage(override driverService.OverrideModel) *ratev1.Override {
+	return &ratev1.Override{
+		Mode:       override.Mode,
+		Multiplier: override.Multiplier,
+		Reason:     override.Reason,
+		ExpiresAt:  override.ExpiresAt.UnixMilli(),
+	}
+}
+
+// limitMessage converts a limit of a key to its gRPC message.
+func limitMessage(limit driverService.LimitModel) *ratev1.Limit {
+	return &ratev1.Limit{
+		Limit:     limit.Limit,
+		WindowMs:  limit.Window.Milliseconds(),
+		Period:    limit.Period,
+		Remaining: limit.Remaining,
+		ResetsAt:  limit.ResetsAt.UnixMilli(),
+		Shadow:    limit.Shadow,
+	}
+}
+
+// policyError maps an error of the policy calls to its gRPC status.
+func policyError(message string, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrEmptyPolicy), errors.Is(err, domain.ErrInvalidPolicyLimit),
+		errors.Is(err, domain.ErrInvalidPolicyWindow), errors.Is(err, domain.ErrDuplicatePolicyWindow):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrPolicyNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrPoliciesDisabled):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Errorf(codes.Internal, "%s: %v", message, err)
+}
+
+// hierarchyError maps an error of the hierarchy calls to its gRPC status.
+func hierarchyError(message string, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidParent), errors.Is(err, domain.ErrParentCycle),
+		errors.Is(err, domain.ErrHierarchyTooDeep):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrParentNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrHierarchyDisabled):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Errorf(codes.Internal, "%s: %v", message, err)
+}
+
+// tierError maps an error of the tier calls to its gRPC status.
+func tierError(message string, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidTier), errors.Is(err, domain.ErrInvalidTierMapping):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrTierNotFound), errors.Is(err, domain.ErrTierMappingNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrTiersDisabled), errors.Is(err, domain.ErrTierInUse):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Errorf(codes.Internal, "%s: %v", message, err)
+}
+
+// accessError maps an error of the access rule calls to its gRPC status.
+func accessError(message string, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidAccessRule), errors.Is(err, domain.ErrInvalidCIDR),
+		errors.Is(err, domain.ErrAccessRuleExpired):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrAccessRuleNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrAccessListsDisabled):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Errorf(codes.Internal, "%s: %v", message, err)
+}
+
+// overrideError maps an error of the override calls to its gRPC status.
+func overrideError(message string, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidOverrideMode), errors.Is(err, domain.ErrInvalidOverrideMultiplier),
+		errors.Is(err, domain.ErrInvalidOverrideDuration):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrOverrideNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrOverridesDisabled):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Errorf(codes.Internal, "%s: %v", message, err)
+}
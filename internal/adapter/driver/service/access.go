@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	domainModel "github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+	"github.com/nullexp/limiter-x/internal/port/driver/service"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/singleflight"
+)
+
+// WithAccessLists consults the allowlist and denylist before counting a request. Keys a deny
+// rule matches are denied and keys only an allow rule matches are allowed, without counting
+// against any limit. All rules are cached in-process and reloaded once they are older than
+// cacheTTL, so a changed rule takes up to that long to reach the other instances.
+func WithAccessLists(repoFactory repository.AccessRuleRepositoryFactory, cacheTTL time.Duration) Option {
+	return func(rls *RateLimitService) {
+		rls.accessRepoFactory = repoFactory
+		rls.access = &accessCache{ttl: cacheTTL}
+	}
+}
+
+// accessCache keeps the compiled access rules of the instance.
+type accessCache struct {
+	ttl   time.Duration
+	loads singleflight.Group
+
+	mu       sync.Mutex
+	list     *accessList
+	loadedAt time.Time
+	// generation counts changes, rules loaded before the latest one are not kept
+	generation uint64
+}
+
+// accessList holds access rules by how they match keys.
+type accessList struct {
+	exact    map[string][]domainModel.AccessRule
+	prefixes []domainModel.AccessRule
+	networks []accessNetwork
+}
+
+type accessNetwork struct {
+	network *net.IPNet
+	rule    domainModel.AccessRule
+}
+
+func newAccessList(rules []domainModel.AccessRule) *accessList {
+	list := &accessList{exact: make(map[string][]domainModel.AccessRule)}
+	for _, rule := range rules {
+		switch rule.Match {
+		case domainModel.MatchExact:
+			list.exact[rule.Pattern] = append(list.exact[rule.Pattern], rule)
+		case domainModel.MatchPrefix:
+			list.prefixes = append(list.prefixes, rule)
+		case domainModel.MatchCIDR:
+			_, network, err := net.ParseCIDR(rule.Pattern)
+			if err != nil {
+				log.Printf("skipping access rule %s with invalid network %q", rule.Id, rule.Pattern)
+				continue
+			}
+			list.networks = append(list.networks, accessNetwork{network: network, rule: rule})
+		}
+	}
+	return list
+}
+
+// match returns the rule that decides the requests of the key at now: a deny rule if any matches,
+// otherwise an allow rule if any matches, otherwise none.
+func (l *accessList) match(key string, now time.Time) *domainModel.AccessRule {
+	matched := append([]domainModel.AccessRule(nil), l.exact[key]...)
+	for _, rule := range l.prefixes {
+		if strings.HasPrefix(key, rule.Pattern) {
+			matched = append(matched, rule)
+		}
+	}
+	if ip := net.ParseIP(key); ip != nil {
+		for _, network := range l.networks {
+			if network.network.Contains(ip) {
+				matched = append(matched, network.rule)
+			}
+		}
+	}
+
+	var allow *domainModel.AccessRule
+	for i := range matched {
+		rule := &matched[i]
+		if rule.Expired(now) {
+			continue
+		}
+		if rule.Action == domainModel.AccessDeny {
+			return rule
+		}
+		if allow == nil {
+			allow = rule
+		}
+	}
+	return allow
+}
+
+// accessRuleFor returns the rule that decides the requests of the key, none if they are to be
+// counted. Rules are reloaded once the cached ones are older than the cache TTL; if that fails,
+// the cached ones stay in use until the next attempt.
+func (rls *RateLimitService) accessRuleFor(ctx context.Context, key string) (*domainModel.AccessRule, error) {
+	if rls.accessRepoFactory == nil {
+		return nil, nil
+	}
+
+	list, err := rls.accessList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return list.match(key, time.Now()), nil
+}
+
+// accessList returns the cached access rules, reloading them when they are stale. The rules are
+// loaded without holding the cache, and lookups wait for a reload in progress rather than each
+// loading the rules themselves.
+func (rls *RateLimitService) accessList(ctx context.Context) (*accessList, error) {
+	access := rls.access
+	access.mu.Lock()
+	list, loadedAt, generation := access.list, access.loadedAt, access.generation
+	access.mu.Unlock()
+	if list != nil && time.Since(loadedAt) <= access.ttl {
+		return list, nil
+	}
+
+	// A change since a reload started needs one of its own
+	loaded, err, _ := access.loads.Do(strconv.FormatUint(generation, 10), func() (any, error) {
+		return rls.loadAccessList(ctx, generation)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loaded.(*accessList), nil
+}
+
+// loadAccessList reads the access rules from the repository and caches them unless they changed
+// since generation. If reading fails, the cached rules stay in use until the next attempt.
+func (rls *RateLimitService) loadAccessList(ctx context.Context, generation uint64) (*accessList, error) {
+	var rules []domainModel.AccessRule
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		var err error
+		rules, err = rls.accessRepoFactory.New(tx).ListAccessRules(ctx)
+		return err
+	}, db.ReadOnly())
+
+	access := rls.access
+	access.mu.Lock()
+	defer access.mu.Unlock()
+	current := access.generation == generation
+	if err != nil {
+		if access.list == nil {
+			return nil, errors.Wrap(err, "failed to list access rules from repo")
+		}
+		log.Printf("failed to reload access rules, using the cached ones: %v", err)
+		if current {
+			access.loadedAt = time.Now()
+		}
+		return access.list, nil
+	}
+
+	list := newAccessList(rules)
+	if current {
+		access.list, access.loadedAt = list, time.Now()
+	}
+	return list, nil
+}
+
+// forgetAccessRules makes the next lookup reload the access rules. The cached ones stay in use if
+// the reload fails.
+func (rls *RateLimitService) forgetAccessRules() {
+	rls.access.mu.Lock()
+	defer rls.access.mu.Unlock()
+	rls.access.loadedAt = time.Time{}
+	rls.access.generation++
+}
+
+// accessDecision decides a request of the user by the access rule that matched it.
+func (rls *RateLimitService) accessDecision(userId string, rule *domainModel.AccessRule) *service.DecisionModel {
+	if rule.Action == domainModel.AccessDeny {
+		rls.recordUsage(userId, 0, 1)
+		return &service.DecisionModel{Reason: service.ReasonDenylisted}
+	}
+	rls.recordUsage(userId, 1, 0)
+	return &service.DecisionModel{Allowed: true, Reason: service.ReasonAllowlisted}
+}
+
+// SetAccessRule adds a rule to the allowlist or denylist, replacing the rule with the same match
+// and pattern, and returns its ID.
+func (rls *RateLimitService) SetAccessRule(ctx context.Context, model service.AccessRuleModel) (string, error) {
+	if rls.accessRepoFactory == nil {
+		return "", domain.ErrAccessListsDisabled
+	}
+	rule, err := accessRuleOf(model)
+	if err != nil {
+		return "", err
+	}
+
+	err = rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		repo := rls.accessRepoFactory.New(tx)
+		rules, err := repo.ListAccessRules(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to list access rules from repo")
+		}
+
+		var existing *domainModel.AccessRule
+		rule.Id = uuid.New().String()
+		for i := range rules {
+			if rules[i].Match == rule.Match && rules[i].Pattern == rule.Pattern {
+				existing = &rules[i]
+				rule.Id = existing.Id
+			}
+		}
+		if err := repo.SetAccessRule(ctx, rule); err != nil {
+			return errors.Wrap(err, "failed to set access rule in repo")
+		}
+		return rls.auditAccessChange(ctx, tx, existing, &rule)
+	})
+	if err != nil {
+		return "", err
+	}
+	rls.forgetAccessRules()
+	return rule.Id, nil
+}
+
+// accessRuleOf validates a rule given to SetAccessRule. The networks of cidr rules are stored in
+// their canonical form, e.g. 10.0.0.0/8 for 10.1.2.3/8.
+func accessRuleOf(model service.AccessRuleModel) (domainModel.AccessRule, error) {
+	rule := domainModel.AccessRule{
+		Action:    domainModel.AccessAction(model.Action),
+		Match:     domainModel.AccessMatch(model.Match),
+		Pattern:   model.Pattern,
+		Reason:    model.Reason,
+		ExpiresAt: model.ExpiresAt,
+	}
+	if rule.Action != domainModel.AccessAllow && rule.Action != domainModel.AccessDeny {
+		return rule, domain.ErrInvalidAccessRule
+	}
+	switch rule.Match {
+	case domainModel.MatchExact, domainModel.MatchPrefix:
+	case domainModel.MatchCIDR:
+		_, network, err := net.ParseCIDR(rule.Pattern)
+		if err != nil {
+			return rule, domain.ErrInvalidCIDR
+		}
+		rule.Pattern = network.String()
+	default:
+		return rule, domain.ErrInvalidAccessRule
+	}
+	if rule.Pattern == "" {
+		return rule, domain.ErrInvalidAccessRule
+	}
+	if rule.Expired(time.Now()) {
+		return rule, domain.ErrAccessRuleExpired
+	}
+	return rule, nil
+}
+
+// DeleteAccessRule removes a rule from the allowlist or denylist.
+func (rls *RateLimitService) DeleteAccessRule(ctx context.Context, id string) error {
+	if rls.accessRepoFactory == nil {
+		return domain.ErrAccessListsDisabled
+	}
+
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		repo := rls.accessRepoFactory.New(tx)
+		rules, err := repo.ListAccessRules(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to list access rules from repo")
+		}
+		if err := repo.DeleteAccessRule(ctx, id); err != nil {
+			return err
+		}
+		for i := range rules {
+			if rules[i].Id == id {
+				return rls.auditAccessChange(ctx, tx, &rules[i], nil)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	rls.forgetAccessRules()
+	return nil
+}
+
+// ListAccessRules returns the rules of the allowlist and denylist that have not expired.
+func (rls *RateLimitService) ListAccessRules(ctx context.Context) ([]service.AccessRuleModel, error) {
+	if rls.accessRepoFactory == nil {
+		return nil, domain.ErrAccessListsDisabled
+	}
+
+	var rules []domainModel.AccessRule
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		var err error
+		rules, err = rls.accessRepoFactory.New(tx).ListAccessRules(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to list access rules from repo")
+		}
+		return nil
+	}, db.ReadOnly())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	models := make([]service.AccessRuleModel, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Expired(now) {
+			continue
+		}
+		models = append(models, service.AccessRuleModel{
+			Id:        rule.Id,
+			Action:    string(rule.Action),
+			Match:     string(rule.Match),
+			Pattern:   rule.Pattern,
+			Reason:    rule.Reason,
+			ExpiresAt: rule.ExpiresAt,
+		})
+	}
+	return models, nil
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nullexp/limiter-x/internal/adapter/driven/cache"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/db/memory"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+	"github.com/nullexp/limiter-x/internal/port/driver/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitService_AccessLists(t *testing.T) {
+	ctx := context.Background()
+	memoryCache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, memoryCache.Connect())
+	t.Cleanup(func() { memoryCache.Disconnect() })
+	rateService := NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), memoryCache, memory.NewTransactionFactory(memory.NewStore()), time.Minute,
+		WithAccessLists(memory.NewAccessRuleRepositoryFactory(), time.Minute))
+
+	for _, rule := range []service.AccessRuleModel{
+		{Action: "allow", Match: "prefix", Pattern: "svc-", Reason: "internal services"},
+		{Action: "deny", Match: "exact", Pattern: "svc-compromised", Reason: "leaked credentials"},
+		{Action: "deny", Match: "cidr", Pattern: "10.1.2.3/8", Reason: "abuse"},
+	} {
+		_, err := rateService.SetAccessRule(ctx, rule)
+		assert.Nil(t, err)
+	}
+
+	// Allowlisted keys are not counted against their limit
+	for i := 0; i < 3; i++ {
+		decision, err := rateService.Check(ctx, service.CheckRequest{UserId: "svc-billing", Limit: 1})
+		assert.Nil(t, err)
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, service.ReasonAllowlisted, decision.Reason)
+	}
+
+	// Deny rules win over allow rules
+	decision, err := rateService.Check(ctx, service.CheckRequest{UserId: "svc-compromised"})
+	assert.Nil(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, service.ReasonDenylisted, decision.Reason)
+
+	decision, err = rateService.Check(ctx, service.CheckRequest{UserId: "10.200.0.1"})
+	assert.Nil(t, err)
+	assert.False(t, decision.Allowed)
+	decision, err = rateService.Check(ctx, service.CheckRequest{UserId: "11.0.0.1", Limit: 1})
+	assert.Nil(t, err)
+	assert.True(t, decision.Allowed)
+	assert.Empty(t, decision.Reason)
+
+	lease, err := rateService.LeaseQuota(ctx, "svc-billing", 50, 1, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 50, lease.Granted)
+
+	// Rules stop applying once they expire
+	id, err := rateService.SetAccessRule(ctx, service.AccessRuleModel{Action: "deny", Match: "exact", Pattern: "11.0.0.2", ExpiresAt: time.Now().Add(20 * time.Millisecond)})
+	assert.Nil(t, err)
+	decision, err = rateService.Check(ctx, service.CheckRequest{UserId: "11.0.0.2"})
+	assert.Nil(t, err)
+	assert.False(t, decision.Allowed)
+	time.Sleep(30 * time.Millisecond)
+	decision, err = rateService.Check(ctx, service.CheckRequest{UserId: "11.0.0.2"})
+	assert.Nil(t, err)
+	assert.True(t, decision.Allowed)
+
+	rules, err := rateService.ListAccessRules(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, rules, 3)
+	assert.Equal(t, "10.0.0.0/8", rules[0].Pattern)
+
+	// Setting a rule for the same pattern replaces it
+	replaced, err := rateService.SetAccessRule(ctx, service.AccessRuleModel{Action: "allow", Match: "exact", Pattern: "11.0.0.2"})
+	assert.Nil(t, err)
+	assert.Equal(t, id, replaced)
+	assert.Nil(t, rateService.DeleteAccessRule(ctx, id))
+	assert.ErrorIs(t, rateService.DeleteAccessRule(ctx, id), domain.ErrAccessRuleNotFound)
+}
+
+// flakyAccessRuleRepositoryFactory counts listings of the access rules, fails them while failing
+// is set and holds them until gate is closed if it is set.
+type flakyAccessRuleRepositoryFactory struct {
+	repository.AccessRuleRepositoryFactory
+	failing atomic.Bool
+	lists   atomic.Int32
+	gate    chan struct{}
+}
+
+func (f *flakyAccessRuleRepositoryFactory) New(handler db.DbHandler) repository.AccessRuleRepository {
+	return &flakyAccessRuleRepository{AccessRuleRepository: f.AccessRuleRepositoryFactory.New(handler), factory: f}
+}
+
+type flakyAccessRuleRepository struct {
+	repository.AccessRuleRepository
+	factory *flakyAccessRuleRepositoryFactory
+}
+
+func (r *flakyAccessRuleRepository) ListAccessRules(ctx context.Context) ([]model.AccessRule, error) {
+	r.factory.lists.Add(1)
+	if r.factory.gate != nil {
+		<-r.factory.gate
+	}
+	if r.factory.failing.Load() {
+		return nil, errors.New("connection reset")
+	}
+	return r.AccessRuleRepository.ListAccessRules(ctx)
+}
+
+func TestRateLimitService_AccessLists_KeptWhenReloadFails(t *testing.T) {
+	ctx := context.Background()
+	memoryCache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, memoryCache.Connect())
+	t.Cleanup(func() { memoryCache.Disconnect() })
+	repoFactory := &flakyAccessRuleRepositoryFactory{AccessRuleRepositoryFactory: memory.NewAccessRuleRepositoryFactory()}
+	rateService := NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), memoryCache, memory.NewTransactionFactory(memory.NewStore()), time.Minute,
+		WithAccessLists(repoFactory, time.Minute))
+
+	_, err := rateService.SetAccessRule(ctx, service.AccessRuleModel{Action: "deny", Match: "exact", Pattern: "abuser"})
+	assert.Nil(t, err)
+	decision, err := rateService.Check(ctx, service.CheckRequest{UserId: "abuser"})
+	assert.Nil(t, err)
+	assert.Equal(t, service.ReasonDenylisted, decision.Reason)
+
+	// The rules a change dropped stay in use while reloading them fails
+	repoFactory.failing.Store(true)
+	rateService.forgetAccessRules()
+	decision, err = rateService.Check(ctx, service.CheckRequest{UserId: "abuser"})
+	assert.Nil(t, err)
+	assert.Equal(t, service.ReasonDenylisted, decision.Reason)
+
+	// The next change reloads them
+	repoFactory.failing.Store(false)
+	_, err = rateService.SetAccessRule(ctx, service.AccessRuleModel{Action: "allow", Match: "exact", Pattern: "partner"})
+	assert.Nil(t, err)
+	decision, err = rateService.Check(ctx, service.CheckRequest{UserId: "partner"})
+	assert.Nil(t, err)
+	assert.Equal(t, service.ReasonAllowlisted, decision.Reason)
+}
+
+func TestRateLimitService_AccessLists_SlowReload(t *testing.T) {
+	ctx := context.Background()
+	memoryCache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, memoryCache.Connect())
+	t.Cleanup(func() { memoryCache.Disconnect() })
+	repoFactory := &flakyAccessRuleRepositoryFactory{AccessRuleRepositoryFactory: memory.NewAccessRuleRepositoryFactory()}
+	rateService := NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), memoryCache, memory.NewTransactionFactory(memory.NewStore()), time.Minute,
+		WithAccessLists(repoFactory, time.Minute))
+	_, err := rateService.SetAccessRule(ctx, service.AccessRuleModel{Action: "deny", Match: "exact", Pattern: "abuser"})
+	assert.Nil(t, err)
+
+	// Lookups while the rules are being loaded share that one load
+	repoFactory.lists.Store(0)
+	repoFactory.gate = make(chan struct{})
+	var wg sync.WaitGroup
+	check := func() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			decision, err := rateService.Check(ctx, service.CheckRequest{UserId: "abuser"})
+			assert.Nil(t, err)
+			assert.Equal(t, service.ReasonDenylisted, decision.Reason)
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		check()
+	}
+	assert.Eventually(t, func() bool { return repoFactory.lists.Load() == 1 }, time.Second, time.Millisecond)
+	close(repoFactory.gate)
+	wg.Wait()
+	assert.Equal(t, int32(1), repoFactory.lists.Load())
+
+	// The cached rules are not held while they load, so changes don't wait for it
+	repoFactory.gate = make(chan struct{})
+	rateService.forgetAccessRules()
+	check()
+	assert.Eventually(t, func() bool { return repoFactory.lists.Load() == 2 }, time.Second, time.Millisecond)
+	forgotten := make(chan struct{})
+	go func() {
+		rateService.forgetAccessRules()
+		close(forgotten)
+	}()
+	select {
+	case <-forgotten:
+	case <-time.After(time.Second):
+		t.Fatal("forgetting the access rules waited for the load in progress")
+	}
+	close(repoFactory.gate)
+	wg.Wait()
+
+	// Rules loaded before the change are not kept, the next lookup loads them again
+	repoFactory.gate = nil
+	check()
+	wg.Wait()
+	assert.Equal(t, int32(3), repoFactory.lists.Load())
+}
+
+func TestRateLimitService_AccessRuleValidation(t *testing.T) {
+	ctx := context.Background()
+	rateService := NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), unavailableCache{}, memory.NewTransactionFactory(memory.NewStore()), time.Minute,
+		WithAccessLists(memory.NewAccessRuleRepositoryFactory(), time.Minute))
+
+	_, err := rateService.SetAccessRule(ctx, service.AccessRuleModel{Action: "block", Match: "exact", Pattern: "user"})
+	assert.ErrorIs(t, err, domain.ErrInvalidAccessRule)
+	_, err = rateService.SetAccessRule(ctx, service.AccessRuleModel{Action: "deny", Match: "prefix"})
+	assert.ErrorIs(t, err, domain.ErrInvalidAccessRule)
+	_, err = rateService.SetAccessRule(ctx, service.AccessRuleModel{Action: "deny", Match: "cidr", Pattern: "10.0.0.1"})
+	assert.ErrorIs(t, err, domain.ErrInvalidCIDR)
+	_, err = rateService.SetAccessRule(ctx, service.AccessRuleModel{Action: "deny", Match: "exact", Pattern: "user", ExpiresAt: time.Now().Add(-time.Minute)})
+	assert.ErrorIs(t, err, domain.ErrAccessRuleExpired)
+
+	disabled := NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), unavailableCache{}, memory.NewTransactionFactory(memory.NewStore()), time.Minute)
+	_, err = disabled.SetAccessRule(ctx, service.AccessRuleModel{Action: "deny", Match: "exact", Pattern: "user"})
+	assert.ErrorIs(t, err, domain.ErrAccessListsDisabled)
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	domainModel "github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+	"github.com/nullexp/limiter-x/internal/port/driver/service"
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultAuditPageSize is how many events ListAuditEvents returns when no limit is given.
+	defaultAuditPageSize = 100
+	// maxAuditPageSize caps how many events ListAuditEvents returns at once.
+	maxAuditPageSize = 1000
+)
+
+// WithAuditLog records every change of a user's limit in the audit log, along with who made it,
+// and each denied request with probability denySampleRate, so that throttling can be explained
+// without writing every denial to the database.
+func WithAuditLog(repoFactory repository.AuditEventRepositoryFactory, denySampleRate float64) Option {
+	return func(rls *RateLimitService) {
+		rls.auditRepoFactory = repoFactory
+		rls.denySampleRate = denySampleRate
+	}
+}
+
+// auditLimitChange appends the change of the user's limit from oldLimit to newLimit within the
+// transaction making it, so the change and its record are stored together. A nil oldLimit
+// means the user had no limit of their own.
+func (rls *RateLimitService) auditLimitChange(ctx context.Context, tx db.DbHandler, userId string, oldLimit, newLimit *domainModel.UserRateLimit) error {
+	if rls.auditRepoFactory == nil {
+		return nil
+	}
+
+	event := domainModel.AuditEvent{
+		Key:      userId,
+		Actor:    service.ActorFromContext(ctx),
+		Type:     domainModel.AuditLimitChanged,
+		NewValue: describeLimit(newLimit),
+	}
+	if oldLimit != nil && oldLimit.RateLimit > 0 {
+		event.OldValue = describeLimit(oldLimit)
+	}
+	if err := rls.auditRepoFactory.New(tx).AppendEvent(ctx, event); err != nil {
+		return errors.Wrap(err, "failed to append audit event")
+	}
+	return nil
+}
+
+// describeLimit formats a user's limit for the audit log, e.g. "100", or "100 per 30s" for a
+// user with a window of their own.
+func describeLimit(rateLimit *domainModel.UserRateLimit) string {
+	if rateLimit.Window > 0 {
+		return fmt.Sprintf("%d per %s", rateLimit.RateLimit, rateLimit.Window)
+	}
+	return fmt.Sprint(rateLimit.RateLimit)
+}
+
+// auditQuotaChange appends the change of a key's quota from oldQuota to newQuota within the
+// transaction making it. A nil quota means there was none before, or none is left after.
+func (rls *RateLimitService) auditQuotaChange(ctx context.Context, tx db.DbHandler, key string, oldQuota, newQuota *domainModel.Quota) error {
+	if rls.auditRepoFactory == nil {
+		return nil
+	}
+
+	event := domainModel.AuditEvent{
+		Key:      key,
+		Actor:    service.ActorFromContext(ctx),
+		Type:     domainModel.AuditQuotaChanged,
+		OldValue: describeQuota(oldQuota),
+		NewValue: describeQuota(newQuota),
+	}
+	if err := rls.auditRepoFactory.New(tx).AppendEvent(ctx, event); err != nil {
+		return errors.Wrap(err, "failed to append audit event")
+	}
+	return nil
+}
+
+// auditPolicyChange appends the change of a key's policy from oldLimits to newLimits within the
+// transaction making it. No limits means there was no policy before, or none is left after.
+func (rls *RateLimitService) auditPolicyChange(ctx context.Context, tx db.DbHandler, key string, oldLimits, newLimits []domainModel.PolicyLimit) error {
+	if rls.auditRepoFactory == nil {
+		return nil
+	}
+
+	event := domainModel.AuditEvent{
+		Key:      key,
+		Actor:    service.ActorFromContext(ctx),
+		Type:     domainModel.AuditPolicyChanged,
+		OldValue: describePolicy(oldLimits),
+		NewValue: describePolicy(newLimits),
+	}
+	if err := rls.auditRepoFactory.New(tx).AppendEvent(ctx, event); err != nil {
+		return errors.Wrap(err, "failed to append audit event")
+	}
+	return nil
+}
+
+// auditParentChange appends the change of a key's parent from the existing link to parent within
+// the transaction making it. An empty parent means the parent was deleted.
+func (rls *RateLimitService) auditParentChange(ctx context.Context, tx db.DbHandler, key string, existing *domainModel.KeyParent, parent string) error {
+	if rls.auditRepoFactory == nil {
+		return nil
+	}
+
+	event := domainModel.AuditEvent{
+		Key:      key,
+		Actor:    service.ActorFromContext(ctx),
+		Type:     domainModel.AuditParentChanged,
+		NewValue: parent,
+	}
+	if existing != nil {
+		event.OldValue = existing.Parent
+	}
+	if err := rls.auditRepoFactory.New(tx).AppendEvent(ctx, event); err != nil {
+		return errors.Wrap(err, "failed to append audit event")
+	}
+	return nil
+}
+
+// auditTierChange appends the change of a tier's limit, or of the tier a role or plan is mapped
+// to, within the transaction making it. The key is the tier or the role or plan.
+func (rls *RateLimitService) auditTierChange(ctx context.Context, tx db.DbHandler, key, oldValue, newValue string) error {
+	if rls.auditRepoFactory == nil {
+		return nil
+	}
+
+	event := domainModel.AuditEvent{
+		Key:      key,
+		Actor:    service.ActorFromContext(ctx),
+		Type:     domainModel.AuditTierChanged,
+		OldValue: oldValue,
+		NewValue: newValue,
+	}
+	if err := rls.auditRepoFactory.New(tx).AppendEvent(ctx, event); err != nil {
+		return errors.Wrap(err, "failed to append audit event")
+	}
+	return nil
+}
+
+// auditAccessChange appends the change of an access rule from oldRule to newRule within the
+// transaction making it. A nil rule means there was none before, or none is left after.
+func (rls *RateLimitService) auditAccessChange(ctx context.Context, tx db.DbHandler, oldRule, newRule *domainModel.AccessRule) error {
+	if rls.auditRepoFactory == nil {
+		return nil
+	}
+
+	event := domainModel.AuditEvent{
+		Actor:    service.ActorFromContext(ctx),
+		Type:     domainModel.AuditAccessChanged,
+		OldValue: describeAccessRule(oldRule),
+		NewValue: describeAccessRule(newRule),
+	}
+	for _, rule := range []*domainModel.AccessRule{oldRule, newRule} {
+		if rule != nil {
+			event.Key, event.Detail = rule.Pattern, rule.Reason
+		}
+	}
+	if err := rls.auditRepoFactory.New(tx).AppendEvent(ctx, event); err != nil {
+		return errors.Wrap(err, "failed to append audit event")
+	}
+	return nil
+}
+
+// auditOverrideChange appends the change of the global override from oldOverride to newOverride
+// within the transaction making it. A nil override means there was none before, or none is left
+// after. The event has no key, as the override applies to all of them.
+func (rls *RateLimitService) auditOverrideChange(ctx context.Context, tx db.DbHandler, oldOverride, newOverride *domainModel.Override) error {
+	if rls.auditRepoFactory == nil {
+		return nil
+	}
+
+	event := domainModel.AuditEvent{
+		Actor:    service.ActorFromContext(ctx),
+		Type:     domainModel.AuditOverrideChanged,
+		OldValue: describeOverride(oldOverride),
+		NewValue: describeOverride(newOverride),
+	}
+	if newOverride != nil {
+		event.Detail = newOverride.Reason
+	}
+	if err := rls.auditRepoFactory.New(tx).AppendEvent(ctx, event); err != nil {
+		return errors.Wrap(err, "failed to append audit event")
+	}
+	return nil
+}
+
+// describeOverride renders an override for the audit log, e.g. "multiplier 0.5 until
+// 2024-01-02T15:04:05Z". Expired overrides are described as none.
+func describeOverride(override *domainModel.Override) string {
+	if override == nil || !override.Active(time.Now()) {
+		return ""
+	}
+	description := string(override.Mode)
+	if override.Mode == domainModel.OverrideMultiplier {
+		description += fmt.Sprintf(" %g", override.Multiplier)
+	}
+	return description + " until " + override.ExpiresAt.UTC().Format(time.RFC3339)
+}
+
+// describeAccessRule renders an access rule for the audit log, e.g. "deny prefix until
+// 2024-01-02T15:04:05Z".
+func describeAccessRule(rule *domainModel.AccessRule) string {
+	if rule == nil {
+		return ""
+	}
+	description := fmt.Sprintf("%s %s", rule.Action, rule.Match)
+	if !rule.ExpiresAt.IsZero() {
+		description += " until " + rule.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	return description
+}
+
+// auditDenied samples a denied request into the audit log along with the limit that denied it.
+// The request has already been decided, so failing to record it is only logged.
+func (rls *RateLimitService) auditDenied(ctx context.Context, userId string, binding *service.LimitModel) {
+	rls.auditSampled(ctx, userId, domainModel.AuditRequestDenied, binding)
+}
+
+// reportShadowDenied logs an allowed request that a shadow limit would have denied, and samples it
+// into the audit log like denied requests.
+func (rls *RateLimitService) reportShadowDenied(ctx context.Context, userId string, binding *service.LimitModel) {
+	log.Printf("request of user %s would have been denied by the shadow limit of %d per %s", userId, binding.Limit, binding.Window)
+	rls.auditSampled(ctx, userId, domainModel.AuditShadowDenied, binding)
+}
+
+// auditSampled samples a request into the audit log as an event of the given type, along with the
+// limit that decided it.
+func (rls *RateLimitService) auditSampled(ctx context.Context, userId string, eventType domainModel.AuditEventType, binding *service.LimitModel) {
+	if rls.auditRepoFactory == nil || rand.Float64() >= rls.denySampleRate {
+		return
+	}
+
+	detail := "denied while the cache was unavailable"
+	if binding != nil {
+		per := binding.Period
+		if per == "" {
+			per = binding.Window.String()
+		}
+		detail = fmt.Sprintf("%d of %d requests used per %s, resets at %s", binding.Limit-binding.Remaining, binding.Limit, per,
+			binding.ResetsAt.UTC().Format(time.RFC3339))
+	}
+
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		return rls.auditRepoFactory.New(tx).AppendEvent(ctx, domainModel.AuditEvent{
+			Key:    userId,
+			Actor:  service.ActorFromContext(ctx),
+			Type:   eventType,
+			Detail: detail,
+		})
+	})
+	if err != nil {
+		log.Printf("failed to audit %s request of user %s: %v", eventType, userId, err)
+	}
+}
+
+// ListAuditEvents returns the audit log entries matching the query, newest first.
+func (rls *RateLimitService) ListAuditEvents(ctx context.Context, query service.AuditQuery) ([]service.AuditEventModel, error) {
+	if rls.auditRepoFactory == nil {
+		return nil, domain.ErrAuditLogDisabled
+	}
+
+	filter := domainModel.AuditFilter{
+		Key:   query.Key,
+		Actor: query.Actor,
+		Type:  domainModel.AuditEventType(query.EventType),
+		From:  query.From,
+		To:    query.To,
+		Limit: query.Limit,
+	}
+	switch filter.Type {
+	case "", domainModel.AuditLimitChanged, domainModel.AuditRequestDenied, domainModel.AuditQuotaChanged,
+		domainModel.AuditPolicyChanged, domainModel.AuditParentChanged, domainModel.AuditTierChanged,
+		domainModel.AuditAccessChanged, domainModel.AuditShadowDenied, domainModel.AuditOverrideChanged:
+	default:
+		return nil, domain.ErrInvalidAuditEventType
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditPageSize
+	}
+	if filter.Limit > maxAuditPageSize {
+		filter.Limit = maxAuditPageSize
+	}
+
+	var events []domainModel.AuditEvent
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		var err error
+		events, err = rls.auditRepoFactory.New(tx).ListEvents(ctx, filter)
+		if err != nil {
+			return errors.Wrap(err, "failed to list audit events")
+		}
+		return nil
+	}, db.ReadOnly())
+	if err != nil {
+		return nil, err
+	}
+
+	models := make([]service.AuditEventModel, 0, len(events))
+	for _, event := range events {
+		models = append(models, service.AuditEventModel{
+			Id:        event.Id,
+			Key:       event.Key,
+			Actor:     event.Actor,
+			EventType: string(event.Type),
+			OldValue:  event.OldValue,
+			NewValue:  event.NewValue,
+			Detail:    event.Detail,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+	return models, nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/cache"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/db/memory"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/port/driver/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAuditTestService(t *testing.T, denySampleRate float64) *RateLimitService {
+	cache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, cache.Connect())
+	t.Cleanup(func() { cache.Disconnect() })
+
+	return NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), cache, memory.NewTransactionFactory(memory.NewStore()), time.Second*10,
+		WithAuditLog(memory.NewAuditEventRepositoryFactory(), denySampleRate))
+}
+
+func TestRateLimitService_AuditsLimitChanges(t *testing.T) {
+	rateService := newAuditTestService(t, 0)
+	ctx := service.ContextWithActor(context.Background(), "support@example.com")
+	userId := uuid.New().String()
+
+	assert.Nil(t, rateService.UpdateUserRateLimit(ctx, userId, 10, 0))
+	assert.Nil(t, rateService.UpdateUserRateLimit(ctx, userId, 25, 0))
+
+	events, err := rateService.ListAuditEvents(ctx, service.AuditQuery{Key: userId})
+	assert.Nil(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, "limit_changed", events[0].EventType)
+	assert.Equal(t, "support@example.com", events[0].Actor)
+	assert.Equal(t, "10", events[0].OldValue)
+	assert.Equal(t, "25", events[0].NewValue)
+	assert.Equal(t, "", events[1].OldValue, "the user had no limit of their own")
+
+	rateLimit, err := rateService.GetUserRateLimit(ctx, userId)
+	assert.Nil(t, err)
+	assert.Equal(t, 25, rateLimit.Limit)
+}
+
+func TestRateLimitService_AuditsSampledDenials(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		name           string
+		denySampleRate float64
+		expect         int
+	}{
+		{name: "Every denial", denySampleRate: 1, expect: 2},
+		{name: "No denials", denySampleRate: 0, expect: 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			rateService := newAuditTestService(t, test.denySampleRate)
+			userId := uuid.New().String()
+
+			for i := 0; i < 3; i++ {
+				_, err := rateService.RateLimit(ctx, userId, 1)
+				assert.Nil(t, err)
+			}
+
+			events, err := rateService.ListAuditEvents(ctx, service.AuditQuery{Key: userId, EventType: "request_denied"})
+			assert.Nil(t, err)
+			assert.Len(t, events, test.expect)
+			for _, event := range events {
+				assert.Contains(t, event.Detail, "1 of 1 requests used")
+			}
+		})
+	}
+}
+
+func TestRateLimitService_ListAuditEvents(t *testing.T) {
+	ctx := context.Background()
+	rateService := newAuditTestService(t, 0)
+	before := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, rateService.UpdateUserRateLimit(service.ContextWithActor(ctx, "admin"), uuid.New().String(), 10, 0))
+	}
+
+	events, err := rateService.ListAuditEvents(ctx, service.AuditQuery{Actor: "admin", From: before, Limit: 2})
+	assert.Nil(t, err)
+	assert.Len(t, events, 2)
+	assert.False(t, events[0].CreatedAt.Before(events[1].CreatedAt), "newest first")
+
+	events, err = rateService.ListAuditEvents(ctx, service.AuditQuery{To: before})
+	assert.Nil(t, err)
+	assert.Empty(t, events)
+
+	_, err = rateService.ListAuditEvents(ctx, service.AuditQuery{EventType: "deleted"})
+	assert.Equal(t, domain.ErrInvalidAuditEventType, err)
+
+	_, err = NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), unavailableCache{}, memory.NewTransactionFactory(memory.NewStore()), time.Second).
+		ListAuditEvents(ctx, service.AuditQuery{})
+	assert.Equal(t, domain.ErrAuditLogDisabled, err)
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	domainModel "github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driver/service"
+)
+
+// consumption is what counting units against every limit of a user came to.
+type consumption struct {
+	granted int
+	// state is the user's single-limit state, nil if a quota or policy decided alone.
+	state *domainModel.UserRateLimit
+	// pools are the keys of the ancestors whose policies the granted units were counted against.
+	pools []string
+	// bounds are the limits counted against, with what is left of them afterwards.
+	bounds []service.LimitModel
+}
+
+// binding returns the limit that decided a request for units: one without room left if fewer
+// were granted, otherwise the one with the least room. A request denied while every limit had
+// room, as by a failure policy, was not decided by any of them. Shadow limits decide nothing.
+func (c consumption) binding(units int) *service.LimitModel {
+	var binding *service.LimitModel
+	for i := range c.bounds {
+		if c.bounds[i].Shadow {
+			continue
+		}
+		if binding == nil || c.bounds[i].Remaining < binding.Remaining {
+			binding = &c.bounds[i]
+		}
+	}
+	if binding != nil && c.granted < units && binding.Remaining > 0 {
+		return nil
+	}
+	return binding
+}
+
+// shadowBinding returns the shadow limit counted furthest past its limit, which would have denied
+// the granted units if it was enforced, nil if every shadow limit had room for them.
+func (c consumption) shadowBinding() *service.LimitModel {
+	var binding *service.LimitModel
+	for i := range c.bounds {
+		if c.bounds[i].Shadow && c.bounds[i].Remaining < 0 && (binding == nil || c.bounds[i].Remaining < binding.Remaining) {
+			binding = &c.bounds[i]
+		}
+	}
+	return binding
+}
+
+// Check counts a request against the user's quotas and short-term limits together and reports
+// the limit that decided it.
+func (rls *RateLimitService) Check(ctx context.Context, request service.CheckRequest) (*service.DecisionModel, error) {
+	if err := rls.validateWindow(request.Window); err != nil {
+		return nil, err
+	}
+
+	// The global override decides before anything else
+	if decision := rls.overrideDecision(request.UserId); decision != nil {
+		return decision, nil
+	}
+
+	// Allowlisted and denylisted keys are decided without counting
+	rule, err := rls.accessRuleFor(ctx, request.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if rule != nil {
+		return rls.accessDecision(request.UserId, rule), nil
+	}
+
+	// Banned keys are denied without counting, and their denials don't extend the ban
+	if penalty := rls.penaltyOf(ctx, request.UserId); penalty != nil {
+		return rls.penaltyDecision(request.UserId, penalty), nil
+	}
+
+	var (
+		result consumption
+		undo   undoLog
+	)
+	err = rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		// Counts of a failed attempt stay in the cache, give them back before counting again
+		undo.rollback(ctx)
+		var err error
+		result, err = rls.consumeLimits(ctx, tx, &undo, request.UserId, request.Role, request.Limit, request.Window, 1)
+		return err
+	})
+	if err != nil {
+		undo.rollback(ctx)
+		return nil, err
+	}
+
+	decision := &service.DecisionModel{Allowed: result.granted > 0, Binding: result.binding(1)}
+	if !decision.Allowed {
+		rls.recordUsage(request.UserId, 0, 1)
+		rls.auditDenied(ctx, request.UserId, decision.Binding)
+		rls.recordViolation(ctx, request.UserId)
+		return decision, nil
+	}
+	rls.recordUsage(request.UserId, 1, 0)
+	if decision.Shadow = result.shadowBinding(); decision.Shadow != nil {
+		rls.reportShadowDenied(ctx, request.UserId, decision.Shadow)
+	}
+	return decision, nil
+}
+
+// consumeLimits takes up to units requests from the user's quotas, the pools of the user's
+// ancestors and the user's short-term limits. The short-term limits are those of the user's
+// policy if there is one, and the user's single limit otherwise, which limit and window replace
+// when they are non-zero and which is the limit of the tier of the user's role if the user has no
+// limit of their own. Each level caps how many units the next is asked for, so requests one
+// denies are not counted against the levels below it, and units a lower level denies are refunded
+// to the pools. Only the units granted in the end are counted against the quotas. Units counted
+// in the cache are recorded in undo, to be given back if the transaction fails.
+func (rls *RateLimitService) consumeLimits(ctx context.Context, tx db.DbHandler, undo *undoLog, userId, role string, limit int, window time.Duration, units int) (result consumption, err error) {
+	quotas, err := rls.currentQuotas(ctx, tx, userId, time.Now())
+	if err != nil {
+		return result, err
+	}
+	allowance := units
+	for _, quota := range quotas {
+		if remaining := quota.remaining(); remaining < int64(allowance) {
+			allowance = int(remaining)
+		}
+	}
+
+	var pools []pool
+	defer func() {
+		// Nothing is granted when a step fails, give back what the pools still count. In row
+		// locking mode their counts roll back with the transaction.
+		if err != nil && !rls.rowLocking {
+			rls.refundPools(ctx, tx, pools, 0)
+		}
+	}()
+	if allowance > 0 {
+		ancestors, err := rls.ancestorsOf(ctx, tx, userId)
+		if err != nil {
+			return result, err
+		}
+		pools, allowance, result.bounds, err = rls.consumePools(ctx, tx, ancestors, allowance)
+		if err != nil {
+			return result, err
+		}
+		if !rls.rowLocking && len(pools) > 0 {
+			// Refunding down to nothing gives back whatever the pools still count by then
+			undo.add(func(ctx context.Context) {
+				rls.refundPools(ctx, nil, pools, 0)
+			})
+		}
+	}
+
+	if allowance > 0 {
+		policy, err := rls.policyFor(ctx, tx, userId)
+		if err != nil {
+			return result, err
+		}
+		var bounds []service.LimitModel
+		if len(policy) > 0 {
+			var limits []domainModel.PolicyLimit
+			result.granted, limits, err = rls.consumePolicy(ctx, tx, undo, userId, policy, allowance)
+			bounds = policyBounds(limits)
+		} else {
+			var fallback int
+			if fallback, err = rls.fallbackLimit(ctx, tx, role); err != nil {
+				return result, err
+			}
+			result.granted, result.state, err = rls.consume(ctx, tx, undo, userId, limit, fallback, window, allowance)
+			bounds = rls.stateBounds(limit, fallback, window, result.state)
+		}
+		if err != nil {
+			return result, err
+		}
+		result.bounds = append(result.bounds, bounds...)
+	}
+
+	if err := rls.refundPools(ctx, tx, pools, result.granted); err != nil {
+		return result, err
+	}
+	if result.granted > 0 {
+		for _, pool := range pools {
+			result.pools = append(result.pools, pool.key)
+		}
+	}
+
+	if result.granted > 0 && len(quotas) > 0 {
+		if err := rls.addQuotaUsage(ctx, tx, userId, quotas, result.granted); err != nil {
+			return result, err
+		}
+	}
+	for _, quota := range quotas {
+		result.bounds = append(result.bounds, quota.bound())
+	}
+	return result, nil
+}
+
+// stateBounds describes the user's single limit from its counted state. A state that was never
+// stored, like those of requests counted while the cache is unavailable, has no count to go by.
+func (rls *RateLimitService) stateBounds(limit, fallback int, window time.Duration, state *domainModel.UserRateLimit) []service.LimitModel {
+	if state == nil || state.Version == 0 {
+		return nil
+	}
+
+	effectiveLimit := rls.limitOf(limit, fallback, state)
+	effectiveWindow := rls.windowOf(window, state)
+	remaining := effectiveLimit - state.RequestCount
+	if remaining < 0 {
+		remaining = 0
+	}
+	return []service.LimitModel{{
+		Limit:     int64(effectiveLimit),
+		Window:    effectiveWindow,
+		Remaining: int64(remaining),
+		ResetsAt:  state.Timestamp.Add(effectiveWindow),
+	}}
+}
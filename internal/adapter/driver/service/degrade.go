@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	domainModel "github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/pkg/errors"
+)
+
+// FailurePolicy decides how requests are limited while the shared cache is unavailable.
+type FailurePolicy int
+
+const (
+	// FailToDatabase counts in the repository alone until the cache is back.
+	FailToDatabase FailurePolicy = iota
+	// FailOpen allows every request.
+	FailOpen
+	// FailClosed denies every request.
+	FailClosed
+	// FailLocal counts per instance in memory, dividing each limit by the expected number of
+	// instances so that the fleet as a whole stays close to the global limit.
+	FailLocal
+)
+
+// ParseFailurePolicy converts the configuration value of a failure policy.
+func ParseFailurePolicy(value string) (FailurePolicy, error) {
+	switch strings.ToLower(value) {
+	case "", "database":
+		return FailToDatabase, nil
+	case "open":
+		return FailOpen, nil
+	case "closed":
+		return FailClosed, nil
+	case "local":
+		return FailLocal, nil
+	}
+	return FailToDatabase, fmt.Errorf("unknown cache failure policy %q", value)
+}
+
+// WithFailurePolicy sets how requests are limited while the cache is unavailable. With FailLocal,
+// instances is the number of service instances expected to share the traffic.
+func WithFailurePolicy(policy FailurePolicy, instances int) Option {
+	return func(rls *RateLimitService) {
+		if instances < 1 {
+			instances = 1
+		}
+		rls.failurePolicy = policy
+		rls.local = newLocalLimiter(instances)
+	}
+}
+
+// consumeDegraded limits a request according to the failure policy after the cache failed.
+func (rls *RateLimitService) consumeDegraded(ctx context.Context, tx db.DbHandler, userId string, limit, fallback int, window time.Duration, units int) (int, *domainModel.UserRateLimit, error) {
+	switch rls.failurePolicy {
+	case FailOpen:
+		return units, &domainModel.UserRateLimit{UserId: userId, Timestamp: time.Now()}, nil
+	case FailClosed:
+		return 0, &domainModel.UserRateLimit{UserId: userId, Timestamp: time.Now()}, nil
+	case FailLocal:
+		effectiveLimit, effectiveWindow := rls.limitOf(limit, fallback, nil), window
+		if limit == 0 || window == 0 {
+			rateLimit, err := rls.repoFactory.New(tx).GetRateLimitByUserId(ctx, userId)
+			if err != nil {
+				return 0, nil, errors.Wrap(err, "failed to get user rate limit from repo")
+			}
+			effectiveLimit = rls.limitOf(limit, fallback, rateLimit)
+			effectiveWindow = rls.windowOf(window, rateLimit)
+		}
+		granted, windowStart := rls.local.take(userId, effectiveLimit, units, effectiveWindow)
+		return granted, &domainModel.UserRateLimit{UserId: userId, Timestamp: windowStart}, nil
+	default:
+		return rls.consumeFromRepository(ctx, tx, userId, limit, fallback, window, units, false)
+	}
+}
+
+// localLimiter is a per-instance fixed window counter used while the shared cache is down.
+type localLimiter struct {
+	instances int
+
+	mu        sync.Mutex
+	windows   map[string]*localWindow
+	lastSweep time.Time
+}
+
+type localWindow struct {
+	start  time.Time
+	window time.Duration
+	count  int
+}
+
+func newLocalLimiter(instances int) *localLimiter {
+	return &localLimiter{instances: instances, windows: make(map[string]*localWindow)}
+}
+
+// take grants up to units requests against this instance's share of limit and returns the
+// start of the window they were counted in.
+func (l *localLimiter) take(userId string, limit, units int, window time.Duration) (int, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	w := l.window(userId, window, now)
+	granted := grant(w.count, l.share(limit), units)
+	w.count += granted
+	return granted, w.start
+}
+
+// takePolicy grants up to units requests if this instance's share of every limit of the policy
+// has room for them, counting them against all of the limits. Shadow limits don't deny.
+func (l *localLimiter) takePolicy(userId string, policy []domainModel.PolicyLimit, units int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	windows := make([]*localWindow, len(policy))
+	granted := units
+	for i, limit := range policy {
+		windows[i] = l.window(userId+"/"+limit.Window.String(), limit.Window, now)
+		if !limit.Shadow {
+			granted = grant(windows[i].count, l.share(limit.Limit), granted)
+		}
+	}
+	for _, w := range windows {
+		w.count += granted
+	}
+	return granted
+}
+
+// window returns the current window of the key, sweeping out windows that ended. The caller
+// holds the lock.
+func (l *localLimiter) window(key string, window time.Duration, now time.Time) *localWindow {
+	if now.Sub(l.lastSweep) > window {
+		for key, w := range l.windows {
+			if now.Sub(w.start) > w.window {
+				delete(l.windows, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) > window {
+		w = &localWindow{start: now, window: window}
+		l.windows[key] = w
+	}
+	return w
+}
+
+// share returns this instance's share of a limit.
+func (l *localLimiter) share(limit int) int {
+	share := limit / l.instances
+	if share < 1 && limit > 0 {
+		share = 1
+	}
+	return share
+}
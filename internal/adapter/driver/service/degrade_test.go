@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/db/memory"
+	"github.com/nullexp/limiter-x/internal/port/driven"
+	"github.com/stretchr/testify/assert"
+)
+
+// unavailableCache fails every operation the way a tripped circuit breaker does.
+type unavailableCache struct{}
+
+func (unavailableCache) Connect() error    { return driven.ErrCacheUnavailable }
+func (unavailableCache) Disconnect() error { return nil }
+func (unavailableCache) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	return driven.ErrCacheUnavailable
+}
+
+func (unavailableCache) Fetch(ctx context.Context, key string) ([]byte, error) {
+	return nil, driven.ErrCacheUnavailable
+}
+
+func (unavailableCache) Delete(ctx context.Context, key string) error {
+	return driven.ErrCacheUnavailable
+}
+
+func (unavailableCache) Update(ctx context.Context, key string, expiration time.Duration, fn func(current []byte) ([]byte, error)) error {
+	return driven.ErrCacheUnavailable
+}
+
+func (unavailableCache) Increment(ctx context.Context, key string, delta int64, expiration time.Duration) (int64, error) {
+	return 0, driven.ErrCacheUnavailable
+}
+
+func TestRateLimitService_FailurePolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    FailurePolicy
+		instances int
+		limit     int
+		requests  int
+		expect    []bool
+	}{
+		{
+			name:     "Fail open allows past the limit",
+			policy:   FailOpen,
+			limit:    1,
+			requests: 3,
+			expect:   []bool{true, true, true},
+		},
+		{
+			name:     "Fail closed denies everything",
+			policy:   FailClosed,
+			limit:    10,
+			requests: 2,
+			expect:   []bool{false, false},
+		},
+		{
+			name:      "Local fallback splits the limit across instances",
+			policy:    FailLocal,
+			instances: 2,
+			limit:     4,
+			requests:  3,
+			expect:    []bool{true, true, false},
+		},
+		{
+			name:     "Database fallback counts in the repository",
+			policy:   FailToDatabase,
+			limit:    2,
+			requests: 3,
+			expect:   []bool{true, true, false},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service := NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), unavailableCache{}, memory.NewTransactionFactory(memory.NewStore()), time.Second*10,
+				WithFailurePolicy(test.policy, test.instances))
+
+			userId := uuid.New().String()
+			for i := 0; i < test.requests; i++ {
+				allowed, err := service.RateLimit(context.Background(), userId, test.limit)
+				assert.Nil(t, err)
+				assert.Equal(t, test.expect[i], allowed, "request %d", i+1)
+			}
+		})
+	}
+}
+
+func TestParseFailurePolicy(t *testing.T) {
+	for value, expect := range map[string]FailurePolicy{"": FailToDatabase, "open": FailOpen, "Closed": FailClosed, "local": FailLocal} {
+		policy, err := ParseFailurePolicy(value)
+		assert.Nil(t, err)
+		assert.Equal(t, expect, policy)
+	}
+
+	_, err := ParseFailurePolicy("sometimes")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	domainModel "github.com/nullexp/limiter-x/internal/domain/model"
+)
+
+// WithDeniedMarkers makes the service remember denied users for up to ttl under a separate key.
+// Paired with a near cache that keeps those keys in-process, repeated requests from a user who is
+// over the limit are denied without a round trip to the shared cache.
+func WithDeniedMarkers(ttl time.Duration) Option {
+	return func(rls *RateLimitService) {
+		rls.deniedTTL = ttl
+	}
+}
+
+// knownDenied returns the state recorded when the user was last denied, if the user would still
+// be denied under limit, or fallback if the user has no limit of their own, in the same window.
+// Any failure to read the marker just means counting as usual.
+func (rls *RateLimitService) knownDenied(ctx context.Context, userId string, limit, fallback int, window time.Duration) *domainModel.UserRateLimit {
+	if rls.deniedTTL <= 0 {
+		return nil
+	}
+
+	data, err := rls.cache.Fetch(ctx, deniedKey(userId))
+	if err != nil {
+		return nil
+	}
+
+	var rateLimit domainModel.UserRateLimit
+	if err := json.Unmarshal(data, &rateLimit); err != nil {
+		return nil
+	}
+
+	if rateLimit.RequestCount < rls.limitOf(limit, fallback, &rateLimit) || time.Since(rateLimit.Timestamp) > rls.windowOf(window, &rateLimit) {
+		return nil
+	}
+	return &rateLimit
+}
+
+// markDenied records that the user was denied with the given state, for no longer than the rest
+// of the window it was counted in.
+func (rls *RateLimitService) markDenied(ctx context.Context, rateLimit *domainModel.UserRateLimit, window time.Duration) {
+	if rls.deniedTTL <= 0 {
+		return
+	}
+
+	ttl := rls.deniedTTL
+	if left := time.Until(rateLimit.Timestamp.Add(rls.windowOf(window, rateLimit))); ttl > left {
+		ttl = left
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	data, err := json.Marshal(rateLimit)
+	if err != nil {
+		log.Printf("failed to marshal denied marker: %v", err)
+		return
+	}
+	if err := rls.cache.Set(ctx, deniedKey(rateLimit.UserId), data, ttl); err != nil {
+		log.Printf("failed to store denied marker for user %s: %v", rateLimit.UserId, err)
+	}
+}
+
+// clearDenied drops the user's denied marker after quota was given back or the limit changed.
+func (rls *RateLimitService) clearDenied(ctx context.Context, userId string) error {
+	if rls.deniedTTL <= 0 {
+		return nil
+	}
+	return rls.cache.Delete(ctx, deniedKey(userId))
+}
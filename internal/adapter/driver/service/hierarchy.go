@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	domainModel "github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+	"github.com/nullexp/limiter-x/internal/port/driver/service"
+	"github.com/pkg/errors"
+)
+
+// maxAncestors is the most ancestors a key may have, which bounds the pools a request of a key is
+// counted against.
+const maxAncestors = 8
+
+// WithHierarchy counts the requests of keys that have a parent against the policies of all of
+// their ancestors too, so that an organization's policy is a pool its teams and users draw from.
+// Parent links are cached in-process for cacheTTL.
+func WithHierarchy(repoFactory repository.ParentRepositoryFactory, cacheTTL time.Duration) Option {
+	return func(rls *RateLimitService) {
+		rls.parentRepoFactory = repoFactory
+		rls.parents = newKeyCache[string](cacheTTL)
+	}
+}
+
+// pool is an ancestor's policy that granted units of a request.
+type pool struct {
+	key     string
+	policy  []domainModel.PolicyLimit
+	granted int
+}
+
+// ancestorsOf returns the ancestors of the key from its parent up to the root, none if the key
+// has no parent or hierarchies are not enabled.
+func (rls *RateLimitService) ancestorsOf(ctx context.Context, tx db.DbHandler, key string) ([]string, error) {
+	if rls.parentRepoFactory == nil {
+		return nil, nil
+	}
+
+	now := time.Now()
+	seen := map[string]bool{key: true}
+	var ancestors []string
+	for len(ancestors) < maxAncestors {
+		parent, ok := rls.parents.get(key, now)
+		if !ok {
+			link, err := rls.parentRepoFactory.New(tx).GetParent(ctx, key)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to get parent from repo")
+			}
+			parent = ""
+			if link != nil {
+				parent = link.Parent
+			}
+			rls.parents.put(key, parent, now)
+		}
+		// Links set concurrently may form a cycle, which ends the chain where it closes
+		if parent == "" || seen[parent] {
+			break
+		}
+		seen[parent] = true
+		ancestors = append(ancestors, parent)
+		key = parent
+	}
+	return ancestors, nil
+}
+
+// consumePools takes up to units requests from the policies of the ancestors, starting at the
+// root. Each pool is asked for no more than the one above it granted, so the last one's grant is
+// what all of them have room for. It returns the pools that granted units, that grant, and the
+// limits of every pool counted against. On failure it still returns the pools counted so far, so
+// their units can be given back.
+func (rls *RateLimitService) consumePools(ctx context.Context, tx db.DbHandler, ancestors []string, units int) ([]pool, int, []service.LimitModel, error) {
+	var (
+		pools  []pool
+		bounds []service.LimitModel
+	)
+	for i := len(ancestors) - 1; i >= 0 && units > 0; i-- {
+		policy, err := rls.policyFor(ctx, tx, ancestors[i])
+		if err != nil {
+			return pools, 0, nil, err
+		}
+		if len(policy) == 0 {
+			// Keys without a policy only group their children
+			continue
+		}
+
+		granted, limits, err := rls.consumePolicy(ctx, tx, nil, ancestors[i], policy, units)
+		if err != nil {
+			return pools, 0, nil, err
+		}
+		bounds = append(bounds, policyBounds(limits)...)
+		if granted > 0 {
+			pools = append(pools, pool{key: ancestors[i], policy: policy, granted: granted})
+		}
+		units = granted
+	}
+	return pools, units, bounds, nil
+}
+
+// refundPools gives back what each pool granted beyond the units the request was granted in the
+// end, and lowers what the pool counts as granted to match. A request is counted against the
+// pools one after the other rather than in one atomic update, so for that moment a pool may count
+// units that a lower level then denied. Counts in the cache that cannot be refunded just stay
+// counted until their window ends.
+func (rls *RateLimitService) refundPools(ctx context.Context, tx db.DbHandler, pools []pool, granted int) error {
+	now := time.Now()
+	for i := range pools {
+		excess := pools[i].granted - granted
+		if excess <= 0 {
+			continue
+		}
+		if _, err := rls.creditPolicyCounts(ctx, tx, pools[i].key, pools[i].policy, excess, now); err != nil {
+			if rls.rowLocking {
+				return err
+			}
+			log.Printf("failed to refund %d requests to the pool of %s: %v", excess, pools[i].key, err)
+		}
+		pools[i].granted = granted
+	}
+	return nil
+}
+
+// leasedPools returns the pools of a lease that still have a policy to credit.
+func (rls *RateLimitService) leasedPools(ctx context.Context, tx db.DbHandler, keys []string) ([]pool, error) {
+	var pools []pool
+	for _, key := range keys {
+		policy, err := rls.policyFor(ctx, tx, key)
+		if err != nil {
+			return nil, err
+		}
+		if len(policy) > 0 {
+			pools = append(pools, pool{key: key, policy: policy})
+		}
+	}
+	return pools, nil
+}
+
+// creditPools gives units of a lease taken at leasedAt back to the pools it was counted against.
+func (rls *RateLimitService) creditPools(ctx context.Context, pools []pool, units int, leasedAt time.Time) error {
+	for _, pool := range pools {
+		if _, err := rls.creditPolicy(ctx, pool.key, pool.policy, units, leasedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetParent makes parent the parent of the key, so the key's requests are also counted against
+// the policies of parent and its ancestors.
+func (rls *RateLimitService) SetParent(ctx context.Context, key, parent string) error {
+	if rls.parentRepoFactory == nil {
+		return domain.ErrHierarchyDisabled
+	}
+	if parent == "" || parent == key {
+		return domain.ErrInvalidParent
+	}
+
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		repo := rls.parentRepoFactory.New(tx)
+
+		// Cached links may be stale, check the stored ones
+		chain, err := storedChain(ctx, repo, parent)
+		if err != nil {
+			return err
+		}
+		for _, ancestor := range chain {
+			if ancestor == key {
+				return domain.ErrParentCycle
+			}
+		}
+		height, err := subtreeHeight(ctx, repo, key, maxAncestors-len(chain))
+		if err != nil {
+			return err
+		}
+		if len(chain)+height > maxAncestors {
+			return domain.ErrHierarchyTooDeep
+		}
+
+		existing, err := repo.GetParent(ctx, key)
+		if err != nil {
+			return errors.Wrap(err, "failed to get parent from repo")
+		}
+		if err := repo.SetParent(ctx, key, parent); err != nil {
+			return errors.Wrap(err, "failed to set parent in repo")
+		}
+		return rls.auditParentChange(ctx, tx, key, existing, parent)
+	})
+	if err != nil {
+		return err
+	}
+	rls.parents.forget(key)
+	return nil
+}
+
+// DeleteParent removes the parent of the key, which no longer draws from its former ancestors.
+func (rls *RateLimitService) DeleteParent(ctx context.Context, key string) error {
+	if rls.parentRepoFactory == nil {
+		return domain.ErrHierarchyDisabled
+	}
+
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		repo := rls.parentRepoFactory.New(tx)
+		existing, err := repo.GetParent(ctx, key)
+		if err != nil {
+			return errors.Wrap(err, "failed to get parent from repo")
+		}
+		if err := repo.DeleteParent(ctx, key); err != nil {
+			return err
+		}
+		return rls.auditParentChange(ctx, tx, key, existing, "")
+	})
+	if err != nil {
+		return err
+	}
+	rls.parents.forget(key)
+	return nil
+}
+
+// GetHierarchy returns the ancestors of a key, from its parent up to the root, and its children.
+func (rls *RateLimitService) GetHierarchy(ctx context.Context, key string) (*service.HierarchyModel, error) {
+	if rls.parentRepoFactory == nil {
+		return nil, domain.ErrHierarchyDisabled
+	}
+
+	hierarchy := &service.HierarchyModel{Key: key}
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		repo := rls.parentRepoFactory.New(tx)
+		chain, err := storedChain(ctx, repo, key)
+		if err != nil {
+			return err
+		}
+		hierarchy.Ancestors = chain[1:]
+
+		children, err := repo.ListChildren(ctx, key)
+		if err != nil {
+			return errors.Wrap(err, "failed to list children from repo")
+		}
+		hierarchy.Children = make([]string, 0, len(children))
+		for _, child := range children {
+			hierarchy.Children = append(hierarchy.Children, child.Key)
+		}
+		return nil
+	}, db.ReadOnly())
+	if err != nil {
+		return nil, err
+	}
+	return hierarchy, nil
+}
+
+// storedChain returns the key followed by its stored ancestors, as many as a key may have.
+func storedChain(ctx context.Context, repo repository.ParentRepository, key string) ([]string, error) {
+	chain := []string{key}
+	seen := map[string]bool{key: true}
+	for len(chain) <= maxAncestors {
+		link, err := repo.GetParent(ctx, key)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get parent from repo")
+		}
+		if link == nil || seen[link.Parent] {
+			break
+		}
+		seen[link.Parent] = true
+		chain = append(chain, link.Parent)
+		key = link.Parent
+	}
+	return chain, nil
+}
+
+// subtreeHeight returns how many levels of descendants the key has, counting no further than
+// one past limit.
+func subtreeHeight(ctx context.Context, repo repository.ParentRepository, key string, limit int) (int, error) {
+	level := []string{key}
+	seen := map[string]bool{key: true}
+	height := 0
+	for height <= limit {
+		var next []string
+		for _, parent := range level {
+			children, err := repo.ListChildren(ctx, parent)
+			if err != nil {
+				return 0, errors.Wrap(err, "failed to list children from repo")
+			}
+			for _, child := range children {
+				if !seen[child.Key] {
+					seen[child.Key] = true
+					next = append(next, child.Key)
+				}
+			}
+		}
+		if len(next) == 0 {
+			break
+		}
+		height++
+		level = next
+	}
+	return height, nil
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/cache"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/db/memory"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+	"github.com/nullexp/limiter-x/internal/port/driver/service"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitService_HierarchyPools(t *testing.T) {
+	memoryCache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, memoryCache.Connect())
+	t.Cleanup(func() { memoryCache.Disconnect() })
+
+	tests := []struct {
+		name    string
+		service *RateLimitService
+	}{
+		{name: "Counting in the cache", service: newPolicyTestService(t, memoryCache, WithHierarchy(memory.NewParentRepositoryFactory(), time.Minute))},
+		{name: "Counting in the database", service: newPolicyTestService(t, memoryCache, WithHierarchy(memory.NewParentRepositoryFactory(), time.Minute), WithRowLocking())},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			org, team := uuid.New().String(), uuid.New().String()
+			first, second := uuid.New().String(), uuid.New().String()
+			assert.Nil(t, test.service.SetPolicy(ctx, org, []service.LimitModel{{Limit: 3, Window: time.Minute}}))
+			// The team has no policy of its own and only groups its users
+			assert.Nil(t, test.service.SetParent(ctx, team, org))
+			assert.Nil(t, test.service.SetParent(ctx, first, team))
+			assert.Nil(t, test.service.SetParent(ctx, second, team))
+
+			for _, userId := range []string{first, first, second} {
+				decision, err := test.service.Check(ctx, service.CheckRequest{UserId: userId})
+				assert.Nil(t, err)
+				assert.True(t, decision.Allowed)
+			}
+
+			// The users are well within their own limits, but the organization's pool is used up
+			decision, err := test.service.Check(ctx, service.CheckRequest{UserId: second})
+			assert.Nil(t, err)
+			assert.False(t, decision.Allowed)
+			assert.Equal(t, time.Minute, decision.Binding.Window)
+			assert.Equal(t, int64(0), decision.Binding.Remaining)
+
+			// Keys outside the hierarchy are not affected
+			decision, err = test.service.Check(ctx, service.CheckRequest{UserId: uuid.New().String()})
+			assert.Nil(t, err)
+			assert.True(t, decision.Allowed)
+		})
+	}
+}
+
+func TestRateLimitService_HierarchyRefunds(t *testing.T) {
+	ctx := context.Background()
+	memoryCache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, memoryCache.Connect())
+	t.Cleanup(func() { memoryCache.Disconnect() })
+	rateService := newPolicyTestService(t, memoryCache, WithHierarchy(memory.NewParentRepositoryFactory(), time.Minute))
+	org, userId := uuid.New().String(), uuid.New().String()
+
+	assert.Nil(t, rateService.SetPolicy(ctx, org, []service.LimitModel{{Limit: 10, Window: time.Minute}}))
+	assert.Nil(t, rateService.SetPolicy(ctx, userId, []service.LimitModel{{Limit: 1, Window: time.Minute}}))
+	assert.Nil(t, rateService.SetParent(ctx, userId, org))
+
+	decision, err := rateService.Check(ctx, service.CheckRequest{UserId: userId})
+	assert.Nil(t, err)
+	assert.True(t, decision.Allowed)
+
+	// Requests the user's own limit denies are given back to the pool
+	decision, err = rateService.Check(ctx, service.CheckRequest{UserId: userId})
+	assert.Nil(t, err)
+	assert.False(t, decision.Allowed)
+	limits, err := rateService.GetPolicy(ctx, org)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(9), limits[0].Remaining)
+
+	// Unused units of a lease are returned to the pool too
+	other := uuid.New().String()
+	assert.Nil(t, rateService.SetParent(ctx, other, org))
+	lease, err := rateService.LeaseQuota(ctx, other, 5, 0, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 5, lease.Granted)
+	_, err = rateService.ReturnQuota(ctx, lease.LeaseId, 5)
+	assert.Nil(t, err)
+	limits, err = rateService.GetPolicy(ctx, org)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(9), limits[0].Remaining)
+}
+
+// failingRepositoryFactory fails every read of the rate limit of one user.
+type failingRepositoryFactory struct {
+	repository.UserRateLimitRepositoryFactory
+	userId string
+}
+
+func (f *failingRepositoryFactory) New(handler db.DbHandler) repository.UserRateLimitRepository {
+	return &failingRepository{UserRateLimitRepository: f.UserRateLimitRepositoryFactory.New(handler), userId: f.userId}
+}
+
+type failingRepository struct {
+	repository.UserRateLimitRepository
+	userId string
+}
+
+func (r *failingRepository) GetRateLimitByUserId(ctx context.Context, userId string) (*model.UserRateLimit, error) {
+	if userId == r.userId {
+		return nil, errors.New("connection reset")
+	}
+	return r.UserRateLimitRepository.GetRateLimitByUserId(ctx, userId)
+}
+
+func TestRateLimitService_HierarchyPools_RefundedOnFailure(t *testing.T) {
+	ctx := context.Background()
+	memoryCache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, memoryCache.Connect())
+	t.Cleanup(func() { memoryCache.Disconnect() })
+
+	org, failing, other := uuid.New().String(), uuid.New().String(), uuid.New().String()
+	repoFactory := &failingRepositoryFactory{UserRateLimitRepositoryFactory: memory.NewUserRateLimitRepositoryFactory(), userId: failing}
+	rls := NewRateLimitService(repoFactory, memoryCache, memory.NewTransactionFactory(memory.NewStore()), time.Second*10,
+		WithPolicies(memory.NewPolicyRepositoryFactory(), time.Minute), WithHierarchy(memory.NewParentRepositoryFactory(), time.Minute))
+	assert.Nil(t, rls.SetPolicy(ctx, org, []service.LimitModel{{Limit: 1, Window: time.Minute}}))
+	assert.Nil(t, rls.SetParent(ctx, failing, org))
+	assert.Nil(t, rls.SetParent(ctx, other, org))
+
+	// The pool counts the unit before the user's own limit fails to be read
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		_, err := rls.consumeLimits(ctx, tx, nil, failing, "", 0, 0, 1)
+		return err
+	})
+	assert.Error(t, err)
+
+	// The unit went back to the pool, so the other user still gets it
+	decision, err := rls.Check(ctx, service.CheckRequest{UserId: other})
+	assert.Nil(t, err)
+	assert.True(t, decision.Allowed)
+	decision, err = rls.Check(ctx, service.CheckRequest{UserId: other})
+	assert.Nil(t, err)
+	assert.False(t, decision.Allowed)
+}
+
+func TestRateLimitService_Hierarchy(t *testing.T) {
+	ctx := context.Background()
+	memoryCache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, memoryCache.Connect())
+	t.Cleanup(func() { memoryCache.Disconnect() })
+	rateService := newPolicyTestService(t, memoryCache, WithHierarchy(memory.NewParentRepositoryFactory(), time.Minute))
+
+	prefix := uuid.New().String()
+	keys := make([]string, maxAncestors+2)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%s-%d", prefix, i)
+	}
+	// Every key is the parent of the one before it
+	for i := 0; i < maxAncestors; i++ {
+		assert.Nil(t, rateService.SetParent(ctx, keys[i], keys[i+1]))
+	}
+
+	hierarchy, err := rateService.GetHierarchy(ctx, keys[1])
+	assert.Nil(t, err)
+	assert.Equal(t, keys[2:maxAncestors+1], hierarchy.Ancestors)
+	assert.Equal(t, []string{keys[0]}, hierarchy.Children)
+
+	assert.ErrorIs(t, rateService.SetParent(ctx, keys[0], keys[0]), domain.ErrInvalidParent)
+	assert.ErrorIs(t, rateService.SetParent(ctx, keys[maxAncestors], keys[0]), domain.ErrParentCycle)
+	// The first key would have one ancestor too many
+	assert.ErrorIs(t, rateService.SetParent(ctx, keys[maxAncestors], keys[maxAncestors+1]), domain.ErrHierarchyTooDeep)
+
+	assert.Nil(t, rateService.DeleteParent(ctx, keys[0]))
+	assert.ErrorIs(t, rateService.DeleteParent(ctx, keys[0]), domain.ErrParentNotFound)
+	hierarchy, err = rateService.GetHierarchy(ctx, keys[1])
+	assert.Nil(t, err)
+	assert.Empty(t, hierarchy.Children)
+
+	disabled := newPolicyTestService(t, memoryCache)
+	assert.ErrorIs(t, disabled.SetParent(ctx, keys[0], keys[1]), domain.ErrHierarchyDisabled)
+	_, err = disabled.GetHierarchy(ctx, keys[0])
+	assert.ErrorIs(t, err, domain.ErrHierarchyDisabled)
+}
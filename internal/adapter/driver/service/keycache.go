@@ -0,0 +1,59 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// keyCache keeps values looked up by key in-process for ttl, including the zero value for keys
+// that have none, so repeated lookups of the same key skip the repository.
+type keyCache[V any] struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]keyCacheEntry[V]
+	lastSweep time.Time
+}
+
+type keyCacheEntry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+func newKeyCache[V any](ttl time.Duration) *keyCache[V] {
+	return &keyCache[V]{ttl: ttl, entries: make(map[string]keyCacheEntry[V])}
+}
+
+func (c *keyCache[V]) get(key string, now time.Time) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || now.After(entry.expires) {
+		var zero V
+		return zero, false
+	}
+	return entry.value, true
+}
+
+// put caches the value of the key, sweeping out expired entries once per ttl.
+func (c *keyCache[V]) put(key string, value V, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.lastSweep) > c.ttl {
+		for key, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, key)
+			}
+		}
+		c.lastSweep = now
+	}
+	c.entries[key] = keyCacheEntry[V]{value: value, expires: now.Add(c.ttl)}
+}
+
+func (c *keyCache[V]) forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	deniedKeyPrefix    = "denied:"
+	leaseKeyPrefix     = "lease:"
+	policyKeyPrefix    = "policy:"
+	limitsKeyPrefix    = "limits:"
+	violationKeyPrefix = "violations:"
+	penaltyKeyPrefix   = "penalty:"
+)
+
+// clusterSlots is the number of slots Redis Cluster distributes keys over.
+const clusterSlots = 16384
+
+// The user's counted state lives under the bare user ID. Every other per-user key wraps the ID
+// in a hash tag, so Redis Cluster maps it to the same slot as the state and scripts touching
+// several of a user's keys never cross slots. Braces in the ID would make Redis hash a different
+// part of the key, so such IDs, and the empty one, follow a stand-in tag of the state's slot.
+func slotTag(userId string) string {
+	if userId != "" && !strings.ContainsAny(userId, "{}") {
+		return "{" + userId + "}"
+	}
+	return "{" + slotStandIn(keySlot(userId)) + "}" + userId
+}
+
+// keySlot returns the Redis Cluster slot of key: the CRC16 of its hash tag, the part between the
+// first { and the next } if that is not empty, or of the whole key otherwise.
+func keySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+
+	// CRC16-CCITT (XModem), as in the Redis Cluster specification
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return int(crc) % clusterSlots
+}
+
+var (
+	slotStandInsOnce sync.Once
+	slotStandIns     [clusterSlots]string
+)
+
+// slotStandIn returns the smallest decimal number whose slot is slot.
+func slotStandIn(slot int) string {
+	slotStandInsOnce.Do(func() {
+		for i, missing := 0, clusterSlots; missing > 0; i++ {
+			tag := strconv.Itoa(i)
+			if slot := keySlot(tag); slotStandIns[slot] == "" {
+				slotStandIns[slot] = tag
+				missing--
+			}
+		}
+	})
+	return slotStandIns[slot]
+}
+
+// deniedKey is where a known-denied marker for the user is kept.
+func deniedKey(userId string) string {
+	return deniedKeyPrefix + slotTag(userId)
+}
+
+// policyKey is where the counts of the limits of the user's policy are kept.
+func policyKey(userId string) string {
+	return policyKeyPrefix + slotTag(userId)
+}
+
+// limitsKey is where the limits of the user's policy are cached, without their counts.
+func limitsKey(userId string) string {
+	return limitsKeyPrefix + slotTag(userId)
+}
+
+// violationKey is where the user's recent denials are counted.
+func violationKey(userId string) string {
+	return violationKeyPrefix + slotTag(userId)
+}
+
+// penaltyKey is where the user's penalty is kept.
+func penaltyKey(userId string) string {
+	return penaltyKeyPrefix + slotTag(userId)
+}
+
+// LocalKeyPrefixes lists the prefixes of cache keys whose values may be cached in-process for a
+// short time. They are only written when the underlying state changes, so a near cache can keep
+// them locally as long as it invalidates them on writes. Policy counts change with every request
+// and stay remote; the policies' limits are kept locally.
+func LocalKeyPrefixes() []string {
+	return []string{deniedKeyPrefix, penaltyKeyPrefix, limitsKeyPrefix}
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeySlot(t *testing.T) {
+	// Slots as reported by CLUSTER KEYSLOT
+	tests := []struct {
+		key  string
+		slot int
+	}{
+		{key: "123456789", slot: 12739},
+		{key: "foo", slot: 12182},
+		{key: "bar", slot: 5061},
+		{key: "hello", slot: 866},
+		{key: "{foo}.following", slot: 12182},
+		{key: "x{bar}y{hello}", slot: 5061},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.slot, keySlot(test.key), test.key)
+	}
+
+	// An empty tag means the whole key is hashed
+	assert.NotEqual(t, keySlot("foo"), keySlot("{}foo"))
+}
+
+func TestSlotTag(t *testing.T) {
+	tests := []struct {
+		name   string
+		userId string
+		tag    string
+	}{
+		{name: "Plain ID", userId: "user-1", tag: "{user-1}"},
+		{name: "UUID", userId: "6f1c7c2e-3c5b-4d8a-9f43-0e6a1f6c2b11", tag: "{6f1c7c2e-3c5b-4d8a-9f43-0e6a1f6c2b11}"},
+		{name: "Closing brace", userId: "a}b"},
+		{name: "Opening brace", userId: "a{b"},
+		{name: "Tag in the ID", userId: "{org}user"},
+		{name: "Empty tag in the ID", userId: "{}user"},
+		{name: "Only braces", userId: "}{"},
+		{name: "Empty ID", userId: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.tag != "" {
+				assert.Equal(t, test.tag, slotTag(test.userId))
+			}
+
+			// Every key of the user is in the slot of the user's counted state
+			slot := keySlot(test.userId)
+			for _, key := range []string{deniedKey(test.userId), policyKey(test.userId), limitsKey(test.userId), violationKey(test.userId), penaltyKey(test.userId)} {
+				assert.Equal(t, slot, keySlot(key), key)
+			}
+		})
+	}
+
+	// IDs sharing a slot or a tag keep keys of their own
+	keys := map[string]string{}
+	for _, userId := range []string{"{org}a", "{org}b", "org", "a}b", "a{b", "", "}{", uuid.New().String()} {
+		key := deniedKey(userId)
+		assert.NotContains(t, keys, key, "%q and %q", keys[key], userId)
+		keys[key] = userId
+	}
+}
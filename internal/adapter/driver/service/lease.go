@@ -0,0 +1,323 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	domainModel "github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driver/service"
+	"github.com/pkg/errors"
+)
+
+// LeaseQuota takes up to units requests from the user's quota in one go and records them as a
+// lease the client can spend without calling back. Leased units count against the limit as soon
+// as they are granted, so the sum of outstanding leases and direct checks never exceeds it.
+func (rls *RateLimitService) LeaseQuota(ctx context.Context, userId string, units, limit int, role string) (*service.LeaseModel, error) {
+	if units <= 0 {
+		return nil, domain.ErrInvalidLeaseUnits
+	}
+
+	// While the global override allows or denies all requests, every key is granted everything or
+	// nothing without counting
+	if override := rls.activeOverride(); override != nil && override.Mode != domainModel.OverrideMultiplier {
+		lease := &service.LeaseModel{UserId: userId, ExpiresAt: rls.leaseExpiry(rls.window, time.Time{})}
+		if override.Mode == domainModel.OverrideDenyAll {
+			rls.recordUsage(userId, 0, 1)
+			return lease, nil
+		}
+		lease.Granted = units
+		rls.recordUsage(userId, units, 0)
+		return lease, nil
+	}
+
+	// Allowlisted keys are granted everything and denylisted keys nothing, neither is counted
+	rule, err := rls.accessRuleFor(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if rule != nil {
+		lease := &service.LeaseModel{UserId: userId, ExpiresAt: rls.leaseExpiry(rls.window, time.Time{})}
+		if rule.Action == domainModel.AccessDeny {
+			rls.recordUsage(userId, 0, 1)
+			return lease, nil
+		}
+		lease.Granted = units
+		rls.recordUsage(userId, units, 0)
+		return lease, nil
+	}
+	// Banned keys are granted nothing either
+	if rls.penaltyOf(ctx, userId) != nil {
+		rls.recordUsage(userId, 0, 1)
+		return &service.LeaseModel{UserId: userId, ExpiresAt: rls.leaseExpiry(rls.window, time.Time{})}, nil
+	}
+
+	var (
+		result consumption
+		undo   undoLog
+	)
+	err = rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		// Counts of a failed attempt stay in the cache, give them back before counting again
+		undo.rollback(ctx)
+		var err error
+		result, err = rls.consumeLimits(ctx, tx, &undo, userId, role, limit, 0, units)
+		return err
+	})
+	if err != nil {
+		undo.rollback(ctx)
+		return nil, err
+	}
+
+	granted := result.granted
+	lease := domainModel.QuotaLease{
+		UserId:      userId,
+		Units:       granted,
+		WindowStart: time.Now(),
+		ExpiresAt:   rls.leaseExpiry(rls.windowOf(0, result.state), rls.countedUntil(result)),
+		Pools:       result.pools,
+		LeasedAt:    time.Now(),
+	}
+	if result.state != nil {
+		lease.WindowStart = result.state.Timestamp
+	}
+	if granted == 0 {
+		rls.recordUsage(userId, 0, 1)
+		rls.auditDenied(ctx, userId, result.binding(units))
+		rls.recordViolation(ctx, userId)
+		// Nothing to spend, so there is nothing to return either
+		return &service.LeaseModel{UserId: userId, ExpiresAt: lease.ExpiresAt}, nil
+	}
+
+	// Leased units count as allowed requests when they are handed out
+	rls.recordUsage(userId, granted, 0)
+	if shadow := result.shadowBinding(); shadow != nil {
+		rls.reportShadowDenied(ctx, userId, shadow)
+	}
+
+	lease.Id = uuid.New().String()
+	data, err := json.Marshal(lease)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal lease")
+	}
+	if err := rls.cache.Set(ctx, leaseKeyPrefix+lease.Id, data, time.Until(lease.ExpiresAt)); err != nil {
+		// The units are already counted, so hand them out anyway; they just can't be returned
+		log.Printf("failed to store lease for user %s, it cannot be returned: %v", userId, err)
+		lease.Id = ""
+	}
+
+	return &service.LeaseModel{
+		LeaseId:   lease.Id,
+		UserId:    userId,
+		Granted:   granted,
+		ExpiresAt: lease.ExpiresAt,
+	}, nil
+}
+
+// ReturnQuota credits the unused units of a lease back to the user's quota. Units are only
+// credited while the window the lease was taken from is still current; once it has rolled over
+// the leased units lapsed together with the old count. Long-period quotas are credited the same
+// way for as long as their period is the one the lease was taken in, and the pools of the user's
+// ancestors for as long as the windows of their limits are.
+func (rls *RateLimitService) ReturnQuota(ctx context.Context, leaseId string, unused int) (int, error) {
+	lease, err := rls.claimLease(ctx, leaseId)
+	if err != nil {
+		return 0, err
+	}
+
+	if unused > lease.Units {
+		unused = lease.Units
+	}
+	if unused <= 0 || time.Now().After(lease.ExpiresAt) {
+		return 0, nil
+	}
+
+	var (
+		policy []domainModel.PolicyLimit
+		pools  []pool
+	)
+	err = rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		if err := rls.creditQuotas(ctx, tx, lease.UserId, unused, lease.WindowStart); err != nil {
+			return err
+		}
+
+		var err error
+		if pools, err = rls.leasedPools(ctx, tx, lease.Pools); err != nil {
+			return err
+		}
+
+		// Users with a policy are credited on its limits instead
+		policy, err = rls.policyFor(ctx, tx, lease.UserId)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := rls.creditPools(ctx, pools, unused, lease.LeasedAt); err != nil {
+		return 0, err
+	}
+	if len(policy) > 0 {
+		return rls.creditPolicy(ctx, lease.UserId, policy, unused, lease.WindowStart)
+	}
+
+	credited, err := rls.creditRateLimit(ctx, lease.UserId, unused, lease.WindowStart)
+	if err != nil || credited == 0 {
+		return 0, err
+	}
+	if err := rls.clearDenied(ctx, lease.UserId); err != nil {
+		return 0, errors.Wrap(err, "failed to clear denied marker")
+	}
+	return credited, nil
+}
+
+// claimLease marks the lease as returned and hands it back. Checking and marking happen in one
+// atomic update, so of concurrent returns of the same lease only one claims it and the others
+// fail with ErrLeaseNotFound, as returns of missing leases do. The marked lease is kept for the
+// longest window, which no lease outlives.
+func (rls *RateLimitService) claimLease(ctx context.Context, leaseId string) (*domainModel.QuotaLease, error) {
+	var (
+		lease     domainModel.QuotaLease
+		claimed   bool
+		decodeErr error
+	)
+	err := rls.cache.Update(ctx, leaseKeyPrefix+leaseId, rls.maxWindow, func(current []byte) ([]byte, error) {
+		lease, claimed = domainModel.QuotaLease{}, false
+		if current == nil {
+			return nil, nil
+		}
+		if decodeErr = json.Unmarshal(current, &lease); decodeErr != nil {
+			return nil, decodeErr
+		}
+		if lease.Returned {
+			return nil, nil
+		}
+		claimed, lease.Returned = true, true
+		return json.Marshal(lease)
+	})
+	if decodeErr != nil {
+		return nil, errors.Wrap(decodeErr, "failed to unmarshal lease")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to claim lease in cache")
+	}
+	if !claimed {
+		return nil, domain.ErrLeaseNotFound
+	}
+	return &lease, nil
+}
+
+// creditRateLimit gives units back to the user's single limit for as long as its window is the
+// one that started at windowStart. The count is credited where it is kept: in the cache with one
+// atomic update, like consume counts it, or in the repository in row locking mode and once the
+// cache no longer holds it. It returns how many units were credited, never more than counted.
+func (rls *RateLimitService) creditRateLimit(ctx context.Context, userId string, units int, windowStart time.Time) (int, error) {
+	if !rls.rowLocking {
+		cached, credited, err := rls.creditCached(ctx, userId, units, windowStart)
+		if err != nil || cached {
+			return credited, err
+		}
+	}
+
+	var credited int
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		repo := rls.repoFactory.New(tx)
+		rateLimit, err := rls.readRateLimit(ctx, repo, userId)
+		if err != nil {
+			return errors.Wrap(err, "failed to get user rate limit from repo")
+		}
+		credited = 0
+		if rateLimit == nil {
+			return nil
+		}
+		if credited = rls.creditRequests(rateLimit, units, windowStart); credited == 0 {
+			return nil
+		}
+		return rls.updateRepository(ctx, repo, rateLimit)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return credited, nil
+}
+
+// creditCached gives units back to the user's cached state in one atomic update, like consume
+// counts them, if its window is still the one that started at windowStart. It reports whether the
+// cache holds a state for the user and how many units were credited.
+func (rls *RateLimitService) creditCached(ctx context.Context, userId string, units int, windowStart time.Time) (bool, int, error) {
+	var (
+		cached    bool
+		credited  int
+		decodeErr error
+	)
+	err := rls.cache.Update(ctx, userId, rls.maxWindow, func(current []byte) ([]byte, error) {
+		cached, credited = current != nil, 0
+		if !cached {
+			return nil, nil
+		}
+		var state domainModel.UserRateLimit
+		if decodeErr = json.Unmarshal(current, &state); decodeErr != nil {
+			return nil, decodeErr
+		}
+		if credited = rls.creditRequests(&state, units, windowStart); credited == 0 {
+			return nil, nil
+		}
+		return json.Marshal(state)
+	})
+	if decodeErr != nil {
+		return false, 0, errors.Wrap(decodeErr, "failed to unmarshal cached rate limit")
+	}
+	if err != nil {
+		return false, 0, errors.Wrap(err, "failed to credit cached rate limit")
+	}
+	return cached, credited, nil
+}
+
+// creditRequests takes up to units off the count of rateLimit if its window is still the one that
+// started at windowStart, and returns how many it took.
+func (rls *RateLimitService) creditRequests(rateLimit *domainModel.UserRateLimit, units int, windowStart time.Time) int {
+	if !rateLimit.Timestamp.Equal(windowStart) || time.Since(rateLimit.Timestamp) > rls.windowOf(0, rateLimit) {
+		// The window the units were taken from is over
+		return 0
+	}
+	if units > rateLimit.RequestCount {
+		units = rateLimit.RequestCount
+	}
+	rateLimit.RequestCount -= units
+	return units
+}
+
+// leaseExpiry returns when a lease granted now from a window of the given length expires, and
+// no later than resetsAt if it is set. The units of a lease lapse with the count of the window they
+// were taken from, so a lease never outlives one window.
+func (rls *RateLimitService) leaseExpiry(window time.Duration, resetsAt time.Time) time.Time {
+	duration := window
+	if rls.leaseDuration > 0 && rls.leaseDuration < duration {
+		duration = rls.leaseDuration
+	}
+	expiresAt := time.Now().Add(duration)
+	if !resetsAt.IsZero() && resetsAt.Before(expiresAt) {
+		expiresAt = resetsAt
+	}
+	return expiresAt
+}
+
+// countedUntil returns when the first of the windows and periods that units were counted in ends,
+// zero if they were counted in none. Units spent after that would be counted again by the next one.
+func (rls *RateLimitService) countedUntil(result consumption) time.Time {
+	var resetsAt time.Time
+	if result.state != nil {
+		resetsAt = result.state.Timestamp.Add(rls.windowOf(0, result.state))
+	}
+	for _, bound := range result.bounds {
+		if bound.Shadow || bound.ResetsAt.IsZero() {
+			continue
+		}
+		if resetsAt.IsZero() || bound.ResetsAt.Before(resetsAt) {
+			resetsAt = bound.ResetsAt
+		}
+	}
+	return resetsAt
+}
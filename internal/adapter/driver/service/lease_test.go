@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/cache"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/db/memory"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/port/driver/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLeaseTestService(t *testing.T) *RateLimitService {
+	cache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, cache.Connect())
+	t.Cleanup(func() { cache.Disconnect() })
+
+	repoFactory := memory.NewUserRateLimitRepositoryFactory()
+	transactionFactory := memory.NewTransactionFactory(memory.NewStore())
+	return NewRateLimitService(repoFactory, cache, transactionFactory, time.Second*10)
+}
+
+func TestRateLimitService_LeaseQuota(t *testing.T) {
+	ctx := context.Background()
+	service := newLeaseTestService(t)
+	userId := uuid.New().String()
+
+	// Leased units count against the limit straight away
+	lease, err := service.LeaseQuota(ctx, userId, 8, 10, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 8, lease.Granted)
+	assert.NotEmpty(t, lease.LeaseId)
+	assert.True(t, lease.ExpiresAt.After(time.Now()))
+
+	allowed, err := service.RateLimit(ctx, userId, 10)
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+
+	// Only what is left under the limit is granted
+	partial, err := service.LeaseQuota(ctx, userId, 5, 10, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, partial.Granted)
+
+	allowed, err = service.RateLimit(ctx, userId, 10)
+	assert.Nil(t, err)
+	assert.False(t, allowed)
+
+	empty, err := service.LeaseQuota(ctx, userId, 5, 10, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, empty.Granted)
+	assert.Empty(t, empty.LeaseId)
+
+	_, err = service.LeaseQuota(ctx, userId, 0, 10, "")
+	assert.ErrorIs(t, err, domain.ErrInvalidLeaseUnits)
+}
+
+func TestRateLimitService_ReturnQuota(t *testing.T) {
+	ctx := context.Background()
+	service := newLeaseTestService(t)
+	userId := uuid.New().String()
+
+	lease, err := service.LeaseQuota(ctx, userId, 10, 10, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 10, lease.Granted)
+
+	allowed, err := service.RateLimit(ctx, userId, 10)
+	assert.Nil(t, err)
+	assert.False(t, allowed)
+
+	// More than was leased can never be returned
+	returned, err := service.ReturnQuota(ctx, lease.LeaseId, 25)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, returned)
+
+	allowed, err = service.RateLimit(ctx, userId, 10)
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+
+	// A lease can only be returned once
+	_, err = service.ReturnQuota(ctx, lease.LeaseId, 1)
+	assert.ErrorIs(t, err, domain.ErrLeaseNotFound)
+
+	_, err = service.ReturnQuota(ctx, uuid.New().String(), 1)
+	assert.ErrorIs(t, err, domain.ErrLeaseNotFound)
+}
+
+func TestRateLimitService_ReturnQuota_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	service := newLeaseTestService(t)
+	userId := uuid.New().String()
+
+	lease, err := service.LeaseQuota(ctx, userId, 5, 10, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 5, lease.Granted)
+	for i := 0; i < 5; i++ {
+		allowed, err := service.RateLimit(ctx, userId, 10)
+		assert.Nil(t, err)
+		assert.True(t, allowed)
+	}
+
+	// Of concurrent returns of the same lease only one is credited
+	returned := make(chan int, 20)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			units, err := service.ReturnQuota(ctx, lease.LeaseId, 5)
+			if err != nil {
+				assert.ErrorIs(t, err, domain.ErrLeaseNotFound)
+			}
+			returned <- units
+		}()
+	}
+	wg.Wait()
+	close(returned)
+
+	total := 0
+	for units := range returned {
+		total += units
+	}
+	assert.Equal(t, 5, total)
+
+	for i := 0; i < 5; i++ {
+		allowed, err := service.RateLimit(ctx, userId, 10)
+		assert.Nil(t, err)
+		assert.True(t, allowed)
+	}
+	allowed, err := service.RateLimit(ctx, userId, 10)
+	assert.Nil(t, err)
+	assert.False(t, allowed)
+}
+
+func TestRateLimitService_LeaseQuota_EndsWithWindow(t *testing.T) {
+	ctx := context.Background()
+	cache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, cache.Connect())
+	t.Cleanup(func() { cache.Disconnect() })
+	rateService := NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), cache, memory.NewTransactionFactory(memory.NewStore()), 300*time.Millisecond)
+	userId := uuid.New().String()
+
+	decision, err := rateService.Check(ctx, service.CheckRequest{UserId: userId, Limit: 10})
+	assert.Nil(t, err)
+	assert.True(t, decision.Allowed)
+	resetsAt := decision.Binding.ResetsAt
+
+	// A lease taken late in the window lapses when the window does, not a window later
+	time.Sleep(200 * time.Millisecond)
+	lease, err := rateService.LeaseQuota(ctx, userId, 5, 10, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 5, lease.Granted)
+	assert.WithinDuration(t, resetsAt, lease.ExpiresAt, 0)
+
+	// Once the next window grants the full limit again, nothing of the old lease is spendable
+	time.Sleep(time.Until(resetsAt) + 10*time.Millisecond)
+	next, err := rateService.LeaseQuota(ctx, userId, 10, 10, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 10, next.Granted)
+	assert.True(t, time.Now().After(lease.ExpiresAt))
+	_, err = rateService.ReturnQuota(ctx, lease.LeaseId, 5)
+	assert.ErrorIs(t, err, domain.ErrLeaseNotFound)
+}
@@ -3,8 +3,10 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"log"
 	"time"
 
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
 	domainModel "github.com/nullexp/limiter-x/internal/domain/model"
 	"github.com/nullexp/limiter-x/internal/port/driven"
 	"github.com/nullexp/limiter-x/internal/port/driven/db"
@@ -18,138 +20,346 @@ type RateLimitService struct {
 	cache                driven.Cache
 	dbTransactionFactory db.DbTransactionFactory
 	window               time.Duration
+	maxWindow            time.Duration
+	defaultLimit         int
+	leaseDuration        time.Duration
+	failurePolicy        FailurePolicy
+	local                *localLimiter
+	deniedTTL            time.Duration
+	rowLocking           bool
+	txOptions            []db.TxOption
+	retry                *retrier
+	auditRepoFactory     repository.AuditEventRepositoryFactory
+	denySampleRate       float64
+	usageRepoFactory     repository.UsageRepositoryFactory
+	usage                *usageAggregator
+	quotaRepoFactory     repository.QuotaRepositoryFactory
+	policyRepoFactory    repository.PolicyRepositoryFactory
+	policyTTL            time.Duration
+	parentRepoFactory    repository.ParentRepositoryFactory
+	parents              *keyCache[string]
+	tierRepoFactory      repository.TierRepositoryFactory
+	tiers                *keyCache[int]
+	accessRepoFactory    repository.AccessRuleRepositoryFactory
+	access               *accessCache
+	penalties            *PenaltyPolicy
+	overrideRepoFactory  repository.OverrideRepositoryFactory
+	overrides            *overrideState
+}
+
+// defaultRateLimit applies to users without a limit of their own or a tier when no limit is
+// requested, unless the service is given another default.
+const defaultRateLimit = 100
+
+// Option configures optional behaviour of a RateLimitService.
+type Option func(*RateLimitService)
+
+// WithLeaseDuration caps how long a quota lease stays spendable. Leases never last longer than
+// the window, which is also the default.
+func WithLeaseDuration(d time.Duration) Option {
+	return func(rls *RateLimitService) {
+		rls.leaseDuration = d
+	}
+}
+
+// WithMaxWindow sets the longest window users may be given or checks may ask for, which defaults
+// to the service's window. Counted states are cached for this long after their last update, so a
+// longer maximum keeps idle users in the cache longer.
+func WithMaxWindow(d time.Duration) Option {
+	return func(rls *RateLimitService) {
+		rls.maxWindow = d
+	}
+}
+
+// WithDefaultLimit sets the limit of users without a limit of their own or a tier, 100 by default.
+func WithDefaultLimit(limit int) Option {
+	return func(rls *RateLimitService) {
+		rls.defaultLimit = limit
+	}
 }
 
 // NewRateLimitService creates a new instance of RateLimitService.
-func NewRateLimitService(repo repository.UserRateLimitRepositoryFactory, cache driven.Cache, dbTransactionFactory db.DbTransactionFactory, window time.Duration) *RateLimitService {
-	return &RateLimitService{
+func NewRateLimitService(repo repository.UserRateLimitRepositoryFactory, cache driven.Cache, dbTransactionFactory db.DbTransactionFactory, window time.Duration, opts ...Option) *RateLimitService {
+	rls := &RateLimitService{
 		repoFactory:          repo,
 		cache:                cache,
 		dbTransactionFactory: dbTransactionFactory,
 		window:               window,
+		defaultLimit:         defaultRateLimit,
+		retry:                newRetrier(DefaultRetryPolicy),
+	}
+	for _, opt := range opts {
+		opt(rls)
+	}
+	if rls.maxWindow < rls.window {
+		rls.maxWindow = rls.window
 	}
+	return rls
 }
 
 // RateLimit checks if the request is allowed for the user within the defined rate limit.
 func (rls *RateLimitService) RateLimit(ctx context.Context, userId string, limit int) (bool, error) {
-	// Start the transaction
-	tx := rls.dbTransactionFactory.NewTransaction()
-	transaction, err := tx.Begin(ctx)
+	decision, err := rls.Check(ctx, service.CheckRequest{UserId: userId, Limit: limit})
 	if err != nil {
 		return false, err
 	}
-	defer tx.RollbackUnlessCommitted(ctx)
+	return decision.Allowed, nil
+}
 
-	allowed, err := rls.rateLimit(ctx, transaction, userId, limit)
-	if err != nil {
-		return false, err
+// windowOf returns the window to count a user's requests in: the requested one if any, otherwise
+// the user's own, otherwise the service's.
+func (rls *RateLimitService) windowOf(window time.Duration, rateLimit *domainModel.UserRateLimit) time.Duration {
+	if window > 0 {
+		return window
 	}
+	if rateLimit != nil && rateLimit.Window > 0 {
+		return rateLimit.Window
+	}
+	return rls.window
+}
 
-	// Commit the transaction if everything went well
-	err = tx.Commit(ctx)
-	return allowed, err
+// limitOf returns the limit to count a user's requests against: the requested one if any,
+// otherwise the user's own, otherwise fallback, which is the limit of the user's tier or the
+// default. A global override may multiply it.
+func (rls *RateLimitService) limitOf(limit, fallback int, rateLimit *domainModel.UserRateLimit) int {
+	if limit <= 0 {
+		limit = fallback
+		if rateLimit != nil && rateLimit.RateLimit > 0 {
+			limit = rateLimit.RateLimit
+		}
+	}
+	return int(rls.scaled(int64(limit)))
 }
 
-// rateLimit handles the rate limiting logic within a transaction.
-func (rls *RateLimitService) rateLimit(ctx context.Context, tx db.DbHandler, userId string, limit int) (bool, error) {
-	repo := rls.repoFactory.New(tx)
+// validateWindow checks a requested window, where zero stands for the user's own.
+func (rls *RateLimitService) validateWindow(window time.Duration) error {
+	if window < 0 || window > rls.maxWindow {
+		return domain.ErrInvalidWindow
+	}
+	return nil
+}
 
-	// Try to fetch from cache first
-	cachedRateLimitData, err := rls.cache.Fetch(ctx, userId)
-	if err == nil && cachedRateLimitData != nil {
-		var cachedRateLimit domainModel.UserRateLimit
-		if err := json.Unmarshal(cachedRateLimitData, &cachedRateLimit); err != nil {
-			return false, errors.Wrap(err, "failed to unmarshal cached rate limit")
+// consume handles the rate limiting logic within a transaction. It takes up to units requests
+// from the user's quota and returns how many were granted along with the resulting state. A
+// non-zero window replaces the user's own, like a non-zero limit does, and fallback applies to
+// users without a limit of their own. Units counted in the cache are recorded in undo.
+func (rls *RateLimitService) consume(ctx context.Context, tx db.DbHandler, undo *undoLog, userId string, limit, fallback int, window time.Duration, units int) (int, *domainModel.UserRateLimit, error) {
+	// The database is authoritative, count there with the user's row locked
+	if rls.rowLocking {
+		return rls.consumeFromRepository(ctx, tx, userId, limit, fallback, window, units, false)
+	}
+
+	// Users known to be over the limit are denied without counting
+	if denied := rls.knownDenied(ctx, userId, limit, fallback, window); denied != nil {
+		return 0, denied, nil
+	}
+
+	// Count against the cached state first. The check and the increment happen in one atomic
+	// update, so concurrent requests can never both take the last remaining unit.
+	var (
+		cached    bool
+		granted   int
+		state     domainModel.UserRateLimit
+		decodeErr error
+	)
+	err := rls.cache.Update(ctx, userId, rls.maxWindow, func(current []byte) ([]byte, error) {
+		cached, granted, state = current != nil, 0, domainModel.UserRateLimit{}
+		if !cached {
+			return nil, nil
+		}
+		if decodeErr = json.Unmarshal(current, &state); decodeErr != nil {
+			return nil, decodeErr
+		}
+
+		// The cached state outlives shorter windows, a window that has passed is started afresh
+		// from the repository
+		if time.Since(state.Timestamp) > rls.windowOf(window, &state) {
+			cached = false
+			return nil, nil
 		}
 
-		// Determine which limit to use (parameter or database value)
-		effectiveLimit := limit
-		if limit == 0 {
-			effectiveLimit = cachedRateLimit.RateLimit
+		// Deny request if the request count has reached or exceeded the limit
+		granted = grant(state.RequestCount, rls.limitOf(limit, fallback, &state), units)
+		if granted == 0 {
+			return nil, nil
 		}
 
-		// Cache hit, validate against effective limit
-		if cachedRateLimit.RequestCount >= effectiveLimit {
-			// Deny request if the request count has reached or exceeded the limit
-			return false, nil
+		// Increment the request count
+		state.RequestCount += granted
+		return json.Marshal(state)
+	})
+	if decodeErr != nil {
+		return 0, nil, errors.Wrap(decodeErr, "failed to unmarshal cached rate limit")
+	}
+	if err != nil {
+		// The cache is unavailable, let the failure policy decide
+		return rls.consumeDegraded(ctx, tx, userId, limit, fallback, window, units)
+	}
+	if cached {
+		if granted == 0 {
+			rls.markDenied(ctx, &state, window)
+			return 0, &state, nil
 		}
+		undo.add(rls.uncount(userId, granted, state.Timestamp))
+		return granted, &state, nil
+	}
+
+	// Cache miss, fallback to repository
+	granted, rateLimit, err := rls.consumeFromRepository(ctx, tx, userId, limit, fallback, window, units, true)
+	if err != nil {
+		return 0, nil, err
+	}
+	if granted > 0 {
+		// The cache was written with the count, it stays there if the transaction fails
+		undo.add(rls.uncount(userId, granted, rateLimit.Timestamp))
+	}
+	return granted, rateLimit, nil
+}
 
-		// Increment the request count and update cache
-		cachedRateLimit.RequestCount++
-		if err := rls.updateCache(ctx, userId, &cachedRateLimit); err != nil {
-			return false, err
+// uncount returns how to give back units counted against the user's cached state in the window
+// that started at windowStart.
+func (rls *RateLimitService) uncount(userId string, units int, windowStart time.Time) func(context.Context) {
+	return func(ctx context.Context) {
+		if _, _, err := rls.creditCached(ctx, userId, units, windowStart); err != nil {
+			log.Printf("failed to give back %d requests to user %s: %v", units, userId, err)
 		}
-		return true, nil
 	}
+}
+
+// consumeFromRepository counts the request against the state stored in the repository, keeping
+// the cache in sync when writeCache is set.
+func (rls *RateLimitService) consumeFromRepository(ctx context.Context, tx db.DbHandler, userId string, limit, fallback int, window time.Duration, units int, writeCache bool) (int, *domainModel.UserRateLimit, error) {
+	repo := rls.repoFactory.New(tx)
 
-	// Cache miss or request count exceeded, fallback to repository
-	rateLimit, err := repo.GetRateLimitByUserId(ctx, userId)
+	rateLimit, err := rls.readRateLimit(ctx, repo, userId)
 	if err != nil {
-		return false, errors.Wrap(err, "failed to get user rate limit from repo")
+		return 0, nil, errors.Wrap(err, "failed to get user rate limit from repo")
+	}
+	if rateLimit == nil && rls.rowLocking {
+		if rateLimit, err = rls.createLocked(ctx, repo, userId, limit, window); err != nil {
+			return 0, nil, err
+		}
 	}
 
 	now := time.Now()
 	if rateLimit == nil {
-		// User has no existing rate limit, create new one with default or parameter limit
-		effectiveLimit := limit
-		if limit == 0 {
-			effectiveLimit = 100 // Default rate limit if no record and limit is 0
-		}
+		// User has no existing rate limit, create new one with the parameter limit. Without one
+		// the user has no limit of their own and follows their tier or the default.
+		granted := grant(0, rls.limitOf(limit, fallback, nil), units)
 		rateLimit = &domainModel.UserRateLimit{
 			UserId:       userId,
-			RequestCount: 1,
-			RateLimit:    effectiveLimit, // Store the effective limit in the database
+			RequestCount: granted,
+			RateLimit:    limit,
 			Timestamp:    now,
+			Window:       window,
 		}
-		_, err = repo.CreateRateLimit(ctx, *rateLimit)
-		if err != nil {
-			return false, errors.Wrap(err, "failed to create user rate limit in repo")
+		if err := rls.createRepository(ctx, repo, rateLimit); err != nil {
+			return 0, nil, err
 		}
 
 		// Store new rate limit in cache
-		if err := rls.setCache(ctx, userId, rateLimit); err != nil {
-			return false, err
+		if writeCache {
+			if err := rls.setCache(ctx, userId, rateLimit); err != nil {
+				return rls.cacheWriteFailed(ctx, tx, userId, limit, fallback, window, units, granted, rateLimit, err)
+			}
 		}
-		return true, nil
+		return granted, rateLimit, nil
 	}
 
-	// Determine which limit to use (parameter or database value)
-	effectiveLimit := limit
-	if limit == 0 {
-		effectiveLimit = rateLimit.RateLimit
-	}
+	// Determine which limit to use (parameter, database value or fallback)
+	effectiveLimit := rls.limitOf(limit, fallback, rateLimit)
 
 	// Check if request window has passed
-	if now.Sub(rateLimit.Timestamp) > rls.window {
+	if now.Sub(rateLimit.Timestamp) > rls.windowOf(window, rateLimit) {
 		// Reset rate limit if outside the window
-		rateLimit.RequestCount = 1
+		granted := grant(0, effectiveLimit, units)
+		rateLimit.RequestCount = granted
 		rateLimit.Timestamp = now
-		if err := repo.UpdateRateLimit(ctx, *rateLimit); err != nil {
-			return false, errors.Wrap(err, "failed to update user rate limit")
+		if err := rls.updateRepository(ctx, repo, rateLimit); err != nil {
+			return 0, nil, err
 		}
 		// Update the cache with the reset data
-		if err := rls.setCache(ctx, userId, rateLimit); err != nil {
-			return false, err
+		if writeCache {
+			if err := rls.setCache(ctx, userId, rateLimit); err != nil {
+				return rls.cacheWriteFailed(ctx, tx, userId, limit, fallback, window, units, granted, rateLimit, err)
+			}
 		}
-		return true, nil
+		return granted, rateLimit, nil
 	}
 
 	// If within window, check the request count against effective limit
-	if rateLimit.RequestCount >= effectiveLimit {
+	granted := grant(rateLimit.RequestCount, effectiveLimit, units)
+	if granted == 0 {
 		// Deny the request if the count is equal to or exceeds the limit
-		return false, nil
+		if writeCache {
+			rls.markDenied(ctx, rateLimit, window)
+		}
+		return 0, rateLimit, nil
 	}
 
 	// Increment the request count and update repository
-	rateLimit.RequestCount++
-	if err := repo.UpdateRateLimit(ctx, *rateLimit); err != nil {
-		return false, errors.Wrap(err, "failed to update user rate limit in repo")
+	rateLimit.RequestCount += granted
+	if err := rls.updateRepository(ctx, repo, rateLimit); err != nil {
+		return 0, nil, err
 	}
 	// Update the cache with the incremented count
-	if err := rls.updateCache(ctx, userId, rateLimit); err != nil {
-		return false, err
+	if writeCache {
+		if err := rls.updateCache(ctx, userId, rateLimit); err != nil {
+			return rls.cacheWriteFailed(ctx, tx, userId, limit, fallback, window, units, granted, rateLimit, err)
+		}
+	}
+	return granted, rateLimit, nil
+}
+
+// cacheWriteFailed decides a request that was counted in the repository after a cache miss when
+// storing the count in the cache failed, by the failure policy like any request the cache fails.
+func (rls *RateLimitService) cacheWriteFailed(ctx context.Context, tx db.DbHandler, userId string, limit, fallback int, window time.Duration, units, granted int, rateLimit *domainModel.UserRateLimit, err error) (int, *domainModel.UserRateLimit, error) {
+	log.Printf("failed to cache rate limit of user %s: %v", userId, err)
+	if rls.failurePolicy == FailToDatabase {
+		// The repository already counted the request
+		return granted, rateLimit, nil
+	}
+	return rls.consumeDegraded(ctx, tx, userId, limit, fallback, window, units)
+}
+
+// grant returns how many of the requested units fit under the limit given the current count.
+func grant(count, limit, units int) int {
+	remaining := limit - count
+	if remaining <= 0 {
+		return 0
+	}
+	if units < remaining {
+		return units
+	}
+	return remaining
+}
+
+// createRepository stores rateLimit as the user's first record. When a concurrent transaction
+// created one since it was found missing, ErrRateLimitConflict is returned unwrapped so the
+// transaction is retried against that record rather than overwriting what it counted.
+func (rls *RateLimitService) createRepository(ctx context.Context, repo repository.UserRateLimitRepository, rateLimit *domainModel.UserRateLimit) error {
+	id, err := repo.CreateRateLimit(ctx, *rateLimit)
+	if err != nil {
+		if errors.Is(err, domain.ErrRateLimitConflict) {
+			return err
+		}
+		return errors.Wrap(err, "failed to create user rate limit in repo")
+	}
+	rateLimit.Id, rateLimit.Version = id, 1
+	return nil
+}
+
+// updateRepository stores rateLimit with an optimistic version check and advances its version to
+// the stored one, so state cached from it can be written back later.
+func (rls *RateLimitService) updateRepository(ctx context.Context, repo repository.UserRateLimitRepository, rateLimit *domainModel.UserRateLimit) error {
+	if err := repo.UpdateRateLimit(ctx, *rateLimit); err != nil {
+		if errors.Is(err, domain.ErrRateLimitConflict) {
+			return err
+		}
+		return errors.Wrap(err, "failed to update user rate limit in repo")
 	}
-	return true, nil
+	rateLimit.Version++
+	return nil
 }
 
 // setCache stores the user rate limit in the cache.
@@ -158,7 +368,7 @@ func (rls *RateLimitService) setCache(ctx context.Context, userId string, rateLi
 	if err != nil {
 		return errors.Wrap(err, "failed to marshal rate limit")
 	}
-	return rls.cache.Set(ctx, userId, data, rls.window)
+	return rls.cache.Set(ctx, userId, data, rls.maxWindow)
 }
 
 // updateCache updates the user rate limit in the cache.
@@ -167,12 +377,12 @@ func (rls *RateLimitService) updateCache(ctx context.Context, userId string, rat
 	if err != nil {
 		return errors.Wrap(err, "failed to marshal rate limit")
 	}
-	return rls.cache.Set(ctx, userId, data, rls.window)
+	return rls.cache.Set(ctx, userId, data, rls.maxWindow)
 }
 
 func (rls *RateLimitService) GetUserRateLimit(ctx context.Context, userId string) (*service.RateLimitModel, error) {
-	// Try to fetch from cache first
-	cachedRateLimitData, err := rls.cache.Fetch(ctx, userId)
+	// Try to fetch from cache first, unless counting happens in the database
+	cachedRateLimitData, err := rls.cachedRateLimit(ctx, userId)
 	if err == nil && cachedRateLimitData != nil {
 		var cachedRateLimit domainModel.UserRateLimit
 		if err := json.Unmarshal(cachedRateLimitData, &cachedRateLimit); err != nil {
@@ -184,22 +394,22 @@ func (rls *RateLimitService) GetUserRateLimit(ctx context.Context, userId string
 			UserId:    cachedRateLimit.UserId,
 			Limit:     cachedRateLimit.RateLimit,    // Limit in this case refers to the request count in the cache
 			Remaining: cachedRateLimit.RequestCount, // Example: returning the same count for simplicity
-			Window:    rls.window.String(),
+			Window:    rls.windowOf(0, &cachedRateLimit).String(),
 		}, nil
 	}
 
 	// Cache miss, fallback to repository
-	tx := rls.dbTransactionFactory.NewTransaction()
-	transaction, err := tx.Begin(ctx)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to begin transaction")
-	}
-	defer tx.RollbackUnlessCommitted(ctx)
-
-	repo := rls.repoFactory.New(transaction)
-	rateLimit, err := repo.GetRateLimitByUserId(ctx, userId)
+	var rateLimit *domainModel.UserRateLimit
+	err = rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		var err error
+		rateLimit, err = rls.repoFactory.New(tx).GetRateLimitByUserId(ctx, userId)
+		if err != nil {
+			return errors.Wrap(err, "failed to get rate limit from repository")
+		}
+		return nil
+	}, db.ReadOnly())
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get rate limit from repository")
+		return nil, err
 	}
 	if rateLimit == nil {
 		return nil, errors.New("rate limit not found")
@@ -210,57 +420,64 @@ func (rls *RateLimitService) GetUserRateLimit(ctx context.Context, userId string
 		UserId:    rateLimit.UserId,
 		Limit:     rateLimit.RateLimit,
 		Remaining: rateLimit.RequestCount, // You can adjust how you calculate the remaining requests
-		Window:    rls.window.String(),
+		Window:    rls.windowOf(0, rateLimit).String(),
 	}, nil
 }
 
-// UpdateUserRateLimit updates the rate limit for a specific user
-func (rls *RateLimitService) UpdateUserRateLimit(ctx context.Context, userId string, newLimit int) error {
-	// Begin a transaction
-	tx := rls.dbTransactionFactory.NewTransaction()
-	transaction, err := tx.Begin(ctx)
-	if err != nil {
-		return errors.Wrap(err, "failed to begin transaction")
+// UpdateUserRateLimit updates the rate limit for a specific user, and the user's window unless
+// window is zero
+func (rls *RateLimitService) UpdateUserRateLimit(ctx context.Context, userId string, newLimit int, window time.Duration) error {
+	if err := rls.validateWindow(window); err != nil {
+		return err
 	}
-	defer tx.RollbackUnlessCommitted(ctx)
-
-	repo := rls.repoFactory.New(transaction)
 
-	// Fetch the current rate limit from the repository
-	rateLimit, err := repo.GetRateLimitByUserId(ctx, userId)
-	if err != nil {
-		return errors.Wrap(err, "failed to get user rate limit from repository")
-	}
+	var rateLimit *domainModel.UserRateLimit
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		repo := rls.repoFactory.New(tx)
 
-	// If no rate limit exists for the user, create a new one
-	if rateLimit == nil {
-		rateLimit = &domainModel.UserRateLimit{
-			UserId:       userId,
-			RequestCount: newLimit, // Set the new limit
-			Timestamp:    time.Now(),
-		}
-		_, err = repo.CreateRateLimit(ctx, *rateLimit)
+		// Fetch the current rate limit from the repository
+		var err error
+		rateLimit, err = rls.readRateLimit(ctx, repo, userId)
 		if err != nil {
-			return errors.Wrap(err, "failed to create user rate limit in repository")
+			return errors.Wrap(err, "failed to get user rate limit from repository")
 		}
-	} else {
-		// Update the rate limit in the repository
-		rateLimit.RequestCount = newLimit
-		err = repo.UpdateRateLimit(ctx, *rateLimit)
-		if err != nil {
-			return errors.Wrap(err, "failed to update user rate limit in repository")
+
+		// If no rate limit exists for the user, create a new one
+		if rateLimit == nil {
+			rateLimit = &domainModel.UserRateLimit{
+				UserId:    userId,
+				RateLimit: newLimit, // Set the new limit
+				Timestamp: time.Now(),
+				Window:    window,
+			}
+			if err := rls.createRepository(ctx, repo, rateLimit); err != nil {
+				return err
+			}
+			return rls.auditLimitChange(ctx, tx, userId, nil, rateLimit)
 		}
-	}
 
-	// Commit the transaction if successful
-	if err := tx.Commit(ctx); err != nil {
-		return errors.Wrap(err, "failed to commit transaction")
+		// Update the rate limit in the repository
+		old := *rateLimit
+		rateLimit.RateLimit = newLimit
+		if window > 0 {
+			rateLimit.Window = window
+		}
+		if err := rls.updateRepository(ctx, repo, rateLimit); err != nil {
+			return err
+		}
+		return rls.auditLimitChange(ctx, tx, userId, &old, rateLimit)
+	})
+	if err != nil {
+		return err
 	}
 
 	// Update the cache with the new limit
 	if err := rls.setCache(ctx, userId, rateLimit); err != nil {
 		return errors.Wrap(err, "failed to update cache with new rate limit")
 	}
+	if err := rls.clearDenied(ctx, userId); err != nil {
+		return errors.Wrap(err, "failed to clear denied marker")
+	}
 
 	return nil
 }
@@ -2,22 +2,24 @@ package service
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/nullexp/limiter-x/internal/adapter/driven/cache"
-	"github.com/nullexp/limiter-x/internal/adapter/driven/db"
-	"github.com/nullexp/limiter-x/internal/adapter/driven/db/repository"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/db/memory"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
 	"github.com/nullexp/limiter-x/internal/domain/model"
 	portRepo "github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+	"github.com/nullexp/limiter-x/internal/port/driver/service"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestRateLimitService_RateLimit(t *testing.T) {
 	cache := cache.NewMemoryClient(time.Hour, time.Hour)
-	repoFactory := repository.NewUserRateLimitRepositoryFactoryMock()
-	transactionFactory := db.NewPostgresTransactionFactoryMock()
+	repoFactory := memory.NewUserRateLimitRepositoryFactory()
+	transactionFactory := memory.NewTransactionFactory(memory.NewStore())
 	window := time.Second * 10 // Set your window time here
 
 	service := NewRateLimitService(repoFactory, cache, transactionFactory, window)
@@ -194,8 +196,8 @@ func TestRateLimitService_RateLimit(t *testing.T) {
 
 func setupBenchmark(b *testing.B) (*RateLimitService, func(userId string) error, func(userId string) error, func() error) {
 	cache := cache.NewMemoryClient(time.Hour, time.Hour)
-	repoFactory := repository.NewUserRateLimitRepositoryFactoryMock()
-	transactionFactory := db.NewPostgresTransactionFactoryMock()
+	repoFactory := memory.NewUserRateLimitRepositoryFactory()
+	transactionFactory := memory.NewTransactionFactory(memory.NewStore())
 	window := time.Second * 10 // Set your window time here
 
 	service := NewRateLimitService(repoFactory, cache, transactionFactory, window)
@@ -311,3 +313,172 @@ func BenchmarkRateLimit_LimitReached(b *testing.B) {
 		}
 	}
 }
+
+func TestRateLimitService_RateLimit_Concurrent(t *testing.T) {
+	cache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, cache.Connect())
+	defer cache.Disconnect()
+
+	service := NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), cache, memory.NewTransactionFactory(memory.NewStore()), time.Second*10)
+
+	userId := uuid.New().String()
+	err := cache.Set(context.Background(), userId, []byte(`{"UserId":"`+userId+`","RequestCount":0,"RateLimit":25,"Timestamp":"`+time.Now().Format(time.RFC3339)+`"}`), time.Hour)
+	assert.Nil(t, err)
+
+	// Concurrent requests against cached state never get past the limit
+	allowed := make(chan bool, 100)
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := service.RateLimit(context.Background(), userId, 0)
+			assert.Nil(t, err)
+			allowed <- ok
+		}()
+	}
+	wg.Wait()
+	close(allowed)
+
+	count := 0
+	for ok := range allowed {
+		if ok {
+			count++
+		}
+	}
+	assert.Equal(t, 25, count)
+}
+
+func TestRateLimitService_RateLimit_ConcurrentFirstRequests(t *testing.T) {
+	cache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, cache.Connect())
+	defer cache.Disconnect()
+
+	service := NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), cache, memory.NewTransactionFactory(memory.NewStore()), time.Second*10)
+
+	// Requests racing to create the user's record converge on one: those that lose are retried
+	// against the record the winner created, none fail and none are counted twice
+	userId := uuid.New().String()
+	allowed := make(chan bool, 20)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := service.RateLimit(context.Background(), userId, 10)
+			assert.Nil(t, err)
+			allowed <- ok
+		}()
+	}
+	wg.Wait()
+	close(allowed)
+
+	count := 0
+	for ok := range allowed {
+		if ok {
+			count++
+		}
+	}
+	assert.Equal(t, 10, count)
+}
+
+// rejectingCache misses every lookup and fails every write.
+type rejectingCache struct {
+	unavailableCache
+}
+
+func (rejectingCache) Update(ctx context.Context, key string, expiration time.Duration, fn func(current []byte) ([]byte, error)) error {
+	_, err := fn(nil)
+	return err
+}
+
+func TestRateLimitService_RateLimit_CacheWriteFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy FailurePolicy
+		expect []bool
+	}{
+		{name: "Counting in the database", policy: FailToDatabase, expect: []bool{true, true, false}},
+		{name: "Failing open", policy: FailOpen, expect: []bool{true, true, false}},
+		{name: "Failing closed", policy: FailClosed, expect: []bool{false, false, false}},
+		{name: "Counting locally", policy: FailLocal, expect: []bool{true, true, false}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			repoFactory := memory.NewUserRateLimitRepositoryFactory()
+			transactionFactory := memory.NewTransactionFactory(memory.NewStore())
+			service := NewRateLimitService(repoFactory, rejectingCache{}, transactionFactory, time.Second*10,
+				WithFailurePolicy(test.policy, 1))
+
+			// Storing the count after a cache miss fails, the failure policy decides instead. Once the
+			// repository is at the limit nothing is stored and it denies by itself.
+			userId := uuid.New().String()
+			for i, expect := range test.expect {
+				allowed, err := service.RateLimit(ctx, userId, 2)
+				assert.Nil(t, err)
+				assert.Equal(t, expect, allowed, "request %d", i+1)
+			}
+
+			// The repository keeps what it counted before the cache failed
+			tx := transactionFactory.NewTransaction()
+			handler, err := tx.Begin(ctx)
+			assert.Nil(t, err)
+			defer tx.RollbackUnlessCommitted(ctx)
+			rateLimit, err := repoFactory.New(handler).GetRateLimitByUserId(ctx, userId)
+			assert.Nil(t, err)
+			assert.Equal(t, 2, rateLimit.RequestCount)
+		})
+	}
+}
+
+func TestRateLimitService_Windows(t *testing.T) {
+	ctx := context.Background()
+	cache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, cache.Connect())
+	defer cache.Disconnect()
+
+	rateService := NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), cache, memory.NewTransactionFactory(memory.NewStore()), time.Second*10,
+		WithMaxWindow(time.Minute))
+
+	// A user with a window of their own starts counting afresh once it passed, although the
+	// cached state lives for the longest window
+	userId := uuid.New().String()
+	assert.Nil(t, rateService.UpdateUserRateLimit(ctx, userId, 2, time.Millisecond*100))
+	for _, expect := range []bool{true, true, false} {
+		allowed, err := rateService.RateLimit(ctx, userId, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, expect, allowed)
+	}
+	time.Sleep(time.Millisecond * 150)
+	allowed, err := rateService.RateLimit(ctx, userId, 0)
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+
+	rateLimit, err := rateService.GetUserRateLimit(ctx, userId)
+	assert.Nil(t, err)
+	assert.Equal(t, "100ms", rateLimit.Window)
+
+	// Changing only the limit keeps the window
+	assert.Nil(t, rateService.UpdateUserRateLimit(ctx, userId, 5, 0))
+	rateLimit, err = rateService.GetUserRateLimit(ctx, userId)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, rateLimit.Limit)
+	assert.Equal(t, "100ms", rateLimit.Window)
+
+	// Checks may ask for a window, other users are counted in the service's
+	decision, err := rateService.Check(ctx, service.CheckRequest{UserId: uuid.New().String(), Limit: 3, Window: time.Second * 30})
+	assert.Nil(t, err)
+	assert.Equal(t, time.Second*30, decision.Binding.Window)
+	assert.WithinDuration(t, time.Now().Add(time.Second*30), decision.Binding.ResetsAt, time.Second)
+
+	decision, err = rateService.Check(ctx, service.CheckRequest{UserId: uuid.New().String(), Limit: 3})
+	assert.Nil(t, err)
+	assert.Equal(t, time.Second*10, decision.Binding.Window)
+
+	// Windows past the longest allowed are rejected
+	_, err = rateService.Check(ctx, service.CheckRequest{UserId: userId, Window: time.Hour})
+	assert.ErrorIs(t, err, domain.ErrInvalidWindow)
+	assert.ErrorIs(t, rateService.UpdateUserRateLimit(ctx, userId, 5, -time.Second), domain.ErrInvalidWindow)
+}
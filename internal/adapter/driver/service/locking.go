@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	domainModel "github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+	"github.com/pkg/errors"
+)
+
+// WithRowLocking makes the database the source of truth for counting. Every check reads the
+// user's record with a row lock (SELECT ... FOR UPDATE) and counts there, so concurrent checks
+// from any number of instances queue on the row instead of losing updates. A user's first request
+// inserts an empty record before counting, so there is a row to queue on from the start. The
+// cache is not used for counting in this mode.
+func WithRowLocking() Option {
+	return func(rls *RateLimitService) {
+		rls.rowLocking = true
+	}
+}
+
+// WithIsolation runs the service's transactions at the given isolation level instead of the
+// database's default.
+func WithIsolation(level sql.IsolationLevel) Option {
+	return func(rls *RateLimitService) {
+		rls.txOptions = append(rls.txOptions, db.WithIsolation(level))
+	}
+}
+
+// readRateLimit reads the user's stored rate limit, locking it in row locking mode.
+func (rls *RateLimitService) readRateLimit(ctx context.Context, repo repository.UserRateLimitRepository, userId string) (*domainModel.UserRateLimit, error) {
+	if rls.rowLocking {
+		return repo.GetRateLimitByUserIdForUpdate(ctx, userId)
+	}
+	return repo.GetRateLimitByUserId(ctx, userId)
+}
+
+// createLocked gives a user without a record an empty one and reads it back locked. A user
+// without a record has no row to lock yet, so concurrent first requests would race to create it;
+// inserting first makes them queue on the new row like later requests do. The insert of a losing
+// request waits for the winner's transaction and then leaves its record alone.
+func (rls *RateLimitService) createLocked(ctx context.Context, repo repository.UserRateLimitRepository, userId string, limit int, window time.Duration) (*domainModel.UserRateLimit, error) {
+	created := domainModel.UserRateLimit{UserId: userId, RateLimit: limit, Timestamp: time.Now(), Window: window}
+	if _, err := repo.CreateRateLimit(ctx, created); err != nil && !errors.Is(err, domain.ErrRateLimitConflict) {
+		return nil, errors.Wrap(err, "failed to create user rate limit in repo")
+	}
+
+	rateLimit, err := repo.GetRateLimitByUserIdForUpdate(ctx, userId)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get user rate limit from repo")
+	}
+	if rateLimit == nil {
+		// The winner's record is not visible to this transaction's snapshot, start over
+		return nil, domain.ErrRateLimitConflict
+	}
+	return rateLimit, nil
+}
+
+// cachedRateLimit fetches the user's cached state. In row locking mode the cache does not hold
+// counted state, so it always misses.
+func (rls *RateLimitService) cachedRateLimit(ctx context.Context, userId string) ([]byte, error) {
+	if rls.rowLocking {
+		return nil, driven.ErrCacheMissed
+	}
+	return rls.cache.Fetch(ctx, userId)
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/cache"
+	drivenDb "github.com/nullexp/limiter-x/internal/adapter/driven/db"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/db/memory"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/db/migration"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/db/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitService_RowLocking_IgnoresCachedCounts(t *testing.T) {
+	ctx := context.Background()
+	memoryCache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, memoryCache.Connect())
+	defer memoryCache.Disconnect()
+
+	service := NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), memoryCache, memory.NewTransactionFactory(memory.NewStore()), time.Second*10,
+		WithRowLocking())
+
+	// A cached state claiming the user is exhausted has no say
+	userId := uuid.New().String()
+	err := memoryCache.Set(ctx, userId, []byte(`{"userId":"`+userId+`","requestCount":2,"rateLimit":2,"timestamp":"`+time.Now().Format(time.RFC3339)+`"}`), time.Hour)
+	assert.Nil(t, err)
+
+	for _, expect := range []bool{true, true, false} {
+		allowed, err := service.RateLimit(ctx, userId, 2)
+		assert.Nil(t, err)
+		assert.Equal(t, expect, allowed)
+	}
+}
+
+func TestRateLimitService_RowLocking_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "rates.db")
+	migrator, err := migration.New(drivenDb.Sqlite, path)
+	assert.Nil(t, err)
+	assert.Nil(t, migrator.Up())
+	assert.Nil(t, migrator.Close())
+	sqlDb, err := drivenDb.OpenSqlite(path)
+	assert.Nil(t, err)
+	defer sqlDb.Close()
+
+	service := NewRateLimitService(repository.NewSqliteUserRateLimitRepositoryFactory(), unavailableCache{}, drivenDb.NewSqliteDbTransactionFactory(sqlDb), time.Second*10,
+		WithRowLocking(), WithIsolation(sql.LevelSerializable))
+
+	// Counting in the database never lets concurrent checks past the limit
+	userId := uuid.New().String()
+	allowed := make(chan bool, 30)
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := service.RateLimit(ctx, userId, 10)
+			assert.Nil(t, err)
+			allowed <- ok
+		}()
+	}
+	wg.Wait()
+	close(allowed)
+
+	count := 0
+	for ok := range allowed {
+		if ok {
+			count++
+		}
+	}
+	assert.Equal(t, 10, count)
+}
+
+func TestRateLimitService_RowLocking_ConcurrentFirstRequests(t *testing.T) {
+	ctx := context.Background()
+	repoFactory := memory.NewUserRateLimitRepositoryFactory()
+	transactionFactory := memory.NewTransactionFactory(memory.NewStore())
+	service := NewRateLimitService(repoFactory, unavailableCache{}, transactionFactory, time.Second*10,
+		WithRowLocking())
+
+	// The user has no record yet, concurrent first requests must not overwrite each other's count
+	userId := uuid.New().String()
+	allowed := make(chan bool, 20)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := service.RateLimit(ctx, userId, 10)
+			assert.Nil(t, err)
+			allowed <- ok
+		}()
+	}
+	wg.Wait()
+	close(allowed)
+
+	count := 0
+	for ok := range allowed {
+		if ok {
+			count++
+		}
+	}
+	assert.Equal(t, 10, count)
+
+	handler, err := transactionFactory.NewTransaction().Begin(ctx)
+	assert.Nil(t, err)
+	stored, err := repoFactory.New(handler).GetRateLimitByUserId(ctx, userId)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, stored.RequestCount)
+}
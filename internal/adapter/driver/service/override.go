@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	domainModel "github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+	"github.com/nullexp/limiter-x/internal/port/driver/service"
+	"github.com/pkg/errors"
+)
+
+// maxOverrideDuration is the longest an override may be set for, so one can't be forgotten.
+const maxOverrideDuration = 24 * time.Hour
+
+// maxOverrideMultiplier is the most an override may multiply limits by.
+const maxOverrideMultiplier = 1000
+
+// overrideChannel is the pub/sub channel instances announce a changed override on.
+const overrideChannel = "limiter-x:override"
+
+// WithOverrides lets admins put every key under a global emergency override: allowing or denying
+// all requests, or multiplying every limit. Each instance keeps the override in memory and
+// reloads it every pollInterval, and at once when another instance announces a change on pubsub,
+// which may be nil to rely on polling alone. Overrides end on their own once they expire.
+func WithOverrides(repoFactory repository.OverrideRepositoryFactory, pubsub driven.PubSub, pollInterval time.Duration) Option {
+	return func(rls *RateLimitService) {
+		rls.overrideRepoFactory = repoFactory
+		rls.overrides = &overrideState{pubsub: pubsub, interval: pollInterval}
+	}
+}
+
+// overrideState is the override this instance applies.
+type overrideState struct {
+	pubsub   driven.PubSub
+	interval time.Duration
+	current  atomic.Pointer[domainModel.Override]
+}
+
+// activeOverride returns the override in effect, nil if none is set or it expired.
+func (rls *RateLimitService) activeOverride() *domainModel.Override {
+	if rls.overrides == nil {
+		return nil
+	}
+	override := rls.overrides.current.Load()
+	if override == nil || !override.Active(time.Now()) {
+		return nil
+	}
+	return override
+}
+
+// scaled returns what the override in effect leaves of a limit.
+func (rls *RateLimitService) scaled(limit int64) int64 {
+	if override := rls.activeOverride(); override != nil {
+		return override.Scale(limit)
+	}
+	return limit
+}
+
+// scaledPolicy returns the limits of a policy as the override in effect leaves them. The limits
+// given are not changed, as they may be cached.
+func (rls *RateLimitService) scaledPolicy(policy []domainModel.PolicyLimit) []domainModel.PolicyLimit {
+	override := rls.activeOverride()
+	if override == nil || override.Mode != domainModel.OverrideMultiplier {
+		return policy
+	}
+	limits := make([]domainModel.PolicyLimit, len(policy))
+	for i, limit := range policy {
+		limits[i] = limit
+		limits[i].Limit = int(override.Scale(int64(limit.Limit)))
+	}
+	return limits
+}
+
+// overrideDecision decides a request while all requests are allowed or denied, nil if the
+// override in effect, if any, leaves requests to be counted.
+func (rls *RateLimitService) overrideDecision(userId string) *service.DecisionModel {
+	override := rls.activeOverride()
+	if override == nil {
+		return nil
+	}
+	switch override.Mode {
+	case domainModel.OverrideAllowAll:
+		rls.recordUsage(userId, 1, 0)
+		return &service.DecisionModel{Allowed: true, Reason: service.ReasonAllowAll}
+	case domainModel.OverrideDenyAll:
+		rls.recordUsage(userId, 0, 1)
+		return &service.DecisionModel{Reason: service.ReasonDenyAll}
+	}
+	return nil
+}
+
+// RunOverrides keeps the override of this instance up to date until ctx is done. It does nothing
+// unless overrides are enabled.
+func (rls *RateLimitService) RunOverrides(ctx context.Context) {
+	if rls.overrides == nil {
+		return
+	}
+
+	var changes <-chan []byte
+	if rls.overrides.pubsub != nil {
+		var err error
+		if changes, err = rls.overrides.pubsub.Subscribe(ctx, overrideChannel); err != nil {
+			log.Printf("failed to subscribe to override changes, polling only: %v", err)
+		}
+	}
+	if err := rls.loadOverride(ctx); err != nil {
+		log.Printf("failed to load override: %v", err)
+	}
+
+	var poll <-chan time.Time
+	if rls.overrides.interval > 0 {
+		ticker := time.NewTicker(rls.overrides.interval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-changes:
+			if !ok {
+				// Closed when ctx is done, polling carries on until then
+				changes = nil
+				continue
+			}
+		case <-poll:
+		}
+		if err := rls.loadOverride(ctx); err != nil {
+			log.Printf("failed to load override: %v", err)
+		}
+	}
+}
+
+// loadOverride replaces the override of this instance with the stored one.
+func (rls *RateLimitService) loadOverride(ctx context.Context) error {
+	var override *domainModel.Override
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		var err error
+		override, err = rls.overrideRepoFactory.New(tx).GetOverride(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to get override from repo")
+		}
+		return nil
+	}, db.ReadOnly())
+	if err != nil {
+		return err
+	}
+	rls.overrides.current.Store(override)
+	return nil
+}
+
+// announceOverride tells the other instances to reload the override. The change is stored, so
+// failing to announce it only delays it until they poll.
+func (rls *RateLimitService) announceOverride(ctx context.Context) {
+	if rls.overrides.pubsub == nil {
+		return
+	}
+	if err := rls.overrides.pubsub.Publish(ctx, overrideChannel, []byte("changed")); err != nil {
+		log.Printf("failed to announce override change: %v", err)
+	}
+}
+
+// SetOverride puts every key under the override for its duration, replacing any override set
+// before, and returns it with when it expires.
+func (rls *RateLimitService) SetOverride(ctx context.Context, model service.OverrideModel) (*service.OverrideModel, error) {
+	if rls.overrideRepoFactory == nil {
+		return nil, domain.ErrOverridesDisabled
+	}
+
+	override := domainModel.Override{Mode: domainModel.OverrideMode(model.Mode), Reason: model.Reason}
+	switch override.Mode {
+	case domainModel.OverrideAllowAll, domainModel.OverrideDenyAll:
+	case domainModel.OverrideMultiplier:
+		// Written so a NaN multiplier is rejected too
+		if !(model.Multiplier > 0 && model.Multiplier <= maxOverrideMultiplier) {
+			return nil, domain.ErrInvalidOverrideMultiplier
+		}
+		override.Multiplier = model.Multiplier
+	default:
+		return nil, domain.ErrInvalidOverrideMode
+	}
+	if model.Duration <= 0 || model.Duration > maxOverrideDuration {
+		return nil, domain.ErrInvalidOverrideDuration
+	}
+	// Expiries are stored in microseconds at best
+	override.ExpiresAt = time.Now().Add(model.Duration).Truncate(time.Millisecond)
+
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		repo := rls.overrideRepoFactory.New(tx)
+		existing, err := repo.GetOverride(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to get override from repo")
+		}
+		if err := repo.SetOverride(ctx, override); err != nil {
+			return errors.Wrap(err, "failed to set override in repo")
+		}
+		return rls.auditOverrideChange(ctx, tx, existing, &override)
+	})
+	if err != nil {
+		return nil, err
+	}
+	rls.overrides.current.Store(&override)
+	rls.announceOverride(ctx)
+	return overrideModelOf(override), nil
+}
+
+// ClearOverride ends the override before it expires.
+func (rls *RateLimitService) ClearOverride(ctx context.Context) error {
+	if rls.overrideRepoFactory == nil {
+		return domain.ErrOverridesDisabled
+	}
+
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		repo := rls.overrideRepoFactory.New(tx)
+		existing, err := repo.GetOverride(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to get override from repo")
+		}
+		if err := repo.DeleteOverride(ctx); err != nil {
+			return err
+		}
+		return rls.auditOverrideChange(ctx, tx, existing, nil)
+	})
+	if err != nil {
+		return err
+	}
+	rls.overrides.current.Store(nil)
+	rls.announceOverride(ctx)
+	return nil
+}
+
+// GetOverride returns the override in effect.
+func (rls *RateLimitService) GetOverride(ctx context.Context) (*service.OverrideModel, error) {
+	if rls.overrideRepoFactory == nil {
+		return nil, domain.ErrOverridesDisabled
+	}
+
+	var override *domainModel.Override
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		var err error
+		override, err = rls.overrideRepoFactory.New(tx).GetOverride(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to get override from repo")
+		}
+		return nil
+	}, db.ReadOnly())
+	if err != nil {
+		return nil, err
+	}
+	if override == nil || !override.Active(time.Now()) {
+		return nil, domain.ErrOverrideNotFound
+	}
+	return overrideModelOf(*override), nil
+}
+
+// overrideModelOf describes an override.
+func overrideModelOf(override domainModel.Override) *service.OverrideModel {
+	return &service.OverrideModel{
+		Mode:       string(override.Mode),
+		Multiplier: override.Multiplier,
+		Reason:     override.Reason,
+		ExpiresAt:  override.ExpiresAt,
+	}
+}
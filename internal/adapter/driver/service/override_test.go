@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/cache"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/db/memory"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	domainModel "github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driver/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// broadcastPubSub delivers messages to every subscriber in-process.
+type broadcastPubSub struct {
+	mu          sync.Mutex
+	subscribers []chan []byte
+}
+
+func (ps *broadcastPubSub) Publish(ctx context.Context, channel string, message []byte) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	for _, subscriber := range ps.subscribers {
+		subscriber <- message
+	}
+	return nil
+}
+
+func (ps *broadcastPubSub) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	messages := make(chan []byte, 16)
+	ps.subscribers = append(ps.subscribers, messages)
+	return messages, nil
+}
+
+func (ps *broadcastPubSub) subscribed() int {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return len(ps.subscribers)
+}
+
+func TestRateLimitService_Overrides(t *testing.T) {
+	ctx := context.Background()
+	memoryCache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, memoryCache.Connect())
+	t.Cleanup(func() { memoryCache.Disconnect() })
+	rateService := newPolicyTestService(t, memoryCache, WithOverrides(memory.NewOverrideRepositoryFactory(), nil, 0))
+	userId := uuid.New().String()
+
+	_, err := rateService.GetOverride(ctx)
+	assert.ErrorIs(t, err, domain.ErrOverrideNotFound)
+
+	override, err := rateService.SetOverride(ctx, service.OverrideModel{Mode: "allow_all", Reason: "incident", Duration: time.Minute})
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), override.ExpiresAt, time.Second)
+	for i := 0; i < 3; i++ {
+		decision, err := rateService.Check(ctx, service.CheckRequest{UserId: userId, Limit: 1})
+		assert.Nil(t, err)
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, service.ReasonAllowAll, decision.Reason)
+	}
+
+	_, err = rateService.SetOverride(ctx, service.OverrideModel{Mode: "deny_all", Duration: time.Minute})
+	assert.Nil(t, err)
+	decision, err := rateService.Check(ctx, service.CheckRequest{UserId: userId, Limit: 1})
+	assert.Nil(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, service.ReasonDenyAll, decision.Reason)
+	lease, err := rateService.LeaseQuota(ctx, userId, 5, 10, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, lease.Granted)
+
+	// Neither counted anything, the multiplier halves the user's limit and the policy's
+	_, err = rateService.SetOverride(ctx, service.OverrideModel{Mode: "multiplier", Multiplier: 0.5, Duration: time.Minute})
+	assert.Nil(t, err)
+	for i := 0; i < 2; i++ {
+		decision, err = rateService.Check(ctx, service.CheckRequest{UserId: userId, Limit: 4})
+		assert.Nil(t, err)
+		assert.True(t, decision.Allowed)
+	}
+	decision, err = rateService.Check(ctx, service.CheckRequest{UserId: userId, Limit: 4})
+	assert.Nil(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, int64(2), decision.Binding.Limit)
+
+	other := uuid.New().String()
+	assert.Nil(t, rateService.SetPolicy(ctx, other, []service.LimitModel{{Limit: 3, Window: time.Minute}}))
+	decision, err = rateService.Check(ctx, service.CheckRequest{UserId: other})
+	assert.Nil(t, err)
+	assert.True(t, decision.Allowed)
+	decision, err = rateService.Check(ctx, service.CheckRequest{UserId: other})
+	assert.Nil(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, int64(1), decision.Binding.Limit)
+
+	// Overrides end on their own
+	_, err = rateService.SetOverride(ctx, service.OverrideModel{Mode: "deny_all", Duration: 20 * time.Millisecond})
+	assert.Nil(t, err)
+	time.Sleep(30 * time.Millisecond)
+	decision, err = rateService.Check(ctx, service.CheckRequest{UserId: uuid.New().String()})
+	assert.Nil(t, err)
+	assert.True(t, decision.Allowed)
+	_, err = rateService.GetOverride(ctx)
+	assert.ErrorIs(t, err, domain.ErrOverrideNotFound)
+
+	assert.Nil(t, rateService.ClearOverride(ctx))
+	assert.ErrorIs(t, rateService.ClearOverride(ctx), domain.ErrOverrideNotFound)
+
+	events, err := rateService.ListAuditEvents(ctx, service.AuditQuery{EventType: "override_changed"})
+	assert.Nil(t, err)
+	assert.Len(t, events, 5)
+}
+
+func TestOverride_ScaleClamps(t *testing.T) {
+	override := domainModel.Override{Mode: domainModel.OverrideMultiplier, Multiplier: 1000}
+	assert.Equal(t, int64(10000), override.Scale(10))
+	assert.Equal(t, int64(math.MaxInt64), override.Scale(math.MaxInt64/10))
+	assert.Equal(t, int64(math.MaxInt64), override.Scale(math.MaxInt64))
+
+	override.Multiplier = 0.001
+	assert.Equal(t, int64(1), override.Scale(10))
+}
+
+func TestRateLimitService_OverrideValidation(t *testing.T) {
+	ctx := context.Background()
+	memoryCache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, memoryCache.Connect())
+	t.Cleanup(func() { memoryCache.Disconnect() })
+	rateService := newPolicyTestService(t, memoryCache, WithOverrides(memory.NewOverrideRepositoryFactory(), nil, 0))
+
+	_, err := rateService.SetOverride(ctx, service.OverrideModel{Mode: "allow_some", Duration: time.Minute})
+	assert.ErrorIs(t, err, domain.ErrInvalidOverrideMode)
+	_, err = rateService.SetOverride(ctx, service.OverrideModel{Mode: "multiplier", Duration: time.Minute})
+	assert.ErrorIs(t, err, domain.ErrInvalidOverrideMultiplier)
+	for _, multiplier := range []float64{-1, 1000.5, math.Inf(1), math.NaN()} {
+		_, err = rateService.SetOverride(ctx, service.OverrideModel{Mode: "multiplier", Multiplier: multiplier, Duration: time.Minute})
+		assert.ErrorIs(t, err, domain.ErrInvalidOverrideMultiplier, "multiplier %v", multiplier)
+	}
+	_, err = rateService.SetOverride(ctx, service.OverrideModel{Mode: "deny_all"})
+	assert.ErrorIs(t, err, domain.ErrInvalidOverrideDuration)
+	_, err = rateService.SetOverride(ctx, service.OverrideModel{Mode: "deny_all", Duration: 25 * time.Hour})
+	assert.ErrorIs(t, err, domain.ErrInvalidOverrideDuration)
+
+	disabled := newPolicyTestService(t, memoryCache)
+	_, err = disabled.SetOverride(ctx, service.OverrideModel{Mode: "deny_all", Duration: time.Minute})
+	assert.ErrorIs(t, err, domain.ErrOverridesDisabled)
+}
+
+func TestRateLimitService_OverridePropagation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	memoryCache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, memoryCache.Connect())
+	t.Cleanup(func() { memoryCache.Disconnect() })
+
+	// Two instances sharing a database, told of changes only through pub/sub
+	txFactory, pubsub := memory.NewTransactionFactory(memory.NewStore()), &broadcastPubSub{}
+	newInstance := func() *RateLimitService {
+		return NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), memoryCache, txFactory, time.Minute,
+			WithOverrides(memory.NewOverrideRepositoryFactory(), pubsub, 0))
+	}
+	first, second := newInstance(), newInstance()
+	go second.RunOverrides(ctx)
+	assert.Eventually(t, func() bool { return pubsub.subscribed() == 1 }, time.Second, time.Millisecond)
+
+	_, err := first.SetOverride(ctx, service.OverrideModel{Mode: "deny_all", Duration: time.Minute})
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		decision, err := second.Check(ctx, service.CheckRequest{UserId: uuid.New().String()})
+		return err == nil && decision.Reason == service.ReasonDenyAll
+	}, time.Second, time.Millisecond)
+
+	assert.Nil(t, first.ClearOverride(ctx))
+	assert.Eventually(t, func() bool { return second.activeOverride() == nil }, time.Second, time.Millisecond)
+}
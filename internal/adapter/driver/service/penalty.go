@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	domainModel "github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driver/service"
+)
+
+// PenaltyPolicy decides when a key that keeps sending requests after being denied is banned, and
+// for how long.
+type PenaltyPolicy struct {
+	// Threshold is how many denials within Window earn a ban.
+	Threshold int
+	Window    time.Duration
+	// Bans are the lengths of consecutive bans; a key banned more often stays at the last one.
+	Bans []time.Duration
+}
+
+// DefaultPenaltyPolicy bans keys denied ten times within a minute, for a minute, then ten
+// minutes, then an hour.
+var DefaultPenaltyPolicy = PenaltyPolicy{
+	Threshold: 10,
+	Window:    time.Minute,
+	Bans:      []time.Duration{time.Minute, 10 * time.Minute, time.Hour},
+}
+
+// WithPenaltyBox bans keys that keep sending requests after being denied. Denials are counted
+// per key in the cache, and a key reaching the policy's threshold is denied every request without
+// counting until its ban ends. Each ban placed within twice the longest ban of the previous one
+// is the next longer one. Bans are not placed while the cache is unavailable.
+func WithPenaltyBox(policy PenaltyPolicy) Option {
+	return func(rls *RateLimitService) {
+		if policy.Threshold > 0 && policy.Window > 0 && len(policy.Bans) > 0 {
+			rls.penalties = &policy
+		}
+	}
+}
+
+// penaltyOf returns the key's penalty if it is banned. Any failure to read the penalty just
+// means counting as usual.
+func (rls *RateLimitService) penaltyOf(ctx context.Context, userId string) *domainModel.Penalty {
+	if rls.penalties == nil {
+		return nil
+	}
+
+	data, err := rls.cache.Fetch(ctx, penaltyKey(userId))
+	if err != nil {
+		return nil
+	}
+
+	var penalty domainModel.Penalty
+	if err := json.Unmarshal(data, &penalty); err != nil || !penalty.Active(time.Now()) {
+		return nil
+	}
+	return &penalty
+}
+
+// penaltyDecision denies a request of a banned key.
+func (rls *RateLimitService) penaltyDecision(userId string, penalty *domainModel.Penalty) *service.DecisionModel {
+	rls.recordUsage(userId, 0, 1)
+	return &service.DecisionModel{Reason: service.ReasonPenalized, PenalizedUntil: penalty.Until}
+}
+
+// recordViolation counts a denial of the key and bans the key once it was denied often enough.
+// The request has already been decided, so failures are only logged.
+func (rls *RateLimitService) recordViolation(ctx context.Context, userId string) {
+	if rls.penalties == nil {
+		return
+	}
+
+	// Only the denial reaching the threshold places the ban, however many instances count
+	count, err := rls.cache.Increment(ctx, violationKey(userId), 1, rls.penalties.Window)
+	if err != nil || count != int64(rls.penalties.Threshold) {
+		return
+	}
+	if err := rls.cache.Delete(ctx, violationKey(userId)); err != nil {
+		log.Printf("failed to reset the denials of user %s: %v", userId, err)
+	}
+
+	var penalty domainModel.Penalty
+	err = rls.cache.Update(ctx, penaltyKey(userId), rls.penaltyMemory(), func(current []byte) ([]byte, error) {
+		penalty = domainModel.Penalty{}
+		if current != nil {
+			if err := json.Unmarshal(current, &penalty); err != nil {
+				// A record that cannot be read starts the escalation over
+				penalty = domainModel.Penalty{}
+			}
+		}
+		penalty.Level++
+		ban := rls.penalties.Bans[min(penalty.Level, len(rls.penalties.Bans))-1]
+		penalty.Until = time.Now().Add(ban)
+		return json.Marshal(penalty)
+	})
+	if err != nil {
+		log.Printf("failed to penalize user %s: %v", userId, err)
+		return
+	}
+	log.Printf("user %s was denied %d times within %s and is banned until %s", userId, rls.penalties.Threshold,
+		rls.penalties.Window, penalty.Until.UTC().Format(time.RFC3339))
+}
+
+// penaltyMemory is how long after a key was last banned its next ban is still a longer one.
+func (rls *RateLimitService) penaltyMemory() time.Duration {
+	return 2 * rls.penalties.Bans[len(rls.penalties.Bans)-1]
+}
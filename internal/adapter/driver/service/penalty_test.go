@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/cache"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/db/memory"
+	"github.com/nullexp/limiter-x/internal/port/driver/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitService_PenaltyBox(t *testing.T) {
+	ctx := context.Background()
+	memoryCache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, memoryCache.Connect())
+	t.Cleanup(func() { memoryCache.Disconnect() })
+	rateService := NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), memoryCache, memory.NewTransactionFactory(memory.NewStore()), time.Minute,
+		WithPenaltyBox(PenaltyPolicy{Threshold: 3, Window: time.Minute, Bans: []time.Duration{50 * time.Millisecond, 200 * time.Millisecond}}))
+	userId := uuid.New().String()
+
+	check := func() *service.DecisionModel {
+		decision, err := rateService.Check(ctx, service.CheckRequest{UserId: userId, Limit: 1})
+		assert.Nil(t, err)
+		return decision
+	}
+
+	assert.True(t, check().Allowed)
+	for i := 0; i < 3; i++ {
+		decision := check()
+		assert.False(t, decision.Allowed)
+		assert.Empty(t, decision.Reason)
+	}
+
+	// The third denial placed the first ban
+	decision := check()
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, service.ReasonPenalized, decision.Reason)
+	assert.WithinDuration(t, time.Now().Add(50*time.Millisecond), decision.PenalizedUntil, 50*time.Millisecond)
+	lease, err := rateService.LeaseQuota(ctx, userId, 5, 10, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, lease.Granted)
+
+	// Requests during the ban are not counted as denials, the next ban takes three more
+	time.Sleep(60 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		assert.Empty(t, check().Reason)
+	}
+	decision = check()
+	assert.Equal(t, service.ReasonPenalized, decision.Reason)
+	assert.True(t, decision.PenalizedUntil.After(time.Now().Add(100*time.Millisecond)))
+
+	// Other keys are not affected
+	decision, err = rateService.Check(ctx, service.CheckRequest{UserId: uuid.New().String()})
+	assert.Nil(t, err)
+	assert.True(t, decision.Allowed)
+}
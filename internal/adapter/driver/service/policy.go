@@ -0,0 +1,432 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	domainModel "github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+	"github.com/nullexp/limiter-x/internal/port/driver/service"
+	"github.com/pkg/errors"
+)
+
+// maxPolicyWindow is the longest window a policy limit may have. Longer periods are better
+// served by quotas, which are aligned to the calendar.
+const maxPolicyWindow = 24 * time.Hour
+
+// WithPolicies limits keys that have a rate limit policy by every limit of the policy instead
+// of their single limit. Policies are cached for cacheTTL, including that a key has none, and a
+// changed policy is dropped from the cache. Behind a near cache every instance keeps them
+// in-process and drops its copy when a policy changes.
+func WithPolicies(repoFactory repository.PolicyRepositoryFactory, cacheTTL time.Duration) Option {
+	return func(rls *RateLimitService) {
+		rls.policyRepoFactory = repoFactory
+		rls.policyTTL = cacheTTL
+	}
+}
+
+// policyFor returns the limits of the user's policy, none if the user has no policy.
+func (rls *RateLimitService) policyFor(ctx context.Context, tx db.DbHandler, userId string) ([]domainModel.PolicyLimit, error) {
+	if rls.policyRepoFactory == nil {
+		return nil, nil
+	}
+
+	if rls.policyTTL > 0 {
+		// Any cache error just means reading the policy from the repository
+		if data, err := rls.cache.Fetch(ctx, limitsKey(userId)); err == nil {
+			var limits []domainModel.PolicyLimit
+			if err := json.Unmarshal(data, &limits); err != nil {
+				return nil, errors.Wrap(err, "failed to unmarshal cached policy")
+			}
+			return limits, nil
+		}
+	}
+
+	limits, err := rls.policyRepoFactory.New(tx).GetPolicy(ctx, userId)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get policy from repo")
+	}
+	// Only the limits are cached, their counts are kept elsewhere
+	limits = withCounts(limits, nil)
+	if rls.policyTTL > 0 {
+		data, err := json.Marshal(limits)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal policy")
+		}
+		if err := rls.cache.Set(ctx, limitsKey(userId), data, rls.policyTTL); err != nil {
+			log.Printf("failed to cache policy of %s: %v", userId, err)
+		}
+	}
+	return limits, nil
+}
+
+// forgetPolicy drops the cached limits of the key's policy after it changed, on every instance
+// when a near cache keeps them. The change is already stored, so a failure only leaves a stale copy
+// until it expires.
+func (rls *RateLimitService) forgetPolicy(ctx context.Context, key string) {
+	if rls.policyTTL <= 0 {
+		return
+	}
+	if err := rls.cache.Delete(ctx, limitsKey(key)); err != nil {
+		log.Printf("failed to invalidate cached policy of %s: %v", key, err)
+	}
+}
+
+// consumePolicy takes up to units requests from every limit of the user's policy at once, and
+// returns how many were granted along with the counted limits. The counts of all limits are kept
+// in one cache entry, so they are checked and counted in one atomic update. Units counted in the
+// cache are recorded in undo unless it is nil.
+func (rls *RateLimitService) consumePolicy(ctx context.Context, tx db.DbHandler, undo *undoLog, userId string, policy []domainModel.PolicyLimit, units int) (int, []domainModel.PolicyLimit, error) {
+	policy = rls.scaledPolicy(policy)
+	// The database is authoritative, count there with the policy's rows locked
+	if rls.rowLocking {
+		return rls.consumePolicyFromRepository(ctx, tx, userId, policy, units)
+	}
+
+	var (
+		granted   int
+		limits    []domainModel.PolicyLimit
+		decodeErr error
+	)
+	err := rls.cache.Update(ctx, policyKey(userId), longestWindow(policy), func(current []byte) ([]byte, error) {
+		var counted []domainModel.PolicyLimit
+		if current != nil {
+			if decodeErr = json.Unmarshal(current, &counted); decodeErr != nil {
+				return nil, decodeErr
+			}
+		}
+
+		limits = withCounts(policy, counted)
+		granted = domainModel.TakePolicy(limits, units, time.Now())
+		if granted == 0 {
+			return nil, nil
+		}
+		return json.Marshal(limits)
+	})
+	if decodeErr != nil {
+		return 0, nil, errors.Wrap(decodeErr, "failed to unmarshal cached policy counts")
+	}
+	if err != nil {
+		// The cache is unavailable, let the failure policy decide
+		return rls.consumePolicyDegraded(ctx, tx, userId, policy, units)
+	}
+	if granted > 0 {
+		countedAt := time.Now()
+		undo.add(func(ctx context.Context) {
+			if _, err := rls.creditPolicyCounts(ctx, nil, userId, policy, granted, countedAt); err != nil {
+				log.Printf("failed to give back %d requests to the policy of %s: %v", granted, userId, err)
+			}
+		})
+	}
+	return granted, limits, nil
+}
+
+// consumePolicyDegraded limits a request of a user with a policy according to the failure
+// policy after the cache failed. Only counting in the database keeps the counts to report.
+func (rls *RateLimitService) consumePolicyDegraded(ctx context.Context, tx db.DbHandler, userId string, policy []domainModel.PolicyLimit, units int) (int, []domainModel.PolicyLimit, error) {
+	switch rls.failurePolicy {
+	case FailOpen:
+		return units, nil, nil
+	case FailClosed:
+		return 0, nil, nil
+	case FailLocal:
+		return rls.local.takePolicy(userId, policy, units), nil, nil
+	default:
+		return rls.consumePolicyFromRepository(ctx, tx, userId, policy, units)
+	}
+}
+
+// consumePolicyFromRepository counts the request against the policy counts stored in the
+// repository.
+func (rls *RateLimitService) consumePolicyFromRepository(ctx context.Context, tx db.DbHandler, userId string, policy []domainModel.PolicyLimit, units int) (int, []domainModel.PolicyLimit, error) {
+	repo := rls.policyRepoFactory.New(tx)
+	limits, err := repo.GetPolicyForUpdate(ctx, userId)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "failed to get policy from repo")
+	}
+	if len(limits) == 0 {
+		// Deleted since it was cached, there are no counts to keep
+		limits = withCounts(policy, nil)
+		return domainModel.TakePolicy(limits, units, time.Now()), limits, nil
+	}
+	// Only the counts are stored back
+	limits = rls.scaledPolicy(limits)
+
+	granted := domainModel.TakePolicy(limits, units, time.Now())
+	if granted == 0 {
+		return 0, limits, nil
+	}
+	if err := repo.UpdatePolicyCounts(ctx, userId, limits); err != nil {
+		return 0, nil, errors.Wrap(err, "failed to update policy counts in repo")
+	}
+	return granted, limits, nil
+}
+
+// creditPolicy gives units of a lease taken at leasedAt back to every limit of the user's policy
+// whose window has not started over since. It returns the units credited.
+func (rls *RateLimitService) creditPolicy(ctx context.Context, userId string, policy []domainModel.PolicyLimit, units int, leasedAt time.Time) (int, error) {
+	var (
+		credited bool
+		err      error
+	)
+	if rls.rowLocking {
+		err = rls.inTransaction(ctx, func(tx db.DbHandler) error {
+			credited, err = rls.creditPolicyCounts(ctx, tx, userId, policy, units, leasedAt)
+			return err
+		})
+	} else {
+		credited, err = rls.creditPolicyCounts(ctx, nil, userId, policy, units, leasedAt)
+	}
+	if err != nil || !credited {
+		return 0, err
+	}
+	return units, nil
+}
+
+// creditPolicyCounts gives units back to every limit of the key's policy whose window has not
+// started over since the given time, where the counts are kept: in the repository within tx in
+// row locking mode, in the cache otherwise. It reports whether any limit was credited.
+func (rls *RateLimitService) creditPolicyCounts(ctx context.Context, tx db.DbHandler, key string, policy []domainModel.PolicyLimit, units int, since time.Time) (bool, error) {
+	if rls.rowLocking {
+		repo := rls.policyRepoFactory.New(tx)
+		limits, err := repo.GetPolicyForUpdate(ctx, key)
+		if err != nil {
+			return false, errors.Wrap(err, "failed to get policy from repo")
+		}
+		if !creditLimits(limits, units, since) {
+			return false, nil
+		}
+		if err := repo.UpdatePolicyCounts(ctx, key, limits); err != nil {
+			return false, errors.Wrap(err, "failed to update policy counts in repo")
+		}
+		return true, nil
+	}
+
+	var credited bool
+	err := rls.cache.Update(ctx, policyKey(key), longestWindow(policy), func(current []byte) ([]byte, error) {
+		credited = false
+		if current == nil {
+			return nil, nil
+		}
+		var limits []domainModel.PolicyLimit
+		if err := json.Unmarshal(current, &limits); err != nil {
+			return nil, err
+		}
+		if credited = creditLimits(limits, units, since); !credited {
+			return nil, nil
+		}
+		return json.Marshal(limits)
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "failed to credit policy counts in cache")
+	}
+	return credited, nil
+}
+
+// creditLimits takes units off the count of every limit whose current window started no later
+// than since, and reports whether there was any.
+func creditLimits(limits []domainModel.PolicyLimit, units int, since time.Time) bool {
+	credited, now := false, time.Now()
+	for i := range limits {
+		if limits[i].CountAt(now) == 0 || limits[i].WindowStart.After(since) {
+			continue
+		}
+		limits[i].Count -= units
+		if limits[i].Count < 0 {
+			limits[i].Count = 0
+		}
+		credited = true
+	}
+	return credited
+}
+
+// SetPolicy replaces the limits of a key's policy. Limits with a window the policy already had
+// keep what they counted.
+func (rls *RateLimitService) SetPolicy(ctx context.Context, key string, models []service.LimitModel) error {
+	if rls.policyRepoFactory == nil {
+		return domain.ErrPoliciesDisabled
+	}
+
+	limits, err := policyFromModels(models)
+	if err != nil {
+		return err
+	}
+
+	err = rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		repo := rls.policyRepoFactory.New(tx)
+		existing, err := repo.GetPolicyForUpdate(ctx, key)
+		if err != nil {
+			return errors.Wrap(err, "failed to get policy from repo")
+		}
+		if err := repo.SetPolicy(ctx, key, limits); err != nil {
+			return errors.Wrap(err, "failed to set policy in repo")
+		}
+		return rls.auditPolicyChange(ctx, tx, key, existing, limits)
+	})
+	if err != nil {
+		return err
+	}
+	rls.forgetPolicy(ctx, key)
+	return nil
+}
+
+// DeletePolicy removes a key's policy, limiting it by its single limit again.
+func (rls *RateLimitService) DeletePolicy(ctx context.Context, key string) error {
+	if rls.policyRepoFactory == nil {
+		return domain.ErrPoliciesDisabled
+	}
+
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		repo := rls.policyRepoFactory.New(tx)
+		existing, err := repo.GetPolicyForUpdate(ctx, key)
+		if err != nil {
+			return errors.Wrap(err, "failed to get policy from repo")
+		}
+		if err := repo.DeletePolicy(ctx, key); err != nil {
+			return err
+		}
+		return rls.auditPolicyChange(ctx, tx, key, existing, nil)
+	})
+	if err != nil {
+		return err
+	}
+	rls.forgetPolicy(ctx, key)
+	return nil
+}
+
+// GetPolicy returns the limits of a key's policy with what is left of them.
+func (rls *RateLimitService) GetPolicy(ctx context.Context, key string) ([]service.LimitModel, error) {
+	if rls.policyRepoFactory == nil {
+		return nil, domain.ErrPoliciesDisabled
+	}
+
+	var limits []domainModel.PolicyLimit
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		var err error
+		limits, err = rls.policyRepoFactory.New(tx).GetPolicy(ctx, key)
+		if err != nil {
+			return errors.Wrap(err, "failed to get policy from repo")
+		}
+		return nil
+	}, db.ReadOnly())
+	if err != nil {
+		return nil, err
+	}
+	if len(limits) == 0 {
+		return nil, domain.ErrPolicyNotFound
+	}
+
+	// Unless the database counts, the current counts are in the cache
+	if !rls.rowLocking {
+		data, err := rls.cache.Fetch(ctx, policyKey(key))
+		if err != nil && !errors.Is(err, driven.ErrCacheMissed) {
+			return nil, errors.Wrap(err, "failed to fetch policy counts from cache")
+		}
+		var counted []domainModel.PolicyLimit
+		if data != nil {
+			if err := json.Unmarshal(data, &counted); err != nil {
+				return nil, errors.Wrap(err, "failed to unmarshal cached policy counts")
+			}
+		}
+		limits = withCounts(limits, counted)
+	}
+	return policyBounds(limits), nil
+}
+
+// withCounts returns the limits of a policy with the counts of the counted limits of the same
+// window, and no counts for the others.
+func withCounts(policy, counted []domainModel.PolicyLimit) []domainModel.PolicyLimit {
+	limits := make([]domainModel.PolicyLimit, len(policy))
+	for i, limit := range policy {
+		limits[i] = domainModel.PolicyLimit{Key: limit.Key, Window: limit.Window, Limit: limit.Limit, Shadow: limit.Shadow}
+		for _, count := range counted {
+			if count.Window == limit.Window {
+				limits[i].Count, limits[i].WindowStart = count.Count, count.WindowStart
+			}
+		}
+	}
+	return limits
+}
+
+// policyBounds describes the limits of a policy with what is left of them. Shadow limits that
+// counted more than their limit have less than nothing left.
+func policyBounds(limits []domainModel.PolicyLimit) []service.LimitModel {
+	now := time.Now()
+	bounds := make([]service.LimitModel, 0, len(limits))
+	for _, limit := range limits {
+		count, resetsAt := limit.CountAt(now), limit.WindowStart.Add(limit.Window)
+		if !resetsAt.After(now) {
+			resetsAt = now.Add(limit.Window)
+		}
+		remaining := limit.Limit - count
+		if remaining < 0 && !limit.Shadow {
+			remaining = 0
+		}
+		bounds = append(bounds, service.LimitModel{
+			Limit:     int64(limit.Limit),
+			Window:    limit.Window,
+			Remaining: int64(remaining),
+			ResetsAt:  resetsAt,
+			Shadow:    limit.Shadow,
+		})
+	}
+	return bounds
+}
+
+// policyFromModels validates the limits of a policy to store.
+func policyFromModels(models []service.LimitModel) ([]domainModel.PolicyLimit, error) {
+	if len(models) == 0 {
+		return nil, domain.ErrEmptyPolicy
+	}
+
+	limits := make([]domainModel.PolicyLimit, 0, len(models))
+	windows := make(map[time.Duration]bool, len(models))
+	for _, model := range models {
+		// Windows are stored in milliseconds
+		window := model.Window.Truncate(time.Millisecond)
+		if window <= 0 || window > maxPolicyWindow {
+			return nil, domain.ErrInvalidPolicyWindow
+		}
+		if model.Limit <= 0 {
+			return nil, domain.ErrInvalidPolicyLimit
+		}
+		if windows[window] {
+			return nil, domain.ErrDuplicatePolicyWindow
+		}
+		windows[window] = true
+		limits = append(limits, domainModel.PolicyLimit{Window: window, Limit: int(model.Limit), Shadow: model.Shadow})
+	}
+	return limits, nil
+}
+
+// longestWindow returns the longest window of a policy, for as long as its counts matter.
+func longestWindow(policy []domainModel.PolicyLimit) time.Duration {
+	var longest time.Duration
+	for _, limit := range policy {
+		if limit.Window > longest {
+			longest = limit.Window
+		}
+	}
+	return longest
+}
+
+// describePolicy formats the limits of a policy for the audit log, e.g. "5 per 1s, 100 per 1m0s
+// (shadow)".
+func describePolicy(limits []domainModel.PolicyLimit) string {
+	described := make([]string, 0, len(limits))
+	for _, limit := range limits {
+		description := fmt.Sprintf("%d per %s", limit.Limit, limit.Window)
+		if limit.Shadow {
+			description += " (shadow)"
+		}
+		described = append(described, description)
+	}
+	return strings.Join(described, ", ")
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/cache"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/db/memory"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/port/driven"
+	"github.com/nullexp/limiter-x/internal/port/driver/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPolicyTestService(t *testing.T, cache driven.Cache, opts ...Option) *RateLimitService {
+	opts = append(opts, WithPolicies(memory.NewPolicyRepositoryFactory(), time.Minute), WithAuditLog(memory.NewAuditEventRepositoryFactory(), 0))
+	return NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), cache, memory.NewTransactionFactory(memory.NewStore()), time.Second*10, opts...)
+}
+
+func TestRateLimitService_Policies(t *testing.T) {
+	memoryCache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, memoryCache.Connect())
+	t.Cleanup(func() { memoryCache.Disconnect() })
+
+	tests := []struct {
+		name    string
+		service *RateLimitService
+	}{
+		{name: "Counting in the cache", service: newPolicyTestService(t, memoryCache)},
+		{name: "Counting in the database", service: newPolicyTestService(t, memoryCache, WithRowLocking())},
+		{name: "Counting locally", service: newPolicyTestService(t, unavailableCache{}, WithFailurePolicy(FailLocal, 1))},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			userId := uuid.New().String()
+			assert.Nil(t, test.service.SetPolicy(ctx, userId, []service.LimitModel{
+				{Limit: 5, Window: time.Minute},
+				{Limit: 2, Window: time.Hour},
+			}))
+
+			decision, err := test.service.Check(ctx, service.CheckRequest{UserId: userId})
+			assert.Nil(t, err)
+			assert.True(t, decision.Allowed)
+			allowed, err := test.service.RateLimit(ctx, userId, 0)
+			assert.Nil(t, err)
+			assert.True(t, allowed)
+
+			// The hourly limit is used up, which does not count against the other
+			decision, err = test.service.Check(ctx, service.CheckRequest{UserId: userId})
+			assert.Nil(t, err)
+			assert.False(t, decision.Allowed)
+			if test.service.failurePolicy == FailLocal {
+				return
+			}
+			assert.Equal(t, time.Hour, decision.Binding.Window)
+			assert.Equal(t, int64(0), decision.Binding.Remaining)
+
+			limits, err := test.service.GetPolicy(ctx, userId)
+			assert.Nil(t, err)
+			assert.Len(t, limits, 2)
+			assert.Equal(t, int64(3), limits[0].Remaining)
+			assert.True(t, limits[0].ResetsAt.After(time.Now()))
+		})
+	}
+}
+
+func TestRateLimitService_Policies_NearCache(t *testing.T) {
+	ctx := context.Background()
+	remote := cache.NewMemoryClient(time.Hour, time.Hour)
+	pubsub := &broadcastPubSub{}
+	txFactory := memory.NewTransactionFactory(memory.NewStore())
+
+	// Two instances sharing a database and a remote cache, each keeping policies locally
+	newInstance := func() (*RateLimitService, driven.Cache) {
+		local := cache.NewMemoryClient(time.Hour, time.Hour)
+		nearCache := cache.NewNearCache(local, remote, pubsub, "invalidate", time.Minute, LocalKeyPrefixes()...)
+		assert.Nil(t, nearCache.Connect())
+		t.Cleanup(func() { nearCache.Disconnect() })
+		return NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), nearCache, txFactory, time.Minute,
+			WithPolicies(memory.NewPolicyRepositoryFactory(), time.Minute)), local
+	}
+	first, _ := newInstance()
+	second, secondLocal := newInstance()
+	assert.Eventually(t, func() bool { return pubsub.subscribed() == 2 }, time.Second, time.Millisecond)
+
+	userId := uuid.New().String()
+	assert.Nil(t, first.SetPolicy(ctx, userId, []service.LimitModel{{Limit: 1, Window: time.Minute}}))
+	decision, err := second.Check(ctx, service.CheckRequest{UserId: userId})
+	assert.Nil(t, err)
+	assert.True(t, decision.Allowed)
+	decision, err = second.Check(ctx, service.CheckRequest{UserId: userId})
+	assert.Nil(t, err)
+	assert.False(t, decision.Allowed)
+
+	// The policy is kept in-process until it changes on any instance
+	_, err = secondLocal.Fetch(ctx, limitsKey(userId))
+	assert.Nil(t, err)
+	assert.Nil(t, first.SetPolicy(ctx, userId, []service.LimitModel{{Limit: 5, Window: time.Minute}}))
+	assert.Eventually(t, func() bool {
+		_, err := secondLocal.Fetch(ctx, limitsKey(userId))
+		return err != nil
+	}, time.Second, time.Millisecond)
+	decision, err = second.Check(ctx, service.CheckRequest{UserId: userId})
+	assert.Nil(t, err)
+	assert.True(t, decision.Allowed)
+
+	assert.Nil(t, first.DeletePolicy(ctx, userId))
+	assert.Eventually(t, func() bool {
+		_, err := secondLocal.Fetch(ctx, limitsKey(userId))
+		return err != nil
+	}, time.Second, time.Millisecond)
+}
+
+func TestRateLimitService_ShadowPolicies(t *testing.T) {
+	memoryCache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, memoryCache.Connect())
+	t.Cleanup(func() { memoryCache.Disconnect() })
+
+	tests := []struct {
+		name    string
+		service *RateLimitService
+	}{
+		{name: "Counting in the cache", service: newPolicyTestService(t, memoryCache)},
+		{name: "Counting in the database", service: newPolicyTestService(t, memoryCache, WithRowLocking())},
+		{name: "Counting locally", service: newPolicyTestService(t, unavailableCache{}, WithFailurePolicy(FailLocal, 1))},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			userId := uuid.New().String()
+			assert.Nil(t, test.service.SetPolicy(ctx, userId, []service.LimitModel{
+				{Limit: 3, Window: time.Minute},
+				{Limit: 1, Window: time.Hour, Shadow: true},
+			}))
+
+			decision, err := test.service.Check(ctx, service.CheckRequest{UserId: userId})
+			assert.Nil(t, err)
+			assert.True(t, decision.Allowed)
+			assert.Nil(t, decision.Shadow)
+
+			// The shadow limit would have denied the request, but only the enforced one decides
+			decision, err = test.service.Check(ctx, service.CheckRequest{UserId: userId})
+			assert.Nil(t, err)
+			assert.True(t, decision.Allowed)
+			if test.service.failurePolicy == FailLocal {
+				return
+			}
+			assert.Equal(t, time.Minute, decision.Binding.Window)
+			assert.Equal(t, time.Hour, decision.Shadow.Window)
+			assert.Equal(t, int64(-1), decision.Shadow.Remaining)
+
+			decision, err = test.service.Check(ctx, service.CheckRequest{UserId: userId})
+			assert.Nil(t, err)
+			assert.True(t, decision.Allowed)
+			decision, err = test.service.Check(ctx, service.CheckRequest{UserId: userId})
+			assert.Nil(t, err)
+			assert.False(t, decision.Allowed)
+			assert.Equal(t, time.Minute, decision.Binding.Window)
+
+			// Denied requests are not counted against the shadow limit either
+			limits, err := test.service.GetPolicy(ctx, userId)
+			assert.Nil(t, err)
+			assert.True(t, limits[1].Shadow)
+			assert.Equal(t, int64(-2), limits[1].Remaining)
+		})
+	}
+}
+
+func TestRateLimitService_PolicyLeases(t *testing.T) {
+	ctx := context.Background()
+	memoryCache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, memoryCache.Connect())
+	t.Cleanup(func() { memoryCache.Disconnect() })
+	rateService := newPolicyTestService(t, memoryCache)
+	userId := uuid.New().String()
+
+	assert.Nil(t, rateService.SetPolicy(ctx, userId, []service.LimitModel{{Limit: 10, Window: time.Second * 30}, {Limit: 4, Window: time.Minute}}))
+	lease, err := rateService.LeaseQuota(ctx, userId, 10, 0, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, lease.Granted)
+
+	returned, err := rateService.ReturnQuota(ctx, lease.LeaseId, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, returned)
+	limits, err := rateService.GetPolicy(ctx, userId)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{9, 3}, []int64{limits[0].Remaining, limits[1].Remaining})
+
+	// Without its policy the user is limited by the single limit again
+	assert.Nil(t, rateService.DeletePolicy(ctx, userId))
+	decision, err := rateService.Check(ctx, service.CheckRequest{UserId: userId, Limit: 1})
+	assert.Nil(t, err)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, time.Second*10, decision.Binding.Window)
+	assert.Equal(t, int64(0), decision.Binding.Remaining)
+
+	events, err := rateService.ListAuditEvents(ctx, service.AuditQuery{Key: userId, EventType: "policy_changed"})
+	assert.Nil(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, "10 per 30s, 4 per 1m0s", events[0].OldValue)
+	assert.Empty(t, events[0].NewValue)
+}
+
+func TestRateLimitService_InvalidPolicies(t *testing.T) {
+	ctx := context.Background()
+	rateService := newPolicyTestService(t, unavailableCache{})
+	userId := uuid.New().String()
+
+	for expect, limits := range map[error][]service.LimitModel{
+		domain.ErrEmptyPolicy:           nil,
+		domain.ErrInvalidPolicyLimit:    {{Limit: 0, Window: time.Second}},
+		domain.ErrInvalidPolicyWindow:   {{Limit: 1, Window: 48 * time.Hour}},
+		domain.ErrDuplicatePolicyWindow: {{Limit: 1, Window: time.Second}, {Limit: 2, Window: time.Second}},
+	} {
+		assert.ErrorIs(t, rateService.SetPolicy(ctx, userId, limits), expect)
+	}
+	_, err := rateService.GetPolicy(ctx, userId)
+	assert.ErrorIs(t, err, domain.ErrPolicyNotFound)
+	assert.ErrorIs(t, rateService.DeletePolicy(ctx, userId), domain.ErrPolicyNotFound)
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	domainModel "github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+	"github.com/nullexp/limiter-x/internal/port/driver/service"
+	"github.com/pkg/errors"
+)
+
+// WithQuotas evaluates the long-period quotas of keys together with their short-term limit.
+// Quotas are counted in the repository, so every check of a key reads its quotas there.
+func WithQuotas(repoFactory repository.QuotaRepositoryFactory) Option {
+	return func(rls *RateLimitService) {
+		rls.quotaRepoFactory = repoFactory
+	}
+}
+
+// currentQuota is a quota along with the bounds of its current period.
+type currentQuota struct {
+	domainModel.Quota
+	start, end time.Time
+}
+
+// remaining returns how many requests are left of the quota in its current period.
+func (q currentQuota) remaining() int64 {
+	remaining := q.Limit - q.UsedIn(q.start)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// bound describes the quota as a limit of the key.
+func (q currentQuota) bound() service.LimitModel {
+	return service.LimitModel{Limit: q.Limit, Period: string(q.Period), Remaining: q.remaining(), ResetsAt: q.end}
+}
+
+// currentQuotas locks the user's quotas and returns them with the periods current at now, and
+// with their limits as the global override leaves them.
+func (rls *RateLimitService) currentQuotas(ctx context.Context, tx db.DbHandler, userId string, now time.Time) ([]currentQuota, error) {
+	if rls.quotaRepoFactory == nil {
+		return nil, nil
+	}
+
+	quotas, err := rls.quotaRepoFactory.New(tx).GetQuotasByKeyForUpdate(ctx, userId)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get quotas from repo")
+	}
+	current := make([]currentQuota, 0, len(quotas))
+	for _, quota := range quotas {
+		start, end, err := quota.CurrentPeriod(now)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get quota period")
+		}
+		quota.Limit = rls.scaled(quota.Limit)
+		current = append(current, currentQuota{Quota: quota, start: start, end: end})
+	}
+	return current, nil
+}
+
+// addQuotaUsage counts units granted to the user against each of the quotas.
+func (rls *RateLimitService) addQuotaUsage(ctx context.Context, tx db.DbHandler, userId string, quotas []currentQuota, units int) error {
+	repo := rls.quotaRepoFactory.New(tx)
+	for i := range quotas {
+		if err := repo.AddQuotaUsage(ctx, userId, quotas[i].Period, quotas[i].start, int64(units)); err != nil {
+			return errors.Wrap(err, "failed to add quota usage in repo")
+		}
+		quotas[i].Used, quotas[i].PeriodStart = quotas[i].UsedIn(quotas[i].start)+int64(units), quotas[i].start
+	}
+	return nil
+}
+
+// creditQuotas gives units of a lease taken at leasedAt back to the user's quotas, as long as
+// their period did not start over since.
+func (rls *RateLimitService) creditQuotas(ctx context.Context, tx db.DbHandler, userId string, units int, leasedAt time.Time) error {
+	if rls.quotaRepoFactory == nil || units <= 0 {
+		return nil
+	}
+
+	repo := rls.quotaRepoFactory.New(tx)
+	quotas, err := repo.GetQuotasByKeyForUpdate(ctx, userId)
+	if err != nil {
+		return errors.Wrap(err, "failed to get quotas from repo")
+	}
+	for _, quota := range quotas {
+		start, _, err := quota.CurrentPeriod(time.Now())
+		if err != nil {
+			return errors.Wrap(err, "failed to get quota period")
+		}
+		if leasedAt.Before(start) {
+			continue
+		}
+		if err := repo.AddQuotaUsage(ctx, userId, quota.Period, start, -int64(units)); err != nil {
+			return errors.Wrap(err, "failed to add quota usage in repo")
+		}
+	}
+	return nil
+}
+
+// SetQuota creates or changes the quota of a key for a period. What the quota counted so far in
+// the current period is kept.
+func (rls *RateLimitService) SetQuota(ctx context.Context, quotaModel service.QuotaModel) error {
+	if rls.quotaRepoFactory == nil {
+		return domain.ErrQuotasDisabled
+	}
+
+	quota := domainModel.Quota{
+		Key:      quotaModel.Key,
+		Period:   domainModel.QuotaPeriod(quotaModel.Period),
+		Limit:    quotaModel.Limit,
+		TimeZone: quotaModel.TimeZone,
+		ResetDay: quotaModel.ResetDay,
+	}
+	if err := validateQuota(quota); err != nil {
+		return err
+	}
+
+	return rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		repo := rls.quotaRepoFactory.New(tx)
+		existing, err := rls.findQuota(ctx, repo, quota.Key, quota.Period)
+		if err != nil {
+			return err
+		}
+		if err := repo.SetQuota(ctx, quota); err != nil {
+			return errors.Wrap(err, "failed to set quota in repo")
+		}
+		return rls.auditQuotaChange(ctx, tx, quota.Key, existing, &quota)
+	})
+}
+
+// DeleteQuota removes the quota of a key for a period.
+func (rls *RateLimitService) DeleteQuota(ctx context.Context, key, period string) error {
+	if rls.quotaRepoFactory == nil {
+		return domain.ErrQuotasDisabled
+	}
+
+	return rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		repo := rls.quotaRepoFactory.New(tx)
+		existing, err := rls.findQuota(ctx, repo, key, domainModel.QuotaPeriod(period))
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return domain.ErrQuotaNotFound
+		}
+		if err := repo.DeleteQuota(ctx, key, existing.Period); err != nil {
+			return err
+		}
+		return rls.auditQuotaChange(ctx, tx, key, existing, nil)
+	})
+}
+
+// GetQuotas returns the quotas of a key with what they counted in their current period.
+func (rls *RateLimitService) GetQuotas(ctx context.Context, key string) ([]service.QuotaModel, error) {
+	if rls.quotaRepoFactory == nil {
+		return nil, domain.ErrQuotasDisabled
+	}
+
+	var quotas []domainModel.Quota
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		var err error
+		quotas, err = rls.quotaRepoFactory.New(tx).GetQuotasByKey(ctx, key)
+		if err != nil {
+			return errors.Wrap(err, "failed to get quotas from repo")
+		}
+		return nil
+	}, db.ReadOnly())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	models := make([]service.QuotaModel, 0, len(quotas))
+	for _, quota := range quotas {
+		start, end, err := quota.CurrentPeriod(now)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get quota period")
+		}
+		used := quota.UsedIn(start)
+		remaining := quota.Limit - used
+		if remaining < 0 {
+			remaining = 0
+		}
+		models = append(models, service.QuotaModel{
+			Key:       quota.Key,
+			Period:    string(quota.Period),
+			Limit:     quota.Limit,
+			TimeZone:  quota.TimeZone,
+			ResetDay:  quota.ResetDay,
+			Used:      used,
+			Remaining: remaining,
+			ResetsAt:  end,
+		})
+	}
+	return models, nil
+}
+
+// findQuota returns the key's quota for the period, or nil if there is none.
+func (rls *RateLimitService) findQuota(ctx context.Context, repo repository.QuotaRepository, key string, period domainModel.QuotaPeriod) (*domainModel.Quota, error) {
+	quotas, err := repo.GetQuotasByKeyForUpdate(ctx, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get quotas from repo")
+	}
+	for i := range quotas {
+		if quotas[i].Period == period {
+			return &quotas[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// validateQuota checks a quota before it is stored.
+func validateQuota(quota domainModel.Quota) error {
+	maxResetDay := 0
+	switch quota.Period {
+	case domainModel.QuotaDaily:
+	case domainModel.QuotaWeekly:
+		maxResetDay = 7
+	case domainModel.QuotaMonthly:
+		maxResetDay = 31
+	default:
+		return domain.ErrInvalidQuotaPeriod
+	}
+	if quota.ResetDay < 0 || quota.ResetDay > maxResetDay {
+		return domain.ErrInvalidQuotaResetDay
+	}
+	if quota.Limit <= 0 {
+		return domain.ErrInvalidQuotaLimit
+	}
+	if _, err := quota.Location(); err != nil {
+		return domain.ErrInvalidQuotaTimeZone
+	}
+	return nil
+}
+
+// describeQuota formats a quota for the audit log, e.g. "1000000 per month (UTC, day 1)".
+func describeQuota(quota *domainModel.Quota) string {
+	if quota == nil {
+		return ""
+	}
+	timeZone := quota.TimeZone
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+	if quota.Period == domainModel.QuotaDaily {
+		return fmt.Sprintf("%d per %s (%s)", quota.Limit, quota.Period, timeZone)
+	}
+	return fmt.Sprintf("%d per %s (%s, day %d)", quota.Limit, quota.Period, timeZone, quota.ResetDay)
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/cache"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/db/memory"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driver/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func newQuotaTestService(t *testing.T) *RateLimitService {
+	cache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, cache.Connect())
+	t.Cleanup(func() { cache.Disconnect() })
+
+	return NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), cache, memory.NewTransactionFactory(memory.NewStore()), time.Second*10,
+		WithQuotas(memory.NewQuotaRepositoryFactory()), WithAuditLog(memory.NewAuditEventRepositoryFactory(), 0))
+}
+
+func TestQuota_CurrentPeriod(t *testing.T) {
+	tehran, err := time.LoadLocation("Asia/Tehran")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name  string
+		quota model.Quota
+		now   time.Time
+		start time.Time
+		end   time.Time
+	}{
+		{
+			name:  "Daily in UTC",
+			quota: model.Quota{Period: model.QuotaDaily},
+			now:   time.Date(2024, 3, 10, 15, 4, 0, 0, time.UTC),
+			start: time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC),
+			end:   time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "Daily in another time zone",
+			quota: model.Quota{Period: model.QuotaDaily, TimeZone: "Asia/Tehran"},
+			now:   time.Date(2024, 3, 10, 22, 0, 0, 0, time.UTC),
+			start: time.Date(2024, 3, 11, 0, 0, 0, 0, tehran),
+			end:   time.Date(2024, 3, 12, 0, 0, 0, 0, tehran),
+		},
+		{
+			name:  "Weekly from Monday",
+			quota: model.Quota{Period: model.QuotaWeekly},
+			now:   time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC), // A Sunday
+			start: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC),
+			end:   time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "Weekly from Saturday",
+			quota: model.Quota{Period: model.QuotaWeekly, ResetDay: 6},
+			now:   time.Date(2024, 3, 8, 12, 0, 0, 0, time.UTC), // A Friday
+			start: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC),
+			end:   time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "Monthly from the 15th before the reset",
+			quota: model.Quota{Period: model.QuotaMonthly, ResetDay: 15},
+			now:   time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC),
+			start: time.Date(2023, 12, 15, 0, 0, 0, 0, time.UTC),
+			end:   time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "Monthly from the 31st in a short month",
+			quota: model.Quota{Period: model.QuotaMonthly, ResetDay: 31},
+			now:   time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+			start: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+			end:   time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start, end, err := test.quota.CurrentPeriod(test.now)
+			assert.Nil(t, err)
+			assert.True(t, test.start.Equal(start), "start %s", start)
+			assert.True(t, test.end.Equal(end), "end %s", end)
+		})
+	}
+}
+
+func TestRateLimitService_Quotas(t *testing.T) {
+	ctx := context.Background()
+	rateService := newQuotaTestService(t)
+	userId := uuid.New().String()
+
+	assert.ErrorIs(t, rateService.SetQuota(ctx, service.QuotaModel{Key: userId, Period: "year", Limit: 1}), domain.ErrInvalidQuotaPeriod)
+	assert.ErrorIs(t, rateService.SetQuota(ctx, service.QuotaModel{Key: userId, Period: "day", Limit: 0}), domain.ErrInvalidQuotaLimit)
+	assert.ErrorIs(t, rateService.SetQuota(ctx, service.QuotaModel{Key: userId, Period: "week", Limit: 1, ResetDay: 8}), domain.ErrInvalidQuotaResetDay)
+	assert.ErrorIs(t, rateService.SetQuota(ctx, service.QuotaModel{Key: userId, Period: "day", Limit: 1, TimeZone: "Mars/Olympus"}), domain.ErrInvalidQuotaTimeZone)
+	assert.Nil(t, rateService.SetQuota(ctx, service.QuotaModel{Key: userId, Period: "day", Limit: 3}))
+
+	// The quota caps the grant of a lease, and requests it denies leave the short-term limit alone
+	lease, err := rateService.LeaseQuota(ctx, userId, 5, 10, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, lease.Granted)
+	allowed, err := rateService.RateLimit(ctx, userId, 10)
+	assert.Nil(t, err)
+	assert.False(t, allowed)
+	rateLimit, err := rateService.GetUserRateLimit(ctx, userId)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, rateLimit.Remaining) // The count so far, as reported by GetUserRateLimit
+
+	// Returned units go back to the quota too
+	returned, err := rateService.ReturnQuota(ctx, lease.LeaseId, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, returned)
+	quotas, err := rateService.GetQuotas(ctx, userId)
+	assert.Nil(t, err)
+	assert.Len(t, quotas, 1)
+	assert.Equal(t, int64(1), quotas[0].Used)
+	assert.Equal(t, int64(2), quotas[0].Remaining)
+	assert.True(t, quotas[0].ResetsAt.After(time.Now()))
+
+	allowed, err = rateService.RateLimit(ctx, userId, 10)
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+
+	// Changing the quota keeps what it counted, deleting it lifts it
+	assert.Nil(t, rateService.SetQuota(ctx, service.QuotaModel{Key: userId, Period: "day", Limit: 2}))
+	allowed, err = rateService.RateLimit(ctx, userId, 10)
+	assert.Nil(t, err)
+	assert.False(t, allowed)
+	assert.Nil(t, rateService.DeleteQuota(ctx, userId, "day"))
+	assert.ErrorIs(t, rateService.DeleteQuota(ctx, userId, "day"), domain.ErrQuotaNotFound)
+	allowed, err = rateService.RateLimit(ctx, userId, 10)
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+
+	events, err := rateService.ListAuditEvents(ctx, service.AuditQuery{Key: userId, EventType: "quota_changed"})
+	assert.Nil(t, err)
+	assert.Len(t, events, 3)
+	assert.Equal(t, "3 per day (UTC)", events[1].OldValue)
+	assert.Equal(t, "2 per day (UTC)", events[1].NewValue)
+}
+
+func TestRateLimitService_QuotasDisabled(t *testing.T) {
+	rateService := NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), unavailableCache{}, memory.NewTransactionFactory(memory.NewStore()), time.Second*10)
+	_, err := rateService.GetQuotas(context.Background(), uuid.New().String())
+	assert.ErrorIs(t, err, domain.ErrQuotasDisabled)
+}
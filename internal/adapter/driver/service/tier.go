@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	domainModel "github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+	"github.com/nullexp/limiter-x/internal/port/driver/service"
+	"github.com/pkg/errors"
+)
+
+// WithTiers limits users without a limit of their own by the tier their role or plan is mapped
+// to, and by the default limit only if it is mapped to none. The limit of each role is cached
+// in-process for cacheTTL.
+func WithTiers(repoFactory repository.TierRepositoryFactory, cacheTTL time.Duration) Option {
+	return func(rls *RateLimitService) {
+		rls.tierRepoFactory = repoFactory
+		rls.tiers = newKeyCache[int](cacheTTL)
+	}
+}
+
+// fallbackLimit returns the limit of users with the role who have no limit of their own: the
+// limit of the role's tier, or the default limit if the role has none.
+func (rls *RateLimitService) fallbackLimit(ctx context.Context, tx db.DbHandler, role string) (int, error) {
+	if rls.tierRepoFactory == nil || role == "" {
+		return rls.defaultLimit, nil
+	}
+
+	now := time.Now()
+	limit, ok := rls.tiers.get(role, now)
+	if !ok {
+		tier, err := rls.tierRepoFactory.New(tx).GetTierFor(ctx, role)
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to get tier from repo")
+		}
+		limit = 0
+		if tier != nil {
+			limit = tier.RateLimit
+		}
+		rls.tiers.put(role, limit, now)
+	}
+	if limit == 0 {
+		return rls.defaultLimit, nil
+	}
+	return limit, nil
+}
+
+// SetTier creates the tier or changes its limit.
+func (rls *RateLimitService) SetTier(ctx context.Context, name string, limit int) error {
+	if rls.tierRepoFactory == nil {
+		return domain.ErrTiersDisabled
+	}
+	if name == "" || limit <= 0 {
+		return domain.ErrInvalidTier
+	}
+
+	var subjects []string
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		repo := rls.tierRepoFactory.New(tx)
+		existing, err := repo.GetTier(ctx, name)
+		if err != nil {
+			return errors.Wrap(err, "failed to get tier from repo")
+		}
+		if err := repo.SetTier(ctx, domainModel.Tier{Name: name, RateLimit: limit}); err != nil {
+			return errors.Wrap(err, "failed to set tier in repo")
+		}
+		if subjects, err = mappedTo(ctx, repo, name); err != nil {
+			return err
+		}
+
+		oldValue := ""
+		if existing != nil {
+			oldValue = strconv.Itoa(existing.RateLimit)
+		}
+		return rls.auditTierChange(ctx, tx, name, oldValue, strconv.Itoa(limit))
+	})
+	if err != nil {
+		return err
+	}
+	for _, subject := range subjects {
+		rls.tiers.forget(subject)
+	}
+	return nil
+}
+
+// DeleteTier removes a tier that no role or plan is mapped to anymore.
+func (rls *RateLimitService) DeleteTier(ctx context.Context, name string) error {
+	if rls.tierRepoFactory == nil {
+		return domain.ErrTiersDisabled
+	}
+
+	return rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		repo := rls.tierRepoFactory.New(tx)
+		subjects, err := mappedTo(ctx, repo, name)
+		if err != nil {
+			return err
+		}
+		if len(subjects) > 0 {
+			return domain.ErrTierInUse
+		}
+
+		existing, err := repo.GetTier(ctx, name)
+		if err != nil {
+			return errors.Wrap(err, "failed to get tier from repo")
+		}
+		if err := repo.DeleteTier(ctx, name); err != nil {
+			return err
+		}
+		return rls.auditTierChange(ctx, tx, name, strconv.Itoa(existing.RateLimit), "")
+	})
+}
+
+// ListTiers returns all tiers with the roles and plans mapped to them.
+func (rls *RateLimitService) ListTiers(ctx context.Context) ([]service.TierModel, error) {
+	if rls.tierRepoFactory == nil {
+		return nil, domain.ErrTiersDisabled
+	}
+
+	var tiers []service.TierModel
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		repo := rls.tierRepoFactory.New(tx)
+		stored, err := repo.ListTiers(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to list tiers from repo")
+		}
+		mappings, err := repo.ListTierMappings(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to list tier mappings from repo")
+		}
+
+		subjects := make(map[string][]string, len(stored))
+		for _, mapping := range mappings {
+			subjects[mapping.Tier] = append(subjects[mapping.Tier], mapping.Subject)
+		}
+		tiers = make([]service.TierModel, 0, len(stored))
+		for _, tier := range stored {
+			tiers = append(tiers, service.TierModel{Name: tier.Name, Limit: tier.RateLimit, Subjects: subjects[tier.Name]})
+		}
+		return nil
+	}, db.ReadOnly())
+	if err != nil {
+		return nil, err
+	}
+	return tiers, nil
+}
+
+// SetTierMapping maps a role ID or plan name to a tier, replacing the tier it was mapped to.
+func (rls *RateLimitService) SetTierMapping(ctx context.Context, subject, tier string) error {
+	if rls.tierRepoFactory == nil {
+		return domain.ErrTiersDisabled
+	}
+	if subject == "" || tier == "" {
+		return domain.ErrInvalidTierMapping
+	}
+
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		repo := rls.tierRepoFactory.New(tx)
+		stored, err := repo.GetTier(ctx, tier)
+		if err != nil {
+			return errors.Wrap(err, "failed to get tier from repo")
+		}
+		if stored == nil {
+			return domain.ErrTierNotFound
+		}
+
+		existing, err := repo.GetTierFor(ctx, subject)
+		if err != nil {
+			return errors.Wrap(err, "failed to get tier from repo")
+		}
+		if err := repo.SetTierMapping(ctx, subject, tier); err != nil {
+			return errors.Wrap(err, "failed to set tier mapping in repo")
+		}
+
+		oldValue := ""
+		if existing != nil {
+			oldValue = existing.Name
+		}
+		return rls.auditTierChange(ctx, tx, subject, oldValue, tier)
+	})
+	if err != nil {
+		return err
+	}
+	rls.tiers.forget(subject)
+	return nil
+}
+
+// DeleteTierMapping removes the mapping of a role or plan, whose users then have the default
+// limit.
+func (rls *RateLimitService) DeleteTierMapping(ctx context.Context, subject string) error {
+	if rls.tierRepoFactory == nil {
+		return domain.ErrTiersDisabled
+	}
+
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		repo := rls.tierRepoFactory.New(tx)
+		existing, err := repo.GetTierFor(ctx, subject)
+		if err != nil {
+			return errors.Wrap(err, "failed to get tier from repo")
+		}
+		if err := repo.DeleteTierMapping(ctx, subject); err != nil {
+			return err
+		}
+
+		oldValue := ""
+		if existing != nil {
+			oldValue = existing.Name
+		}
+		return rls.auditTierChange(ctx, tx, subject, oldValue, "")
+	})
+	if err != nil {
+		return err
+	}
+	rls.tiers.forget(subject)
+	return nil
+}
+
+// mappedTo returns the roles and plans mapped to the tier.
+func mappedTo(ctx context.Context, repo repository.TierRepository, tier string) ([]string, error) {
+	mappings, err := repo.ListTierMappings(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list tier mappings from repo")
+	}
+	var subjects []string
+	for _, mapping := range mappings {
+		if mapping.Tier == tier {
+			subjects = append(subjects, mapping.Subject)
+		}
+	}
+	return subjects, nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/cache"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/db/memory"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/port/driver/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTierTestService(opts ...Option) *RateLimitService {
+	opts = append(opts, WithTiers(memory.NewTierRepositoryFactory(), time.Minute), WithDefaultLimit(2))
+	return NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), cache.NewMemoryClient(time.Hour, time.Hour), memory.NewTransactionFactory(memory.NewStore()), time.Minute, opts...)
+}
+
+// allowedChecks counts the checks allowed out of n.
+func allowedChecks(t *testing.T, rateService *RateLimitService, request service.CheckRequest, n int) int {
+	allowed := 0
+	for i := 0; i < n; i++ {
+		decision, err := rateService.Check(context.Background(), request)
+		assert.Nil(t, err)
+		if decision.Allowed {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func TestRateLimitService_Tiers(t *testing.T) {
+	tests := []struct {
+		name    string
+		service *RateLimitService
+	}{
+		{name: "Counting in the cache", service: newTierTestService()},
+		{name: "Counting in the database", service: newTierTestService(WithRowLocking())},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			assert.Nil(t, test.service.cache.Connect())
+			t.Cleanup(func() { test.service.cache.Disconnect() })
+			assert.Nil(t, test.service.SetTier(ctx, "pro", 3))
+			assert.Nil(t, test.service.SetTierMapping(ctx, "plan-pro", "pro"))
+
+			// Users follow the tier of their plan, and the default without one
+			assert.Equal(t, 3, allowedChecks(t, test.service, service.CheckRequest{UserId: uuid.New().String(), Role: "plan-pro"}, 5))
+			assert.Equal(t, 2, allowedChecks(t, test.service, service.CheckRequest{UserId: uuid.New().String(), Role: "plan-unknown"}, 5))
+
+			// A limit of the user's own wins over the tier
+			userId := uuid.New().String()
+			assert.Nil(t, test.service.UpdateUserRateLimit(ctx, userId, 1, 0))
+			assert.Equal(t, 1, allowedChecks(t, test.service, service.CheckRequest{UserId: userId, Role: "plan-pro"}, 5))
+
+			// Changing the tier applies to the users counted already
+			userId = uuid.New().String()
+			assert.Equal(t, 3, allowedChecks(t, test.service, service.CheckRequest{UserId: userId, Role: "plan-pro"}, 5))
+			assert.Nil(t, test.service.SetTier(ctx, "pro", 4))
+			decision, err := test.service.Check(ctx, service.CheckRequest{UserId: userId, Role: "plan-pro"})
+			assert.Nil(t, err)
+			assert.True(t, decision.Allowed)
+			assert.Equal(t, int64(4), decision.Binding.Limit)
+		})
+	}
+}
+
+func TestRateLimitService_TierAdministration(t *testing.T) {
+	ctx := context.Background()
+	rateService := newTierTestService()
+	assert.Nil(t, rateService.cache.Connect())
+	t.Cleanup(func() { rateService.cache.Disconnect() })
+
+	assert.ErrorIs(t, rateService.SetTier(ctx, "free", 0), domain.ErrInvalidTier)
+	assert.ErrorIs(t, rateService.SetTierMapping(ctx, "plan-free", "free"), domain.ErrTierNotFound)
+
+	assert.Nil(t, rateService.SetTier(ctx, "free", 10))
+	assert.Nil(t, rateService.SetTier(ctx, "enterprise", 1000))
+	assert.Nil(t, rateService.SetTierMapping(ctx, "plan-free", "free"))
+	assert.Nil(t, rateService.SetTierMapping(ctx, "role-guest", "free"))
+
+	tiers, err := rateService.ListTiers(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, tiers, 2)
+	assert.Equal(t, "enterprise", tiers[0].Name)
+	assert.Empty(t, tiers[0].Subjects)
+	assert.Equal(t, []string{"plan-free", "role-guest"}, tiers[1].Subjects)
+
+	// Tiers cannot be deleted while roles or plans are mapped to them
+	assert.ErrorIs(t, rateService.DeleteTier(ctx, "free"), domain.ErrTierInUse)
+	assert.Nil(t, rateService.DeleteTierMapping(ctx, "plan-free"))
+	assert.Nil(t, rateService.DeleteTierMapping(ctx, "role-guest"))
+	assert.ErrorIs(t, rateService.DeleteTierMapping(ctx, "role-guest"), domain.ErrTierMappingNotFound)
+	assert.Nil(t, rateService.DeleteTier(ctx, "free"))
+	assert.ErrorIs(t, rateService.DeleteTier(ctx, "free"), domain.ErrTierNotFound)
+
+	disabled := NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), rateService.cache, memory.NewTransactionFactory(memory.NewStore()), time.Minute)
+	assert.ErrorIs(t, disabled.SetTier(ctx, "free", 10), domain.ErrTiersDisabled)
+	_, err = disabled.ListTiers(ctx)
+	assert.ErrorIs(t, err, domain.ErrTiersDisabled)
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/pkg/errors"
+)
+
+// RetryPolicy controls how a unit of work is repeated after a transient database failure: a lost
+// optimistic update, a serialization failure or a deadlock.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a unit of work runs at most, including the first.
+	MaxAttempts int
+	// BaseDelay is the backoff before the first retry. It doubles for every further retry up to
+	// MaxDelay, and the actual wait is a random duration below it.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Budget caps retries across all requests, so a contended database does not get flooded with
+	// them. Every retry spends a token and every unit of work that succeeds first time earns
+	// BudgetRatio tokens, up to Budget.
+	Budget      float64
+	BudgetRatio float64
+}
+
+// DefaultRetryPolicy retries up to three times and allows roughly one retry per ten requests once
+// the initial budget is spent.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   5 * time.Millisecond,
+	MaxDelay:    100 * time.Millisecond,
+	Budget:      100,
+	BudgetRatio: 0.1,
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(rls *RateLimitService) {
+		rls.retry = newRetrier(policy)
+	}
+}
+
+// PostgreSQL error codes of failures that succeed when the transaction is run again.
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+)
+
+// sqlStateError is implemented by driver errors carrying an SQLSTATE code, such as *pq.Error.
+type sqlStateError interface {
+	SQLState() string
+}
+
+// retryable reports whether err is a transient failure worth running the unit of work again for.
+func retryable(err error) bool {
+	if errors.Is(err, domain.ErrRateLimitConflict) {
+		return true
+	}
+
+	var stateErr sqlStateError
+	if errors.As(err, &stateErr) {
+		switch stateErr.SQLState() {
+		case sqlStateSerializationFailure, sqlStateDeadlockDetected:
+			return true
+		}
+	}
+	return false
+}
+
+type retrier struct {
+	policy RetryPolicy
+
+	mu     sync.Mutex
+	tokens float64
+}
+
+func newRetrier(policy RetryPolicy) *retrier {
+	return &retrier{policy: policy, tokens: policy.Budget}
+}
+
+// succeeded earns back part of a retry for a unit of work that needed none.
+func (r *retrier) succeeded() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens += r.policy.BudgetRatio
+	if r.tokens > r.policy.Budget {
+		r.tokens = r.policy.Budget
+	}
+}
+
+// spend takes a token for a retry, reporting false when the budget is exhausted.
+func (r *retrier) spend() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.tokens < 1 {
+		return false
+	}
+	r.tokens--
+	return true
+}
+
+// backoff returns a random wait before the given retry, counting from one.
+func (r *retrier) backoff(retry int) time.Duration {
+	ceiling := r.policy.BaseDelay << (retry - 1)
+	if ceiling <= 0 || ceiling > r.policy.MaxDelay {
+		ceiling = r.policy.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+// undoLog collects how to give back the counts a unit of work took in the cache. The cache does
+// not roll back with the transaction, so the counts of a failed attempt are given back before the
+// work runs again, and once more when it fails for good.
+type undoLog []func(context.Context)
+
+// add records how to give back a count, unless the log is nil.
+func (u *undoLog) add(fn func(context.Context)) {
+	if u != nil {
+		*u = append(*u, fn)
+	}
+}
+
+// rollback gives back every recorded count, the latest first, and empties the log.
+func (u *undoLog) rollback(ctx context.Context) {
+	for i := len(*u) - 1; i >= 0; i-- {
+		(*u)[i](ctx)
+	}
+	*u = nil
+}
+
+// inTransaction runs work in a transaction and commits it. When the work or the commit fails with
+// a transient error, it is repeated in a fresh transaction after a jittered backoff, as long as
+// the retry policy and its budget allow.
+func (rls *RateLimitService) inTransaction(ctx context.Context, work func(tx db.DbHandler) error, opts ...db.TxOption) error {
+	for attempt := 1; ; attempt++ {
+		err := rls.runTransaction(ctx, work, opts...)
+		if err == nil {
+			if attempt == 1 {
+				rls.retry.succeeded()
+			}
+			return nil
+		}
+		if !retryable(err) || attempt >= rls.retry.policy.MaxAttempts || !rls.retry.spend() {
+			return err
+		}
+
+		select {
+		case <-time.After(rls.retry.backoff(attempt)):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+func (rls *RateLimitService) runTransaction(ctx context.Context, work func(tx db.DbHandler) error, opts ...db.TxOption) error {
+	tx := rls.dbTransactionFactory.NewTransaction()
+	transaction, err := tx.Begin(ctx, append(append([]db.TxOption{}, rls.txOptions...), opts...)...)
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.RollbackUnlessCommitted(ctx)
+
+	if err := work(transaction); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+	return nil
+}
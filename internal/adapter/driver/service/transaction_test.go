@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/cache"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/db/memory"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+	"github.com/nullexp/limiter-x/internal/port/driver/service"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// conflictingRepositoryFactory loses the first conflicts optimistic updates to a concurrent writer.
+type conflictingRepositoryFactory struct {
+	repository.UserRateLimitRepositoryFactory
+	conflicts int
+}
+
+func (f *conflictingRepositoryFactory) New(handler db.DbHandler) repository.UserRateLimitRepository {
+	return &conflictingRepository{UserRateLimitRepository: f.UserRateLimitRepositoryFactory.New(handler), factory: f}
+}
+
+type conflictingRepository struct {
+	repository.UserRateLimitRepository
+	factory *conflictingRepositoryFactory
+}
+
+func (r *conflictingRepository) UpdateRateLimit(ctx context.Context, rateLimit model.UserRateLimit) error {
+	if r.factory.conflicts > 0 {
+		r.factory.conflicts--
+		return domain.ErrRateLimitConflict
+	}
+	return r.UserRateLimitRepository.UpdateRateLimit(ctx, rateLimit)
+}
+
+func TestRateLimitService_RetriesVersionConflicts(t *testing.T) {
+	ctx := context.Background()
+	repoFactory := &conflictingRepositoryFactory{UserRateLimitRepositoryFactory: memory.NewUserRateLimitRepositoryFactory()}
+	service := NewRateLimitService(repoFactory, unavailableCache{}, memory.NewTransactionFactory(memory.NewStore()), time.Second*10)
+	userId := uuid.New().String()
+
+	allowed, err := service.RateLimit(ctx, userId, 5)
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+
+	// A few lost updates are retried transparently
+	repoFactory.conflicts = DefaultRetryPolicy.MaxAttempts - 1
+	allowed, err = service.RateLimit(ctx, userId, 5)
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+
+	// Past the retry limit the conflict is reported
+	repoFactory.conflicts = DefaultRetryPolicy.MaxAttempts
+	_, err = service.RateLimit(ctx, userId, 5)
+	assert.ErrorIs(t, err, domain.ErrRateLimitConflict)
+
+	state, err := service.GetUserRateLimit(ctx, userId)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, state.Remaining)
+}
+
+// conflictingTransactionFactory loses the commits of the first conflicts transactions to a
+// concurrent writer.
+type conflictingTransactionFactory struct {
+	db.DbTransactionFactory
+	conflicts int
+}
+
+func (f *conflictingTransactionFactory) NewTransaction() db.DbTransaction {
+	return &conflictingTransaction{DbTransaction: f.DbTransactionFactory.NewTransaction(), factory: f}
+}
+
+type conflictingTransaction struct {
+	db.DbTransaction
+	factory *conflictingTransactionFactory
+}
+
+func (t *conflictingTransaction) Commit(ctx context.Context) error {
+	if t.factory.conflicts > 0 {
+		t.factory.conflicts--
+		t.DbTransaction.Rollback(ctx)
+		return domain.ErrRateLimitConflict
+	}
+	return t.DbTransaction.Commit(ctx)
+}
+
+func TestRateLimitService_RetriesCountOnceInCache(t *testing.T) {
+	ctx := context.Background()
+	memoryCache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, memoryCache.Connect())
+	t.Cleanup(func() { memoryCache.Disconnect() })
+
+	transactionFactory := &conflictingTransactionFactory{DbTransactionFactory: memory.NewTransactionFactory(memory.NewStore())}
+	rls := NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), memoryCache, transactionFactory, time.Second*10,
+		WithPolicies(memory.NewPolicyRepositoryFactory(), time.Minute), WithHierarchy(memory.NewParentRepositoryFactory(), time.Minute))
+
+	org, single, limited := uuid.New().String(), uuid.New().String(), uuid.New().String()
+	assert.Nil(t, rls.SetPolicy(ctx, org, []service.LimitModel{{Limit: 10, Window: time.Minute}}))
+	assert.Nil(t, rls.SetPolicy(ctx, limited, []service.LimitModel{{Limit: 5, Window: time.Minute}}))
+	assert.Nil(t, rls.SetParent(ctx, single, org))
+	assert.Nil(t, rls.SetParent(ctx, limited, org))
+
+	cachedCount := func(key string) int {
+		data, err := memoryCache.Fetch(ctx, key)
+		assert.Nil(t, err)
+		if key == single {
+			var state model.UserRateLimit
+			assert.Nil(t, json.Unmarshal(data, &state))
+			return state.RequestCount
+		}
+		var limits []model.PolicyLimit
+		assert.Nil(t, json.Unmarshal(data, &limits))
+		return limits[0].Count
+	}
+
+	for _, userId := range []string{single, limited} {
+		decision, err := rls.Check(ctx, service.CheckRequest{UserId: userId, Limit: 5})
+		assert.Nil(t, err)
+		assert.True(t, decision.Allowed)
+	}
+	assert.Equal(t, 1, cachedCount(single))
+	assert.Equal(t, 1, cachedCount(policyKey(limited)))
+	assert.Equal(t, 2, cachedCount(policyKey(org)))
+
+	// The first attempt of each check counts in the cache before its commit is lost, what it
+	// counted is given back before the retry counts again
+	for _, userId := range []string{single, limited} {
+		transactionFactory.conflicts = 1
+		decision, err := rls.Check(ctx, service.CheckRequest{UserId: userId, Limit: 5})
+		assert.Nil(t, err)
+		assert.True(t, decision.Allowed)
+	}
+	assert.Equal(t, 2, cachedCount(single))
+	assert.Equal(t, 2, cachedCount(policyKey(limited)))
+	assert.Equal(t, 4, cachedCount(policyKey(org)))
+
+	// Past the retry limit nothing stays counted
+	transactionFactory.conflicts = DefaultRetryPolicy.MaxAttempts
+	_, err := rls.Check(ctx, service.CheckRequest{UserId: limited, Limit: 5})
+	assert.ErrorIs(t, err, domain.ErrRateLimitConflict)
+	assert.Equal(t, 2, cachedCount(policyKey(limited)))
+	assert.Equal(t, 4, cachedCount(policyKey(org)))
+}
+
+// stateError carries an SQLSTATE the way *pq.Error does.
+type stateError string
+
+func (e stateError) Error() string    { return "pq: " + string(e) }
+func (e stateError) SQLState() string { return string(e) }
+
+func TestRetryable(t *testing.T) {
+	assert.True(t, retryable(domain.ErrRateLimitConflict))
+	assert.True(t, retryable(errors.Wrap(stateError(sqlStateSerializationFailure), "failed to commit transaction")))
+	assert.True(t, retryable(stateError(sqlStateDeadlockDetected)))
+	assert.False(t, retryable(stateError("23505")))
+	assert.False(t, retryable(errors.New("connection refused")))
+}
+
+func TestRateLimitService_RetriesSerializationFailures(t *testing.T) {
+	ctx := context.Background()
+	failures := 2
+	service := NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), unavailableCache{}, memory.NewTransactionFactory(memory.NewStore()), time.Second*10,
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Budget: 1, BudgetRatio: 0.5}))
+
+	attempts := 0
+	work := func(tx db.DbHandler) error {
+		attempts++
+		if attempts <= failures {
+			return stateError(sqlStateSerializationFailure)
+		}
+		return nil
+	}
+
+	// The budget holds a single retry, so the second one is refused
+	assert.ErrorIs(t, service.inTransaction(ctx, work), stateError(sqlStateSerializationFailure))
+	assert.Equal(t, 2, attempts)
+
+	// Two first-time successes earn another retry
+	assert.Nil(t, service.inTransaction(ctx, func(tx db.DbHandler) error { return nil }))
+	assert.Nil(t, service.inTransaction(ctx, func(tx db.DbHandler) error { return nil }))
+	attempts, failures = 0, 1
+	assert.Nil(t, service.inTransaction(ctx, work))
+	assert.Equal(t, 2, attempts)
+}
+
+func TestRetrier_Backoff(t *testing.T) {
+	r := newRetrier(RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 30 * time.Millisecond})
+	for retry, ceiling := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 3: 30 * time.Millisecond, 40: 30 * time.Millisecond} {
+		for i := 0; i < 20; i++ {
+			delay := r.backoff(retry)
+			assert.GreaterOrEqual(t, delay, time.Duration(0))
+			assert.Less(t, delay, ceiling)
+		}
+	}
+}
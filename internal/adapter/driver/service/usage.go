@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	domainModel "github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+	"github.com/nullexp/limiter-x/internal/port/driver/service"
+	"github.com/pkg/errors"
+)
+
+// maxUsagePoints caps how many buckets GetUsageHistory returns at once.
+const maxUsagePoints = 10000
+
+// UsageRetention is how long usage history is kept at each granularity. A zero duration keeps
+// it forever.
+type UsageRetention struct {
+	Hourly time.Duration
+	Daily  time.Duration
+}
+
+// DefaultUsageRetention keeps a week of hourly and three months of daily history.
+var DefaultUsageRetention = UsageRetention{Hourly: 7 * 24 * time.Hour, Daily: 90 * 24 * time.Hour}
+
+// WithUsageHistory aggregates the allowed and denied requests of every key into hourly and daily
+// buckets. Counts are kept in memory and added to the stored buckets every flushInterval by
+// RunUsageHistory, so the history lags behind by up to that long.
+func WithUsageHistory(repoFactory repository.UsageRepositoryFactory, flushInterval time.Duration, retention UsageRetention) Option {
+	return func(rls *RateLimitService) {
+		rls.usageRepoFactory = repoFactory
+		rls.usage = &usageAggregator{interval: flushInterval, retention: retention}
+	}
+}
+
+// usageAggregator collects counts per key and hour between flushes.
+type usageAggregator struct {
+	interval  time.Duration
+	retention UsageRetention
+
+	mu      sync.Mutex
+	pending map[usageSlot]usageCounts
+}
+
+type usageSlot struct {
+	key  string
+	hour int64 // Unix time of the start of the hour
+}
+
+type usageCounts struct {
+	allowed, denied int64
+}
+
+func (a *usageAggregator) add(slot usageSlot, counts usageCounts) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.pending == nil {
+		a.pending = make(map[usageSlot]usageCounts)
+	}
+	current := a.pending[slot]
+	current.allowed += counts.allowed
+	current.denied += counts.denied
+	a.pending[slot] = current
+}
+
+// take hands over the counts collected so far and starts collecting anew.
+func (a *usageAggregator) take() map[usageSlot]usageCounts {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	pending := a.pending
+	a.pending = nil
+	return pending
+}
+
+// recordUsage counts a decision for the key's usage history.
+func (rls *RateLimitService) recordUsage(userId string, allowed, denied int) {
+	if rls.usage == nil {
+		return
+	}
+	hour := domainModel.UsageHourly.Truncate(time.Now()).Unix()
+	rls.usage.add(usageSlot{key: userId, hour: hour}, usageCounts{allowed: int64(allowed), denied: int64(denied)})
+}
+
+// FlushUsage adds the counts collected since the last flush to the stored hourly and daily
+// buckets. Counts that cannot be stored are kept for the next flush.
+func (rls *RateLimitService) FlushUsage(ctx context.Context) error {
+	if rls.usage == nil {
+		return nil
+	}
+	pending := rls.usage.take()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	// The daily buckets are the sums of their hours
+	type bucketKey struct {
+		key         string
+		granularity domainModel.UsageGranularity
+		start       int64
+	}
+	buckets := make(map[bucketKey]*domainModel.UsageBucket)
+	for slot, counts := range pending {
+		hour := time.Unix(slot.hour, 0).UTC()
+		for _, granularity := range []domainModel.UsageGranularity{domainModel.UsageHourly, domainModel.UsageDaily} {
+			start := granularity.Truncate(hour)
+			key := bucketKey{key: slot.key, granularity: granularity, start: start.Unix()}
+			bucket, ok := buckets[key]
+			if !ok {
+				bucket = &domainModel.UsageBucket{Key: slot.key, Granularity: granularity, Start: start}
+				buckets[key] = bucket
+			}
+			bucket.Allowed += counts.allowed
+			bucket.Denied += counts.denied
+		}
+	}
+
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		repo := rls.usageRepoFactory.New(tx)
+		for _, bucket := range buckets {
+			if err := repo.AddUsage(ctx, *bucket); err != nil {
+				return errors.Wrap(err, "failed to add usage")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		for slot, counts := range pending {
+			rls.usage.add(slot, counts)
+		}
+		return err
+	}
+	return nil
+}
+
+// PruneUsage removes the usage buckets that are older than the retention of their granularity.
+func (rls *RateLimitService) PruneUsage(ctx context.Context) error {
+	if rls.usage == nil {
+		return nil
+	}
+
+	now := time.Now()
+	return rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		repo := rls.usageRepoFactory.New(tx)
+		for granularity, retention := range map[domainModel.UsageGranularity]time.Duration{
+			domainModel.UsageHourly: rls.usage.retention.Hourly,
+			domainModel.UsageDaily:  rls.usage.retention.Daily,
+		} {
+			if retention <= 0 {
+				continue
+			}
+			if err := repo.DeleteUsageBefore(ctx, granularity, granularity.Truncate(now.Add(-retention))); err != nil {
+				return errors.Wrap(err, "failed to delete expired usage")
+			}
+		}
+		return nil
+	})
+}
+
+// RunUsageHistory flushes collected usage every flush interval and prunes expired history once
+// an hour until ctx is done, flushing one last time before it returns.
+func (rls *RateLimitService) RunUsageHistory(ctx context.Context) {
+	if rls.usage == nil || rls.usage.interval <= 0 {
+		return
+	}
+
+	flush := time.NewTicker(rls.usage.interval)
+	defer flush.Stop()
+	prune := time.NewTicker(time.Hour)
+	defer prune.Stop()
+
+	if err := rls.PruneUsage(ctx); err != nil {
+		log.Printf("failed to prune usage history: %v", err)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			if err := rls.FlushUsage(context.Background()); err != nil {
+				log.Printf("failed to flush usage history: %v", err)
+			}
+			return
+		case <-flush.C:
+			if err := rls.FlushUsage(ctx); err != nil {
+				log.Printf("failed to flush usage history: %v", err)
+			}
+		case <-prune.C:
+			if err := rls.PruneUsage(ctx); err != nil {
+				log.Printf("failed to prune usage history: %v", err)
+			}
+		}
+	}
+}
+
+// GetUsageHistory returns the key's usage per bucket within the query's range, oldest first.
+// Every bucket of the range is returned, with zero counts where nothing was recorded. The range
+// defaults to the last day of hourly or the last 30 days of daily buckets.
+func (rls *RateLimitService) GetUsageHistory(ctx context.Context, query service.UsageQuery) ([]service.UsagePointModel, error) {
+	if rls.usage == nil {
+		return nil, domain.ErrUsageHistoryDisabled
+	}
+
+	granularity := domainModel.UsageGranularity(query.Granularity)
+	switch granularity {
+	case "":
+		granularity = domainModel.UsageHourly
+	case domainModel.UsageHourly, domainModel.UsageDaily:
+	default:
+		return nil, domain.ErrInvalidUsageGranularity
+	}
+
+	to := query.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	to = granularity.Truncate(to.Add(granularity.Step() - 1))
+	from := query.From
+	if from.IsZero() {
+		from = to.Add(-24 * time.Hour)
+		if granularity == domainModel.UsageDaily {
+			from = to.Add(-30 * 24 * time.Hour)
+		}
+	}
+	from = granularity.Truncate(from)
+	if !from.Before(to) || to.Sub(from)/granularity.Step() > maxUsagePoints {
+		return nil, domain.ErrInvalidUsageRange
+	}
+
+	var buckets []domainModel.UsageBucket
+	err := rls.inTransaction(ctx, func(tx db.DbHandler) error {
+		var err error
+		buckets, err = rls.usageRepoFactory.New(tx).ListUsage(ctx, query.Key, granularity, from, to)
+		if err != nil {
+			return errors.Wrap(err, "failed to list usage")
+		}
+		return nil
+	}, db.ReadOnly())
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]service.UsagePointModel, 0, to.Sub(from)/granularity.Step())
+	for start := from; start.Before(to); start = start.Add(granularity.Step()) {
+		point := service.UsagePointModel{Start: start}
+		for len(buckets) > 0 && buckets[0].Start.Before(start.Add(granularity.Step())) {
+			point.Allowed += buckets[0].Allowed
+			point.Denied += buckets[0].Denied
+			buckets = buckets[1:]
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/cache"
+	"github.com/nullexp/limiter-x/internal/adapter/driven/db/memory"
+	domain "github.com/nullexp/limiter-x/internal/domain/error"
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+	"github.com/nullexp/limiter-x/internal/port/driven/db/repository"
+	"github.com/nullexp/limiter-x/internal/port/driver/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingUsageRepositoryFactory fails to add usage while failing is set.
+type failingUsageRepositoryFactory struct {
+	*memory.UsageRepositoryFactory
+	failing bool
+}
+
+func (f *failingUsageRepositoryFactory) New(handler db.DbHandler) repository.UsageRepository {
+	return &failingUsageRepository{UsageRepository: f.UsageRepositoryFactory.New(handler), factory: f}
+}
+
+type failingUsageRepository struct {
+	repository.UsageRepository
+	factory *failingUsageRepositoryFactory
+}
+
+func (r *failingUsageRepository) AddUsage(ctx context.Context, bucket model.UsageBucket) error {
+	if r.factory.failing {
+		return errors.New("database unavailable")
+	}
+	return r.UsageRepository.AddUsage(ctx, bucket)
+}
+
+func newUsageTestService(t *testing.T, usageRepoFactory repository.UsageRepositoryFactory) (*RateLimitService, *memory.Store) {
+	cache := cache.NewMemoryClient(time.Hour, time.Hour)
+	assert.Nil(t, cache.Connect())
+	t.Cleanup(func() { cache.Disconnect() })
+
+	store := memory.NewStore()
+	return NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), cache, memory.NewTransactionFactory(store), time.Second*10,
+		WithUsageHistory(usageRepoFactory, time.Minute, DefaultUsageRetention)), store
+}
+
+func TestRateLimitService_UsageHistory(t *testing.T) {
+	ctx := context.Background()
+	usageRepoFactory := &failingUsageRepositoryFactory{UsageRepositoryFactory: memory.NewUsageRepositoryFactory(), failing: true}
+	rateService, _ := newUsageTestService(t, usageRepoFactory)
+	userId := uuid.New().String()
+
+	for i := 0; i < 3; i++ {
+		_, err := rateService.RateLimit(ctx, userId, 2)
+		assert.Nil(t, err)
+	}
+	lease, err := rateService.LeaseQuota(ctx, uuid.New().String(), 5, 10, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 5, lease.Granted)
+
+	// Counts that could not be stored are kept for the next flush
+	assert.NotNil(t, rateService.FlushUsage(ctx))
+	usageRepoFactory.failing = false
+	assert.Nil(t, rateService.FlushUsage(ctx))
+
+	for _, granularity := range []string{"hour", "day"} {
+		points, err := rateService.GetUsageHistory(ctx, service.UsageQuery{Key: userId, Granularity: granularity})
+		assert.Nil(t, err)
+		assert.NotEmpty(t, points)
+		assert.Equal(t, service.UsagePointModel{Start: points[len(points)-1].Start, Allowed: 2, Denied: 1}, points[len(points)-1], granularity)
+		for _, point := range points[:len(points)-1] {
+			assert.Zero(t, point.Allowed+point.Denied)
+		}
+	}
+
+	points, err := rateService.GetUsageHistory(ctx, service.UsageQuery{Key: userId, From: time.Now().Add(-3 * time.Hour)})
+	assert.Nil(t, err)
+	assert.Len(t, points, 4, "three full hours and the current one")
+}
+
+func TestRateLimitService_PruneUsage(t *testing.T) {
+	ctx := context.Background()
+	usageRepoFactory := memory.NewUsageRepositoryFactory()
+	rateService, store := newUsageTestService(t, usageRepoFactory)
+	now := time.Now()
+
+	tx := memory.NewTransactionFactory(store).NewTransaction()
+	handler, err := tx.Begin(ctx)
+	assert.Nil(t, err)
+	repo := usageRepoFactory.New(handler)
+	for _, bucket := range []model.UsageBucket{
+		{Key: "tenant", Granularity: model.UsageHourly, Start: model.UsageHourly.Truncate(now.Add(-8 * 24 * time.Hour)), Allowed: 1},
+		{Key: "tenant", Granularity: model.UsageHourly, Start: model.UsageHourly.Truncate(now), Allowed: 2},
+		{Key: "tenant", Granularity: model.UsageDaily, Start: model.UsageDaily.Truncate(now.Add(-8 * 24 * time.Hour)), Allowed: 1},
+	} {
+		assert.Nil(t, repo.AddUsage(ctx, bucket))
+	}
+	assert.Nil(t, tx.Commit(ctx))
+
+	assert.Nil(t, rateService.PruneUsage(ctx))
+
+	handler, err = tx.Begin(ctx)
+	assert.Nil(t, err)
+	hourly, err := usageRepoFactory.New(handler).ListUsage(ctx, "tenant", model.UsageHourly, time.Time{}, now.Add(time.Hour))
+	assert.Nil(t, err)
+	assert.Len(t, hourly, 1)
+	daily, err := usageRepoFactory.New(handler).ListUsage(ctx, "tenant", model.UsageDaily, time.Time{}, now.Add(time.Hour))
+	assert.Nil(t, err)
+	assert.Len(t, daily, 1, "daily history is kept for longer")
+}
+
+func TestRateLimitService_GetUsageHistory_Validates(t *testing.T) {
+	ctx := context.Background()
+	rateService, _ := newUsageTestService(t, memory.NewUsageRepositoryFactory())
+
+	_, err := rateService.GetUsageHistory(ctx, service.UsageQuery{Key: "tenant", Granularity: "minute"})
+	assert.Equal(t, domain.ErrInvalidUsageGranularity, err)
+
+	now := time.Now()
+	_, err = rateService.GetUsageHistory(ctx, service.UsageQuery{Key: "tenant", From: now, To: now.Add(-time.Hour)})
+	assert.Equal(t, domain.ErrInvalidUsageRange, err)
+	_, err = rateService.GetUsageHistory(ctx, service.UsageQuery{Key: "tenant", From: now.Add(-2 * 365 * 24 * time.Hour)})
+	assert.Equal(t, domain.ErrInvalidUsageRange, err)
+
+	_, err = NewRateLimitService(memory.NewUserRateLimitRepositoryFactory(), unavailableCache{}, memory.NewTransactionFactory(memory.NewStore()), time.Second).
+		GetUsageHistory(ctx, service.UsageQuery{Key: "tenant"})
+	assert.Equal(t, domain.ErrUsageHistoryDisabled, err)
+}
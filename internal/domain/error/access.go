@@ -0,0 +1,11 @@
+package domain
+
+import "errors"
+
+var (
+	ErrAccessListsDisabled = errors.New("ACCESS_LISTS_DISABLED: Allowlists and denylists are not enabled")
+	ErrAccessRuleNotFound  = errors.New("ACCESS_RULE_NOT_FOUND: The access rule does not exist")
+	ErrInvalidAccessRule   = errors.New("INVALID_ACCESS_RULE: A rule needs an action of allow or deny, a match of exact, prefix or cidr, and a pattern")
+	ErrInvalidCIDR         = errors.New("INVALID_CIDR: The pattern of a cidr rule must be a network such as 10.0.0.0/8")
+	ErrAccessRuleExpired   = errors.New("ACCESS_RULE_EXPIRED: The expiry of a rule must be in the future")
+)
@@ -0,0 +1,8 @@
+package domain
+
+import "errors"
+
+var (
+	ErrAuditLogDisabled      = errors.New("AUDIT_LOG_DISABLED: The audit log is not enabled")
+	ErrInvalidAuditEventType = errors.New("INVALID_AUDIT_EVENT_TYPE: Unknown audit event type")
+)
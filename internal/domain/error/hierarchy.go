@@ -0,0 +1,11 @@
+package domain
+
+import "errors"
+
+var (
+	ErrHierarchyDisabled = errors.New("HIERARCHY_DISABLED: Hierarchical limits are not enabled")
+	ErrParentNotFound    = errors.New("PARENT_NOT_FOUND: The key has no parent")
+	ErrInvalidParent     = errors.New("INVALID_PARENT: A parent must be a key other than the key itself")
+	ErrParentCycle       = errors.New("PARENT_CYCLE: A key cannot be an ancestor of itself")
+	ErrHierarchyTooDeep  = errors.New("HIERARCHY_TOO_DEEP: A key can have at most 8 ancestors")
+)
@@ -0,0 +1,8 @@
+package domain
+
+import "errors"
+
+var (
+	ErrLeaseNotFound     = errors.New("LEASE_NOT_FOUND: Lease not found or already expired")
+	ErrInvalidLeaseUnits = errors.New("INVALID_LEASE_UNITS: Lease units must be positive")
+)
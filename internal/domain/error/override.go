@@ -0,0 +1,11 @@
+package domain
+
+import "errors"
+
+var (
+	ErrOverridesDisabled         = errors.New("OVERRIDES_DISABLED: The global override is not enabled")
+	ErrOverrideNotFound          = errors.New("OVERRIDE_NOT_FOUND: No global override is set")
+	ErrInvalidOverrideMode       = errors.New("INVALID_OVERRIDE_MODE: The mode must be allow_all, deny_all or multiplier")
+	ErrInvalidOverrideMultiplier = errors.New("INVALID_OVERRIDE_MULTIPLIER: The multiplier must be greater than zero and at most 1000")
+	ErrInvalidOverrideDuration   = errors.New("INVALID_OVERRIDE_DURATION: An override must last longer than zero and at most a day")
+)
@@ -0,0 +1,12 @@
+package domain
+
+import "errors"
+
+var (
+	ErrPoliciesDisabled      = errors.New("POLICIES_DISABLED: Rate limit policies are not enabled")
+	ErrPolicyNotFound        = errors.New("POLICY_NOT_FOUND: Rate limit policy not found")
+	ErrEmptyPolicy           = errors.New("EMPTY_POLICY: A policy needs at least one limit")
+	ErrInvalidPolicyLimit    = errors.New("INVALID_POLICY_LIMIT: Every limit of a policy must be positive")
+	ErrInvalidPolicyWindow   = errors.New("INVALID_POLICY_WINDOW: Windows must be positive and at most a day")
+	ErrDuplicatePolicyWindow = errors.New("DUPLICATE_POLICY_WINDOW: A policy has at most one limit per window")
+)
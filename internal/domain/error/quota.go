@@ -0,0 +1,12 @@
+package domain
+
+import "errors"
+
+var (
+	ErrQuotasDisabled       = errors.New("QUOTAS_DISABLED: Quotas are not enabled")
+	ErrQuotaNotFound        = errors.New("QUOTA_NOT_FOUND: Quota not found")
+	ErrInvalidQuotaPeriod   = errors.New("INVALID_QUOTA_PERIOD: Period must be day, week or month")
+	ErrInvalidQuotaLimit    = errors.New("INVALID_QUOTA_LIMIT: Quota limit must be positive")
+	ErrInvalidQuotaTimeZone = errors.New("INVALID_QUOTA_TIME_ZONE: Unknown time zone")
+	ErrInvalidQuotaResetDay = errors.New("INVALID_QUOTA_RESET_DAY: Reset day must be 1-7 for weekly and 1-31 for monthly quotas")
+)
@@ -0,0 +1,8 @@
+package domain
+
+import "errors"
+
+var (
+	ErrRateLimitConflict = errors.New("RATE_LIMIT_CONFLICT: Rate limit was changed by a concurrent update")
+	ErrInvalidWindow     = errors.New("INVALID_WINDOW: Windows must be positive and at most the longest window allowed")
+)
@@ -0,0 +1,12 @@
+package domain
+
+import "errors"
+
+var (
+	ErrTiersDisabled       = errors.New("TIERS_DISABLED: Tiers are not enabled")
+	ErrTierNotFound        = errors.New("TIER_NOT_FOUND: The tier does not exist")
+	ErrInvalidTier         = errors.New("INVALID_TIER: A tier needs a name and a limit greater than zero")
+	ErrTierInUse           = errors.New("TIER_IN_USE: Roles or plans are still mapped to the tier")
+	ErrTierMappingNotFound = errors.New("TIER_MAPPING_NOT_FOUND: The role or plan is not mapped to a tier")
+	ErrInvalidTierMapping  = errors.New("INVALID_TIER_MAPPING: A mapping needs a role or plan and a tier")
+)
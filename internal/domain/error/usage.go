@@ -0,0 +1,9 @@
+package domain
+
+import "errors"
+
+var (
+	ErrUsageHistoryDisabled    = errors.New("USAGE_HISTORY_DISABLED: Usage history is not enabled")
+	ErrInvalidUsageGranularity = errors.New("INVALID_USAGE_GRANULARITY: Granularity must be hour or day")
+	ErrInvalidUsageRange       = errors.New("INVALID_USAGE_RANGE: The range must end after it starts and span at most 10000 buckets")
+)
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// AccessAction is what an access rule does with the requests of the keys it matches.
+type AccessAction string
+
+const (
+	// AccessAllow exempts the keys from every limit.
+	AccessAllow AccessAction = "allow"
+	// AccessDeny blocks every request of the keys.
+	AccessDeny AccessAction = "deny"
+)
+
+// AccessMatch is how an access rule's pattern matches keys.
+type AccessMatch string
+
+const (
+	// MatchExact matches the key equal to the pattern.
+	MatchExact AccessMatch = "exact"
+	// MatchPrefix matches the keys starting with the pattern.
+	MatchPrefix AccessMatch = "prefix"
+	// MatchCIDR matches the keys that are IP addresses within the pattern's network.
+	MatchCIDR AccessMatch = "cidr"
+)
+
+// AccessRule is an entry of the allowlist or denylist, consulted before a request is counted.
+// There is at most one rule per match and pattern.
+type AccessRule struct {
+	Id        string       `json:"id"`
+	Action    AccessAction `json:"action"`
+	Match     AccessMatch  `json:"match"`
+	Pattern   string       `json:"pattern"`
+	Reason    string       `json:"reason"`
+	ExpiresAt time.Time    `json:"expiresAt"` // Zero if the rule never expires
+	CreatedAt time.Time    `json:"createdAt"`
+	UpdatedAt time.Time    `json:"updatedAt"`
+}
+
+// Expired reports whether the rule no longer applies at now.
+func (r AccessRule) Expired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && !now.Before(r.ExpiresAt)
+}
@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+// AuditEventType names what an audit event records.
+type AuditEventType string
+
+const (
+	// AuditLimitChanged records a change of a user's configured rate limit.
+	AuditLimitChanged AuditEventType = "limit_changed"
+	// AuditRequestDenied records a sampled request that was denied.
+	AuditRequestDenied AuditEventType = "request_denied"
+	// AuditShadowDenied records a sampled request that was allowed but that a shadow limit would
+	// have denied.
+	AuditShadowDenied AuditEventType = "shadow_denied"
+	// AuditQuotaChanged records a long-period quota being set or deleted.
+	AuditQuotaChanged AuditEventType = "quota_changed"
+	// AuditPolicyChanged records the limits of a key's rate limit policy being set or deleted.
+	AuditPolicyChanged AuditEventType = "policy_changed"
+	// AuditParentChanged records the parent of a key being set or deleted.
+	AuditParentChanged AuditEventType = "parent_changed"
+	// AuditTierChanged records a tier's limit, or the tier a role or plan is mapped to, being set
+	// or deleted.
+	AuditTierChanged AuditEventType = "tier_changed"
+	// AuditAccessChanged records a rule of the allowlist or denylist being set or deleted.
+	AuditAccessChanged AuditEventType = "access_changed"
+	// AuditOverrideChanged records the global override being set or cleared.
+	AuditOverrideChanged AuditEventType = "override_changed"
+)
+
+// AuditEvent is an entry of the append-only audit log.
+type AuditEvent struct {
+	Id        string         `json:"id"`
+	Key       string         `json:"key"`   // The rate limited key the event is about, e.g. a user ID
+	Actor     string         `json:"actor"` // Who caused the event, empty when unknown
+	Type      AuditEventType `json:"type"`
+	OldValue  string         `json:"oldValue"`
+	NewValue  string         `json:"newValue"`
+	Detail    string         `json:"detail"`
+	CreatedAt time.Time      `json:"createdAt"`
+}
+
+// AuditFilter selects audit events. Zero fields match every event, and Limit caps how many of
+// the matching events are returned.
+type AuditFilter struct {
+	Key   string
+	Actor string
+	Type  AuditEventType
+	From  time.Time // Inclusive
+	To    time.Time // Exclusive
+	Limit int
+}
+
+// Matches reports whether the event passes the filter, ignoring Limit.
+func (f AuditFilter) Matches(event AuditEvent) bool {
+	return (f.Key == "" || event.Key == f.Key) &&
+		(f.Actor == "" || event.Actor == f.Actor) &&
+		(f.Type == "" || event.Type == f.Type) &&
+		(f.From.IsZero() || !event.CreatedAt.Before(f.From)) &&
+		(f.To.IsZero() || event.CreatedAt.Before(f.To))
+}
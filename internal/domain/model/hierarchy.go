@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// KeyParent links a rate limited key to the key of the pool it draws from, such as a user to
+// their team or a team to its organization. Requests counted against a key are also counted
+// against the policies of all of its ancestors.
+type KeyParent struct {
+	Key       string    `json:"key"`
+	Parent    string    `json:"parent"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// QuotaLease represents a chunk of a user's quota handed out to a client to spend locally.
+type QuotaLease struct {
+	Id          string    `json:"id"`
+	UserId      string    `json:"userId"`
+	Units       int       `json:"units"`
+	WindowStart time.Time `json:"windowStart"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	// Pools are the ancestors of the user the units were also counted against, and LeasedAt when.
+	Pools    []string  `json:"pools,omitempty"`
+	LeasedAt time.Time `json:"leasedAt"`
+	// Returned is set once the unused units have been handed back, a lease is returned only once.
+	Returned bool `json:"returned,omitempty"`
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+// OverrideMode is how the global override replaces the normal limiting of every key.
+type OverrideMode string
+
+const (
+	// OverrideAllowAll allows every request without counting it.
+	OverrideAllowAll OverrideMode = "allow_all"
+	// OverrideDenyAll denies every request without counting it.
+	OverrideDenyAll OverrideMode = "deny_all"
+	// OverrideMultiplier counts requests as usual against every limit multiplied by Multiplier.
+	OverrideMultiplier OverrideMode = "multiplier"
+)
+
+// Override is the global emergency override, which applies to every key until it expires.
+type Override struct {
+	Mode       OverrideMode `json:"mode"`
+	Multiplier float64      `json:"multiplier,omitempty"` // What every limit is multiplied by in multiplier mode
+	Reason     string       `json:"reason"`
+	ExpiresAt  time.Time    `json:"expiresAt"`
+	CreatedAt  time.Time    `json:"createdAt"`
+	UpdatedAt  time.Time    `json:"updatedAt"`
+}
+
+// Active reports whether the override still applies at now.
+func (o Override) Active(now time.Time) bool {
+	return now.Before(o.ExpiresAt)
+}
+
+// Scale returns the limit the override leaves of a limit: the limit multiplied by the multiplier
+// in multiplier mode, but never less than one request nor more than fits an int64, and the limit
+// itself otherwise.
+func (o Override) Scale(limit int64) int64 {
+	if o.Mode != OverrideMultiplier || limit <= 0 {
+		return limit
+	}
+	scaled := math.Floor(float64(limit) * o.Multiplier)
+	if scaled >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	if scaled < 1 {
+		return 1
+	}
+	return int64(scaled)
+}
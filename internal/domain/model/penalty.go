@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// Penalty is a temporary ban of a key that kept sending requests after it was denied.
+type Penalty struct {
+	Level int       `json:"level"` // How many bans in a row the key has earned, starting at 1
+	Until time.Time `json:"until"` // When the ban ends
+}
+
+// Active reports whether the key is still banned at now.
+func (p Penalty) Active(now time.Time) bool {
+	return now.Before(p.Until)
+}
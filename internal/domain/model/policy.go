@@ -0,0 +1,52 @@
+package model
+
+import "time"
+
+// PolicyLimit is one of the limits of a key's rate limit policy, allowing Limit requests per
+// Window. The limits of a policy all apply together, so a request is allowed only if every one
+// of them has room for it. Windows are fixed: they start with the first request counted after the
+// previous one ended. A shadow limit is counted like the others but never denies a request, so a
+// new limit can be tried out on real traffic before it is enforced.
+type PolicyLimit struct {
+	Id     string        `json:"id"`
+	Key    string        `json:"key"`
+	Window time.Duration `json:"window"`
+	Limit  int           `json:"limit"`
+	Shadow bool          `json:"shadow,omitempty"`
+	// Count is the number of requests counted in the window starting at WindowStart.
+	Count       int       `json:"count"`
+	WindowStart time.Time `json:"windowStart"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// CountAt returns how many requests the limit counted in the window current at now.
+func (l PolicyLimit) CountAt(now time.Time) int {
+	if now.Sub(l.WindowStart) >= l.Window {
+		return 0
+	}
+	return l.Count
+}
+
+// TakePolicy grants up to units requests if every limit that is not a shadow limit has room for
+// them and counts the granted requests against all of the limits, starting new windows where the
+// old ones ended. A limit without room denies the request without anything being counted against
+// the others. Shadow limits may count more than their limit.
+func TakePolicy(limits []PolicyLimit, units int, now time.Time) int {
+	granted := units
+	for i := range limits {
+		if now.Sub(limits[i].WindowStart) >= limits[i].Window {
+			limits[i].Count, limits[i].WindowStart = 0, now
+		}
+		if remaining := limits[i].Limit - limits[i].Count; !limits[i].Shadow && remaining < granted {
+			granted = remaining
+		}
+	}
+	if granted <= 0 {
+		return 0
+	}
+	for i := range limits {
+		limits[i].Count += granted
+	}
+	return granted
+}
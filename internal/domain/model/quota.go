@@ -0,0 +1,94 @@
+package model
+
+import (
+	"time"
+	// Quotas reset at midnight in their own time zone, which must resolve on hosts without a
+	// zoneinfo database too
+	_ "time/tzdata"
+)
+
+// QuotaPeriod is the calendar period a quota counts requests over.
+type QuotaPeriod string
+
+const (
+	QuotaDaily   QuotaPeriod = "day"
+	QuotaWeekly  QuotaPeriod = "week"
+	QuotaMonthly QuotaPeriod = "month"
+)
+
+// Quota caps the requests of a key over a calendar period, on top of its short-term rate limit.
+// Periods start at midnight in TimeZone. ResetDay picks the day weekly quotas reset on, from 1
+// for Monday to 7 for Sunday, and the day of the month monthly quotas reset on, from 1 to 31,
+// where days past the end of a month fall on its last day. Zero means the first day.
+type Quota struct {
+	Id       string      `json:"id"`
+	Key      string      `json:"key"`
+	Period   QuotaPeriod `json:"period"`
+	Limit    int64       `json:"limit"`
+	TimeZone string      `json:"timeZone"`
+	ResetDay int         `json:"resetDay"`
+	// PeriodStart is the start of the period Used counts requests of.
+	PeriodStart time.Time `json:"periodStart"`
+	Used        int64     `json:"used"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Location returns the time zone the quota's periods are aligned to.
+func (q Quota) Location() (*time.Location, error) {
+	if q.TimeZone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(q.TimeZone)
+}
+
+// CurrentPeriod returns the start and end of the period now falls into.
+func (q Quota) CurrentPeriod(now time.Time) (time.Time, time.Time, error) {
+	loc, err := q.Location()
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	now = now.In(loc)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	switch q.Period {
+	case QuotaWeekly:
+		resetDay := q.ResetDay
+		if resetDay == 0 {
+			resetDay = 1
+		}
+		// time.Weekday counts from Sunday, reset days from Monday
+		weekday := (int(now.Weekday())+6)%7 + 1
+		start := midnight.AddDate(0, 0, -((weekday - resetDay + 7) % 7))
+		return start, start.AddDate(0, 0, 7), nil
+	case QuotaMonthly:
+		start := q.monthlyReset(now.Year(), now.Month(), loc)
+		if now.Before(start) {
+			start = q.monthlyReset(now.Year(), now.Month()-1, loc)
+		}
+		return start, q.monthlyReset(start.Year(), start.Month()+1, loc), nil
+	default:
+		return midnight, midnight.AddDate(0, 0, 1), nil
+	}
+}
+
+// monthlyReset returns when the quota resets in the given month.
+func (q Quota) monthlyReset(year int, month time.Month, loc *time.Location) time.Time {
+	day := q.ResetDay
+	if day == 0 {
+		day = 1
+	}
+	// Day zero of the next month is the last day of this one
+	if last := time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day(); day > last {
+		day = last
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// UsedIn returns how many requests the quota counted in the period starting at start.
+func (q Quota) UsedIn(start time.Time) int64 {
+	if !q.PeriodStart.Equal(start) {
+		return 0
+	}
+	return q.Used
+}
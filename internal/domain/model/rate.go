@@ -9,4 +9,10 @@ type UserRateLimit struct {
 	RequestCount int       `json:"requestCount"`
 	RateLimit    int       `json:"rateLimit"`
 	Timestamp    time.Time `json:"timestamp"`
+	// Window is the length of the user's window, zero for the service's default window.
+	Window time.Duration `json:"window,omitempty"`
+	// Version is incremented by every stored update, for optimistic concurrency control.
+	Version   int64     `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
 }
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Tier is a named plan, such as free, pro or enterprise, whose limit applies to the users it is
+// mapped to unless they have a limit of their own.
+type Tier struct {
+	Name      string    `json:"name"`
+	RateLimit int       `json:"rateLimit"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TierMapping maps a role ID or plan name to the tier of the users that have it.
+type TierMapping struct {
+	Subject   string    `json:"subject"`
+	Tier      string    `json:"tier"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
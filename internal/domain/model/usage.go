@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// UsageGranularity is the length of the buckets usage history is aggregated into.
+type UsageGranularity string
+
+const (
+	UsageHourly UsageGranularity = "hour"
+	UsageDaily  UsageGranularity = "day"
+)
+
+// Step returns the length of one bucket.
+func (g UsageGranularity) Step() time.Duration {
+	if g == UsageDaily {
+		return 24 * time.Hour
+	}
+	return time.Hour
+}
+
+// Truncate returns the start of the bucket t falls into. Buckets are aligned to UTC.
+func (g UsageGranularity) Truncate(t time.Time) time.Time {
+	return t.UTC().Truncate(g.Step())
+}
+
+// UsageBucket holds how many requests for a key were allowed and denied during one bucket.
+type UsageBucket struct {
+	Key         string           `json:"key"`
+	Granularity UsageGranularity `json:"granularity"`
+	Start       time.Time        `json:"start"`
+	Allowed     int64            `json:"allowed"`
+	Denied      int64            `json:"denied"`
+}
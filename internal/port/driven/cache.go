@@ -32,14 +32,49 @@ type (
 		Delete(ctx context.Context, key string) error
 	}
 
+	// Updater defines an interface for atomically replacing a value based on its current one.
+	// fn receives nil when the key is missing and may be called again if the value changed
+	// concurrently; returning a nil value leaves the key untouched.
+	Updater interface {
+		Update(ctx context.Context, key string, expiration time.Duration, fn func(current []byte) ([]byte, error)) error
+	}
+
+	// Incrementer defines an interface for atomically adding to an integer counter. A missing
+	// counter starts at zero and expires after expiration.
+	Incrementer interface {
+		Increment(ctx context.Context, key string, delta int64, expiration time.Duration) (int64, error)
+	}
+
 	// Cache combines all the raw cache operations into a single interface.
 	Cache interface {
 		RawSetter
 		RawFetcher
 		Deleter
+		Updater
+		Incrementer
 		Connecter
 		Disconnecter
 	}
+
+	// Publisher defines an interface for broadcasting messages to every subscribed instance.
+	Publisher interface {
+		Publish(ctx context.Context, channel string, message []byte) error
+	}
+
+	// Subscriber defines an interface for receiving broadcast messages. The returned channel is
+	// closed once ctx is done.
+	Subscriber interface {
+		Subscribe(ctx context.Context, channel string) (<-chan []byte, error)
+	}
+
+	// PubSub combines publishing and subscribing into a single interface.
+	PubSub interface {
+		Publisher
+		Subscriber
+	}
 )
 
-var ErrCacheMissed = errors.New("cache missed")
+var (
+	ErrCacheMissed      = errors.New("cache missed")
+	ErrCacheUnavailable = errors.New("cache unavailable")
+)
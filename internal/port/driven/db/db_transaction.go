@@ -2,10 +2,11 @@ package db
 
 import (
 	"context"
+	"database/sql"
 )
 
 type DbTransaction interface {
-	Begin(ctx context.Context) (DbHandler, error)
+	Begin(ctx context.Context, opts ...TxOption) (DbHandler, error)
 	Commit(ctx context.Context) error
 	Rollback(ctx context.Context) error
 	RollbackUnlessCommitted(ctx context.Context)
@@ -14,3 +15,36 @@ type DbTransaction interface {
 type DbTransactionFactory interface {
 	NewTransaction() DbTransaction
 }
+
+// TxOptions configures a transaction. The zero value keeps the database's defaults.
+type TxOptions struct {
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+}
+
+// TxOption sets one of the TxOptions.
+type TxOption func(*TxOptions)
+
+// WithIsolation runs the transaction at the given isolation level. Stores without isolation
+// levels ignore it.
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return func(o *TxOptions) {
+		o.Isolation = level
+	}
+}
+
+// ReadOnly runs a transaction that only reads. Stores without read-only transactions ignore it.
+func ReadOnly() TxOption {
+	return func(o *TxOptions) {
+		o.ReadOnly = true
+	}
+}
+
+// NewTxOptions applies opts to the default options.
+func NewTxOptions(opts ...TxOption) TxOptions {
+	var o TxOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+)
+
+// AccessRuleRepository stores the rules of the allowlist and denylist.
+type AccessRuleRepository interface {
+	// SetAccessRule inserts the rule or replaces the one with its ID.
+	SetAccessRule(ctx context.Context, rule model.AccessRule) error
+	// ListAccessRules returns all rules, expired ones included, ordered by match and pattern.
+	ListAccessRules(context.Context) ([]model.AccessRule, error)
+	// DeleteAccessRule removes the rule with the ID and returns domain.ErrAccessRuleNotFound if
+	// there is none.
+	DeleteAccessRule(context.Context, string) error
+}
+
+type AccessRuleRepositoryFactory interface {
+	New(db.DbHandler) AccessRuleRepository
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+)
+
+// AuditEventRepository stores the audit log. It is append-only: stored events are never changed
+// or removed.
+type AuditEventRepository interface {
+	// AppendEvent stores the event with a generated ID, keeping its CreatedAt if it is set.
+	AppendEvent(context.Context, model.AuditEvent) error
+	// ListEvents returns the events that match the filter, newest first.
+	ListEvents(context.Context, model.AuditFilter) ([]model.AuditEvent, error)
+}
+
+type AuditEventRepositoryFactory interface {
+	New(db.DbHandler) AuditEventRepository
+}
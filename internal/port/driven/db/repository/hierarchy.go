@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+)
+
+// ParentRepository stores the parent of rate limited keys, at most one per key.
+type ParentRepository interface {
+	// SetParent sets the parent of the key, replacing the one it had.
+	SetParent(ctx context.Context, key, parent string) error
+	// GetParent returns the key's parent link, nil if the key has no parent.
+	GetParent(context.Context, string) (*model.KeyParent, error)
+	// ListChildren returns the links of the keys whose parent is the given key, sorted by key.
+	ListChildren(context.Context, string) ([]model.KeyParent, error)
+	// DeleteParent removes the key's parent and returns domain.ErrParentNotFound if there is none.
+	DeleteParent(context.Context, string) error
+}
+
+type ParentRepositoryFactory interface {
+	New(db.DbHandler) ParentRepository
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+)
+
+// OverrideRepository stores the global emergency override.
+type OverrideRepository interface {
+	// SetOverride stores the override, replacing the one set before.
+	SetOverride(ctx context.Context, override model.Override) error
+	// GetOverride returns the override, expired or not, and nil if none is set.
+	GetOverride(context.Context) (*model.Override, error)
+	// DeleteOverride removes the override and returns domain.ErrOverrideNotFound if none is set.
+	DeleteOverride(context.Context) error
+}
+
+type OverrideRepositoryFactory interface {
+	New(db.DbHandler) OverrideRepository
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+)
+
+// PolicyRepository stores the rate limit policies of keys, one limit per key and window, along
+// with the requests counted in each limit's current window.
+type PolicyRepository interface {
+	// SetPolicy replaces the limits of the key's policy. Limits with a window the policy already
+	// had keep what they counted.
+	SetPolicy(ctx context.Context, key string, limits []model.PolicyLimit) error
+	// GetPolicy returns the limits of the key's policy from the shortest window to the longest,
+	// none if the key has no policy.
+	GetPolicy(context.Context, string) ([]model.PolicyLimit, error)
+	// GetPolicyForUpdate is GetPolicy, but keeps concurrent transactions from changing the
+	// limits until this one ends.
+	GetPolicyForUpdate(context.Context, string) ([]model.PolicyLimit, error)
+	// UpdatePolicyCounts stores the counts and window starts of the given limits of the key.
+	UpdatePolicyCounts(ctx context.Context, key string, limits []model.PolicyLimit) error
+	// DeletePolicy removes the key's policy and returns domain.ErrPolicyNotFound if there is none.
+	DeletePolicy(context.Context, string) error
+}
+
+type PolicyRepositoryFactory interface {
+	New(db.DbHandler) PolicyRepository
+}
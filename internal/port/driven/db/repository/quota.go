@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+)
+
+// QuotaRepository stores the long-period quotas of keys, at most one per key and period, along
+// with the requests counted against them.
+type QuotaRepository interface {
+	// SetQuota creates the key's quota for the period, or changes the limit, time zone and
+	// reset day of the existing one, keeping what it counted so far.
+	SetQuota(context.Context, model.Quota) error
+	GetQuotasByKey(context.Context, string) ([]model.Quota, error)
+	// GetQuotasByKeyForUpdate is GetQuotasByKey, but keeps concurrent transactions from changing
+	// the quotas until this one ends.
+	GetQuotasByKeyForUpdate(context.Context, string) ([]model.Quota, error)
+	// AddQuotaUsage adds units, which may be negative, to what the quota counted in the period
+	// starting at periodStart. A quota still counting an earlier period starts over from zero.
+	// The count never drops below zero.
+	AddQuotaUsage(ctx context.Context, key string, period model.QuotaPeriod, periodStart time.Time, units int64) error
+	// DeleteQuota removes the key's quota for the period and returns domain.ErrQuotaNotFound if
+	// there is none.
+	DeleteQuota(context.Context, string, model.QuotaPeriod) error
+}
+
+type QuotaRepositoryFactory interface {
+	New(db.DbHandler) QuotaRepository
+}
@@ -8,8 +8,19 @@ import (
 )
 
 type UserRateLimitRepository interface {
+	// CreateRateLimit stores the user's first rate limit record at version 1 and returns its ID.
+	// It returns domain.ErrRateLimitConflict if the user already has a record, which happens when
+	// a concurrent transaction created it since it was found missing. It is not an upsert on
+	// purpose: the caller decided against a missing record, so overwriting the existing one would
+	// lose what it counted, and returning it would leave a decision made against the wrong count.
+	// The conflict is retryable, and the retried transaction counts against the existing record.
 	CreateRateLimit(context.Context, model.UserRateLimit) (string, error)
 	GetRateLimitByUserId(context.Context, string) (*model.UserRateLimit, error)
+	// GetRateLimitByUserIdForUpdate is GetRateLimitByUserId, but keeps concurrent transactions
+	// from changing the record until this one ends.
+	GetRateLimitByUserIdForUpdate(context.Context, string) (*model.UserRateLimit, error)
+	// UpdateRateLimit stores the record only if its stored version still equals the given one,
+	// incrementing the version, and returns domain.ErrRateLimitConflict otherwise.
 	UpdateRateLimit(context.Context, model.UserRateLimit) error
 	DeleteRateLimit(context.Context, string) error
 }
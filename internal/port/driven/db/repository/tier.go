@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+)
+
+// TierRepository stores tiers and the mappings of roles and plans to them.
+type TierRepository interface {
+	// SetTier inserts or changes the tier with the name of the given one.
+	SetTier(ctx context.Context, tier model.Tier) error
+	// GetTier returns the tier with the name, nil if there is none.
+	GetTier(context.Context, string) (*model.Tier, error)
+	// ListTiers returns all tiers ordered by name.
+	ListTiers(context.Context) ([]model.Tier, error)
+	// DeleteTier removes the tier with the name and returns domain.ErrTierNotFound if there is none.
+	DeleteTier(context.Context, string) error
+	// SetTierMapping maps the role or plan to the tier, replacing the tier it was mapped to.
+	SetTierMapping(ctx context.Context, subject, tier string) error
+	// ListTierMappings returns all mappings ordered by role or plan.
+	ListTierMappings(context.Context) ([]model.TierMapping, error)
+	// DeleteTierMapping removes the mapping of the role or plan and returns
+	// domain.ErrTierMappingNotFound if there is none.
+	DeleteTierMapping(context.Context, string) error
+	// GetTierFor returns the tier the role or plan is mapped to, nil if it is mapped to none.
+	GetTierFor(context.Context, string) (*model.Tier, error)
+}
+
+type TierRepositoryFactory interface {
+	New(db.DbHandler) TierRepository
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/nullexp/limiter-x/internal/domain/model"
+	"github.com/nullexp/limiter-x/internal/port/driven/db"
+)
+
+// UsageRepository stores usage history as counts per key and bucket.
+type UsageRepository interface {
+	// AddUsage adds the bucket's counts to the stored bucket with the same key, granularity and
+	// start, creating it if there is none.
+	AddUsage(context.Context, model.UsageBucket) error
+	// ListUsage returns the key's buckets of the granularity that start within [from, to),
+	// oldest first.
+	ListUsage(ctx context.Context, key string, granularity model.UsageGranularity, from, to time.Time) ([]model.UsageBucket, error)
+	// DeleteUsageBefore removes the buckets of the granularity that start before the given time.
+	DeleteUsageBefore(context.Context, model.UsageGranularity, time.Time) error
+}
+
+type UsageRepositoryFactory interface {
+	New(db.DbHandler) UsageRepository
+}
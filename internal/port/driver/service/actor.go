@@ -0,0 +1,16 @@
+package service
+
+import "context"
+
+type actorKey struct{}
+
+// ContextWithActor returns a copy of ctx carrying who makes the call, for the audit log.
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor carried by ctx, or an empty string if there is none.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"time"
 )
 
 // RateLimiter defines the interface for rate-limiting service operations
@@ -9,17 +10,220 @@ type RateLimiter interface {
 	// RateLimit checks if a request is allowed for a specific user based on the rate limit
 	RateLimit(ctx context.Context, userId string, limit int) (bool, error)
 
+	// Check counts a request against every limit of a user and reports the limit that decided it
+	Check(ctx context.Context, request CheckRequest) (*DecisionModel, error)
+
 	// GetUserRateLimit fetches the current rate limit configuration for a specific user
 	GetUserRateLimit(ctx context.Context, userId string) (*RateLimitModel, error)
 
-	// UpdateUserRateLimit updates the rate limit for a specific user
-	UpdateUserRateLimit(ctx context.Context, userId string, newLimit int) error
+	// UpdateUserRateLimit updates the rate limit for a specific user, and the user's window unless it is zero
+	UpdateUserRateLimit(ctx context.Context, userId string, newLimit int, window time.Duration) error
+
+	// LeaseQuota reserves up to units requests of the user's quota for a client to spend locally
+	LeaseQuota(ctx context.Context, userId string, units, limit int, role string) (*LeaseModel, error)
+
+	// ReturnQuota credits the unused units of a lease back to the user's quota
+	ReturnQuota(ctx context.Context, leaseId string, unused int) (int, error)
+
+	// ListAuditEvents returns the audit log entries matching the query, newest first
+	ListAuditEvents(ctx context.Context, query AuditQuery) ([]AuditEventModel, error)
+
+	// GetUsageHistory returns a key's allowed and denied requests per hour or day
+	GetUsageHistory(ctx context.Context, query UsageQuery) ([]UsagePointModel, error)
+
+	// SetQuota creates or changes the long-period quota of a key
+	SetQuota(ctx context.Context, quota QuotaModel) error
+
+	// DeleteQuota removes the quota of a key for a period
+	DeleteQuota(ctx context.Context, key, period string) error
+
+	// GetQuotas returns the quotas of a key with their usage in the current period
+	GetQuotas(ctx context.Context, key string) ([]QuotaModel, error)
+
+	// SetPolicy replaces the limits that all apply to a key, one per window
+	SetPolicy(ctx context.Context, key string, limits []LimitModel) error
+
+	// GetPolicy returns the limits of a key's policy with what is left of them
+	GetPolicy(ctx context.Context, key string) ([]LimitModel, error)
+
+	// DeletePolicy removes a key's policy
+	DeletePolicy(ctx context.Context, key string) error
+
+	// SetParent makes parent the parent of a key, whose requests then also count against the policies of its ancestors
+	SetParent(ctx context.Context, key, parent string) error
+
+	// DeleteParent removes the parent of a key
+	DeleteParent(ctx context.Context, key string) error
+
+	// GetHierarchy returns the ancestors and children of a key
+	GetHierarchy(ctx context.Context, key string) (*HierarchyModel, error)
+
+	// SetTier creates a tier or changes its limit
+	SetTier(ctx context.Context, name string, limit int) error
+
+	// DeleteTier removes a tier no role or plan is mapped to
+	DeleteTier(ctx context.Context, name string) error
+
+	// ListTiers returns all tiers with the roles and plans mapped to them
+	ListTiers(ctx context.Context) ([]TierModel, error)
+
+	// SetTierMapping maps a role ID or plan name to a tier
+	SetTierMapping(ctx context.Context, subject, tier string) error
+
+	// DeleteTierMapping removes the mapping of a role ID or plan name
+	DeleteTierMapping(ctx context.Context, subject string) error
+
+	// SetAccessRule adds a rule to the allowlist or denylist, replacing the one with the same match and pattern, and returns its ID
+	SetAccessRule(ctx context.Context, rule AccessRuleModel) (string, error)
+
+	// DeleteAccessRule removes a rule from the allowlist or denylist
+	DeleteAccessRule(ctx context.Context, id string) error
+
+	// ListAccessRules returns the rules of the allowlist and denylist that have not expired
+	ListAccessRules(ctx context.Context) ([]AccessRuleModel, error)
+
+	// SetOverride puts every key under a global emergency override for its duration and returns it with its expiry
+	SetOverride(ctx context.Context, override OverrideModel) (*OverrideModel, error)
+
+	// ClearOverride ends the global override before it expires
+	ClearOverride(ctx context.Context) error
+
+	// GetOverride returns the global override in effect
+	GetOverride(ctx context.Context) (*OverrideModel, error)
 }
 
 // RateLimitModel holds the rate limit configuration for a user
 type RateLimitModel struct {
 	UserId    string // The ID of the user
-	Limit     int    // The rate limit for the user (e.g., 100 requests per second)
+	Limit     int    // The user's own rate limit (e.g., 100 requests per second), zero if the user follows their tier or the default
 	Remaining int    // The remaining number of requests the user can make in the current window
 	Window    string // The time window for the rate limit (e.g., "10 seconds")
 }
+
+// CheckRequest describes a request to count against the limits of a user
+type CheckRequest struct {
+	UserId string        // The ID of the user
+	Limit  int           // The limit to apply instead of the user's own, zero for the user's own
+	Role   string        // The role ID or plan of the user, whose tier applies if the user has no limit of their own
+	Window time.Duration // The window to apply instead of the user's own, zero for the user's own; unused for keys with a policy
+}
+
+// DecisionModel is the outcome of a check
+type DecisionModel struct {
+	Allowed bool        // Whether the request is allowed
+	Binding *LimitModel // The limit that denied the request, or the one with the least room left; nil if none decided it
+	Reason  string      // Why the request was decided without counting it, empty if it was counted
+	// PenalizedUntil is when the ban of a key denied for its penalty ends
+	PenalizedUntil time.Time
+	// Shadow is the shadow limit that would have denied the allowed request, nil if none would have
+	Shadow *LimitModel
+}
+
+// Reasons a request was decided without counting it
+const (
+	ReasonAllowlisted = "allowlisted" // An allow rule matched the key
+	ReasonDenylisted  = "denylisted"  // A deny rule matched the key
+	ReasonPenalized   = "penalized"   // The key is banned for being denied too often
+	ReasonAllowAll    = "allow_all"   // The global override allows all requests
+	ReasonDenyAll     = "deny_all"    // The global override denies all requests
+)
+
+// LimitModel describes one of the limits of a key and what is left of it
+type LimitModel struct {
+	Limit     int64         // The number of requests allowed per window or period
+	Window    time.Duration // The length of the window, zero for a quota
+	Period    string        // The calendar period of a quota, empty for a windowed limit
+	Remaining int64         // The number of requests left in the current window or period, negative for a shadow limit counted past its limit
+	ResetsAt  time.Time     // When the current window or period ends
+	Shadow    bool          // Whether the limit is only counted and never denies a request
+}
+
+// LeaseModel describes a chunk of quota leased to a client
+type LeaseModel struct {
+	LeaseId   string    // The ID used to return unused units
+	UserId    string    // The ID of the user the quota belongs to
+	Granted   int       // The number of requests granted, may be less than requested
+	ExpiresAt time.Time // The time after which the lease can no longer be spent
+}
+
+// AuditQuery selects audit log entries, empty fields match every entry
+type AuditQuery struct {
+	Key       string    // The rate limited key, e.g. a user ID
+	Actor     string    // Who caused the event
+	EventType string    // "limit_changed", "request_denied", "quota_changed", "policy_changed", "parent_changed" or "tier_changed"
+	From      time.Time // Only events at or after this time
+	To        time.Time // Only events before this time
+	Limit     int       // The maximum number of events to return
+}
+
+// AuditEventModel describes an entry of the audit log
+type AuditEventModel struct {
+	Id        string    // The ID of the entry
+	Key       string    // The rate limited key the event is about
+	Actor     string    // Who caused the event, empty when unknown
+	EventType string    // What happened
+	OldValue  string    // The value before a change
+	NewValue  string    // The value after a change
+	Detail    string    // Free-form context, e.g. the count a request was denied at
+	CreatedAt time.Time // When the event happened
+}
+
+// UsageQuery selects the usage history of a key
+type UsageQuery struct {
+	Key         string    // The rate limited key, e.g. a user ID
+	Granularity string    // "hour" (default) or "day"
+	From        time.Time // Start of the range, defaults to a day or 30 days before To
+	To          time.Time // End of the range, defaults to now
+}
+
+// UsagePointModel holds a key's usage during one bucket of its history
+type UsagePointModel struct {
+	Start   time.Time // The start of the bucket
+	Allowed int64     // The number of requests allowed
+	Denied  int64     // The number of requests denied
+}
+
+// QuotaModel describes a long-period quota of a key
+type QuotaModel struct {
+	Key       string    // The rate limited key, e.g. a user ID
+	Period    string    // "day", "week" or "month"
+	Limit     int64     // The number of requests allowed per period
+	TimeZone  string    // The IANA time zone the periods are aligned to, defaults to UTC
+	ResetDay  int       // The weekday (1 is Monday) or day of month the period starts on, defaults to 1
+	Used      int64     // The number of requests counted in the current period
+	Remaining int64     // The number of requests left in the current period
+	ResetsAt  time.Time // When the current period ends
+}
+
+// HierarchyModel describes where a key sits in the hierarchy of pools
+type HierarchyModel struct {
+	Key       string   // The rate limited key, e.g. a user ID
+	Ancestors []string // The parent of the key, its parent and so on up to the root
+	Children  []string // The keys whose parent is the key
+}
+
+// TierModel describes a tier and the roles and plans mapped to it
+type TierModel struct {
+	Name     string   // The name of the tier, e.g. "pro"
+	Limit    int      // The limit of users with no limit of their own whose role or plan is mapped to the tier
+	Subjects []string // The role IDs and plan names mapped to the tier
+}
+
+// AccessRuleModel describes a rule of the allowlist or denylist
+type AccessRuleModel struct {
+	Id        string    // The ID of the rule
+	Action    string    // "allow" or "deny"
+	Match     string    // "exact", "prefix" or "cidr" for keys that are IP addresses
+	Pattern   string    // The key, key prefix or network, e.g. 10.0.0.0/8
+	Reason    string    // Why the rule was added
+	ExpiresAt time.Time // When the rule stops applying, zero if never
+}
+
+// OverrideModel describes the global emergency override
+type OverrideModel struct {
+	Mode       string        // "allow_all", "deny_all" or "multiplier"
+	Multiplier float64       // What every limit is multiplied by in multiplier mode
+	Reason     string        // Why the override was set
+	Duration   time.Duration // How long the override lasts when it is set
+	ExpiresAt  time.Time     // When the override ends
+}
@@ -0,0 +1,156 @@
+// Package client is a Go client for the limiter-x gRPC API. Besides plain remote checks it can
+// lease chunks of quota for hot keys and spend them locally, cutting one round trip per request
+// down to one per lease.
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	ratev1 "github.com/nullexp/limiter-x/internal/adapter/driver/grpc/proto/rate/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultLeaseSize = 50
+
+// Client checks rate limits against a limiter-x server.
+type Client struct {
+	rpc       ratev1.RateLimiterServiceClient
+	leaseSize int
+
+	mu     sync.Mutex
+	leases map[string]*lease
+}
+
+// lease is the locally held part of a user's quota.
+type lease struct {
+	mu         sync.Mutex
+	id         string
+	remaining  int
+	expiresAt  time.Time
+	returnFrom time.Time
+}
+
+// Option configures optional behaviour of a Client.
+type Option func(*Client)
+
+// WithLeaseSize sets how many requests AllowLeased leases from the server at a time.
+func WithLeaseSize(units int) Option {
+	return func(c *Client) {
+		c.leaseSize = units
+	}
+}
+
+// New creates a client on top of an established gRPC connection.
+func New(conn grpc.ClientConnInterface, opts ...Option) *Client {
+	c := &Client{
+		rpc:       ratev1.NewRateLimiterServiceClient(conn),
+		leaseSize: defaultLeaseSize,
+		leases:    make(map[string]*lease),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Allow asks the server whether a single request for the user is allowed.
+func (c *Client) Allow(ctx context.Context, userId string) (bool, error) {
+	resp, err := c.rpc.CheckRateLimit(ctx, &ratev1.CheckRateLimitRequest{UserId: userId})
+	if err != nil {
+		return false, err
+	}
+	return resp.Allowed, nil
+}
+
+// AllowLeased decides locally whether a request for the user is allowed, spending quota leased
+// from the server. A new lease is taken once the current one is spent or close to expiring, and
+// the unused part of the old one is returned first. When the server grants nothing, requests are
+// denied locally until that empty lease expires.
+func (c *Client) AllowLeased(ctx context.Context, userId string) (bool, error) {
+	l := c.lease(userId)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(l.returnFrom) {
+		if l.remaining > 0 {
+			l.remaining--
+			return true, nil
+		}
+		if l.id == "" {
+			// The server had nothing left to lease
+			return false, nil
+		}
+	}
+
+	if err := c.release(ctx, l); err != nil {
+		return false, err
+	}
+
+	resp, err := c.rpc.LeaseQuota(ctx, &ratev1.LeaseQuotaRequest{UserId: userId, Units: int32(c.leaseSize)})
+	if err != nil {
+		return false, err
+	}
+	l.id = resp.LeaseId
+	l.remaining = int(resp.Granted)
+	l.expiresAt = time.UnixMilli(resp.ExpiresAt)
+	// Hand leftovers back a little before the server voids the lease
+	l.returnFrom = l.expiresAt.Add(-time.Until(l.expiresAt) / 10)
+
+	if l.remaining == 0 {
+		return false, nil
+	}
+	l.remaining--
+	return true, nil
+}
+
+// Close returns the unused part of every outstanding lease to the server.
+func (c *Client) Close(ctx context.Context) error {
+	c.mu.Lock()
+	leases := c.leases
+	c.leases = make(map[string]*lease)
+	c.mu.Unlock()
+
+	var firstErr error
+	for _, l := range leases {
+		l.mu.Lock()
+		if err := c.release(ctx, l); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		l.mu.Unlock()
+	}
+	return firstErr
+}
+
+// lease returns the local lease state for the user, creating it on first use.
+func (c *Client) lease(userId string) *lease {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l, ok := c.leases[userId]
+	if !ok {
+		l = &lease{}
+		c.leases[userId] = l
+	}
+	return l
+}
+
+// release returns the unused units of l to the server and clears it. The caller must hold l.mu.
+func (c *Client) release(ctx context.Context, l *lease) error {
+	id, unused := l.id, l.remaining
+	l.id, l.remaining = "", 0
+	if id == "" || unused == 0 || time.Now().After(l.expiresAt) {
+		// Expired leases lapse on the server together with their window
+		return nil
+	}
+
+	_, err := c.rpc.ReturnQuota(ctx, &ratev1.ReturnQuotaRequest{LeaseId: id, Unused: int32(unused)})
+	if status.Code(err) == codes.NotFound {
+		return nil
+	}
+	return err
+}
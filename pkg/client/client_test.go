@@ -0,0 +1,176 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	ratev1 "github.com/nullexp/limiter-x/internal/adapter/driver/grpc/proto/rate/v1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeRPC is a server that hands out leases from a fixed number of available requests.
+type fakeRPC struct {
+	ratev1.RateLimiterServiceClient
+
+	mu        sync.Mutex
+	available int
+	ttl       time.Duration
+	leased    int
+	returned  int
+	outgoing  map[string]int
+	returnErr error
+}
+
+func newFakeRPC(available int) *fakeRPC {
+	return &fakeRPC{available: available, ttl: time.Minute, outgoing: make(map[string]int)}
+}
+
+func (f *fakeRPC) CheckRateLimit(ctx context.Context, in *ratev1.CheckRateLimitRequest, opts ...grpc.CallOption) (*ratev1.CheckRateLimitResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.available == 0 {
+		return &ratev1.CheckRateLimitResponse{}, nil
+	}
+	f.available--
+	return &ratev1.CheckRateLimitResponse{Allowed: true}, nil
+}
+
+func (f *fakeRPC) LeaseQuota(ctx context.Context, in *ratev1.LeaseQuotaRequest, opts ...grpc.CallOption) (*ratev1.LeaseQuotaResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	granted := min(int(in.Units), f.available)
+	f.available -= granted
+	f.leased++
+	resp := &ratev1.LeaseQuotaResponse{Granted: int32(granted), ExpiresAt: time.Now().Add(f.ttl).UnixMilli()}
+	if granted > 0 {
+		resp.LeaseId = fmt.Sprintf("lease-%d", f.leased)
+		f.outgoing[resp.LeaseId] = granted
+	}
+	return resp, nil
+}
+
+func (f *fakeRPC) ReturnQuota(ctx context.Context, in *ratev1.ReturnQuotaRequest, opts ...grpc.CallOption) (*ratev1.ReturnQuotaResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.returnErr != nil {
+		return nil, f.returnErr
+	}
+	units, ok := f.outgoing[in.LeaseId]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "lease not found")
+	}
+	delete(f.outgoing, in.LeaseId)
+	unused := min(int(in.Unused), units)
+	f.available += unused
+	f.returned += unused
+	return &ratev1.ReturnQuotaResponse{Returned: int32(unused)}, nil
+}
+
+func newTestClient(rpc *fakeRPC, opts ...Option) *Client {
+	c := New(nil, opts...)
+	c.rpc = rpc
+	return c
+}
+
+func TestClient_Allow(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(newFakeRPC(1))
+
+	allowed, err := client.Allow(ctx, "user")
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+
+	allowed, err = client.Allow(ctx, "user")
+	assert.Nil(t, err)
+	assert.False(t, allowed)
+}
+
+func TestClient_AllowLeased(t *testing.T) {
+	ctx := context.Background()
+	rpc := newFakeRPC(25)
+	client := newTestClient(rpc, WithLeaseSize(10))
+
+	// Requests are decided locally until the lease is spent
+	for i := 0; i < 10; i++ {
+		allowed, err := client.AllowLeased(ctx, "user")
+		assert.Nil(t, err)
+		assert.True(t, allowed)
+	}
+	assert.Equal(t, 1, rpc.leased)
+
+	// The next lease gets only what the server has left
+	for i := 0; i < 15; i++ {
+		allowed, err := client.AllowLeased(ctx, "user")
+		assert.Nil(t, err)
+		assert.True(t, allowed)
+	}
+	assert.Equal(t, 3, rpc.leased)
+
+	// An empty lease denies locally without asking the server again
+	allowed, err := client.AllowLeased(ctx, "user")
+	assert.Nil(t, err)
+	assert.False(t, allowed)
+	allowed, err = client.AllowLeased(ctx, "user")
+	assert.Nil(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 4, rpc.leased)
+}
+
+func TestClient_AllowLeased_RenewsExpiringLease(t *testing.T) {
+	ctx := context.Background()
+	rpc := newFakeRPC(100)
+	rpc.ttl = time.Second
+	client := newTestClient(rpc, WithLeaseSize(10))
+
+	allowed, err := client.AllowLeased(ctx, "user")
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+
+	// Close to expiring, the leftovers are handed back before a new lease is taken
+	time.Sleep(950 * time.Millisecond)
+	allowed, err = client.AllowLeased(ctx, "user")
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, 2, rpc.leased)
+	assert.Equal(t, 9, rpc.returned)
+}
+
+func TestClient_Close(t *testing.T) {
+	ctx := context.Background()
+	rpc := newFakeRPC(100)
+	client := newTestClient(rpc, WithLeaseSize(10))
+
+	for _, userId := range []string{"a", "b"} {
+		allowed, err := client.AllowLeased(ctx, userId)
+		assert.Nil(t, err)
+		assert.True(t, allowed)
+	}
+
+	// Unused units of every lease go back to the server
+	assert.Nil(t, client.Close(ctx))
+	assert.Equal(t, 18, rpc.returned)
+	assert.Equal(t, 98, rpc.available)
+
+	// Leases the server no longer knows are not an error
+	allowed, err := client.AllowLeased(ctx, "a")
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+	rpc.outgoing = make(map[string]int)
+	assert.Nil(t, client.Close(ctx))
+
+	// Other failures are reported
+	allowed, err = client.AllowLeased(ctx, "a")
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+	rpc.returnErr = status.Error(codes.Unavailable, "unavailable")
+	assert.ErrorIs(t, client.Close(ctx), rpc.returnErr)
+}